@@ -0,0 +1,386 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// aggregate : Hit counts collected from analyzed lines, reports are built from
+// them. Aggregates of different sources can be merged into overall totals.
+type aggregate struct {
+	ipHits  map[string]int
+	urlHits urlTrie
+	// overflowedURLs : Hits counted under OverflowURL once MaxURLs was reached
+	overflowedURLs int
+	networkHits    map[string]int
+	// decayedIPs, decayedURLs : Decayed hits per IP and URL, when a followed
+	// log ranks them by recent activity, nil otherwise
+	decayedIPs  *decayedHits
+	decayedURLs *decayedHits
+	// uniqueIPs : Sketch of the IPs of the latest UniqueIPWindows, nil when
+	// there are none
+	uniqueIPs *slidingHLL
+	// enrichedHits : Hits per value, per enrichment field
+	enrichedHits map[string]map[string]int
+	// ipScores : Summed script scores per client IP
+	ipScores      map[string]float64
+	pageviews     int
+	referrerHits  map[string]int
+	spamReferrals int
+	campaignHits  map[string]int
+	// timeseries : Time series counts, by series name
+	timeseries map[string]seriesHits
+	upstreams  map[string]*upstreamHits
+	// users : Requests per authenticated user
+	users map[string]*userHits
+	// keepaliveRequests, reusedRequests : Requests on known connections, and
+	// on already used ones
+	keepaliveRequests   int
+	reusedRequests      int
+	connectionHits      map[string]int
+	keepaliveClientHits map[string]int
+	reusingClientHits   map[string]int
+	compression         map[string]*compressionHits
+	uncompressedHits    map[string]int
+	// cacheStatusHits : Requests per cache status; cacheBytes, cacheHitBytes :
+	// Bytes sent of the requests with a cache status, and of the cache hits
+	cacheStatusHits map[string]int
+	cacheBytes      int64
+	cacheHitBytes   int64
+	// vhostHits : Requests per virtual host
+	vhostHits map[string]int
+	// tlsProtocolHits, tlsCipherHits : Requests per TLS protocol version and
+	// per cipher suite
+	tlsProtocolHits map[string]int
+	tlsCipherHits   map[string]int
+	// slowest : The slowest requests, by duration in seconds
+	slowest leaderboard
+	// largest : The largest responses, by bytes
+	largest leaderboard
+	// responseSizes : The response size histogram, nil until a size is counted
+	responseSizes *histogram
+	// firstSeen, lastSeen : Time range of the lines, in Unix nanoseconds, 0
+	// until a line is counted
+	firstSeen int64
+	lastSeen  int64
+	// openSessions : Sessions still open, per visitor, and the most of them
+	// open at once
+	openSessions     map[string]*session
+	peakOpenSessions int
+	sessionsSwept    time.Time
+	// sessions, bounces, landingHits, exitHits : Closed sessions
+	sessions    int
+	bounces     int
+	landingHits map[string]int
+	exitHits    map[string]int
+	// samples : The lines sampled as they were read
+	samples *lineSamples
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{
+		ipHits:              make(map[string]int),
+		networkHits:         make(map[string]int),
+		enrichedHits:        make(map[string]map[string]int),
+		ipScores:            make(map[string]float64),
+		referrerHits:        make(map[string]int),
+		campaignHits:        make(map[string]int),
+		timeseries:          make(map[string]seriesHits),
+		upstreams:           make(map[string]*upstreamHits),
+		users:               make(map[string]*userHits),
+		connectionHits:      make(map[string]int),
+		keepaliveClientHits: make(map[string]int),
+		reusingClientHits:   make(map[string]int),
+		compression:         make(map[string]*compressionHits),
+		uncompressedHits:    make(map[string]int),
+		cacheStatusHits:     make(map[string]int),
+		vhostHits:           make(map[string]int),
+		tlsProtocolHits:     make(map[string]int),
+		tlsCipherHits:       make(map[string]int),
+		openSessions:        make(map[string]*session),
+		landingHits:         make(map[string]int),
+		exitHits:            make(map[string]int),
+		samples:             &lineSamples{},
+	}
+}
+
+// merge : Adds the counts of other to a
+func (a *aggregate) merge(other *aggregate) {
+	mergeHits(a.ipHits, other.ipHits)
+	a.urlHits.merge(&other.urlHits)
+	a.overflowedURLs += other.overflowedURLs
+	mergeHits(a.networkHits, other.networkHits)
+	a.decayedIPs = mergeDecayed(a.decayedIPs, other.decayedIPs)
+	a.decayedURLs = mergeDecayed(a.decayedURLs, other.decayedURLs)
+	a.uniqueIPs = mergeSlidingHLL(a.uniqueIPs, other.uniqueIPs)
+	mergeFieldHits(a.enrichedHits, other.enrichedHits)
+	for k, v := range other.ipScores {
+		a.ipScores[k] += v
+	}
+	a.pageviews += other.pageviews
+	mergeHits(a.referrerHits, other.referrerHits)
+	a.spamReferrals += other.spamReferrals
+	mergeHits(a.campaignHits, other.campaignHits)
+	mergeTimeseries(a.timeseries, other.timeseries)
+	mergeUpstreams(a.upstreams, other.upstreams)
+	mergeUsers(a.users, other.users)
+	a.keepaliveRequests += other.keepaliveRequests
+	a.reusedRequests += other.reusedRequests
+	mergeHits(a.connectionHits, other.connectionHits)
+	mergeHits(a.keepaliveClientHits, other.keepaliveClientHits)
+	mergeHits(a.reusingClientHits, other.reusingClientHits)
+	mergeCompression(a.compression, other.compression)
+	mergeHits(a.uncompressedHits, other.uncompressedHits)
+	mergeHits(a.cacheStatusHits, other.cacheStatusHits)
+	a.cacheBytes += other.cacheBytes
+	a.cacheHitBytes += other.cacheHitBytes
+	mergeHits(a.vhostHits, other.vhostHits)
+	mergeHits(a.tlsProtocolHits, other.tlsProtocolHits)
+	mergeHits(a.tlsCipherHits, other.tlsCipherHits)
+	a.slowest.merge(other.slowest)
+	a.largest.merge(other.largest)
+	a.responseSizes = mergeHistogram(a.responseSizes, other.responseSizes)
+	a.mergeTimeRange(other.firstSeen, other.lastSeen)
+	a.mergeSessions(other.sessionTotals())
+	a.peakOpenSessions += other.peakOpenSessions
+	a.samples.merge(other.samples)
+}
+
+// mergeSessions : Adds the sessions as closed ones; sessions of one visitor
+// spanning both aggregates are counted twice
+func (a *aggregate) mergeSessions(totals *sessionTotals) {
+	a.sessions += totals.sessions
+	a.bounces += totals.bounces
+	mergeHits(a.landingHits, totals.landingHits)
+	mergeHits(a.exitHits, totals.exitHits)
+}
+
+func mergeHits(into, from map[string]int) {
+	for k, v := range from {
+		into[k] += v
+	}
+}
+
+// copyHits : A copy of the hits, for a report, nil when there are none
+func copyHits(hits map[string]int) map[string]int {
+	if len(hits) == 0 {
+		return nil
+	}
+	copied := make(map[string]int, len(hits))
+	mergeHits(copied, hits)
+	return copied
+}
+
+func mergeFieldHits(into, from map[string]map[string]int) {
+	for field, hits := range from {
+		if _, ok := into[field]; !ok {
+			into[field] = make(map[string]int, len(hits))
+		}
+		mergeHits(into[field], hits)
+	}
+}
+
+// aggregateJSON : The serialized form of an aggregate
+type aggregateJSON struct {
+	IPHits              map[string]int              `json:"ipHits"`
+	URLHits             map[string]int              `json:"urlHits"`
+	OverflowedURLs      int                         `json:"overflowedURLs,omitempty"`
+	NetworkHits         map[string]int              `json:"networkHits"`
+	DecayedIPs          *decayedHits                `json:"decayedIPs,omitempty"`
+	DecayedURLs         *decayedHits                `json:"decayedURLs,omitempty"`
+	UniqueIPs           *slidingHLL                 `json:"uniqueIPs,omitempty"`
+	EnrichedHits        map[string]map[string]int   `json:"enrichedHits,omitempty"`
+	IPScores            map[string]float64          `json:"ipScores,omitempty"`
+	Pageviews           int                         `json:"pageviews,omitempty"`
+	ReferrerHits        map[string]int              `json:"referrerHits,omitempty"`
+	SpamReferrals       int                         `json:"spamReferrals,omitempty"`
+	CampaignHits        map[string]int              `json:"campaignHits,omitempty"`
+	Timeseries          map[string]seriesHits       `json:"timeseries,omitempty"`
+	Upstreams           map[string]*upstreamHits    `json:"upstreams,omitempty"`
+	Users               map[string]*userHits        `json:"users,omitempty"`
+	KeepaliveRequests   int                         `json:"keepaliveRequests,omitempty"`
+	ReusedRequests      int                         `json:"reusedRequests,omitempty"`
+	ConnectionHits      map[string]int              `json:"connectionHits,omitempty"`
+	KeepaliveClientHits map[string]int              `json:"keepaliveClientHits,omitempty"`
+	ReusingClientHits   map[string]int              `json:"reusingClientHits,omitempty"`
+	Compression         map[string]*compressionHits `json:"compression,omitempty"`
+	UncompressedHits    map[string]int              `json:"uncompressedHits,omitempty"`
+	CacheStatusHits     map[string]int              `json:"cacheStatusHits,omitempty"`
+	CacheBytes          int64                       `json:"cacheBytes,omitempty"`
+	CacheHitBytes       int64                       `json:"cacheHitBytes,omitempty"`
+	VHostHits           map[string]int              `json:"vhostHits,omitempty"`
+	TLSProtocolHits     map[string]int              `json:"tlsProtocolHits,omitempty"`
+	TLSCipherHits       map[string]int              `json:"tlsCipherHits,omitempty"`
+	Slowest             leaderboard                 `json:"slowest,omitempty"`
+	Largest             leaderboard                 `json:"largest,omitempty"`
+	ResponseSizes       *histogram                  `json:"responseSizes,omitempty"`
+	FirstSeen           int64                       `json:"firstSeen,omitempty"`
+	LastSeen            int64                       `json:"lastSeen,omitempty"`
+	Sessions            int                         `json:"sessions,omitempty"`
+	Bounces             int                         `json:"bounces,omitempty"`
+	LandingHits         map[string]int              `json:"landingHits,omitempty"`
+	ExitHits            map[string]int              `json:"exitHits,omitempty"`
+	PeakOpenSessions    int                         `json:"peakOpenSessions,omitempty"`
+	Samples             *lineSamples                `json:"samples,omitempty"`
+}
+
+// MarshalJSON : Implements json.Marshaler
+func (a *aggregate) MarshalJSON() ([]byte, error) {
+	// open sessions are stored as closed ones
+	sessions := a.sessionTotals()
+	var samples *lineSamples
+	if a.samples != nil && !a.samples.empty() {
+		samples = a.samples
+	}
+	return json.Marshal(&aggregateJSON{
+		IPHits:              a.ipHits,
+		URLHits:             a.urlHits.counts(),
+		OverflowedURLs:      a.overflowedURLs,
+		NetworkHits:         a.networkHits,
+		DecayedIPs:          a.decayedIPs,
+		DecayedURLs:         a.decayedURLs,
+		UniqueIPs:           a.uniqueIPs,
+		EnrichedHits:        a.enrichedHits,
+		IPScores:            a.ipScores,
+		Pageviews:           a.pageviews,
+		ReferrerHits:        a.referrerHits,
+		SpamReferrals:       a.spamReferrals,
+		CampaignHits:        a.campaignHits,
+		Timeseries:          a.timeseries,
+		Upstreams:           a.upstreams,
+		Users:               a.users,
+		KeepaliveRequests:   a.keepaliveRequests,
+		ReusedRequests:      a.reusedRequests,
+		ConnectionHits:      a.connectionHits,
+		KeepaliveClientHits: a.keepaliveClientHits,
+		ReusingClientHits:   a.reusingClientHits,
+		Compression:         a.compression,
+		UncompressedHits:    a.uncompressedHits,
+		CacheStatusHits:     a.cacheStatusHits,
+		CacheBytes:          a.cacheBytes,
+		CacheHitBytes:       a.cacheHitBytes,
+		VHostHits:           a.vhostHits,
+		TLSProtocolHits:     a.tlsProtocolHits,
+		TLSCipherHits:       a.tlsCipherHits,
+		Slowest:             a.slowest,
+		Largest:             a.largest,
+		ResponseSizes:       a.responseSizes,
+		FirstSeen:           a.firstSeen,
+		LastSeen:            a.lastSeen,
+		Sessions:            sessions.sessions,
+		Bounces:             sessions.bounces,
+		LandingHits:         sessions.landingHits,
+		ExitHits:            sessions.exitHits,
+		PeakOpenSessions:    a.peakOpenSessions,
+		Samples:             samples,
+	})
+}
+
+// UnmarshalJSON : Implements json.Unmarshaler
+func (a *aggregate) UnmarshalJSON(data []byte) error {
+	var v aggregateJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = *newAggregate()
+	mergeHits(a.ipHits, v.IPHits)
+	a.urlHits.mergeCounts(v.URLHits)
+	a.overflowedURLs = v.OverflowedURLs
+	mergeHits(a.networkHits, v.NetworkHits)
+	a.decayedIPs = mergeDecayed(nil, v.DecayedIPs)
+	a.decayedURLs = mergeDecayed(nil, v.DecayedURLs)
+	a.uniqueIPs = mergeSlidingHLL(nil, v.UniqueIPs)
+	mergeFieldHits(a.enrichedHits, v.EnrichedHits)
+	for k, score := range v.IPScores {
+		a.ipScores[k] += score
+	}
+	a.pageviews = v.Pageviews
+	mergeHits(a.referrerHits, v.ReferrerHits)
+	a.spamReferrals = v.SpamReferrals
+	mergeHits(a.campaignHits, v.CampaignHits)
+	mergeTimeseries(a.timeseries, v.Timeseries)
+	mergeUpstreams(a.upstreams, v.Upstreams)
+	mergeUsers(a.users, v.Users)
+	a.keepaliveRequests = v.KeepaliveRequests
+	a.reusedRequests = v.ReusedRequests
+	mergeHits(a.connectionHits, v.ConnectionHits)
+	mergeHits(a.keepaliveClientHits, v.KeepaliveClientHits)
+	mergeHits(a.reusingClientHits, v.ReusingClientHits)
+	mergeCompression(a.compression, v.Compression)
+	mergeHits(a.uncompressedHits, v.UncompressedHits)
+	mergeHits(a.cacheStatusHits, v.CacheStatusHits)
+	a.cacheBytes = v.CacheBytes
+	a.cacheHitBytes = v.CacheHitBytes
+	mergeHits(a.vhostHits, v.VHostHits)
+	mergeHits(a.tlsProtocolHits, v.TLSProtocolHits)
+	mergeHits(a.tlsCipherHits, v.TLSCipherHits)
+	a.slowest.merge(v.Slowest)
+	a.largest.merge(v.Largest)
+	a.responseSizes = mergeHistogram(a.responseSizes, v.ResponseSizes)
+	a.mergeTimeRange(v.FirstSeen, v.LastSeen)
+	a.mergeSessions(&sessionTotals{
+		sessions:    v.Sessions,
+		bounces:     v.Bounces,
+		landingHits: v.LandingHits,
+		exitHits:    v.ExitHits,
+	})
+	a.peakOpenSessions = v.PeakOpenSessions
+	if v.Samples != nil {
+		a.samples = v.Samples
+	}
+	return nil
+}
+
+// hitTables : The per-key counts held by the aggregate in maps, by table name
+func (a *aggregate) hitTables() map[string]map[string]int {
+	tables := map[string]map[string]int{
+		"ips":               a.ipHits,
+		"networks":          a.networkHits,
+		"referrers":         a.referrerHits,
+		"campaigns":         a.campaignHits,
+		"landing_pages":     a.landingHits,
+		"exit_pages":        a.exitHits,
+		"connections":       a.connectionHits,
+		"keepalive_clients": a.keepaliveClientHits,
+		"reusing_clients":   a.reusingClientHits,
+		"uncompressed_urls": a.uncompressedHits,
+		"cache_statuses":    a.cacheStatusHits,
+		"vhosts":            a.vhostHits,
+		"tls_protocols":     a.tlsProtocolHits,
+		"tls_ciphers":       a.tlsCipherHits,
+	}
+	for field, hits := range a.enrichedHits {
+		tables["enriched."+field] = hits
+	}
+	return tables
+}
+
+// keys : Distinct keys held by the aggregate
+func (a *aggregate) keys() int {
+	n := len(a.upstreams) + len(a.users) + len(a.compression) + a.urlHits.len()
+	for _, hits := range a.hitTables() {
+		n += len(hits)
+	}
+	return n
+}
+
+// keyBytes : Total length of the keys held by the aggregate, URLs counting
+// for the bytes they take once their prefixes are shared
+func (a *aggregate) keyBytes() int {
+	n := a.urlHits.bytes
+	for k := range a.upstreams {
+		n += len(k)
+	}
+	for k := range a.users {
+		n += len(k)
+	}
+	for k := range a.compression {
+		n += len(k)
+	}
+	for _, hits := range a.hitTables() {
+		for k := range hits {
+			n += len(k)
+		}
+	}
+	return n
+}
@@ -2,12 +2,17 @@ package analyzer
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/formats"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
-	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,148 +21,768 @@ const (
 	ErrConfigIsRequired = "config is required"
 	// ErrLineRegexIsRequired :
 	ErrLineRegexIsRequired = "line regex is required"
+	// ErrConflictingFormats :
+	ErrConflictingFormats = "only one of JSON, Logfmt, W3C, CloudFront, Heroku, CEF, Parser, LineFormat and FormatName can be set"
+	// ErrUnknownFormat :
+	ErrUnknownFormat = "no format is registered under this name"
+	// ErrConflictingMultiline :
+	ErrConflictingMultiline = "only one of the Start and Continuation multiline patterns can be set"
 	// ErrOpeningFile :
 	ErrOpeningFile = "error opening file"
+	// ErrReadingFile :
+	ErrReadingFile = "error reading file"
 )
 
 // LogAnalytics :
 type LogAnalytics struct {
 	// UniqueIPCount : The number of unique IP addresses
 	UniqueIPCount int
+	// FirstTimeIPs : The MostActiveIPs no previous run saw, with SeenIPs
+	FirstTimeIPs []string `json:",omitempty"`
+	// UniqueIPWindows : The estimated number of unique IP addresses in each
+	// of the UniqueIPWindows up to the latest line
+	UniqueIPWindows []*UniqueIPWindow `json:",omitempty"`
 	// Most active IP addresses
 	MostActiveIPs []string
 	// Most visited URLs
 	MostVisitedURLs []string
+	// Most active client networks, in CIDR notation
+	MostActiveNetworks []string
+	// TopEnrichedValues : Most common values of every enrichment field
+	TopEnrichedValues map[string][]string
+	// TopScoredIPs : Client IPs with the highest summed script scores
+	TopScoredIPs []string
+	// Pageviews : Requests for pages by people, when pageview rules are set
+	Pageviews int
+	// TopReferrers : Hosts referring the most requests, spam excluded
+	TopReferrers []string
+	// SpamReferrals : Requests referred by spam domains, left out of TopReferrers
+	SpamReferrals int
+	// TopCampaigns : Marketing campaigns, from the UTM parameters of URLs, that
+	// brought the most requests, as "source / medium / campaign"
+	TopCampaigns []string
+	// Upstreams : Backends requests were proxied to, busiest first, with
+	// their error rates and latencies
+	Upstreams []*UpstreamStats
+	// TopUsers : The authenticated users of the most requests, with their
+	// bytes and error rate, when the format logs remote users. Anonymous
+	// requests are not counted.
+	TopUsers []*UserStats `json:",omitempty"`
+	// Keepalive : Connection reuse, when the format logs connections
+	Keepalive *KeepaliveStats
+	// Compression : Compression per content type, the most served first, when
+	// the format logs content encodings or original sizes
+	Compression []*CompressionStats
+	// UncompressedURLs : URLs serving the most large responses uncompressed
+	UncompressedURLs []string
+	// Cache : Cache hit ratios, when the format logs cache statuses
+	Cache *CacheStats `json:",omitempty"`
+	// RequestsByVHost : Requests per virtual host, when the format logs
+	// virtual hosts, e.g. Apache's %v or nginx's $server_name
+	RequestsByVHost map[string]int `json:",omitempty"`
+	// TLSProtocols, TLSCiphers : Requests per TLS protocol version and per
+	// cipher suite, when the format logs them, e.g. to find the clients left
+	// before deprecating TLS 1.0 and 1.1. Requests over plain HTTP are not
+	// counted.
+	TLSProtocols map[string]int `json:",omitempty"`
+	TLSCiphers   map[string]int `json:",omitempty"`
+	// SlowestRequests : The slowest individual requests, slowest first, when
+	// the format logs durations
+	SlowestRequests []*RequestSample
+	// Annotations : The configured annotations within the time range of the
+	// analyzed lines, in time order
+	Annotations []*Annotation `json:",omitempty"`
+	// LargestResponses : The largest individual responses, largest first
+	LargestResponses []*RequestSample
+	// ResponseSizes : Mean and percentile response sizes, when SizeBuckets is
+	// set
+	ResponseSizes *SizeStats `json:",omitempty"`
+	// Timeseries : Counts per time interval and label, by series name, e.g.
+	// requests per device type per day under DeviceSeries, or per status
+	// class of an endpoint group
+	Timeseries map[string][]*TimeseriesBucket
+	// Sessions : Visits reconstructed out of pageviews, when session rules are set
+	Sessions int
+	// BounceRate : Ratio of sessions with a single pageview
+	BounceRate float64
+	// TopLandingPages : Most common first pages of sessions
+	TopLandingPages []string
+	// TopExitPages : Most common last pages of sessions
+	TopExitPages []string
+	// Resources : What the analytics were built from, e.g. distinct keys per
+	// table and URL hits past MaxURLs, when ResourceStats is set
+	Resources *ResourceStats `json:",omitempty"`
+	// MatchedSamples, UnmatchedSamples : Random lines, as logged, of those
+	// that matched the log format and of those that did not, when
+	// MatchedSamplesCount and UnmatchedSamplesCount are set, to check the
+	// parser did what was expected of it
+	MatchedSamples   []string `json:",omitempty"`
+	UnmatchedSamples []string `json:",omitempty"`
+	// Sources : Per file analytics, when several files were analyzed together.
+	// The fields above are then the merged totals.
+	Sources map[string]*LogAnalytics
 }
 
 // LogAnalyzer :
 type LogAnalyzer interface {
 	Analyze(filePath string) (*LogAnalytics, error)
+	// AnalyzeFiles : Analyzes the files together, attributing their
+	// contributions to each of them
+	AnalyzeFiles(filePaths ...string) (*LogAnalytics, error)
+	// RunBatch : Analyzes the files listed in a manifest with bounded
+	// parallelism, recording completion so an interrupted batch can resume
+	RunBatch(config *BatchConfig) (*LogAnalytics, error)
+	// AnalyzeBatch : Analyzes many small files, e.g. thousands of rotated
+	// logs, together, reusing the parser, buffers and line blocks from one
+	// file to the next instead of setting them up for each
+	AnalyzeBatch(filePaths []string, opts ...BatchOption) (*LogAnalytics, error)
+	// Follow : Analyzes the file, then the lines appended to it, until ctx is
+	// done. Immutable snapshots of the analytics so far are emitted on the
+	// returned channel periodically, and on demand with a snapshot signal.
+	// Once ctx is done, a final snapshot including every line read so far is
+	// emitted before the channels are closed.
+	Follow(ctx context.Context, filePath string) (<-chan *LogAnalytics, <-chan error)
+	// Reconfigure : Applies the top-N sizes of config to the running analyzer,
+	// e.g. on a config reload, keeping what was accumulated so far. The other
+	// settings of config are ignored, they require a new analyzer.
+	Reconfigure(config *LogAnalyzerConfig) error
+	// SelfMetrics : Returns the analyzer's own metrics (throughput, parse
+	// errors, queue depth, aggregate sizes), to monitor it while it runs
+	SelfMetrics() *SelfMetrics
+	// EstimateCost : Dry run, projecting the cost of analyzing the file from
+	// a sample of its first lines
+	EstimateCost(filePath string, sampleLines int) (*CostEstimate, error)
+	// ComparePeriods : Analyzes the files of two periods, reporting the URLs
+	// and client IPs of the largest relative traffic changes between them
+	ComparePeriods(previous, current []string) (*PeriodComparison, error)
+	// SaveState : Writes the full state Follow counts into (counts, open
+	// sessions, time series, leaderboards), so it can be migrated to another
+	// host or version. The state is only meaningful to an analyzer with the
+	// same config.
+	SaveState(w io.Writer) error
+	// LoadState : Replaces the state Follow counts into with one written by
+	// SaveState; the running or next Follow continues from it. Follow reads
+	// its file from the start, so a loaded state goes with a new log, e.g.
+	// after a rotation.
+	LoadState(r io.Reader) error
+	// AnalyzePartial : Analyzes a shard of log files, returning their counts
+	// for MergePartials to merge with the other shards'
+	AnalyzePartial(filePaths ...string) (*Partial, error)
+	// MergePartials : The analytics of all the logs of the partials
+	MergePartials(partials ...*Partial) *LogAnalytics
+	// AnalyzeErrorLog : Analyzes Apache or nginx error logs instead of access
+	// logs, reporting levels, top messages and errors over time
+	AnalyzeErrorLog(filePaths ...string) (*ErrorLogAnalytics, error)
 }
 type logAnalyzer struct {
-	lineRegex            *regexp.Regexp
-	mostActiveIPsCount   int
-	mostVisitedURLsCount int
+	lineRegex *regexp.Regexp
+	// stateMu, state : The aggregate Follow counts into, nil until followed
+	// or loaded
+	stateMu sync.Mutex
+	state   *aggregate
+	// lineFields : Set when lines are structured, e.g. JSON objects, instead
+	// of matching lineRegex
+	lineFields fieldFormat
+	// schema : The captures of lineRegex checked in strict schema mode, nil
+	// when not strict
+	schema *lineSchema
+	// trustedProxies : Proxies whose X-Forwarded-For gives the client, nil
+	// for none
+	trustedProxies trustedProxies
+	// timeLayouts : The layouts of the times of lineRegex, nil for the
+	// default ones
+	timeLayouts timeLayouts
+	// projection : The fields parsed, nil for all
+	projection          projection
+	syslog              bool
+	multiline           *MultilineRules
+	settings            atomic.Value // *reloadableSettings
+	ipv6AggregatePrefix int
+	ipv4NetworkPrefix   int
+	ipv6NetworkPrefix   int
+	keepRawURLs         bool
+	maxURLs             int
+	resourceStats       bool
+	timeOrdered         bool
+	sortChunkSize       int
+	lineBatchSize       int
+	tokenizer           Tokenizer
+	readAheadSize       int
+	sortTempDir         string
+	followPollInterval  time.Duration
+	snapshotInterval    time.Duration
+	decayHalfLife       time.Duration
+	// uniqueIPWindows, uniqueIPWindow : Windows unique IPs are estimated in,
+	// and the longest one, 0 when there are none
+	uniqueIPWindows    []time.Duration
+	uniqueIPWindow     time.Duration
+	seenIPs            *SeenIPs
+	snapshotSignals    []os.Signal
+	queueSize          int
+	queuePolicy        QueuePolicy
+	enrichers          []*EnrichmentStep
+	enrichmentCache    *enrichmentCache
+	enrichConcurrency  int
+	enrichInOrder      bool
+	script             *Script
+	redaction          *RedactionPolicy
+	lineIndex          *LineIndex
+	collectors         map[Collector]bool
+	pageviews          *PageviewRules
+	sessionTimeout     time.Duration
+	referrerBlocklist  referrerBlocklist
+	timeseriesInterval time.Duration
+	retention          []*RetentionTier
+	deviceTimeseries   bool
+	endpointGroups     []*EndpointGroup
+	logsDurations      bool
+	latencyBounds      []float64
+	sizeBounds         []float64
+	percentiles        []float64
+	logsCompression    bool
+	logsCache          bool
+	logsVHosts         bool
+	logsTLS            bool
+	logsUsers          bool
+	logsTime           bool
+	onWarning          func(Warning)
+	matchedSamples     int
+	unmatchedSamples   int
+	largeResponseBytes int
+	metrics            selfMetrics
 }
 
 // Line : Represents a line in the log
 type Line struct {
+	// RemoteHost : The client address, resolved from ForwardedFor when the
+	// peer is a trusted proxy
 	RemoteHost string
 	Time       time.Time
-	Request    string
-	Status     int
-	Bytes      int
-	Referer    string
-	UserAgent  string
-	URL        string
+	// Method, Path, RawQuery, Protocol : The parts of the request line, e.g.
+	// GET, /search, q=go and HTTP/1.1 for "GET /search?q=go HTTP/1.1". Path
+	// is unescaped and RawQuery has no '?', as in net/url.
+	Method    string
+	Path      string
+	RawQuery  string
+	Protocol  string
+	Status    int
+	Bytes     int
+	Referer   string
+	UserAgent string
+	// URL : The request target, as logged
+	URL string
+	// RemoteUser : The user the request authenticated as, when the format
+	// logs it, empty for anonymous requests
+	RemoteUser string `json:",omitempty"`
+	// VHost : Virtual host that served the request, when the format logs it
+	VHost string `json:",omitempty"`
+	// ForwardedFor : The X-Forwarded-For chain, as logged, when the format
+	// logs it
+	ForwardedFor string `json:",omitempty"`
+	// Peer : The trusted proxy the request came from, when RemoteHost was
+	// resolved from ForwardedFor
+	Peer string `json:",omitempty"`
+	// Upstream : Backend the request was proxied to, when the format logs it
+	Upstream string `json:",omitempty"`
+	// Duration : Response time, when the format logs it
+	Duration time.Duration `json:",omitempty"`
+	// Connection, ConnectionRequests : Connection ID, and number of the
+	// request on that connection, when the format logs them
+	Connection         string `json:",omitempty"`
+	ConnectionRequests int    `json:",omitempty"`
+	// ContentType, ContentEncoding, OriginalBytes : Response content type,
+	// encoding, and size before compression, when the format logs them
+	ContentType     string `json:",omitempty"`
+	ContentEncoding string `json:",omitempty"`
+	OriginalBytes   int    `json:",omitempty"`
+	// TLSProtocol, TLSCipher : The TLS protocol version, e.g. TLSv1.3, and
+	// cipher suite of the connection, when the format logs them, empty for
+	// requests over plain HTTP
+	TLSProtocol string `json:",omitempty"`
+	TLSCipher   string `json:",omitempty"`
+	// Extras : Values of the named groups of the line regex not mapped to a
+	// field, e.g. custom nginx variables, by group name, or of the JSON keys
+	// not mapped to one, by key
+	Extras map[string]string `json:",omitempty"`
+	// Enrichments : Fields derived by the enrichers, as "<enricher>.<field>"
+	Enrichments map[string]string `json:",omitempty"`
+}
+
+// Request : The request line, e.g. "GET /search?q=go HTTP/1.1", without the
+// parts not logged
+func (line *Line) Request() string {
+	request := line.Method
+	for _, part := range []string{line.URL, line.Protocol} {
+		if part != "" {
+			if request != "" {
+				request += " "
+			}
+			request += part
+		}
+	}
+	return request
 }
 
 const ()
 
 func (l *logAnalyzer) Analyze(filePath string) (*LogAnalytics, error) {
+	aggs, err := l.aggregateFiles([]string{filePath})
+	if err != nil {
+		return nil, err
+	}
+
+	return l.report(aggs[filePath]), nil
+}
+
+func (l *logAnalyzer) AnalyzeFiles(filePaths ...string) (*LogAnalytics, error) {
+	filePaths = uniquePaths(filePaths)
+	aggs, err := l.aggregateFiles(filePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := newAggregate()
+	sources := make(map[string]*LogAnalytics, len(filePaths))
+	for _, filePath := range filePaths {
+		merged.merge(aggs[filePath])
+		sources[filePath] = l.report(aggs[filePath])
+	}
+
+	analytics := l.report(merged)
+	analytics.Sources = sources
+	return analytics, nil
+}
+
+// uniquePaths : The paths without the ones given again, e.g. by a glob and by
+// name, so that no file is counted twice
+func uniquePaths(filePaths []string) []string {
+	seen := make(map[string]bool, len(filePaths))
+	unique := make([]string, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		if seen[filepath.Clean(filePath)] {
+			continue
+		}
+		seen[filepath.Clean(filePath)] = true
+		unique = append(unique, filePath)
+	}
+	return unique
+}
+
+// aggregateFiles : Aggregates every file on its own or, when lines have to be
+// processed in time order, all files as a single time-sorted stream
+func (l *logAnalyzer) aggregateFiles(filePaths []string) (map[string]*aggregate, error) {
+	aggs := make(map[string]*aggregate, len(filePaths))
+	if !l.timeOrdered {
+		for _, filePath := range filePaths {
+			agg, err := l.aggregateFile(filePath)
+			if err != nil {
+				return nil, err
+			}
+			aggs[filePath] = agg
+		}
+		return aggs, nil
+	}
+
+	files := make([]*os.File, 0, len(filePaths))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, errors.New(ErrOpeningFile)
+		}
+		files = append(files, file)
+		aggs[filePath] = newAggregate()
+	}
+
+	sourcedCh := make(chan *sourcedLine)
+	var readErr error
+	go func() {
+		defer close(sourcedCh)
+		for i, file := range files {
+			lineCh, errCh := l.readLogLines(file, aggs[filePaths[i]].samples)
+			failed := logReadErrors(errCh)
+			for line := range lineCh {
+				sourcedCh <- &sourcedLine{Source: filePaths[i], Line: line}
+			}
+			if readErr = <-failed; readErr != nil {
+				return
+			}
+		}
+	}()
+
+	sortedCh, sortErrCh := externalSort(sourcedCh, l.sortChunkSize, sortFanIn, l.sortTempDir)
+	for line := range sortedCh {
+		l.consolidate(aggs[line.Source], line.Line)
+	}
+	if err := <-sortErrCh; err != nil {
+		return nil, err
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	return aggs, nil
+}
+
+func (l *logAnalyzer) aggregateFile(filePath string) (*aggregate, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, errors.New(ErrOpeningFile)
 	}
 	defer file.Close()
 
-	lineCh, errCh := readLogLines(file, l.lineRegex)
+	agg := newAggregate()
+	lineCh, errCh := l.readLogLines(file, agg.samples)
+	failed := logReadErrors(errCh)
+
+	for line := range lineCh {
+		l.consolidate(agg, line)
+	}
+	if err := <-failed; err != nil {
+		return nil, err
+	}
+
+	return agg, nil
+}
+
+// logReadErrors : Logs the read error of a log, if any, but for a line not
+// fitting the schema in strict schema mode, which fails the analysis: it is
+// emitted instead
+func logReadErrors(errCh <-chan error) <-chan error {
+	failed := make(chan error, 1)
 	go func() {
+		defer close(failed)
 		err := <-errCh
-		if err != nil {
+		if isSchemaMismatch(err) {
+			failed <- err
+		} else if err != nil {
 			// TODO: stream somewhere else. Skipping bad lines intentionally
 			fmt.Println(fmt.Sprintf("error: %+v", err))
 		}
 	}()
+	return failed
+}
 
-	uniqueIps := make(map[string]int)
-	urlHits := make(map[string]int)
-	for line := range lineCh {
-		// consolidate IP metrics
-		count, exists := uniqueIps[line.RemoteHost]
-		if !exists {
-			uniqueIps[line.RemoteHost] = 0
+// consolidate : Counts the line into the aggregate, keeping track of it in
+// the self-metrics
+func (l *logAnalyzer) consolidate(agg *aggregate, line *Line) {
+	overflowedURLs := agg.overflowedURLs
+	l.countLine(agg, line)
+	if overflowedURLs == 0 && agg.overflowedURLs > 0 {
+		l.warn(&URLOverflow{MaxURLs: l.maxURLs})
+	}
+	atomic.AddInt64(&l.metrics.overflowedURLs, int64(agg.overflowedURLs-overflowedURLs))
+	atomic.AddInt64(&l.metrics.linesConsolidated, 1)
+}
+
+// countLine : Counts the line into the aggregate, leaving the self-metrics be
+func (l *logAnalyzer) countLine(agg *aggregate, line *Line) {
+	agg.observeTime(line.Time)
+
+	// consolidate IP metrics
+	var live int64
+	if agg.decayedIPs != nil || l.uniqueIPWindow > 0 {
+		live = liveTime(line)
+	}
+	ip := normalizeIP(line.RemoteHost, l.ipv6AggregatePrefix)
+	if l.collectors[CollectIPs] {
+		agg.ipHits[ip]++
+		if agg.decayedIPs != nil {
+			agg.decayedIPs.hit(ip, live)
+		}
+		if l.uniqueIPWindow > 0 {
+			if agg.uniqueIPs == nil {
+				agg.uniqueIPs = newSlidingHLL(l.uniqueIPWindow)
+			}
+			agg.uniqueIPs.add(ip, live)
 		}
-		uniqueIps[line.RemoteHost] = count + 1
+	}
 
-		// consolidate URL metrics
-		count, exists = urlHits[line.URL]
-		if !exists {
-			urlHits[line.URL] = 0
+	// consolidate URL metrics
+	if l.collectors[CollectURLs] {
+		url := l.capURL(agg, l.countedURL(line))
+		agg.urlHits.add(url, 1)
+		if agg.decayedURLs != nil {
+			agg.decayedURLs.hit(url, live)
 		}
-		urlHits[line.URL] = count + 1
 	}
 
-	mostActiveIPs := topMost(uniqueIps, l.mostActiveIPsCount)
-	mostVisitedURLs := topMost(urlHits, l.mostVisitedURLsCount)
+	// consolidate network metrics, once they are reported
+	if l.collectors[CollectNetworks] && l.reloadable().mostActiveNetworksCount > 0 {
+		if network, ok := networkOf(line.RemoteHost, l.ipv4NetworkPrefix, l.ipv6NetworkPrefix); ok {
+			agg.networkHits[network]++
+		}
+	}
 
-	return &LogAnalytics{
-		UniqueIPCount:   len(uniqueIps),
-		MostActiveIPs:   mostActiveIPs,
-		MostVisitedURLs: mostVisitedURLs,
-	}, nil
+	// consolidate pageviews and the sessions they make up, once they are reported
+	countPageviews := l.collectors[CollectPageviews] && l.pageviews != nil
+	trackSessions := l.collectors[CollectSessions] && l.sessionTimeout > 0
+	if (countPageviews || trackSessions) && l.pageviewRules().isPageview(line) {
+		if countPageviews {
+			agg.pageviews++
+		}
+		if trackSessions {
+			l.trackSession(agg, ip+" "+line.UserAgent, l.countedURL(line), line.Time)
+		}
+	}
 
-}
+	// consolidate referrer metrics, once they are reported
+	if l.collectors[CollectReferrers] && l.reloadable().topReferrersCount > 0 {
+		if host := referrerHost(line.Referer); host != "" {
+			if l.referrerBlocklist.blocks(host) {
+				agg.spamReferrals++
+			} else {
+				agg.referrerHits[host]++
+			}
+		}
+	}
 
-func readLogLines(file *os.File, lineRegex *regexp.Regexp) (<-chan *Line, <-chan error) {
-	outCh := make(chan *Line)
-	errCh := make(chan error)
-	go func() {
-		defer close(outCh)
-		defer close(errCh)
+	// consolidate campaign metrics, once they are reported
+	if l.collectors[CollectCampaigns] && l.reloadable().topCampaignsCount > 0 {
+		if campaign := campaignOf(line.URL); campaign != "" {
+			agg.campaignHits[campaign]++
+		}
+	}
 
-		scanner := bufio.NewScanner(file)
+	// consolidate time series, once they are reported
+	if l.collectors[CollectTimeseries] {
+		if l.deviceTimeseries {
+			l.count(agg, DeviceSeries, line.Time, deviceOf(line))
+		}
+		if group := endpointGroupOf(l.endpointGroups, line.URL); group != "" {
+			l.count(agg, StatusSeriesPrefix+group, line.Time, statusClass(line.Status))
+		}
+	}
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			result := lineRegex.FindStringSubmatch(line)
-			// skip empty lines
-			if len(result) <= 0 {
-				continue
-			}
+	// consolidate backend metrics, once they are reported
+	if l.collectors[CollectUpstreams] && line.Upstream != "" && l.reloadable().upstreamsCount > 0 {
+		l.consolidateUpstream(agg, line)
+	}
 
-			lineItem := &Line{
-				RemoteHost: result[1],
-				Request:    result[3] + " " + result[4] + " " + result[5],
-				Referer:    result[9],
-				UserAgent:  result[10],
-			}
+	// consolidate authenticated users, once they are reported
+	if l.collectors[CollectUsers] && l.logsUsers && line.RemoteUser != "" && l.reloadable().topUsersCount > 0 {
+		l.consolidateUser(agg, line)
+	}
 
-			value := result[2]
-			layout := "02/Jan/2006:15:04:05 -0700"
-			t, _ := time.Parse(layout, value)
-			lineItem.Time = t
+	// consolidate connection reuse metrics
+	if l.collectors[CollectKeepalive] {
+		l.consolidateKeepalive(agg, ip, line)
+	}
 
-			status, err := strconv.Atoi(result[7])
-			if err != nil {
-				status = 0
-			}
-			lineItem.Status = status
+	// consolidate compression metrics, for formats logging compression
+	if l.collectors[CollectCompression] && l.logsCompression {
+		l.consolidateCompression(agg, line)
+	}
 
-			bytes, err := strconv.Atoi(result[8])
-			if err != nil {
-				bytes = 0
-			}
-			lineItem.Bytes = bytes
+	// consolidate cache statuses, for formats logging them
+	if l.collectors[CollectCache] && l.logsCache {
+		l.consolidateCache(agg, line)
+	}
+
+	// consolidate requests per virtual host, for formats logging them
+	if l.collectors[CollectVHosts] && l.logsVHosts && line.VHost != "" {
+		agg.vhostHits[line.VHost]++
+	}
+
+	// consolidate TLS protocols and ciphers, for formats logging them
+	if l.collectors[CollectTLS] && l.logsTLS {
+		if line.TLSProtocol != "" {
+			agg.tlsProtocolHits[line.TLSProtocol]++
+		}
+		if line.TLSCipher != "" {
+			agg.tlsCipherHits[line.TLSCipher]++
+		}
+	}
+
+	// consolidate the slowest requests, for formats logging durations
+	if l.collectors[CollectSlowest] && l.logsDurations {
+		agg.slowest.offer(l.reloadable().slowestRequestsCount, line.Duration.Seconds(), func() *RequestSample {
+			return newRequestSample(line)
+		})
+	}
 
-			url := result[4]
-			altURL := result[6]
-			if url == "" && altURL != "" {
-				url = altURL
+	// consolidate the response size histogram, once it is reported
+	if l.collectors[CollectSizes] && l.sizeBounds != nil {
+		l.consolidateSize(agg, line)
+	}
+
+	// consolidate the largest responses
+	if l.collectors[CollectLargest] {
+		agg.largest.offer(l.reloadable().largestResponsesCount, float64(line.Bytes), func() *RequestSample {
+			return newRequestSample(line)
+		})
+	}
+
+	// consolidate script scores
+	if l.collectors[CollectScores] {
+		if score, ok := l.scriptScore(line); ok {
+			agg.ipScores[ip] += score
+		}
+	}
+
+	// consolidate enrichment metrics, once they are reported
+	if l.collectors[CollectEnrichments] && l.reloadable().topEnrichedValuesCount > 0 {
+		for field, value := range line.Enrichments {
+			hits, ok := agg.enrichedHits[field]
+			if !ok {
+				hits = make(map[string]int)
+				agg.enrichedHits[field] = hits
 			}
-			lineItem.URL = url
+			hits[value]++
+		}
+	}
+}
+
+// report : Builds the analytics out of the aggregated counts
+func (l *logAnalyzer) report(agg *aggregate) *LogAnalytics {
+	settings := l.reloadable()
+	analytics := &LogAnalytics{
+		UniqueIPCount:      len(agg.ipHits),
+		MostActiveIPs:      topMost(agg.ipHits, settings.mostActiveIPsCount),
+		MostVisitedURLs:    agg.urlHits.top(settings.mostVisitedURLsCount),
+		MostActiveNetworks: topMost(agg.networkHits, settings.mostActiveNetworksCount),
+		Pageviews:          agg.pageviews,
+	}
+	analytics.UniqueIPWindows = uniqueIPWindowsReport(agg.uniqueIPs, l.uniqueIPWindows)
+	if agg.decayedIPs != nil {
+		analytics.MostActiveIPs = agg.decayedIPs.top(settings.mostActiveIPsCount)
+		analytics.MostVisitedURLs = agg.decayedURLs.top(settings.mostVisitedURLsCount)
+	}
+	if l.seenIPs != nil {
+		analytics.FirstTimeIPs = l.seenIPs.firstTime(analytics.MostActiveIPs)
+		l.seenIPs.record(agg.ipHits)
+	}
+	if settings.topReferrersCount > 0 {
+		analytics.TopReferrers = topMost(agg.referrerHits, settings.topReferrersCount)
+		analytics.SpamReferrals = agg.spamReferrals
+	}
+	if settings.upstreamsCount > 0 && len(agg.upstreams) > 0 {
+		analytics.Upstreams = l.upstreamsReport(agg.upstreams, settings.upstreamsCount)
+	}
+	if settings.topUsersCount > 0 && len(agg.users) > 0 {
+		analytics.TopUsers = usersReport(agg.users, settings.topUsersCount)
+	}
+	analytics.Keepalive = l.keepaliveReport(agg, settings.nonReusingClientsCount)
+	if len(agg.compression) > 0 {
+		analytics.Compression = compressionReport(agg.compression)
+		analytics.UncompressedURLs = topMost(agg.uncompressedHits, settings.uncompressedURLsCount)
+	}
+	analytics.Cache = cacheReport(agg)
+	analytics.RequestsByVHost = copyHits(agg.vhostHits)
+	analytics.TLSProtocols = copyHits(agg.tlsProtocolHits)
+	analytics.TLSCiphers = copyHits(agg.tlsCipherHits)
+	if settings.slowestRequestsCount > 0 && len(agg.slowest) > 0 {
+		analytics.SlowestRequests = agg.slowest.top(settings.slowestRequestsCount)
+	}
+	if agg.responseSizes != nil {
+		analytics.ResponseSizes = l.sizeReport(agg.responseSizes)
+	}
+	if settings.largestResponsesCount > 0 && len(agg.largest) > 0 {
+		analytics.LargestResponses = agg.largest.top(settings.largestResponsesCount)
+	}
+	if settings.topCampaignsCount > 0 {
+		analytics.TopCampaigns = topMost(agg.campaignHits, settings.topCampaignsCount)
+	}
+	timeseries := agg.timeseries
+	if len(l.retention) > 0 {
+		// reported as compacted, whether or not the aggregate itself was
+		timeseries = make(map[string]seriesHits, len(agg.timeseries))
+		mergeTimeseries(timeseries, agg.timeseries)
+		l.compactTimeseries(timeseries, agg.lastSeen)
+	}
+	analytics.Timeseries = timeseriesReport(timeseries)
+	if len(settings.annotations) > 0 {
+		analytics.Annotations = annotationsReport(settings.annotations, agg.firstSeen, agg.lastSeen)
+		annotateTimeseries(analytics.Timeseries, analytics.Annotations, func(start time.Time) time.Duration {
+			return l.bucketInterval(start, agg.lastSeen)
+		})
+	}
+	if l.sessionTimeout > 0 {
+		sessions := agg.sessionTotals()
+		analytics.Sessions = sessions.sessions
+		if sessions.sessions > 0 {
+			analytics.BounceRate = float64(sessions.bounces) / float64(sessions.sessions)
+		}
+		analytics.TopLandingPages = topMost(sessions.landingHits, settings.topLandingPagesCount)
+		analytics.TopExitPages = topMost(sessions.exitHits, settings.topExitPagesCount)
+	}
+	if settings.topScoredIPsCount > 0 {
+		analytics.TopScoredIPs = topScored(agg.ipScores, settings.topScoredIPsCount)
+	}
+	if settings.topEnrichedValuesCount > 0 && len(agg.enrichedHits) > 0 {
+		analytics.TopEnrichedValues = make(map[string][]string, len(agg.enrichedHits))
+		for field, hits := range agg.enrichedHits {
+			analytics.TopEnrichedValues[field] = topMost(hits, settings.topEnrichedValuesCount)
+		}
+	}
+	if l.resourceStats {
+		analytics.Resources = resourceReport(agg)
+	}
+	analytics.MatchedSamples, analytics.UnmatchedSamples = agg.samples.report(l.matchedSamples, l.unmatchedSamples)
+	return analytics
+}
+
+// readLogLines : Emits the lines of the file as they are parsed, sampling
+// them into samples
+func (l *logAnalyzer) readLogLines(file *os.File, samples *lineSamples) (<-chan *Line, <-chan error) {
+	outCh := l.newQueue()
+	errCh := make(chan error)
+	go func() {
+		defer close(outCh)
+		defer close(errCh)
 
+		reader, stopReading := l.reader(file)
+		defer stopReading()
+		scanner := bufio.NewScanner(reader)
+		scanner.Split(l.scanLines())
+		records := l.records(scanner)
+
+		parseLine := l.newLineParser()
+		// batch reports count every line, whatever the queue policy
+		window := l.newEnrichmentWindow(func(lineItem *Line, _ string) {
 			outCh <- lineItem
+		})
+		var err error
+		for err == nil && records.Scan() {
+			err = l.readLine(parseLine, window, samples, records.Text())
+		}
+		window.wait()
 
-			if err := scanner.Err(); err != nil {
-				errCh <- err
-			}
+		if err == nil {
+			err = records.Err()
+		}
+		if err != nil {
+			errCh <- err
 		}
 	}()
 
 	return outCh, errCh
 }
 
+// readLine : Parses and samples the line, resolves its client and redacts
+// it, and adds it to the enrichment window, which passes it on unless the
+// script filters it out. Empty and malformed lines are skipped, the malformed
+// ones with a warning. Lines not fitting the schema in strict schema mode are
+// returned as errors.
+func (l *logAnalyzer) readLine(parseLine lineParser, window *enrichmentWindow, samples *lineSamples, text string) error {
+	lineItem, err := l.parse(parseLine, text)
+	if isSchemaMismatch(err) {
+		return err
+	}
+	l.sample(samples, text, err)
+	if err != nil {
+		return nil
+	}
+	l.resolveClient(lineItem)
+	l.redact(lineItem)
+	window.add(lineItem, text)
+	return nil
+}
+
 func topMost(metrics map[string]int, top int) []string {
 	type stat struct {
 		address string
@@ -166,7 +791,8 @@ func topMost(metrics map[string]int, top int) []string {
 
 	// To retrieve The top m count:
 	//  	collect IP specific metrics,
-	//		sort the collected metrics by the count
+	//		sort the collected metrics by the count, then by key, so that
+	//		ties rank the same in every run
 	//		retrieve top m
 	stats := make([]*stat, 0, len(metrics))
 	for k, v := range metrics {
@@ -177,8 +803,14 @@ func topMost(metrics map[string]int, top int) []string {
 	}
 
 	sort.Slice(stats, func(i, j int) bool {
-		return stats[i].count > stats[j].count
+		if stats[i].count != stats[j].count {
+			return stats[i].count > stats[j].count
+		}
+		return stats[i].address < stats[j].address
 	})
+	if top > len(stats) {
+		top = len(stats)
+	}
 	var topMost []string
 	for i := 0; i < top; i++ {
 		topMost = append(topMost, stats[i].address)
@@ -189,9 +821,290 @@ func topMost(metrics map[string]int, top int) []string {
 
 // LogAnalyzerConfig :
 type LogAnalyzerConfig struct {
-	LineRegex            *regexp.Regexp
+	// Format : A ready-made log format, e.g. formats.CommonLog, used when
+	// LineRegex is not set
+	Format *formats.Format
+	// LineRegex : The line format, of the ten positional groups and optional
+	// named ones described by formats.Format, or of groups named after the
+	// fields they fill, e.g. (?P<remote_host>\S+) or (?P<ip>\S+), in any order
+	LineRegex *regexp.Regexp
+	// JSON : Lines are JSON objects, one per line, instead of matching a line
+	// regex. Fields are read from the keys JSONFields maps them to, and the
+	// other top level keys are kept in the line's extras.
+	JSON bool
+	// JSONFields : Line fields, named as the groups of line regexes (e.g.
+	// "remote_host", "url"), to the JSON keys holding them. Dotted keys, e.g.
+	// "request.method", read nested objects. DefaultJSONFields when not set.
+	JSONFields map[string]string
+	// Logfmt : Lines are logfmt key=value pairs, e.g. Heroku router logs,
+	// instead of matching a line regex. Values may be double quoted; words
+	// that are not pairs are skipped.
+	Logfmt bool
+	// LogfmtFields : Line fields, named as the groups of line regexes, to the
+	// logfmt keys holding them. DefaultLogfmtFields when not set.
+	LogfmtFields map[string]string
+	// W3C : Lines are of the W3C extended log format, e.g. of IIS, their
+	// fields declared by the #Fields headers of the log. c-ip, date, time,
+	// cs-method, cs-uri-stem (or cs-uri), cs-uri-query, cs-version,
+	// sc-status, sc-bytes, cs(Referer), cs(User-Agent) and time-taken are
+	// mapped to line fields, other fields are kept in the line's extras.
+	W3C bool
+	// CloudFront : Lines are of the CloudFront standard log format, the W3C
+	// format with tab separated values, time-taken in seconds and URL-encoded
+	// user agents. x-edge-location, x-edge-result-type and the other
+	// CloudFront fields are kept in the line's extras.
+	CloudFront bool
+	// Heroku : Lines are Heroku router logs, logfmt pairs as drained from
+	// Logplex or printed by heroku logs, their time read from the Logplex
+	// header. Lines of other processes are skipped. LogfmtFields applies,
+	// HerokuLogfmtFields when not set; the dyno is the upstream, so that
+	// backend reports are per dyno.
+	Heroku bool
+	// CEF : Lines are CEF or LEEF events, e.g. of SIEM exports, with or
+	// without a syslog header. Header fields and custom fields with a label
+	// are read by name, e.g. "deviceVendor" or "Response Code".
+	CEF bool
+	// CEFFields : Line fields, named as the groups of line regexes, to the CEF
+	// extension keys or LEEF attributes holding them. DefaultCEFFields and
+	// DefaultLEEFFields when not set.
+	CEFFields map[string]string
+	// Parser : Parses lines instead of a line regex, e.g. code generated by
+	// cmd/parsergen for maximum throughput on a fixed format. Fields does not
+	// apply to it.
+	Parser *Parser
+	// LineFormat : Parses lines instead of a line regex, e.g. a third party
+	// format, or one looked up by name with LookupFormat. Fields does not
+	// apply to it.
+	LineFormat Format
+	// FormatName : The name of the format lines are parsed with, as
+	// registered in Formats or globally with RegisterFormat, e.g. a preset's
+	// or a proprietary format's, in place of LineFormat
+	FormatName string
+	// Formats : The formats FormatName is looked up in, the global ones when
+	// not set
+	Formats *FormatRegistry
+	// Syslog : Lines may carry an RFC 3164 or RFC 5424 syslog header, e.g. as
+	// written by rsyslog or syslog-ng, which is stripped before they are
+	// parsed. Its host name and tag (app name) are kept as the syslog_host and
+	// syslog_tag extras.
+	Syslog bool
+	// Multiline : How continuation lines, e.g. stack traces, are joined into
+	// the record they continue before it is parsed, nil to parse every line
+	// on its own. Follow ends a record once no line came for
+	// FollowPollInterval.
+	Multiline *MultilineRules
+	// Fields : The line fields the run needs, named as the groups of line
+	// regexes, e.g. ["remote_host", "url"]. The others are not extracted, nor
+	// converted (e.g. times parsed), which speeds up parsing; they are left
+	// empty, as are the analytics derived from them. All fields when not set.
+	Fields               []string
 	MostActiveIPsCount   int
 	MostVisitedURLsCount int
+	// IPv6AggregatePrefix : When set (e.g. 64), IPv6 clients are counted per
+	// network of that prefix length, so privacy extension addresses of a
+	// single host are not reported as many unique IPs
+	IPv6AggregatePrefix int
+	// MostActiveNetworksCount : Number of most active client networks to report
+	MostActiveNetworksCount int
+	// IPv4NetworkPrefix, IPv6NetworkPrefix : Prefix lengths client addresses
+	// are grouped by into networks, DefaultIPv4NetworkPrefix and
+	// DefaultIPv6NetworkPrefix when not set. There is no /0, which would
+	// group every client into a single network.
+	IPv4NetworkPrefix int
+	IPv6NetworkPrefix int
+	// KeepRawURLs : Count URLs as logged, instead of percent-decoded and
+	// Unicode normalized
+	KeepRawURLs bool
+	// MaxURLs : Distinct URLs tracked per source (DefaultMaxURLs when not
+	// set). Once reached, hits of further URLs are counted under OverflowURL,
+	// so random tokens in paths cannot exhaust memory.
+	MaxURLs int
+	// ResourceStats : Report the resources the analytics took in
+	// LogAnalytics.Resources
+	ResourceStats bool
+	// TimeOrdered : Process lines in time order, even when the files are not
+	// sorted or several files are analyzed together. Lines are sorted with an
+	// external merge sort, so inputs larger than memory are supported.
+	TimeOrdered bool
+	// SortChunkSize : Lines sorted in memory before spilling to disk,
+	// DefaultSortChunkSize when not set
+	SortChunkSize int
+	// LineBatchSize : Lines allocated at once when reading a log,
+	// DefaultLineBatchSize when not set, 1 to allocate them one by one. See
+	// Line.Copy for keeping lines.
+	LineBatchSize int
+	// ReadAheadSize : Bytes of the log read ahead of parsing by a goroutine
+	// of its own, into two buffers in turn, so that reads overlap parsing,
+	// e.g. 4 MiB on network filesystems or spinning disks. Logs are read as
+	// parsed when not set.
+	ReadAheadSize int
+	// Tokenizer : How lines of the Common and Combined Log Formats are split,
+	// by default by scanning for their delimiters (TokenizerScan). Other
+	// formats are always matched against their regex.
+	Tokenizer Tokenizer
+	// SortTempDir : Directory sorted chunks are spilled to, the OS temporary
+	// directory when not set
+	SortTempDir string
+	// FollowPollInterval : How often a followed file is checked for appended
+	// lines, DefaultFollowPollInterval when not set
+	FollowPollInterval time.Duration
+	// SnapshotInterval : How often snapshots are emitted while following a
+	// file, DefaultSnapshotInterval when not set
+	SnapshotInterval time.Duration
+	// UniqueIPWindows : Windows, e.g. 5 minutes and 1 hour, the unique IPs of
+	// which are estimated up to the latest line, in UniqueIPWindows. IPs are
+	// not kept: a HyperLogLog sketch of the longest window estimates them
+	// within about 2%.
+	UniqueIPWindows []time.Duration
+	// SeenIPs : Client IPs seen by previous runs, e.g. read with ReadSeenIPs,
+	// to mark the most active IPs seen for the first time. The IPs of the run
+	// are added to it, for the caller to write for the next run.
+	SeenIPs *SeenIPs
+	// DecayHalfLife : While following a file, MostActiveIPs and
+	// MostVisitedURLs rank hits decayed by half every DecayHalfLife, e.g. 5
+	// minutes, so snapshots reflect recent activity rather than all-time
+	// totals. Hits decay from the logged time of their line. All-time totals
+	// when not set.
+	DecayHalfLife time.Duration
+	// SnapshotSignals : Signals (e.g. SIGUSR1) that trigger an extra snapshot
+	// while following a file
+	SnapshotSignals []os.Signal
+	// QueueSize : Lines buffered between reading and counting them,
+	// DefaultQueueSize when not set
+	QueueSize int
+	// QueuePolicy : What happens to followed lines when that buffer is full,
+	// by default reading waits (QueueBlock). Batch analyses always wait, so
+	// their reports count every line.
+	QueuePolicy QueuePolicy
+	// Enrichers : Enrichment chain run on every line, in order
+	Enrichers []*EnrichmentStep
+	// EnrichmentConcurrency : Lines enriched at once, so that slow lookups
+	// overlap; one after the other when not set
+	EnrichmentConcurrency int
+	// PreserveEnrichmentOrder : Count lines enriched concurrently in the
+	// order they were read, rather than as soon as they are enriched, e.g.
+	// for sessions. A slow lookup then holds the lines after it back, up to
+	// EnrichmentConcurrency of them.
+	PreserveEnrichmentOrder bool
+	// EnrichmentCacheSize : Lookups cached across the enrichers,
+	// DefaultEnrichmentCacheSize when not set
+	EnrichmentCacheSize int
+	// TopEnrichedValuesCount : Number of most common values to report for
+	// every enrichment field
+	TopEnrichedValuesCount int
+	// Script : Filtering, field derivation and scoring hooks, see LoadScript
+	Script *Script
+	// TopScoredIPsCount : Number of client IPs with the highest summed script
+	// scores to report
+	TopScoredIPsCount int
+	// Redaction : Rules removing secrets from URLs and referrers as lines are
+	// read, nil to keep them as logged
+	Redaction *RedactionPolicy
+	// LineIndex : Keeps the recent lines Follow reads searchable, nil to not
+	// keep them
+	LineIndex *LineIndex
+	// DisabledCollectors : Analytics not collected at all, to keep minimal runs
+	// fast; see the Collector constants for what each costs
+	DisabledCollectors []Collector
+	// Pageviews : Rules pageviews are counted by, nil to not count them
+	Pageviews *PageviewRules
+	// Sessions : Rules sessions are reconstructed by, nil to not reconstruct
+	// them. Sessions are made of pageviews, as defined by Pageviews or by
+	// default.
+	Sessions             *SessionRules
+	TopLandingPagesCount int
+	TopExitPagesCount    int
+	// TopReferrersCount : Number of referring hosts to report
+	TopReferrersCount int
+	// ReferrerBlocklist : Spam domains, with their subdomains, whose referrals
+	// are filtered out of TopReferrers. DefaultReferrerSpam when nil.
+	ReferrerBlocklist []string
+	// TopCampaignsCount : Number of UTM campaigns to report
+	TopCampaignsCount int
+	// TopErrorMessagesCount : Number of most common messages AnalyzeErrorLog
+	// reports
+	TopErrorMessagesCount int
+	// TimeseriesInterval : Interval of time series buckets,
+	// DefaultTimeseriesInterval when not set
+	TimeseriesInterval time.Duration
+	// TimeseriesRetention : Tiers time series buckets are rolled up into as
+	// they age, e.g. per-minute buckets kept a day, then per-hour ones kept 30
+	// days, after which they are dropped. Followed logs are compacted at every
+	// snapshot, so long running analyzers do not grow without bound. All
+	// buckets are kept at TimeseriesInterval when not set.
+	TimeseriesRetention []*RetentionTier
+	// DeviceTimeseries : Report requests per device type (mobile, tablet,
+	// desktop or bot) per interval, under the DeviceSeries time series
+	DeviceTimeseries bool
+	// EndpointGroups : Groups of URLs, whose requests per status class and
+	// per interval are reported as the StatusSeriesPrefix+name time series.
+	// A URL belongs to the first group matching it.
+	EndpointGroups []*EndpointGroup
+	// UpstreamsCount : Number of backends to report, the busiest first, when
+	// the line regex captures the upstream named group
+	UpstreamsCount int
+	// TopUsersCount : Number of authenticated users to report, those of the
+	// most requests first, when the format logs remote users
+	TopUsersCount int
+	// NonReusingClientsCount : Number of clients never reusing connections to
+	// report, when the format logs connections
+	NonReusingClientsCount int
+	// UncompressedURLsCount : Number of URLs serving large responses
+	// uncompressed to report, when the format logs compression
+	UncompressedURLsCount int
+	// LargeResponseBytes : Size from which uncompressed responses are
+	// reported, DefaultLargeResponseBytes when not set
+	LargeResponseBytes int
+	// SlowestRequestsCount : Number of slowest requests to report, when the
+	// format logs durations
+	SlowestRequestsCount int
+	// LargestResponsesCount : Number of largest responses to report
+	LargestResponsesCount int
+	// Percentiles : Percentiles reported for latencies and response sizes,
+	// e.g. 99.9, DefaultPercentiles when not set
+	Percentiles []float64
+	// LatencyBuckets : Upper bounds of the latency histogram buckets, which
+	// percentiles are estimated from, DefaultLatencyBuckets when not set
+	LatencyBuckets []time.Duration
+	// SizeBuckets : Upper bounds in bytes of the response size histogram
+	// buckets. When set, e.g. to DefaultSizeBuckets, ResponseSizes is reported.
+	SizeBuckets []int
+	// TopMoversCount : Number of risers and fallers reported by
+	// ComparePeriods, DefaultTopMoversCount when not set
+	TopMoversCount int
+	// Annotations : Known events, e.g. deploys or incidents, listed in the
+	// reports covering their time and on their time series buckets
+	Annotations []*Annotation
+	// TrustedProxies : IP addresses and networks in CIDR notation, e.g.
+	// "10.0.0.0/8", of the proxies trusted to report clients in
+	// X-Forwarded-For. A line from one of them is counted for the client the
+	// chain resolves to, for formats logging it, e.g. with
+	// %{X-Forwarded-For}i or $http_x_forwarded_for.
+	TrustedProxies []string
+	// StrictSchema : Fail the analysis on the first line matching the line
+	// regex with captures not of the type of their field, e.g. a status that
+	// is not a status code, or a time that does not parse, rather than count
+	// garbage from a slightly wrong regex. Positional line regexes must have
+	// exactly ten unnamed groups. Formats of structured lines, e.g. JSON, are
+	// not checked.
+	StrictSchema bool
+	// MatchedSamplesCount, UnmatchedSamplesCount : Number of lines, picked at
+	// random, reported as logged of those matching the log format and of those
+	// that do not, e.g. to check a line regex on an unfamiliar log. Lines are
+	// not sampled when Redaction is set.
+	MatchedSamplesCount   int
+	UnmatchedSamplesCount int
+	// TimeLayouts : Layouts of the times logged by line regex formats, as in
+	// time.Parse, or TimeLayoutUnix and TimeLayoutUnixMillis for Unix times,
+	// tried in order, DefaultTimeLayouts when not set. Lines whose time
+	// parses with none of them are counted as SelfMetrics.TimeParseErrors.
+	TimeLayouts []string
+	// OnWarning : Called with the problems of lines as they are read, e.g. to
+	// log, count or sample them: lines skipped as malformed (ParseFailure) or
+	// longer than 64 KiB (OversizedLine), lines whose time does not parse
+	// (TimeParseFailure), and URLs past MaxURLs (URLOverflow). It may be called from several goroutines at once,
+	// e.g. by AnalyzeBatch. Warnings are dropped when not set.
+	OnWarning func(Warning)
 }
 
 // NewLogAnalyzer : Returns a log analyzer that implements LogAnalyzer interface
@@ -199,13 +1112,259 @@ func NewLogAnalyzer(config *LogAnalyzerConfig) (LogAnalyzer, error) {
 	if config == nil {
 		return nil, errors.New(ErrConfigIsRequired)
 	}
-	if config.LineRegex == nil {
+	if config.Multiline != nil && config.Multiline.Start != nil && config.Multiline.Continuation != nil {
+		return nil, errors.New(ErrConflictingMultiline)
+	}
+	lineRegex := config.LineRegex
+	if lineRegex == nil && config.Format != nil {
+		lineRegex = config.Format.LineRegex
+	}
+	lineFormat := config.LineFormat
+	if config.FormatName != "" {
+		if lineFormat != nil {
+			return nil, errors.New(ErrConflictingFormats)
+		}
+		if lineFormat = config.Formats.Lookup(config.FormatName); lineFormat == nil {
+			return nil, errors.Wrap(errors.New(ErrUnknownFormat), config.FormatName)
+		}
+	}
+	formatsSet := 0
+	for _, set := range []bool{config.JSON, config.Logfmt, config.W3C, config.CloudFront, config.Heroku, config.CEF, config.Parser != nil, lineFormat != nil} {
+		if set {
+			formatsSet++
+		}
+	}
+	var lineFields fieldFormat
+	switch {
+	case formatsSet > 1:
+		return nil, errors.New(ErrConflictingFormats)
+	case config.JSON:
+		jsonFields := config.JSONFields
+		if len(jsonFields) == 0 {
+			jsonFields = DefaultJSONFields
+		}
+		lineFields = newJSONFormat(jsonFields)
+	case config.Logfmt:
+		logfmtFields := config.LogfmtFields
+		if len(logfmtFields) == 0 {
+			logfmtFields = DefaultLogfmtFields
+		}
+		lineFields = newLogfmtFormat(logfmtFields)
+	case config.Heroku:
+		herokuFields := config.LogfmtFields
+		if len(herokuFields) == 0 {
+			herokuFields = HerokuLogfmtFields
+		}
+		lineFields = newHerokuFormat(herokuFields)
+	case config.CEF:
+		lineFields = newCEFFormat(config.CEFFields)
+	case config.W3C:
+		lineFields = w3cFormat{}
+	case config.CloudFront:
+		lineFields = cloudFrontFormat{}
+	case config.Parser != nil:
+		lineFields = parserFormat{config.Parser}
+	case lineFormat != nil:
+		// formats of the formats package, e.g. the presets, are read with
+		// their line regex, as if set as Format
+		if format, ok := lineFormat.(regexFormat); ok && lineRegex == nil {
+			lineRegex = format.format.LineRegex
+		} else {
+			lineFields = parserFormat{formatParser(lineFormat)}
+		}
+	case lineRegex == nil:
 		return nil, errors.New(ErrLineRegexIsRequired)
 	}
+	fieldsNeeded := newProjection(config.Fields)
+	var fields []string
+	if lineFields != nil {
+		if fieldsNeeded != nil {
+			lineFields = lineFields.project(fieldsNeeded)
+		}
+		fields = lineFields.fields()
+	} else {
+		fields = fieldsNeeded.names(groupNames(lineRegex))
+	}
+	var schema *lineSchema
+	if config.StrictSchema && lineFields == nil {
+		var err error
+		if schema, err = newLineSchema(lineRegex, config.TimeLayouts); err != nil {
+			return nil, err
+		}
+	}
+	trusted, err := newTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	logsTime := containsString(fields, "time")
+	if lineFields == nil {
+		// the time of positional line regexes is their second group
+		if !namesGroups(lineRegex.SubexpNames()) {
+			logsTime = fieldsNeeded.keeps("time")
+		}
+	}
+	if config.IPv6AggregatePrefix < 0 || config.IPv6AggregatePrefix > 128 {
+		return nil, errors.New(ErrInvalidIPv6Prefix)
+	}
+	ipv4NetworkPrefix := config.IPv4NetworkPrefix
+	if ipv4NetworkPrefix == 0 {
+		ipv4NetworkPrefix = DefaultIPv4NetworkPrefix
+	}
+	ipv6NetworkPrefix := config.IPv6NetworkPrefix
+	if ipv6NetworkPrefix == 0 {
+		ipv6NetworkPrefix = DefaultIPv6NetworkPrefix
+	}
+	if ipv4NetworkPrefix < 1 || ipv4NetworkPrefix > 32 || ipv6NetworkPrefix < 1 || ipv6NetworkPrefix > 128 {
+		return nil, errors.New(ErrInvalidNetworkPrefix)
+	}
+	sortChunkSize := config.SortChunkSize
+	if sortChunkSize <= 0 {
+		sortChunkSize = DefaultSortChunkSize
+	}
+	lineBatchSize := config.LineBatchSize
+	if lineBatchSize <= 0 {
+		lineBatchSize = DefaultLineBatchSize
+	}
+	uniqueIPWindow := longestWindow(config.UniqueIPWindows)
+	if len(config.UniqueIPWindows) > 0 && uniqueIPWindow == 0 {
+		return nil, errors.New(ErrInvalidUniqueIPWindow)
+	}
+	if config.QueuePolicy < QueueBlock || config.QueuePolicy > QueueDropOldest {
+		return nil, errors.New(ErrInvalidQueuePolicy)
+	}
+	if config.Tokenizer < TokenizerScan || config.Tokenizer > TokenizerRegex {
+		return nil, errors.New(ErrInvalidTokenizer)
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	enrichmentCacheSize := config.EnrichmentCacheSize
+	if enrichmentCacheSize <= 0 {
+		enrichmentCacheSize = DefaultEnrichmentCacheSize
+	}
+	collectors, err := enabledCollectors(config.DisabledCollectors)
+	if err != nil {
+		return nil, err
+	}
+	maxURLs := config.MaxURLs
+	if maxURLs <= 0 {
+		maxURLs = DefaultMaxURLs
+	}
+	var sessionTimeout time.Duration
+	if config.Sessions != nil {
+		sessionTimeout = config.Sessions.Timeout
+		if sessionTimeout <= 0 {
+			sessionTimeout = DefaultSessionTimeout
+		}
+	}
+	referrerSpam := config.ReferrerBlocklist
+	if referrerSpam == nil {
+		referrerSpam = DefaultReferrerSpam
+	}
+	timeseriesInterval := config.TimeseriesInterval
+	if timeseriesInterval <= 0 {
+		timeseriesInterval = DefaultTimeseriesInterval
+	}
+	if err := checkRetention(config.TimeseriesRetention, timeseriesInterval); err != nil {
+		return nil, err
+	}
+	percentiles, err := checkPercentiles(config.Percentiles)
+	if err != nil {
+		return nil, err
+	}
+	latencyBuckets := config.LatencyBuckets
+	if len(latencyBuckets) == 0 {
+		latencyBuckets = DefaultLatencyBuckets
+	}
+	latencyBounds := secondsBounds(latencyBuckets)
+	if err := checkBounds(latencyBounds); err != nil {
+		return nil, errors.Wrap(err, "latency buckets")
+	}
+	var sizeBounds []float64
+	if len(config.SizeBuckets) > 0 {
+		sizeBounds = bytesBounds(config.SizeBuckets)
+		if err := checkBounds(sizeBounds); err != nil {
+			return nil, errors.Wrap(err, "size buckets")
+		}
+	}
+	largeResponseBytes := config.LargeResponseBytes
+	if largeResponseBytes <= 0 {
+		largeResponseBytes = DefaultLargeResponseBytes
+	}
+	followPollInterval := config.FollowPollInterval
+	if followPollInterval <= 0 {
+		followPollInterval = DefaultFollowPollInterval
+	}
+	snapshotInterval := config.SnapshotInterval
+	if snapshotInterval <= 0 {
+		snapshotInterval = DefaultSnapshotInterval
+	}
+
+	l := &logAnalyzer{
+		lineRegex:           lineRegex,
+		lineFields:          lineFields,
+		schema:              schema,
+		trustedProxies:      trusted,
+		timeLayouts:         config.TimeLayouts,
+		projection:          fieldsNeeded,
+		syslog:              config.Syslog,
+		multiline:           config.Multiline,
+		ipv6AggregatePrefix: config.IPv6AggregatePrefix,
+		ipv4NetworkPrefix:   ipv4NetworkPrefix,
+		ipv6NetworkPrefix:   ipv6NetworkPrefix,
+		keepRawURLs:         config.KeepRawURLs,
+		maxURLs:             maxURLs,
+		resourceStats:       config.ResourceStats,
+		timeOrdered:         config.TimeOrdered,
+		sortChunkSize:       sortChunkSize,
+		lineBatchSize:       lineBatchSize,
+		tokenizer:           config.Tokenizer,
+		readAheadSize:       config.ReadAheadSize,
+		sortTempDir:         config.SortTempDir,
+		followPollInterval:  followPollInterval,
+		snapshotInterval:    snapshotInterval,
+		decayHalfLife:       config.DecayHalfLife,
+		uniqueIPWindows:     config.UniqueIPWindows,
+		uniqueIPWindow:      uniqueIPWindow,
+		seenIPs:             config.SeenIPs,
+		snapshotSignals:     config.SnapshotSignals,
+		queueSize:           queueSize,
+		queuePolicy:         config.QueuePolicy,
+		enrichers:           config.Enrichers,
+		enrichmentCache:     newEnrichmentCache(enrichmentCacheSize),
+		enrichConcurrency:   config.EnrichmentConcurrency,
+		enrichInOrder:       config.PreserveEnrichmentOrder,
+		script:              config.Script,
+		redaction:           config.Redaction,
+		lineIndex:           config.LineIndex,
+		collectors:          collectors,
+		pageviews:           config.Pageviews,
+		sessionTimeout:      sessionTimeout,
+		referrerBlocklist:   newReferrerBlocklist(referrerSpam),
+		timeseriesInterval:  timeseriesInterval,
+		retention:           config.TimeseriesRetention,
+		deviceTimeseries:    config.DeviceTimeseries,
+		endpointGroups:      config.EndpointGroups,
+		logsDurations: containsString(fields, "duration") || containsString(fields, "duration_ms") ||
+			containsString(fields, "duration_us") || containsString(fields, "duration_ns"),
+		latencyBounds: latencyBounds,
+		sizeBounds:    sizeBounds,
+		percentiles:   percentiles,
+		logsCompression: containsString(fields, "content_encoding") ||
+			containsString(fields, "original_bytes") || containsString(fields, "gzip_ratio"),
+		logsCache:          containsString(fields, "cache_status"),
+		logsVHosts:         containsString(fields, "vhost"),
+		logsTLS:            containsString(fields, "ssl_protocol") || containsString(fields, "ssl_cipher"),
+		logsUsers:          containsString(fields, "remote_user"),
+		logsTime:           logsTime,
+		onWarning:          config.OnWarning,
+		matchedSamples:     config.MatchedSamplesCount,
+		unmatchedSamples:   config.UnmatchedSamplesCount,
+		largeResponseBytes: largeResponseBytes,
+		metrics:            selfMetrics{startedAt: time.Now()},
+	}
+	l.settings.Store(newReloadableSettings(config))
 
-	return &logAnalyzer{
-		lineRegex:            config.LineRegex,
-		mostActiveIPsCount:   config.MostActiveIPsCount,
-		mostVisitedURLsCount: config.MostVisitedURLsCount,
-	}, nil
+	return l, nil
 }
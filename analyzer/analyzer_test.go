@@ -7,6 +7,8 @@ import (
 	"reflect"
 	"regexp"
 	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
 )
 
 func Test_logAnalyzer_Analyze(t *testing.T) {
@@ -30,9 +32,14 @@ func Test_logAnalyzer_Analyze(t *testing.T) {
 	}
 
 	type fields struct {
-		lineRegex            *regexp.Regexp
-		mostActiveIPsCount   int
-		mostVisitedURLsCount int
+		lineRegex               *regexp.Regexp
+		mostActiveIPsCount      int
+		mostVisitedURLsCount    int
+		ipv6AggregatePrefix     int
+		mostActiveNetworksCount int
+		keepRawURLs             bool
+		disabledCollectors      []Collector
+		pageviews               *PageviewRules
 	}
 	type args struct {
 		filePath string
@@ -83,13 +90,102 @@ func Test_logAnalyzer_Analyze(t *testing.T) {
 				MostActiveIPs: []string{"177.71.128.21", "168.41.191.40", "50.112.00.11"},
 			},
 		},
+		{
+			name:   "analytics - ipv6 representations of one address are counted once",
+			fields: fields{lineRegex: defaultLineRegex},
+			args:   args{filePath: "./test-data/ipv6-clients.log"},
+			want: &LogAnalytics{
+				UniqueIPCount: 4,
+			},
+		},
+		{
+			name: "analytics - ipv6 clients aggregated by /64",
+			fields: fields{
+				lineRegex:           defaultLineRegex,
+				mostActiveIPsCount:  1,
+				ipv6AggregatePrefix: 64,
+			},
+			args: args{filePath: "./test-data/ipv6-clients.log"},
+			want: &LogAnalytics{
+				UniqueIPCount: 2,
+				MostActiveIPs: []string{"2001:db8:0:1::/64"},
+			},
+		},
+		{
+			name: "analytics - most active networks",
+			fields: fields{
+				lineRegex:               defaultLineRegex,
+				mostActiveNetworksCount: 2,
+			},
+			args: args{filePath: "./test-data/ipv6-clients.log"},
+			want: &LogAnalytics{
+				UniqueIPCount:      4,
+				MostActiveNetworks: []string{"2001:db8::/48", "177.71.128.0/24"},
+			},
+		},
+		{
+			name: "analytics - encoded urls aggregated with their decoded form",
+			fields: fields{
+				lineRegex:            defaultLineRegex,
+				mostVisitedURLsCount: 2,
+			},
+			args: args{filePath: "./test-data/encoded-urls.log"},
+			want: &LogAnalytics{
+				UniqueIPCount:   2,
+				MostVisitedURLs: []string{"/café", "/menu/"},
+			},
+		},
+		{
+			name: "analytics - raw urls kept",
+			fields: fields{
+				lineRegex:            defaultLineRegex,
+				mostVisitedURLsCount: 1,
+				keepRawURLs:          true,
+			},
+			args: args{filePath: "./test-data/encoded-urls.log"},
+			want: &LogAnalytics{
+				UniqueIPCount:   2,
+				MostVisitedURLs: []string{"/menu/"},
+			},
+		},
+		{
+			name: "analytics - pageviews exclude errors, assets and bots",
+			fields: fields{
+				lineRegex: defaultLineRegex,
+				pageviews: &PageviewRules{},
+			},
+			args: args{filePath: "./test-data/programming-task.log"},
+			want: &LogAnalytics{
+				UniqueIPCount: 11,
+				Pageviews:     15,
+			},
+		},
+		{
+			name: "analytics - disabled collectors report nothing",
+			fields: fields{
+				lineRegex:               defaultLineRegex,
+				mostActiveIPsCount:      1,
+				mostVisitedURLsCount:    1,
+				mostActiveNetworksCount: 1,
+				disabledCollectors:      []Collector{CollectIPs, CollectNetworks},
+			},
+			args: args{filePath: "./test-data/encoded-urls.log"},
+			want: &LogAnalytics{
+				MostVisitedURLs: []string{"/café"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			config := &LogAnalyzerConfig{
-				LineRegex:            tt.fields.lineRegex,
-				MostActiveIPsCount:   tt.fields.mostActiveIPsCount,
-				MostVisitedURLsCount: tt.fields.mostVisitedURLsCount,
+				LineRegex:               tt.fields.lineRegex,
+				MostActiveIPsCount:      tt.fields.mostActiveIPsCount,
+				MostVisitedURLsCount:    tt.fields.mostVisitedURLsCount,
+				IPv6AggregatePrefix:     tt.fields.ipv6AggregatePrefix,
+				MostActiveNetworksCount: tt.fields.mostActiveNetworksCount,
+				KeepRawURLs:             tt.fields.keepRawURLs,
+				DisabledCollectors:      tt.fields.disabledCollectors,
+				Pageviews:               tt.fields.pageviews,
 			}
 			l, err := NewLogAnalyzer(config)
 			if err != nil {
@@ -117,6 +213,99 @@ func Test_logAnalyzer_Analyze(t *testing.T) {
 	}
 }
 
+func Test_logAnalyzer_AnalyzeFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		filePaths []string
+		want      *LogAnalytics
+		wantErr   error
+	}{
+		{
+			name:      "error when one of the file paths is wrong",
+			filePaths: []string{"./test-data/encoded-urls.log", "./test-data.log"},
+			wantErr:   errors.New(ErrOpeningFile),
+		},
+		{
+			name:      "analytics - merged totals with per source sub-reports",
+			filePaths: []string{"./test-data/encoded-urls.log", "./test-data/ipv6-clients.log"},
+			want: &LogAnalytics{
+				UniqueIPCount: 5,
+				MostActiveIPs: []string{"177.71.128.21"},
+				Sources: map[string]*LogAnalytics{
+					"./test-data/encoded-urls.log": {
+						UniqueIPCount: 2,
+						MostActiveIPs: []string{"168.41.191.40"},
+					},
+					"./test-data/ipv6-clients.log": {
+						UniqueIPCount: 4,
+						MostActiveIPs: []string{"2001:db8:0:1:a1b2:c3d4:e5f6:1"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+				LineRegex:          defaultLineRegex,
+				MostActiveIPsCount: 1,
+			})
+			if err != nil {
+				t.Errorf("logAnalyzer.AnalyzeFiles() error = %v, error creating analyzer", err)
+				return
+			}
+			got, err := l.AnalyzeFiles(tt.filePaths...)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Errorf("logAnalyzer.AnalyzeFiles() error is expected")
+					return
+				}
+				if tt.wantErr.Error() != err.Error() {
+					t.Errorf("logAnalyzer.AnalyzeFiles() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logAnalyzer.AnalyzeFiles() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_topMost(t *testing.T) {
+	metrics := map[string]int{"/a": 3, "/d": 1, "/c": 1, "/b": 1, "/e": 2}
+	want := []string{"/a", "/e", "/b"}
+	// ties rank by key, whatever the order of the map
+	for i := 0; i < 20; i++ {
+		if got := topMost(metrics, 3); !reflect.DeepEqual(got, want) {
+			t.Fatalf("topMost() = %v, want %v", got, want)
+		}
+	}
+}
+
+func Test_logAnalyzer_AnalyzeFiles_duplicatePaths(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, Pageviews: &PageviewRules{}})
+	if err != nil {
+		t.Fatalf("logAnalyzer.AnalyzeFiles() error = %v, error creating analyzer", err)
+	}
+	// the same file, by two spellings of its path
+	filePaths := []string{"./test-data/programming-task.log", "test-data/programming-task.log"}
+	got, err := l.AnalyzeFiles(filePaths...)
+	if err != nil {
+		t.Fatalf("logAnalyzer.AnalyzeFiles() error = %v", err)
+	}
+	if got.Pageviews != 15 || len(got.Sources) != 1 {
+		t.Errorf("logAnalyzer.AnalyzeFiles() = %d pageviews of %d sources, want 15 of 1", got.Pageviews, len(got.Sources))
+	}
+	partial, err := l.AnalyzePartial(filePaths...)
+	if err != nil {
+		t.Fatalf("logAnalyzer.AnalyzePartial() error = %v", err)
+	}
+	if got := l.MergePartials(partial); got.Pageviews != 15 {
+		t.Errorf("logAnalyzer.AnalyzePartial() = %d pageviews, want 15", got.Pageviews)
+	}
+}
+
 func TestNewLogAnalyzer(t *testing.T) {
 	type args struct {
 		config *LogAnalyzerConfig
@@ -139,6 +328,46 @@ func TestNewLogAnalyzer(t *testing.T) {
 			},
 			wantErr: errors.New(ErrLineRegexIsRequired),
 		},
+		{
+			name: "error: ipv6 aggregation prefix out of range",
+			args: args{
+				config: &LogAnalyzerConfig{
+					LineRegex:           regexp.MustCompile(`.*`),
+					IPv6AggregatePrefix: 129,
+				},
+			},
+			wantErr: errors.New(ErrInvalidIPv6Prefix),
+		},
+		{
+			name: "error: ipv4 network prefix out of range",
+			args: args{
+				config: &LogAnalyzerConfig{
+					LineRegex:         regexp.MustCompile(`.*`),
+					IPv4NetworkPrefix: 33,
+				},
+			},
+			wantErr: errors.New(ErrInvalidNetworkPrefix),
+		},
+		{
+			name: "error: ipv6 network prefix out of range",
+			args: args{
+				config: &LogAnalyzerConfig{
+					LineRegex:         regexp.MustCompile(`.*`),
+					IPv6NetworkPrefix: -1,
+				},
+			},
+			wantErr: errors.New(ErrInvalidNetworkPrefix),
+		},
+		{
+			name: "error: unknown collector",
+			args: args{
+				config: &LogAnalyzerConfig{
+					LineRegex:          regexp.MustCompile(`.*`),
+					DisabledCollectors: []Collector{"geo"},
+				},
+			},
+			wantErr: errors.New("geo: " + ErrUnknownCollector),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -158,3 +387,60 @@ func TestNewLogAnalyzer(t *testing.T) {
 		})
 	}
 }
+
+func Test_logAnalyzer_Analyze_format(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		Format:               formats.CommonLog,
+		MostActiveIPsCount:   1,
+		MostVisitedURLsCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/common.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	want := &LogAnalytics{
+		UniqueIPCount:   3,
+		MostActiveIPs:   []string{"168.41.191.40"},
+		MostVisitedURLs: []string{"/docs/manage-websites/"},
+	}
+	if got.UniqueIPCount != want.UniqueIPCount || !reflect.DeepEqual(got.MostActiveIPs, want.MostActiveIPs) ||
+		!reflect.DeepEqual(got.MostVisitedURLs, want.MostVisitedURLs) {
+		t.Errorf("logAnalyzer.Analyze() = %d %v %v, want %d %v %v", got.UniqueIPCount, got.MostActiveIPs, got.MostVisitedURLs,
+			want.UniqueIPCount, want.MostActiveIPs, want.MostVisitedURLs)
+	}
+}
+
+func Test_logAnalyzer_report_vhosts(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{Format: formats.VHostCombined})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	l := a.(*logAnalyzer)
+
+	lines := []string{
+		`www.example.com:443 10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 3574 "-" "curl/8.4.0"`,
+		`blog.example.com:443 10.0.0.2 - - [10/Jul/2018:22:21:29 +0200] "GET /posts/ HTTP/1.1" 200 3574 "-" "curl/8.4.0"`,
+		`www.example.com:80 10.0.0.1 - - [10/Jul/2018:22:21:30 +0200] "GET /about HTTP/1.1" 301 0 "-" "curl/8.4.0"`,
+	}
+	agg := newAggregate()
+	for _, text := range lines {
+		line, err := l.parse(l.newLineParser(), text)
+		if err != nil {
+			t.Fatalf("logAnalyzer.parse() error = %v", err)
+		}
+		l.consolidate(agg, line)
+	}
+
+	want := map[string]int{"www.example.com": 2, "blog.example.com": 1}
+	if got := l.report(agg).RequestsByVHost; !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() requests by vhost = %v, want %v", got, want)
+	}
+
+	// formats without virtual hosts report none
+	if got := l.report(newAggregate()).RequestsByVHost; got != nil {
+		t.Errorf("logAnalyzer.report() requests by vhost = %v, want nil", got)
+	}
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+)
+
+// Annotation : A known event, e.g. a deploy or an incident, shown in reports
+// so traffic changes can be correlated with it
+type Annotation struct {
+	Time  time.Time
+	Label string
+}
+
+// observeTime : Extends the time range of the aggregated lines to t. Lines of
+// unparsable times are left out.
+func (a *aggregate) observeTime(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	n := t.UnixNano()
+	if a.firstSeen == 0 || n < a.firstSeen {
+		a.firstSeen = n
+	}
+	if n > a.lastSeen {
+		a.lastSeen = n
+	}
+}
+
+// mergeTimeRange : Extends the time range to cover other's
+func (a *aggregate) mergeTimeRange(firstSeen, lastSeen int64) {
+	if firstSeen != 0 && (a.firstSeen == 0 || firstSeen < a.firstSeen) {
+		a.firstSeen = firstSeen
+	}
+	if lastSeen > a.lastSeen {
+		a.lastSeen = lastSeen
+	}
+}
+
+// annotationsReport : The annotations within the time range of the lines, in
+// time order
+func annotationsReport(annotations []*Annotation, firstSeen, lastSeen int64) []*Annotation {
+	var report []*Annotation
+	for _, a := range annotations {
+		if n := a.Time.UnixNano(); firstSeen != 0 && n >= firstSeen && n <= lastSeen {
+			report = append(report, a)
+		}
+	}
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Time.Before(report[j].Time) })
+	return report
+}
+
+// annotateTimeseries : Lists on every bucket the labels of the annotations
+// of its interval
+func annotateTimeseries(timeseries map[string][]*TimeseriesBucket, annotations []*Annotation, intervalOf func(start time.Time) time.Duration) {
+	for _, buckets := range timeseries {
+		for _, bucket := range buckets {
+			end := bucket.Start.Add(intervalOf(bucket.Start))
+			for _, a := range annotations {
+				if !a.Time.Before(bucket.Start) && a.Time.Before(end) {
+					bucket.Annotations = append(bucket.Annotations, a.Label)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_Analyze_annotations(t *testing.T) {
+	at := func(value string) time.Time {
+		v, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	incident := &Annotation{Time: at("2018-07-10T22:21:32+02:00"), Label: "incident"}
+	deploy := &Annotation{Time: at("2018-07-10T22:21:30+02:00"), Label: "deploy v1.2"}
+	lineRegex := regexp.MustCompile(strings.TrimSuffix(defaultLineRegex.String(), "$") +
+		`\s(?P<upstream>\S+(?:, \S+)*)\s(?P<duration>\S+)$`)
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:          lineRegex,
+		DeviceTimeseries:   true,
+		TimeseriesInterval: time.Hour,
+		Annotations: []*Annotation{
+			incident,
+			{Time: at("2018-07-10T22:00:00+02:00"), Label: "before the lines"},
+			deploy,
+			{Time: at("2018-07-11T09:00:00+02:00"), Label: "after the lines"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/upstreams.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	if want := []*Annotation{deploy, incident}; !reflect.DeepEqual(got.Annotations, want) {
+		t.Errorf("logAnalyzer.Analyze() annotations = %v, want %v", got.Annotations, want)
+	}
+	buckets := got.Timeseries[DeviceSeries]
+	if len(buckets) != 1 {
+		t.Fatalf("logAnalyzer.Analyze() device buckets = %d, want 1", len(buckets))
+	}
+	if want := []string{"deploy v1.2", "incident"}; !reflect.DeepEqual(buckets[0].Annotations, want) {
+		t.Errorf("logAnalyzer.Analyze() bucket annotations = %q, want %q", buckets[0].Annotations, want)
+	}
+}
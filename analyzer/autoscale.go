@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAutoscaleInterval : How often autoscaling batches measure their
+	// throughput and resize
+	DefaultAutoscaleInterval = time.Second
+
+	// autoscaleGain : Relative throughput gain a worker must bring to be kept
+	autoscaleGain = 0.1
+	// autoscaleProbeIntervals : Intervals a settled batch waits before trying
+	// one more worker again, as the files being analyzed change
+	autoscaleProbeIntervals = 10
+	// autoscaleFullBacklog : Share of the queues filled above which counting,
+	// rather than reading, is the bottleneck
+	autoscaleFullBacklog = 0.5
+)
+
+// defaultAutoscaleParallelism : Maximum number of files analyzed at once by
+// an autoscaling batch, when its Parallelism is not set
+func defaultAutoscaleParallelism() int {
+	return 4 * runtime.GOMAXPROCS(0)
+}
+
+// workerLimit : A limit on the number of files analyzed at once, which can be
+// changed while they are
+type workerLimit struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	running int
+}
+
+func newWorkerLimit(limit int) *workerLimit {
+	w := &workerLimit{limit: limit}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// acquire : Waits for room for one more worker
+func (w *workerLimit) acquire() {
+	w.mu.Lock()
+	for w.running >= w.limit {
+		w.cond.Wait()
+	}
+	w.running++
+	w.mu.Unlock()
+}
+
+func (w *workerLimit) release() {
+	w.mu.Lock()
+	w.running--
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// active : The number of files being analyzed
+func (w *workerLimit) active() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.running
+}
+
+// set : Changes the limit. Workers over a lowered limit finish their file.
+func (w *workerLimit) set(limit int) {
+	w.mu.Lock()
+	w.limit = limit
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// autoscaler : Hill climbs the number of workers on the measured throughput:
+// a worker is added while the last one raised throughput by autoscaleGain,
+// and removed when it did not, until the next probe. Workers counting
+// faster than they read (backlog filling the queues) are CPU-bound, and not
+// added beyond GOMAXPROCS, where they would only compete for the cores;
+// workers waiting for reads are, as they overlap the waits.
+type autoscaler struct {
+	limit, max int
+	cpus       int
+	// lastRate : Throughput before the last step, lines per second
+	lastRate float64
+	// climbing : Whether the last step added a worker, to be judged
+	climbing bool
+	// settled : Intervals since the batch settled
+	settled int
+}
+
+// newAutoscaler : An autoscaler starting with one worker, probing for a
+// second one at the first interval
+func newAutoscaler(max int) *autoscaler {
+	return &autoscaler{limit: 1, max: max, cpus: runtime.GOMAXPROCS(0), settled: autoscaleProbeIntervals - 1}
+}
+
+// next : The number of workers for the next interval, given the throughput
+// and the backlog of the last one, as the share of the queues filled
+func (a *autoscaler) next(rate, backlog float64) int {
+	if a.climbing && rate < a.lastRate*(1+autoscaleGain) {
+		// the last worker did not pay for itself
+		a.climbing = false
+		a.settled = 0
+		if a.limit > 1 {
+			a.limit--
+		}
+		return a.limit
+	}
+	if !a.climbing {
+		if a.settled++; a.settled < autoscaleProbeIntervals {
+			return a.limit
+		}
+	}
+	if a.limit >= a.max || a.limit >= a.cpus && backlog > autoscaleFullBacklog {
+		a.climbing = false
+		a.settled = 0
+		a.lastRate = rate
+		return a.limit
+	}
+	a.lastRate = rate
+	a.climbing = true
+	a.limit++
+	return a.limit
+}
+
+// autoscale : Resizes the worker limit every interval until done is closed
+func (l *logAnalyzer) autoscale(workers *workerLimit, max int, interval time.Duration, done <-chan struct{}) {
+	scaler := newAutoscaler(max)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lines := atomic.LoadInt64(&l.metrics.linesConsolidated)
+	last := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			consolidated := atomic.LoadInt64(&l.metrics.linesConsolidated)
+			rate := float64(consolidated-lines) / now.Sub(last).Seconds()
+			lines, last = consolidated, now
+
+			backlog := 0.0
+			if running := workers.active(); running > 0 {
+				backlog = float64(l.SelfMetrics().QueueDepth) / float64(running*l.queueSize)
+			}
+			workers.set(scaler.next(rate, backlog))
+		}
+	}
+}
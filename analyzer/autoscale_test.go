@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_autoscaler_next(t *testing.T) {
+	type interval struct {
+		rate, backlog float64
+	}
+	tests := []struct {
+		name      string
+		max, cpus int
+		intervals []interval
+		want      []int
+	}{
+		{
+			name:      "climbs while workers raise throughput, then backs off",
+			max:       8,
+			cpus:      8,
+			intervals: []interval{{100, 0}, {190, 0}, {270, 0}, {280, 0}, {280, 0}},
+			want:      []int{2, 3, 4, 3, 3},
+		},
+		{
+			name:      "probes again once settled",
+			max:       8,
+			cpus:      8,
+			intervals: []interval{{100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}, {100, 0}},
+			want:      []int{2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2},
+		},
+		{
+			name:      "stops at the maximum",
+			max:       2,
+			cpus:      8,
+			intervals: []interval{{100, 0}, {200, 0}, {400, 0}},
+			want:      []int{2, 2, 2},
+		},
+		{
+			name:      "CPU-bound workers are not added beyond the cores",
+			max:       8,
+			cpus:      2,
+			intervals: []interval{{100, 0.9}, {200, 0.9}, {300, 0.9}},
+			want:      []int{2, 2, 2},
+		},
+		{
+			name:      "workers waiting for reads are",
+			max:       8,
+			cpus:      2,
+			intervals: []interval{{100, 0}, {200, 0}, {300, 0}},
+			want:      []int{2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAutoscaler(tt.max)
+			a.cpus = tt.cpus
+			var got []int
+			for _, i := range tt.intervals {
+				got = append(got, a.next(i.rate, i.backlog))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("autoscaler.next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package analyzer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrManifestIsRequired :
+	ErrManifestIsRequired = "batch manifest is required"
+	// ErrReadingManifest :
+	ErrReadingManifest = "error reading batch manifest"
+	// ErrReadingBatchState :
+	ErrReadingBatchState = "error reading batch state"
+	// ErrWritingBatchState :
+	ErrWritingBatchState = "error writing batch state"
+)
+
+// BatchConfig :
+type BatchConfig struct {
+	// ManifestPath : File listing the log files to analyze, one per line.
+	// Blank lines and lines starting with # are ignored.
+	ManifestPath string
+	// StatePath : File the counts of every completed log file are recorded
+	// in. When it exists, the batch resumes with the files not completed yet.
+	// The state is only meaningful to an analyzer with the same config.
+	StatePath string
+	// Parallelism : Maximum number of files analyzed at once, 1 when not
+	// set, or 4 × GOMAXPROCS when autoscaling
+	Parallelism int
+	// Autoscale : Tune the number of files analyzed at once, from 1 up to
+	// Parallelism, on the measured lines per second and queue backlog,
+	// instead of always analyzing Parallelism files at once
+	Autoscale bool
+	// AutoscaleInterval : How often throughput is measured and the number of
+	// files analyzed at once changed, DefaultAutoscaleInterval when not set
+	AutoscaleInterval time.Duration
+}
+
+// batchState : Completion state of a batch, as persisted in BatchConfig.StatePath
+type batchState struct {
+	Completed map[string]*aggregate `json:"completed"`
+}
+
+func (l *logAnalyzer) RunBatch(config *BatchConfig) (*LogAnalytics, error) {
+	if config == nil || config.ManifestPath == "" {
+		return nil, errors.New(ErrManifestIsRequired)
+	}
+	filePaths, err := readManifest(config.ManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	state, err := readBatchState(config.StatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	parallelism := config.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+		if config.Autoscale {
+			parallelism = defaultAutoscaleParallelism()
+		}
+	}
+	workers := newWorkerLimit(parallelism)
+	if config.Autoscale {
+		interval := config.AutoscaleInterval
+		if interval <= 0 {
+			interval = DefaultAutoscaleInterval
+		}
+		workers.set(1)
+		done := make(chan struct{})
+		defer close(done)
+		go l.autoscale(workers, parallelism, interval, done)
+	}
+
+	pendingCh := make(chan string)
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				workers.acquire()
+				filePath, ok := <-pendingCh
+				if !ok {
+					workers.release()
+					return
+				}
+				agg, err := l.aggregateFile(filePath)
+				workers.release()
+
+				mu.Lock()
+				if err == nil {
+					state.Completed[filePath] = agg
+					err = writeBatchState(config.StatePath, state)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, filePath := range filePaths {
+		mu.Lock()
+		_, completed := state.Completed[filePath]
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		if !completed {
+			pendingCh <- filePath
+		}
+	}
+	close(pendingCh)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	merged := newAggregate()
+	sources := make(map[string]*LogAnalytics, len(filePaths))
+	for _, filePath := range filePaths {
+		agg := state.Completed[filePath]
+		merged.merge(agg)
+		sources[filePath] = l.report(agg)
+	}
+
+	analytics := l.report(merged)
+	analytics.Sources = sources
+	return analytics, nil
+}
+
+func readManifest(manifestPath string) ([]string, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrReadingManifest)
+	}
+	defer file.Close()
+
+	var filePaths []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		filePath := strings.TrimSpace(scanner.Text())
+		if filePath == "" || strings.HasPrefix(filePath, "#") || seen[filePath] {
+			continue
+		}
+		seen[filePath] = true
+		filePaths = append(filePaths, filePath)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, ErrReadingManifest)
+	}
+
+	return filePaths, nil
+}
+
+func readBatchState(statePath string) (*batchState, error) {
+	state := &batchState{Completed: make(map[string]*aggregate)}
+	if statePath == "" {
+		return state, nil
+	}
+
+	data, err := ioutil.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, ErrReadingBatchState)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrap(err, ErrReadingBatchState)
+	}
+	if state.Completed == nil {
+		state.Completed = make(map[string]*aggregate)
+	}
+
+	return state, nil
+}
+
+// writeBatchState : Replaces the state file atomically, so an interruption
+// never leaves a truncated state behind
+func writeBatchState(statePath string, state *batchState) error {
+	if statePath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, ErrWritingBatchState)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(statePath), filepath.Base(statePath)+".*")
+	if err != nil {
+		return errors.Wrap(err, ErrWritingBatchState)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, ErrWritingBatchState)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, ErrWritingBatchState)
+	}
+	if err := os.Rename(tmp.Name(), statePath); err != nil {
+		return errors.Wrap(err, ErrWritingBatchState)
+	}
+
+	return nil
+}
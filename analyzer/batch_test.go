@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_RunBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	manifest := write("manifest.txt", `# july archives
+./test-data/encoded-urls.log
+
+./test-data/ipv6-clients.log
+./test-data/encoded-urls.log
+`)
+	resumeManifest := write("resume-manifest.txt", "./test-data/encoded-urls.log\n./test-data/archived.log\n")
+	resumeState := write("resume-state.json", `{"completed": {"./test-data/archived.log": {"ipHits": {"10.0.0.1": 9}, "urlHits": {"/old/": 9}}}}`)
+
+	tests := []struct {
+		name    string
+		config  *BatchConfig
+		want    *LogAnalytics
+		wantErr error
+	}{
+		{
+			name:    "error: no manifest",
+			config:  &BatchConfig{},
+			wantErr: errors.New(ErrManifestIsRequired),
+		},
+		{
+			name: "analytics - manifest files merged, duplicates and comments skipped",
+			config: &BatchConfig{
+				ManifestPath: manifest,
+				StatePath:    filepath.Join(dir, "state.json"),
+				Parallelism:  2,
+			},
+			want: &LogAnalytics{
+				UniqueIPCount: 5,
+				MostActiveIPs: []string{"177.71.128.21"},
+				Sources: map[string]*LogAnalytics{
+					"./test-data/encoded-urls.log": {
+						UniqueIPCount: 2,
+						MostActiveIPs: []string{"168.41.191.40"},
+					},
+					"./test-data/ipv6-clients.log": {
+						UniqueIPCount: 4,
+						MostActiveIPs: []string{"2001:db8:0:1:a1b2:c3d4:e5f6:1"},
+					},
+				},
+			},
+		},
+		{
+			name: "analytics - autoscaled",
+			config: &BatchConfig{
+				ManifestPath:      manifest,
+				StatePath:         filepath.Join(dir, "autoscaled-state.json"),
+				Autoscale:         true,
+				AutoscaleInterval: time.Millisecond,
+			},
+			want: &LogAnalytics{
+				UniqueIPCount: 5,
+				MostActiveIPs: []string{"177.71.128.21"},
+				Sources: map[string]*LogAnalytics{
+					"./test-data/encoded-urls.log": {
+						UniqueIPCount: 2,
+						MostActiveIPs: []string{"168.41.191.40"},
+					},
+					"./test-data/ipv6-clients.log": {
+						UniqueIPCount: 4,
+						MostActiveIPs: []string{"2001:db8:0:1:a1b2:c3d4:e5f6:1"},
+					},
+				},
+			},
+		},
+		{
+			name: "analytics - completed files are taken from the state, not read again",
+			config: &BatchConfig{
+				ManifestPath: resumeManifest,
+				StatePath:    resumeState,
+			},
+			want: &LogAnalytics{
+				UniqueIPCount: 3,
+				MostActiveIPs: []string{"10.0.0.1"},
+				Sources: map[string]*LogAnalytics{
+					"./test-data/encoded-urls.log": {
+						UniqueIPCount: 2,
+						MostActiveIPs: []string{"168.41.191.40"},
+					},
+					"./test-data/archived.log": {
+						UniqueIPCount: 1,
+						MostActiveIPs: []string{"10.0.0.1"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+				LineRegex:          defaultLineRegex,
+				MostActiveIPsCount: 1,
+			})
+			if err != nil {
+				t.Errorf("logAnalyzer.RunBatch() error = %v, error creating analyzer", err)
+				return
+			}
+			got, err := l.RunBatch(tt.config)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Errorf("logAnalyzer.RunBatch() error is expected")
+					return
+				}
+				if tt.wantErr.Error() != err.Error() {
+					t.Errorf("logAnalyzer.RunBatch() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("logAnalyzer.RunBatch() unexpected error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logAnalyzer.RunBatch() = %+v, want %+v", got, tt.want)
+			}
+
+			// a second run resumes entirely from the recorded state
+			again, err := l.RunBatch(tt.config)
+			if err != nil || !reflect.DeepEqual(again, tt.want) {
+				t.Errorf("logAnalyzer.RunBatch() resumed = %+v, %v, want %+v", again, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"bufio"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BatchOption : An option of AnalyzeBatch
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	parallelism int
+	sources     bool
+}
+
+// WithBatchParallelism : Analyze up to n files at once, 1 when not set. Every
+// file analyzed at once has a reader of its own.
+func WithBatchParallelism(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithBatchSources : Also report every file on its own in
+// LogAnalytics.Sources, as AnalyzeFiles does. Off by default, as thousands of
+// per-file reports cost more than the batch saves.
+func WithBatchSources() BatchOption {
+	return func(o *batchOptions) {
+		o.sources = true
+	}
+}
+
+func (l *logAnalyzer) AnalyzeBatch(filePaths []string, opts ...BatchOption) (*LogAnalytics, error) {
+	options := &batchOptions{parallelism: 1}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.parallelism < 1 {
+		options.parallelism = 1
+	}
+	if l.timeOrdered {
+		// the lines of all the files are sorted as a single stream anyway
+		if options.sources {
+			return l.AnalyzeFiles(filePaths...)
+		}
+		aggs, err := l.aggregateFiles(filePaths)
+		if err != nil {
+			return nil, err
+		}
+		merged := newAggregate()
+		for _, filePath := range filePaths {
+			merged.merge(aggs[filePath])
+		}
+		return l.report(merged), nil
+	}
+
+	pendingCh := make(chan string)
+	var mu sync.Mutex
+	var firstErr error
+	merged := newAggregate()
+	sources := make(map[string]*aggregate)
+	var wg sync.WaitGroup
+	for i := 0; i < options.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader := l.newBatchReader()
+			total := newAggregate()
+			for filePath := range pendingCh {
+				agg := total
+				if options.sources {
+					agg = newAggregate()
+				}
+				err := reader.aggregate(filePath, agg)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if err == nil && options.sources {
+					sources[filePath] = agg
+				}
+				mu.Unlock()
+			}
+			if options.sources {
+				return
+			}
+			mu.Lock()
+			merged.merge(total)
+			mu.Unlock()
+		}()
+	}
+	for _, filePath := range filePaths {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		pendingCh <- filePath
+	}
+	close(pendingCh)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if !options.sources {
+		return l.report(merged), nil
+	}
+	reports := make(map[string]*LogAnalytics, len(filePaths))
+	for _, filePath := range filePaths {
+		merged.merge(sources[filePath])
+		reports[filePath] = l.report(sources[filePath])
+	}
+	analytics := l.report(merged)
+	analytics.Sources = reports
+	return analytics, nil
+}
+
+// batchReader : Reads files one after the other, unlike readLogLines without
+// a goroutine and queue per file, reusing its line parser (and the block of
+// lines it allocates from), and its read buffer. Parser state carries over
+// from file to file, e.g. the W3C #Fields of a rotated log to the next one.
+type batchReader struct {
+	l         *logAnalyzer
+	parseLine lineParser
+	buffer    []byte
+}
+
+func (l *logAnalyzer) newBatchReader() *batchReader {
+	return &batchReader{l: l, parseLine: l.newLineParser(), buffer: make([]byte, bufio.MaxScanTokenSize)}
+}
+
+// aggregate : Counts the lines of the file into agg
+func (r *batchReader) aggregate(filePath string, agg *aggregate) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.New(ErrOpeningFile)
+	}
+	defer file.Close()
+
+	reader, stopReading := r.l.reader(file)
+	defer stopReading()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(r.buffer, maxLineBytes)
+	scanner.Split(r.l.scanLines())
+	records := r.l.records(scanner)
+	window := r.l.newEnrichmentWindow(func(line *Line, _ string) {
+		r.l.consolidate(agg, line)
+	})
+	for records.Scan() {
+		if err := r.l.readLine(r.parseLine, window, agg.samples, records.Text()); err != nil {
+			window.wait()
+			return err
+		}
+	}
+	window.wait()
+	if err := records.Err(); err != nil {
+		return errors.Wrap(err, ErrReadingFile)
+	}
+	return nil
+}
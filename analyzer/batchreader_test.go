@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_logAnalyzer_AnalyzeBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analyzebatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// rotated logs of a few lines, client i and URL i with i hits overall
+	var filePaths []string
+	for i := 0; i < 40; i++ {
+		var lines strings.Builder
+		for j := 0; j <= i%8; j++ {
+			fmt.Fprintf(&lines, `10.0.0.%d - - [10/Jul/2018:22:21:%02d +0200] "GET /page/%d HTTP/1.1" 200 %d "-" "curl"`+"\n", i%8, j, i%8, 100*i+j)
+		}
+		filePath := filepath.Join(dir, fmt.Sprintf("access.log.%d", i))
+		if err := ioutil.WriteFile(filePath, []byte(lines.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		filePaths = append(filePaths, filePath)
+	}
+
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, MostActiveIPsCount: 3, MostVisitedURLsCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := l.AnalyzeFiles(filePaths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSources := want.Sources
+	want.Sources = nil
+
+	tests := []struct {
+		name    string
+		opts    []BatchOption
+		sources bool
+	}{
+		{name: "sequential"},
+		{name: "parallel", opts: []BatchOption{WithBatchParallelism(4)}},
+		{name: "sources", opts: []BatchOption{WithBatchParallelism(3), WithBatchSources()}, sources: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.AnalyzeBatch(filePaths, tt.opts...)
+			if err != nil {
+				t.Fatalf("logAnalyzer.AnalyzeBatch() error = %v", err)
+			}
+			gotSources := got.Sources
+			got.Sources = nil
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("logAnalyzer.AnalyzeBatch() = %+v, want %+v", got, want)
+			}
+			if tt.sources {
+				if !reflect.DeepEqual(gotSources, wantSources) {
+					t.Errorf("logAnalyzer.AnalyzeBatch() sources = %+v, want %+v", gotSources, wantSources)
+				}
+			} else if gotSources != nil {
+				t.Errorf("logAnalyzer.AnalyzeBatch() sources = %+v, want none", gotSources)
+			}
+		})
+	}
+
+	if _, err := l.AnalyzeBatch(append(filePaths, filepath.Join(dir, "missing.log"))); err == nil || err.Error() != ErrOpeningFile {
+		t.Errorf("logAnalyzer.AnalyzeBatch() error = %v, wantErr %v", err, ErrOpeningFile)
+	}
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import "strings"
+
+// CacheStats : How requests were served by a cache, for formats logging
+// cache statuses, e.g. proxies and CDNs
+type CacheStats struct {
+	// Requests : Requests with a cache status
+	Requests int
+	// Hits : Requests served from the cache, fresh, revalidated or stale
+	Hits     int
+	HitRatio float64
+	// ByteHitRatio : Share of the bytes sent that were served from the cache
+	ByteHitRatio float64
+	// Statuses : Requests per cache status, as logged, e.g. TCP_MEM_HIT or
+	// MISS
+	Statuses map[string]int
+}
+
+// isCacheHit : Whether a cache status is of a response served from the
+// cache: Squid's TCP_HIT, TCP_MEM_HIT, TCP_REFRESH_UNMODIFIED..., nginx's
+// HIT, STALE, UPDATING and REVALIDATED, or Varnish's hit. Passes, e.g.
+// Varnish's hit-for-pass, are not.
+func isCacheHit(status string) bool {
+	status = strings.ToUpper(status)
+	if strings.Contains(status, "PASS") {
+		return false
+	}
+	switch status {
+	case "STALE", "UPDATING", "REVALIDATED":
+		return true
+	}
+	return strings.Contains(status, "HIT") || strings.Contains(status, "UNMODIFIED")
+}
+
+// consolidateCache : Counts the response into its cache status, when logged
+func (l *logAnalyzer) consolidateCache(agg *aggregate, line *Line) {
+	status := line.Extras["cache_status"]
+	if status == "" || status == "-" {
+		return
+	}
+	agg.cacheStatusHits[status]++
+	agg.cacheBytes += int64(line.Bytes)
+	if isCacheHit(status) {
+		agg.cacheHitBytes += int64(line.Bytes)
+	}
+}
+
+// cacheReport : The hit ratios of the cache statuses, nil when none was
+// logged
+func cacheReport(agg *aggregate) *CacheStats {
+	if len(agg.cacheStatusHits) == 0 {
+		return nil
+	}
+	stats := &CacheStats{Statuses: make(map[string]int, len(agg.cacheStatusHits))}
+	for status, hits := range agg.cacheStatusHits {
+		stats.Statuses[status] = hits
+		stats.Requests += hits
+		if isCacheHit(status) {
+			stats.Hits += hits
+		}
+	}
+	stats.HitRatio = float64(stats.Hits) / float64(stats.Requests)
+	if agg.cacheBytes > 0 {
+		stats.ByteHitRatio = float64(agg.cacheHitBytes) / float64(agg.cacheBytes)
+	}
+	return stats
+}
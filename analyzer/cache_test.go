@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_isCacheHit(t *testing.T) {
+	for status, want := range map[string]bool{
+		"TCP_HIT":                true,
+		"TCP_MEM_HIT":            true,
+		"TCP_REFRESH_UNMODIFIED": true,
+		"TCP_REFRESH_MODIFIED":   false,
+		"TCP_MISS":               false,
+		"TCP_DENIED":             false,
+		"HIT":                    true,
+		"STALE":                  true,
+		"REVALIDATED":            true,
+		"BYPASS":                 false,
+		"hit":                    true,
+		"hit-for-pass":           false,
+		"pass":                   false,
+	} {
+		if got := isCacheHit(status); got != want {
+			t.Errorf("isCacheHit(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func Test_logAnalyzer_report_cache(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{Format: formats.Squid})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	lines := []string{
+		`1697034772.123    218 192.168.0.68 TCP_MISS/200 3000 GET http://example.com/ - HIER_DIRECT/93.184.216.34 text/html`,
+		`1697034772.451      0 192.168.0.68 TCP_MEM_HIT/200 3000 GET http://example.com/ - HIER_NONE/- text/html`,
+		`1697034772.452      0 192.168.0.69 TCP_MEM_HIT/200 3000 GET http://example.com/ - HIER_NONE/- text/html`,
+		`1697034773.002     12 192.168.0.71 TCP_REFRESH_UNMODIFIED/304 1000 GET http://example.com/logo.png - HIER_DIRECT/93.184.216.34 -`,
+	}
+	agg := newAggregate()
+	for _, text := range lines {
+		line, err := l.parse(l.newLineParser(), text)
+		if err != nil {
+			t.Fatalf("logAnalyzer.parse() error = %v", err)
+		}
+		l.consolidate(agg, line)
+	}
+
+	want := &CacheStats{
+		Requests:     4,
+		Hits:         3,
+		HitRatio:     0.75,
+		ByteHitRatio: 0.7,
+		Statuses:     map[string]int{"TCP_MISS": 1, "TCP_MEM_HIT": 2, "TCP_REFRESH_UNMODIFIED": 1},
+	}
+	if got := l.report(agg).Cache; !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() cache = %+v, want %+v", got, want)
+	}
+
+	// formats without cache statuses report none
+	if got := l.report(newAggregate()).Cache; got != nil {
+		t.Errorf("logAnalyzer.report() cache = %+v, want nil", got)
+	}
+}
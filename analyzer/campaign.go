@@ -0,0 +1,40 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+)
+
+// campaignNotSet : Stands for a campaign parameter missing from the query
+const campaignNotSet = "(not set)"
+
+// campaignOf : The "source / medium / campaign" key of the URL's UTM
+// parameters, as web analytics tools show it, "" when it has none
+func campaignOf(rawURL string) string {
+	i := strings.IndexByte(rawURL, '?')
+	if i < 0 || !strings.Contains(rawURL[i:], "utm_") {
+		return ""
+	}
+	query := rawURL[i+1:]
+	if j := strings.IndexByte(query, '#'); j >= 0 {
+		query = query[:j]
+	}
+	// keep the parameters that parsed, even when others did not
+	values, _ := url.ParseQuery(query)
+
+	parts := []string{values.Get("utm_source"), values.Get("utm_medium"), values.Get("utm_campaign")}
+	set := false
+	for k, part := range parts {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			part = campaignNotSet
+		} else {
+			set = true
+		}
+		parts[k] = part
+	}
+	if !set {
+		return ""
+	}
+	return strings.Join(parts, " / ")
+}
@@ -0,0 +1,29 @@
+package analyzer
+
+import "testing"
+
+func Test_campaignOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		want   string
+	}{
+		{name: "no query", rawURL: "/pricing", want: ""},
+		{name: "no utm parameters", rawURL: "/search?q=utm_source", want: ""},
+		{name: "empty utm parameters", rawURL: "/?utm_source=&utm_medium=", want: ""},
+		{
+			name:   "all parameters",
+			rawURL: "/pricing?utm_source=Newsletter&utm_medium=email&utm_campaign=spring%20sale&page=2",
+			want:   "newsletter / email / spring sale",
+		},
+		{name: "partial", rawURL: "/?utm_source=twitter#top", want: "twitter / (not set) / (not set)"},
+		{name: "invalid escape elsewhere", rawURL: "/?q=%zz&utm_medium=cpc", want: "(not set) / cpc / (not set)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := campaignOf(tt.rawURL); got != tt.want {
+				t.Errorf("campaignOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
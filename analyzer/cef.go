@@ -0,0 +1,295 @@
+package analyzer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCEFFields : The CEF extension keys line fields are read from, when
+// CEFFields is not set: the ArcSight names of the client (src), the request
+// (request, requestMethod, requestClientApplication, requestContext, the
+// referrer), of the receipt time (rt, or start when not logged, e.g. by
+// Imperva) and of the bytes sent to the client (in). The device action (act)
+// and the destination host (dhost) are kept in the line's extras. CEF has no
+// key for the HTTP status, which vendors log in custom fields, e.g.
+// {"status": "cn1"}.
+var DefaultCEFFields = map[string]string{
+	"remote_host": "src",
+	"time":        "rt",
+	"method":      "requestMethod",
+	"url":         "request",
+	"bytes":       "in",
+	"referer":     "requestContext",
+	"user_agent":  "requestClientApplication",
+	"action":      "act",
+	"host":        "dhost",
+}
+
+// DefaultLEEFFields : The LEEF attributes line fields are read from, when
+// CEFFields is not set. The LEEF predefined attributes are used for the time
+// (devTime) and the bytes sent to the client (dstBytes), the CEF keys of
+// DefaultCEFFields for the others.
+var DefaultLEEFFields = func() map[string]string {
+	fields := map[string]string{
+		"time":  "devTime",
+		"bytes": "dstBytes",
+	}
+	for name, key := range DefaultCEFFields {
+		if _, ok := fields[name]; !ok {
+			fields[name] = key
+		}
+	}
+	return fields
+}()
+
+// cefTimeLayouts : The times of CEF (rt, start, end) and LEEF (devTime),
+// besides Unix milliseconds
+var cefTimeLayouts = []string{
+	"Jan 02 2006 15:04:05.000 MST",
+	"Jan 02 2006 15:04:05 MST",
+	"Jan 02 2006 15:04:05.000",
+	"Jan 02 2006 15:04:05",
+}
+
+// cefFormat : Reads CEF and LEEF events, e.g. of SIEM exports, with or without
+// a syslog header:
+//
+//	CEF:0|Vendor|Product|1.0|100|HTTP request|3|src=10.0.0.1 requestMethod=GET request=/index.html
+//	LEEF:1.0|Vendor|Product|1.0|100|src=10.0.0.1<tab>devTime=1696948372000<tab>url=/index.html
+//
+// The header fields are read from the deviceVendor, deviceProduct,
+// deviceVersion, deviceEventClassId, name and severity keys; custom fields
+// with a label, e.g. cn1=200 cn1Label=Response Code, also from their label.
+type cefFormat struct {
+	cef  *logfmtFormat
+	leef *logfmtFormat
+}
+
+// newCEFFormat : A format reading line fields from the keys they map to, those
+// of DefaultCEFFields and DefaultLEEFFields when not set
+func newCEFFormat(fields map[string]string) *cefFormat {
+	if len(fields) > 0 {
+		return &cefFormat{cef: newLogfmtFormat(fields), leef: newLogfmtFormat(fields)}
+	}
+	return &cefFormat{cef: newLogfmtFormat(DefaultCEFFields), leef: newLogfmtFormat(DefaultLEEFFields)}
+}
+
+func (f *cefFormat) newParser(batch *lineBatch) lineParser {
+	return func(text string) (*Line, error) {
+		return f.parse(text, batch)
+	}
+}
+
+func (f *cefFormat) fields() []string {
+	return f.cef.fields()
+}
+
+func (f *cefFormat) project(fields projection) fieldFormat {
+	return &cefFormat{cef: f.cef.project(fields).(*logfmtFormat), leef: f.leef.project(fields).(*logfmtFormat)}
+}
+
+// parse : Parses a CEF or LEEF event, skipping what precedes it, e.g. a syslog
+// header. Other lines do not match.
+func (f *cefFormat) parse(text string, batch *lineBatch) (*Line, error) {
+	var pairs map[string]string
+	format := f.cef
+	if i := strings.Index(text, "CEF:"); i >= 0 {
+		pairs = parseCEF(text[i+len("CEF:"):])
+	} else if i := strings.Index(text, "LEEF:"); i >= 0 {
+		pairs = parseLEEF(text[i+len("LEEF:"):])
+		format = f.leef
+	}
+	if pairs == nil {
+		return nil, errNotMatched
+	}
+	for i, name := range format.names {
+		if name == "time" {
+			if value, ok := pairs[format.keys[i]]; ok {
+				pairs[format.keys[i]] = cefTime(value)
+			}
+		}
+	}
+	return format.parsePairs(pairs, batch)
+}
+
+// cefHeaderKeys : The keys of the CEF header fields after the version, named
+// as ArcSight does
+var cefHeaderKeys = []string{"deviceVendor", "deviceProduct", "deviceVersion", "deviceEventClassId", "name", "severity"}
+
+// parseCEF : The header fields and extension pairs of a CEF event, after its
+// CEF: prefix, the receipt time (rt) being the start of the event when not
+// logged. Nil when the header is incomplete.
+func parseCEF(event string) map[string]string {
+	fields, extension, ok := splitCEFHeader(event, len(cefHeaderKeys)+1)
+	if !ok {
+		return nil
+	}
+	pairs := parseCEFExtension(extension)
+	if _, ok := pairs["rt"]; !ok {
+		if start, ok := pairs["start"]; ok {
+			pairs["rt"] = start
+		}
+	}
+	for i, key := range cefHeaderKeys {
+		pairs[key] = fields[i+1]
+	}
+	return pairs
+}
+
+// parseLEEF : The header fields and attributes of a LEEF 1.0 or 2.0 event,
+// after its LEEF: prefix. Attributes are separated by tabs, or by the
+// delimiter of the LEEF 2.0 header, e.g. ^ or x5E. Nil when the header is
+// incomplete.
+func parseLEEF(event string) map[string]string {
+	fields, attributes, ok := splitCEFHeader(event, 5)
+	if !ok {
+		return nil
+	}
+	delimiter := "\t"
+	if strings.HasPrefix(fields[0], "2.") {
+		// the delimiter is a header field of its own, empty for tabs
+		if header, rest, ok := splitCEFHeader(attributes, 1); ok {
+			attributes = rest
+			if d := leefDelimiter(header[0]); d != "" {
+				delimiter = d
+			}
+		}
+	}
+	pairs := make(map[string]string)
+	for _, attribute := range strings.Split(attributes, delimiter) {
+		if i := strings.IndexByte(attribute, '='); i > 0 {
+			pairs[strings.TrimSpace(attribute[:i])] = attribute[i+1:]
+		}
+	}
+	for i, key := range cefHeaderKeys[:4] {
+		pairs[key] = fields[i+1]
+	}
+	return pairs
+}
+
+// leefDelimiter : The attribute delimiter of a LEEF 2.0 header, a character
+// or its hexadecimal code, e.g. x09 or 0x09. Empty when not set or invalid.
+func leefDelimiter(field string) string {
+	code := strings.TrimPrefix(strings.TrimPrefix(field, "0"), "x")
+	if len(field) > 1 && code != field {
+		if c, err := strconv.ParseUint(code, 16, 32); err == nil {
+			return string(rune(c))
+		}
+		return ""
+	}
+	return field
+}
+
+// splitCEFHeader : The first n fields of the header, separated by pipes that
+// are not escaped (\|), unescaped, and the rest of the event
+func splitCEFHeader(event string, n int) (fields []string, rest string, ok bool) {
+	start := 0
+	for i := 0; i < len(event) && len(fields) < n; i++ {
+		switch event[i] {
+		case '\\':
+			i++
+		case '|':
+			fields = append(fields, unescapeCEF(event[start:i]))
+			start = i + 1
+		}
+	}
+	if len(fields) < n {
+		return nil, "", false
+	}
+	return fields, event[start:], true
+}
+
+// parseCEFExtension : The key=value pairs of a CEF extension. Values run up to
+// the space before the next key, so may contain spaces; equal signs are
+// escaped (\=), though unescaped ones not preceded by a space-separated key,
+// e.g. in query strings, are kept in the value. Custom fields with a label
+// are also read from their label.
+func parseCEFExtension(extension string) map[string]string {
+	pairs := make(map[string]string)
+	key, valueStart := "", 0
+	for i := 0; i < len(extension); i++ {
+		switch extension[i] {
+		case '\\':
+			i++
+			continue
+		case '=':
+		default:
+			continue
+		}
+		keyStart := valueStart + strings.LastIndexByte(extension[valueStart:i], ' ') + 1
+		if key != "" && keyStart == valueStart || !isCEFKey(extension[keyStart:i]) {
+			continue
+		}
+		if key != "" {
+			pairs[key] = unescapeCEF(strings.TrimRight(extension[valueStart:keyStart], " "))
+		}
+		key, valueStart = extension[keyStart:i], i+1
+	}
+	if key != "" {
+		pairs[key] = unescapeCEF(strings.TrimRight(extension[valueStart:], " "))
+	}
+	labeled := make(map[string]string)
+	for key, label := range pairs {
+		if field := strings.TrimSuffix(key, "Label"); field != key && label != "" {
+			if value, ok := pairs[field]; ok {
+				labeled[label] = value
+			}
+		}
+	}
+	for label, value := range labeled {
+		if _, taken := pairs[label]; !taken {
+			pairs[label] = value
+		}
+	}
+	return pairs
+}
+
+// isCEFKey : Whether the word can be an extension key, letters, digits,
+// underscores, dots and brackets
+func isCEFKey(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, c := range word {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '[' || c == ']') {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeCEF : The value with its backslash escapes (\\, \|, \=, \n, \r)
+// replaced
+func unescapeCEF(value string) string {
+	if strings.IndexByte(value, '\\') < 0 {
+		return value
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n':
+				unescaped.WriteByte('\n')
+				continue
+			case 'r':
+				unescaped.WriteByte('\r')
+				continue
+			}
+		}
+		unescaped.WriteByte(value[i])
+	}
+	return unescaped.String()
+}
+
+// cefTime : The time as read by parseTime, from the CEF and LEEF layouts,
+// e.g. Oct 11 2023 14:32:52.123 UTC, or Unix milliseconds
+func cefTime(value string) string {
+	for _, layout := range cefTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return value
+}
@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_cefFormat_parse(t *testing.T) {
+	logged := time.Date(2023, 10, 11, 14, 32, 52, 123000000, time.UTC)
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		text    string
+		want    *Line
+		wantErr error
+	}{
+		{
+			name: "cef with a syslog header",
+			text: `Oct 11 14:32:52 waf1 CEF:0|Acme|WAF|2.1|200|HTTP request|3|src=10.0.0.1 rt=1697034772123 requestMethod=GET request=/search?q\=a b in=512 ` +
+				`requestClientApplication=Mozilla/5.0 (X11; Linux x86_64) requestContext=https://example.com/ act=allowed dhost=example.com`,
+			want: &Line{
+				RemoteHost: "10.0.0.1",
+				Time:       logged,
+				Method:     "GET",
+				Path:       "/search",
+				RawQuery:   "q=a b",
+				URL:        "/search?q=a b",
+				Bytes:      512,
+				Referer:    "https://example.com/",
+				UserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+				Extras:     map[string]string{"action": "allowed", "host": "example.com"},
+			},
+		},
+		{
+			name:   "cef with a labeled status",
+			fields: map[string]string{"remote_host": "src", "time": "rt", "url": "request", "status": "Response Code", "signature": "deviceEventClassId"},
+			text:   `CEF:0|Acme|WAF|2.1|blocked\|sqli|SQL injection|8|cn1Label=Response Code src=10.0.0.2 rt=Oct 11 2023 14:32:52.123 UTC request=/login?user=a cn1=403`,
+			want: &Line{
+				RemoteHost: "10.0.0.2",
+				Time:       logged,
+				Path:       "/login",
+				RawQuery:   "user=a",
+				URL:        "/login?user=a",
+				Status:     403,
+				Extras:     map[string]string{"signature": "blocked|sqli"},
+			},
+		},
+		{
+			name: "leef 1.0",
+			text: "LEEF:1.0|Acme|Proxy|1.0|web|src=10.0.0.3\tdevTime=Oct 11 2023 14:32:52.123\trequestMethod=POST\trequest=/api\tdstBytes=42\tact=blocked",
+			want: &Line{
+				RemoteHost: "10.0.0.3",
+				Time:       logged,
+				Method:     "POST",
+				Path:       "/api",
+				URL:        "/api",
+				Bytes:      42,
+				Extras:     map[string]string{"action": "blocked", "host": ""},
+			},
+		},
+		{
+			name: "leef 2.0 with a delimiter",
+			text: "LEEF:2.0|Acme|Proxy|1.0|web|x5E|src=10.0.0.4^devTime=1697034772123^request=/a=b",
+			want: &Line{
+				RemoteHost: "10.0.0.4",
+				Time:       logged,
+				Path:       "/a=b",
+				URL:        "/a=b",
+				Extras:     map[string]string{"action": "", "host": ""},
+			},
+		},
+		{
+			name:    "incomplete header",
+			text:    `CEF:0|Acme|WAF|2.1|src=10.0.0.1`,
+			wantErr: errNotMatched,
+		},
+		{
+			name:    "not cef",
+			text:    `10.0.0.1 - - [11/Oct/2023:14:32:52 +0000] "GET / HTTP/1.1" 200 13`,
+			wantErr: errNotMatched,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newCEFFormat(tt.fields).parse(tt.text, nil)
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("cefFormat.parse() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cefFormat.parse() error = %v", err)
+			}
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("cefFormat.parse() time = %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time = tt.want.Time
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cefFormat.parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseCEFExtension(t *testing.T) {
+	tests := []struct {
+		name      string
+		extension string
+		want      map[string]string
+	}{
+		{
+			name:      "values with spaces and escapes",
+			extension: `msg=line one\nline two path=C:\\logs cs1=a\=b`,
+			want:      map[string]string{"msg": "line one\nline two", "path": `C:\logs`, "cs1": "a=b"},
+		},
+		{
+			name:      "labels",
+			extension: `cs1=gold cs1Label=tier cn2=7`,
+			want:      map[string]string{"cs1": "gold", "cs1Label": "tier", "tier": "gold", "cn2": "7"},
+		},
+		{
+			name:      "empty",
+			extension: ``,
+			want:      map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCEFExtension(tt.extension); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCEFExtension() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import "github.com/pkg/errors"
+
+const (
+	// ErrUnknownCollector :
+	ErrUnknownCollector = "unknown collector"
+)
+
+// Collector : A group of analytics that can be disabled, so minimal runs
+// skip its per-line work and memory. Costs are per analyzed source.
+type Collector string
+
+const (
+	// CollectIPs : UniqueIPCount and MostActiveIPs. Memory: one key per
+	// distinct client IP, or IPv6 network with IPv6AggregatePrefix.
+	CollectIPs Collector = "ips"
+	// CollectURLs : MostVisitedURLs. Memory: one key per distinct URL, usually
+	// the largest aggregate. CPU: URL decoding and Unicode normalization per
+	// line, unless KeepRawURLs.
+	CollectURLs Collector = "urls"
+	// CollectNetworks : MostActiveNetworks, when MostActiveNetworksCount is
+	// set. Memory: one key per distinct client network. CPU: a network lookup
+	// per line.
+	CollectNetworks Collector = "networks"
+	// CollectEnrichments : TopEnrichedValues, when TopEnrichedValuesCount is
+	// set. Memory: one key per distinct value of every enrichment field. The
+	// enrichers themselves still run, their fields being available to scripts.
+	CollectEnrichments Collector = "enrichments"
+	// CollectScores : TopScoredIPs, when TopScoredIPsCount is set. Memory: one
+	// number per scored client IP. CPU: a call of the script's score hook per
+	// line.
+	CollectScores Collector = "scores"
+	// CollectPageviews : Pageviews, when pageview rules are set. Memory: a
+	// counter. CPU: the rules checked per line.
+	CollectPageviews Collector = "pageviews"
+	// CollectSessions : Sessions, BounceRate, TopLandingPages and
+	// TopExitPages, when session rules are set. Memory: an open session per
+	// visitor active within the session timeout, and a key per distinct
+	// landing and exit page. CPU: the pageview rules checked and a session
+	// lookup per line.
+	CollectSessions Collector = "sessions"
+	// CollectReferrers : TopReferrers and SpamReferrals, when
+	// TopReferrersCount is set. Memory: one key per distinct referring host.
+	// CPU: a referrer URL parse and blocklist lookup per line.
+	CollectReferrers Collector = "referrers"
+	// CollectCampaigns : TopCampaigns, when TopCampaignsCount is set. Memory:
+	// one key per distinct campaign. CPU: a query parse per URL with UTM
+	// parameters.
+	CollectCampaigns Collector = "campaigns"
+	// CollectTimeseries : Timeseries, when a series is enabled. Memory: a
+	// count per label, per interval of every series. CPU: a user agent
+	// classification per line for the device series, and the endpoint group
+	// regexes tried per line.
+	CollectTimeseries Collector = "timeseries"
+	// CollectUpstreams : Upstreams, when UpstreamsCount is set and the format
+	// logs upstreams. Memory: a counter pair and a latency histogram per
+	// backend. CPU: a histogram update per line.
+	CollectUpstreams Collector = "upstreams"
+	// CollectUsers : TopUsers, when TopUsersCount is set and the format logs
+	// remote users. Memory: three counters per authenticated user. CPU: a map
+	// update per authenticated request.
+	CollectUsers Collector = "users"
+	// CollectKeepalive : Keepalive, when the format logs connections. Memory:
+	// a key per connection ID when request numbers are not logged, and per
+	// client IP with NonReusingClientsCount. CPU: map updates per line.
+	CollectKeepalive Collector = "keepalive"
+	// CollectCompression : Compression and UncompressedURLs, when the format
+	// logs compression. Memory: counters per content type, and a key per URL
+	// serving large responses uncompressed. CPU: a few string checks per line.
+	CollectCompression Collector = "compression"
+	// CollectCache : Cache, when the format logs cache statuses. Memory: a
+	// counter per cache status. CPU: a map update per line.
+	CollectCache Collector = "cache"
+	// CollectVHosts : RequestsByVHost, when the format logs virtual hosts.
+	// Memory: a counter per virtual host. CPU: a map update per line.
+	CollectVHosts Collector = "vhosts"
+	// CollectTLS : TLSProtocols and TLSCiphers, when the format logs them.
+	// Memory: a counter per protocol version and per cipher suite. CPU: map
+	// updates per line.
+	CollectTLS Collector = "tls"
+	// CollectSlowest : SlowestRequests, when SlowestRequestsCount is set and
+	// the format logs durations. Memory: SlowestRequestsCount requests. CPU: a
+	// comparison per line, and a heap update per new slowest request.
+	CollectSlowest Collector = "slowest"
+	// CollectLargest : LargestResponses, when LargestResponsesCount is set.
+	// Memory: LargestResponsesCount requests. CPU: a comparison per line, and
+	// a heap update per new largest response.
+	CollectLargest Collector = "largest"
+	// CollectSizes : ResponseSizes, when SizeBuckets is set. Memory: a
+	// histogram. CPU: a histogram update per line.
+	CollectSizes Collector = "sizes"
+)
+
+// collectors : All collectors, enabled unless disabled in the config
+var collectors = []Collector{CollectIPs, CollectURLs, CollectNetworks, CollectEnrichments, CollectScores, CollectPageviews, CollectSessions, CollectReferrers, CollectCampaigns, CollectTimeseries, CollectUpstreams, CollectUsers, CollectKeepalive, CollectCompression, CollectCache, CollectVHosts, CollectTLS, CollectSlowest, CollectLargest, CollectSizes}
+
+// enabledCollectors : The collectors left once the disabled ones are removed
+func enabledCollectors(disabled []Collector) (map[Collector]bool, error) {
+	enabled := make(map[Collector]bool, len(collectors))
+	for _, c := range collectors {
+		enabled[c] = true
+	}
+	for _, c := range disabled {
+		if _, known := enabled[c]; !known {
+			return nil, errors.Wrap(errors.New(ErrUnknownCollector), string(c))
+		}
+		enabled[c] = false
+	}
+	return enabled, nil
+}
@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLargeResponseBytes : Size from which uncompressed responses are
+// reported
+const DefaultLargeResponseBytes = 100 << 10
+
+// CompressionStats : How the responses of a content type were compressed
+type CompressionStats struct {
+	// ContentType : e.g. "text/html", "" when not logged
+	ContentType string
+	Responses   int
+	// CompressedShare : Ratio of the responses that were compressed
+	CompressedShare float64
+	// Ratio : Original over sent bytes of the compressed responses whose
+	// original size is known, 0 when none is
+	Ratio float64
+}
+
+// compressionHits : Responses of a content type
+type compressionHits struct {
+	Responses  int `json:"responses"`
+	Compressed int `json:"compressed"`
+	// SentBytes, OriginalBytes : Of the compressed responses of known
+	// original size
+	SentBytes     int64 `json:"sentBytes"`
+	OriginalBytes int64 `json:"originalBytes"`
+}
+
+// compressibleTypes : Prefixes of content types worth compressing, unlike
+// images, videos or archives, which already are
+var compressibleTypes = []string{
+	"text/", "application/json", "application/javascript", "application/x-javascript",
+	"application/xml", "application/rss+xml", "application/atom+xml", "image/svg+xml",
+	"application/wasm", "font/ttf", "font/otf",
+}
+
+// isCompressed : Whether the response was sent compressed
+func isCompressed(line *Line) bool {
+	switch strings.ToLower(line.ContentEncoding) {
+	case "", "-", "identity":
+		return line.OriginalBytes > line.Bytes
+	}
+	return true
+}
+
+// isCompressible : Whether the content type is worth compressing, unknown
+// types included
+func isCompressible(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType : The content type without its parameters, e.g. "text/html" for
+// "text/html; charset=utf-8"
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "-" {
+		return ""
+	}
+	return contentType
+}
+
+// originalBytes : The original size out of an nginx $gzip_ratio, 0 when the
+// response was not compressed
+func originalBytes(bytes int, gzipRatio string) int {
+	ratio, err := strconv.ParseFloat(gzipRatio, 64)
+	if err != nil || ratio <= 0 {
+		return 0
+	}
+	return int(float64(bytes) * ratio)
+}
+
+// consolidateCompression : Counts the response into its content type's
+// compression, and its URL when it is large and was not compressed
+func (l *logAnalyzer) consolidateCompression(agg *aggregate, line *Line) {
+	contentType := mediaType(line.ContentType)
+	hits, ok := agg.compression[contentType]
+	if !ok {
+		hits = &compressionHits{}
+		agg.compression[contentType] = hits
+	}
+	hits.Responses++
+
+	if isCompressed(line) {
+		hits.Compressed++
+		if line.OriginalBytes > 0 {
+			hits.SentBytes += int64(line.Bytes)
+			hits.OriginalBytes += int64(line.OriginalBytes)
+		}
+		return
+	}
+	if line.Bytes >= l.largeResponseBytes && isCompressible(contentType) && l.reloadable().uncompressedURLsCount > 0 {
+		agg.uncompressedHits[l.countedURL(line)]++
+	}
+}
+
+// compressionReport : The content types with the most responses first
+func compressionReport(compression map[string]*compressionHits) []*CompressionStats {
+	stats := make([]*CompressionStats, 0, len(compression))
+	for contentType, hits := range compression {
+		s := &CompressionStats{
+			ContentType:     contentType,
+			Responses:       hits.Responses,
+			CompressedShare: float64(hits.Compressed) / float64(hits.Responses),
+		}
+		if hits.SentBytes > 0 {
+			s.Ratio = float64(hits.OriginalBytes) / float64(hits.SentBytes)
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Responses != stats[j].Responses {
+			return stats[i].Responses > stats[j].Responses
+		}
+		return stats[i].ContentType < stats[j].ContentType
+	})
+	return stats
+}
+
+func mergeCompression(into, from map[string]*compressionHits) {
+	for contentType, hits := range from {
+		merged, ok := into[contentType]
+		if !ok {
+			merged = &compressionHits{}
+			into[contentType] = merged
+		}
+		merged.Responses += hits.Responses
+		merged.Compressed += hits.Compressed
+		merged.SentBytes += hits.SentBytes
+		merged.OriginalBytes += hits.OriginalBytes
+	}
+}
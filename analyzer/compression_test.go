@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_logAnalyzer_report_compression(t *testing.T) {
+	lineRegex := regexp.MustCompile(strings.TrimSuffix(defaultLineRegex.String(), "$") +
+		`\s"(?P<content_type>[^"]*)"\s(?P<gzip_ratio>\S+)$`)
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:             lineRegex,
+		UncompressedURLsCount: 2,
+		LargeResponseBytes:    1000,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	lines := []string{
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 1000 "-" "curl/7.64.0" "text/html; charset=utf-8" 4.00`,
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /docs/ HTTP/1.1" 200 2000 "-" "curl/7.64.0" "text/html; charset=utf-8" 2.00`,
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /export HTTP/1.1" 200 50000 "-" "curl/7.64.0" "text/html" -`,
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /export HTTP/1.1" 200 50000 "-" "curl/7.64.0" "text/html" -`,
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /small HTTP/1.1" 200 10 "-" "curl/7.64.0" "text/html" -`,
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /api/users HTTP/1.1" 200 3000 "-" "curl/7.64.0" "application/json" -`,
+		`177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /logo.png HTTP/1.1" 200 90000 "-" "curl/7.64.0" "image/png" -`,
+	}
+	agg := newAggregate()
+	for _, text := range lines {
+		line, err := l.parse(l.newLineParser(), text)
+		if err != nil {
+			t.Fatalf("logAnalyzer.parse() error = %v", err)
+		}
+		l.consolidate(agg, line)
+	}
+
+	got := l.report(agg)
+	wantCompression := []*CompressionStats{
+		{ContentType: "text/html", Responses: 5, CompressedShare: 0.4, Ratio: 8000.0 / 3000},
+		{ContentType: "application/json", Responses: 1},
+		{ContentType: "image/png", Responses: 1},
+	}
+	if !reflect.DeepEqual(got.Compression, wantCompression) {
+		for _, s := range got.Compression {
+			t.Errorf("logAnalyzer.report() compression = %+v", *s)
+		}
+	}
+	wantURLs := []string{"/export", "/api/users"}
+	if !reflect.DeepEqual(got.UncompressedURLs, wantURLs) {
+		t.Errorf("logAnalyzer.report() uncompressed urls = %v, want %v", got.UncompressedURLs, wantURLs)
+	}
+}
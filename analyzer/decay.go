@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// decayRescaleHalfLives : Half-lives past the landmark after which decayed
+	// scores are rescaled, long before their weights overflow
+	decayRescaleHalfLives = 64
+	// decayMinScore : Score under which a key is dropped when rescaling, e.g.
+	// a single hit ten half-lives old
+	decayMinScore = 1.0 / 1024
+)
+
+// decayedHits : Hits per key, each weighing half as much every half-life,
+// so that rankings reflect recent activity. Hits are weighed relative to a
+// landmark time, newer ones by more (forward decay), so that older scores
+// need not be updated on every hit; scores are rescaled to a later landmark
+// before the weights grow too large, dropping the keys that decayed away.
+type decayedHits struct {
+	HalfLife time.Duration `json:"halfLife"`
+	// Landmark : Time the scores are as of, Unix nanoseconds, 0 until a hit
+	Landmark int64              `json:"landmark"`
+	Scores   map[string]float64 `json:"scores"`
+}
+
+func newDecayedHits(halfLife time.Duration) *decayedHits {
+	return &decayedHits{HalfLife: halfLife, Scores: make(map[string]float64)}
+}
+
+// weight : The weight of a hit at now, Unix nanoseconds
+func (d *decayedHits) weight(now int64) float64 {
+	return math.Exp2(float64(now-d.Landmark) / float64(d.HalfLife))
+}
+
+// hit : Counts a hit on the key at now, Unix nanoseconds
+func (d *decayedHits) hit(key string, now int64) {
+	if d.Landmark == 0 {
+		d.Landmark = now
+	}
+	if now-d.Landmark > decayRescaleHalfLives*int64(d.HalfLife) {
+		d.rescale(now)
+	}
+	d.Scores[key] += d.weight(now)
+}
+
+// rescale : Moves the landmark to now
+func (d *decayedHits) rescale(now int64) {
+	factor := 1 / d.weight(now)
+	for key, score := range d.Scores {
+		if score *= factor; score < decayMinScore {
+			delete(d.Scores, key)
+			continue
+		}
+		d.Scores[key] = score
+	}
+	d.Landmark = now
+}
+
+// top : The keys of the highest scores, highest first
+func (d *decayedHits) top(n int) []string {
+	keys := make([]string, 0, len(d.Scores))
+	for key := range d.Scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if d.Scores[keys[i]] != d.Scores[keys[j]] {
+			return d.Scores[keys[i]] > d.Scores[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if n < len(keys) {
+		keys = keys[:n]
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys
+}
+
+// mergeDecayed : Adds the scores of from to into, as of the later landmark
+func mergeDecayed(into, from *decayedHits) *decayedHits {
+	if from == nil || len(from.Scores) == 0 {
+		return into
+	}
+	if into == nil {
+		into = newDecayedHits(from.HalfLife)
+	}
+	if len(into.Scores) == 0 {
+		into.Landmark = from.Landmark
+	}
+	if from.Landmark > into.Landmark {
+		into.rescale(from.Landmark)
+	}
+	factor := into.weight(from.Landmark)
+	for key, score := range from.Scores {
+		into.Scores[key] += score * factor
+	}
+	return into
+}
+
+// liveTime : The time of a line for live views, e.g. the time its hits decay
+// from, Unix nanoseconds: its logged time, or the current time when it was
+// not logged
+func liveTime(line *Line) int64 {
+	if line.Time.IsZero() {
+		return time.Now().UnixNano()
+	}
+	return line.Time.UnixNano()
+}
+
+// trackDecay : Has the followed aggregate rank IPs and URLs by decayed hits,
+// when a half-life is set
+func (l *logAnalyzer) trackDecay(agg *aggregate) {
+	if l.decayHalfLife <= 0 {
+		return
+	}
+	if agg.decayedIPs == nil {
+		agg.decayedIPs = newDecayedHits(l.decayHalfLife)
+	}
+	if agg.decayedURLs == nil {
+		agg.decayedURLs = newDecayedHits(l.decayHalfLife)
+	}
+}
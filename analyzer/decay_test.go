@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_decayedHits(t *testing.T) {
+	start := time.Date(2018, 7, 10, 22, 0, 0, 0, time.UTC).UnixNano()
+	minute := int64(time.Minute)
+
+	d := newDecayedHits(time.Minute)
+	// 4 hits a minute ago weigh as 2 now
+	for i := 0; i < 4; i++ {
+		d.hit("old", start)
+	}
+	d.hit("new", start+minute)
+	d.hit("new", start+minute)
+	d.hit("newest", start+minute)
+	d.hit("newest", start+minute)
+	d.hit("newest", start+minute)
+	if got, want := d.top(2), []string{"newest", "new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decayedHits.top() = %v, want %v", got, want)
+	}
+	if got := d.top(10); len(got) != 3 {
+		t.Errorf("decayedHits.top() = %v, want the 3 keys", got)
+	}
+
+	// past 64 half-lives, scores are rescaled and the decayed keys dropped
+	later := start + (decayRescaleHalfLives+1)*minute
+	d.hit("later", later)
+	if d.Landmark != later || len(d.Scores) != 1 || math.Abs(d.Scores["later"]-1) > 1e-9 {
+		t.Errorf("decayedHits after rescaling = %+v, want a single hit on later", d)
+	}
+}
+
+func Test_mergeDecayed(t *testing.T) {
+	start := time.Date(2018, 7, 10, 22, 0, 0, 0, time.UTC).UnixNano()
+	halfLife := int64(time.Minute)
+	times := []int64{start, start + halfLife, start + 3*halfLife, start + 10*halfLife}
+
+	all := newDecayedHits(time.Minute)
+	early, late := newDecayedHits(time.Minute), newDecayedHits(time.Minute)
+	for i, at := range times {
+		key := fmt.Sprintf("key-%d", i%2)
+		all.hit(key, at)
+		if at < start+2*halfLife {
+			early.hit(key, at)
+		} else {
+			late.hit(key, at)
+		}
+	}
+	for _, merged := range []*decayedHits{mergeDecayed(mergeDecayed(nil, early), late), mergeDecayed(mergeDecayed(nil, late), early)} {
+		for key, score := range all.Scores {
+			want := score / all.weight(merged.Landmark)
+			if got := merged.Scores[key] / merged.weight(merged.Landmark); math.Abs(got-want) > 1e-9 {
+				t.Errorf("mergeDecayed() %s = %v, want %v", key, got, want)
+			}
+		}
+	}
+
+	data, err := json.Marshal(&aggregate{decayedIPs: all})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded aggregate
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.decayedIPs, all) || loaded.decayedURLs != nil {
+		t.Errorf("aggregate decayed ips = %+v, want %+v", loaded.decayedIPs, all)
+	}
+}
+
+func Test_logAnalyzer_Follow_decay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a client busy an hour ago, and one busy now with fewer requests
+	var lines strings.Builder
+	for i := 0; i < 10; i++ {
+		lines.WriteString(`10.0.0.1 - - [10/Jul/2018:21:00:00 +0200] "GET /old HTTP/1.1" 200 1 "-" "curl"` + "\n")
+	}
+	for i := 0; i < 3; i++ {
+		lines.WriteString(`10.0.0.2 - - [10/Jul/2018:22:00:00 +0200] "GET /new HTTP/1.1" 200 1 "-" "curl"` + "\n")
+	}
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, []byte(lines.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:            defaultLineRegex,
+		MostActiveIPsCount:   2,
+		MostVisitedURLsCount: 1,
+		FollowPollInterval:   5 * time.Millisecond,
+		SnapshotInterval:     10 * time.Millisecond,
+		DecayHalfLife:        10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Follow() error = %v, error creating analyzer", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	snapshotCh, errCh := l.Follow(ctx, filePath)
+	timeout := time.AfterFunc(5*time.Second, cancel)
+	defer timeout.Stop()
+	for snapshot := range snapshotCh {
+		if snapshot.UniqueIPCount == 2 {
+			cancel()
+		}
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("logAnalyzer.Follow() error = %v", err)
+	}
+
+	got := l.(*logAnalyzer).report(l.(*logAnalyzer).state)
+	if want := []string{"10.0.0.2", "10.0.0.1"}; !reflect.DeepEqual(got.MostActiveIPs, want) {
+		t.Errorf("logAnalyzer.Follow() most active ips = %v, want %v", got.MostActiveIPs, want)
+	}
+	if want := []string{"/new"}; !reflect.DeepEqual(got.MostVisitedURLs, want) {
+		t.Errorf("logAnalyzer.Follow() most visited urls = %v, want %v", got.MostVisitedURLs, want)
+	}
+
+	// the decayed hits are saved with the state
+	var state bytes.Buffer
+	if err := l.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(state.String(), `"decayedIPs"`) {
+		t.Errorf("logAnalyzer.SaveState() = %s, want the decayed ips", state.String())
+	}
+}
@@ -0,0 +1,341 @@
+package analyzer
+
+import (
+	"container/list"
+	"context"
+	"encoding/csv"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrUnknownEnricher :
+	ErrUnknownEnricher = "unknown enricher"
+	// ErrEnrichmentTimeout :
+	ErrEnrichmentTimeout = "enrichment lookup timed out"
+	// ErrInvalidGeoIPNetwork :
+	ErrInvalidGeoIPNetwork = "invalid geoip network"
+
+	// DefaultEnrichmentCacheSize : Lookups kept in the shared enrichment cache
+	DefaultEnrichmentCacheSize = 10000
+)
+
+// Enricher : Derives extra fields for lines, e.g. from a lookup on their IP.
+// Enrichers run in order, so an enricher can build on the fields of the ones
+// before it.
+type Enricher interface {
+	// Name : Identifies the enricher, its fields are stored on the line as
+	// "<name>.<field>"
+	Name() string
+	// Key : The lookup key for the line, e.g. its IP. Lines with the same key
+	// share a cached lookup; an empty key skips the line.
+	Key(line *Line) string
+	// Enrich : Looks up the fields for the key
+	Enrich(ctx context.Context, key string) (map[string]string, error)
+}
+
+// EnrichmentStep : An enricher in the enrichment chain
+type EnrichmentStep struct {
+	Enricher Enricher
+	// Timeout : Maximum time a lookup may take, none when not set. Lines
+	// whose lookup times out or fails are left without the fields. A lookup
+	// ignoring its context is given up on all the same, left to finish in
+	// the background.
+	Timeout time.Duration
+}
+
+// NewBuiltinEnricher : Returns the built-in enricher of that name:
+//
+//	rdns       reverse DNS host name of the client IP (field "host")
+//	useragent  device type ("device": bot, mobile, tablet or desktop), "browser" and "os"
+//
+// The geoip enricher needs its networks, see NewGeoIPEnricher.
+func NewBuiltinEnricher(name string) (Enricher, error) {
+	switch name {
+	case "rdns":
+		return &reverseDNSEnricher{resolver: net.DefaultResolver}, nil
+	case "useragent":
+		return &userAgentEnricher{}, nil
+	}
+	return nil, errors.Wrap(errors.New(ErrUnknownEnricher), name)
+}
+
+// enrich : Runs the enrichment chain on the line
+func (l *logAnalyzer) enrich(line *Line) {
+	for _, step := range l.enrichers {
+		key := step.Enricher.Key(line)
+		if key == "" {
+			continue
+		}
+		name := step.Enricher.Name()
+		fields, ok := l.enrichmentCache.get(name, key)
+		if !ok {
+			var err error
+			fields, err = lookup(step, key)
+			if err != nil {
+				atomic.AddInt64(&l.metrics.enrichmentErrors, 1)
+				continue
+			}
+			l.enrichmentCache.put(name, key, fields)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if line.Enrichments == nil {
+			line.Enrichments = make(map[string]string)
+		}
+		for field, value := range fields {
+			line.Enrichments[name+"."+field] = value
+		}
+	}
+}
+
+// enrichResult : The outcome of a lookup
+type enrichResult struct {
+	fields map[string]string
+	err    error
+}
+
+func lookup(step *EnrichmentStep, key string) (map[string]string, error) {
+	if step.Timeout <= 0 {
+		return step.Enricher.Enrich(context.Background(), key)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), step.Timeout)
+	defer cancel()
+	// buffered, so a lookup finishing after the timeout does not block
+	resultCh := make(chan enrichResult, 1)
+	go func() {
+		fields, err := step.Enricher.Enrich(ctx, key)
+		resultCh <- enrichResult{fields, err}
+	}()
+	timer := time.NewTimer(step.Timeout)
+	defer timer.Stop()
+	select {
+	case result := <-resultCh:
+		return result.fields, result.err
+	case <-timer.C:
+		return nil, errors.New(ErrEnrichmentTimeout)
+	}
+}
+
+// enrichmentCache : LRU cache of lookups, shared by the enrichers
+type enrichmentCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type enrichmentCacheEntry struct {
+	key    string
+	fields map[string]string
+}
+
+func newEnrichmentCache(size int) *enrichmentCache {
+	return &enrichmentCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *enrichmentCache) get(enricher, key string) (map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[enricher+"\x00"+key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*enrichmentCacheEntry).fields, true
+}
+
+func (c *enrichmentCache) put(enricher, key string, fields map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cacheKey := enricher + "\x00" + key
+	if e, ok := c.entries[cacheKey]; ok {
+		e.Value.(*enrichmentCacheEntry).fields = fields
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[cacheKey] = c.order.PushFront(&enrichmentCacheEntry{key: cacheKey, fields: fields})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*enrichmentCacheEntry).key)
+	}
+}
+
+// reverseDNSEnricher : Resolves the client IP to a host name
+type reverseDNSEnricher struct {
+	resolver *net.Resolver
+}
+
+func (e *reverseDNSEnricher) Name() string { return "rdns" }
+
+func (e *reverseDNSEnricher) Key(line *Line) string {
+	if net.ParseIP(line.RemoteHost) == nil {
+		return ""
+	}
+	return line.RemoteHost
+}
+
+func (e *reverseDNSEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	names, err := e.resolver.LookupAddr(ctx, key)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return map[string]string{"host": strings.TrimSuffix(names[0], ".")}, nil
+}
+
+// userAgentEnricher : Classifies the user agent, by well-known tokens
+type userAgentEnricher struct{}
+
+func (e *userAgentEnricher) Name() string { return "useragent" }
+
+func (e *userAgentEnricher) Key(line *Line) string {
+	if line.UserAgent == "-" {
+		return ""
+	}
+	return line.UserAgent
+}
+
+func (e *userAgentEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	ua := strings.ToLower(key)
+	return map[string]string{
+		"device":  classify(ua, deviceTokens, "desktop"),
+		"browser": classify(ua, browserTokens, "other"),
+		"os":      classify(ua, osTokens, "other"),
+	}, nil
+}
+
+// uaToken : A lower case user agent token and what it denotes. Tokens are
+// tried in order, the first match wins.
+type uaToken struct {
+	token string
+	value string
+}
+
+var deviceTokens = []uaToken{
+	{"bot", "bot"}, {"crawl", "bot"}, {"spider", "bot"}, {"slurp", "bot"},
+	{"curl/", "bot"}, {"wget/", "bot"}, {"python-requests", "bot"}, {"go-http-client", "bot"},
+	{"ipad", "tablet"}, {"tablet", "tablet"},
+	{"mobile", "mobile"}, {"iphone", "mobile"}, {"android", "mobile"},
+}
+
+var browserTokens = []uaToken{
+	{"edg/", "Edge"}, {"opr/", "Opera"}, {"opera", "Opera"}, {"firefox/", "Firefox"},
+	{"chrome/", "Chrome"}, {"epiphany/", "Epiphany"}, {"safari/", "Safari"},
+	{"msie", "Internet Explorer"}, {"trident/", "Internet Explorer"},
+}
+
+var osTokens = []uaToken{
+	{"android", "Android"}, {"iphone", "iOS"}, {"ipad", "iOS"}, {"windows", "Windows"},
+	{"mac os x", "macOS"}, {"linux", "Linux"},
+}
+
+func classify(ua string, tokens []uaToken, fallback string) string {
+	for _, t := range tokens {
+		if strings.Contains(ua, t.token) {
+			return t.value
+		}
+	}
+	return fallback
+}
+
+// geoIPEnricher : Locates the client IP in the most specific of its networks
+type geoIPEnricher struct {
+	// prefixes : The prefix lengths of the networks, longest first
+	prefixes []int
+	// networks : The fields of the networks, by their CIDR notation
+	networks map[string]map[string]string
+}
+
+// NewGeoIPEnricher : The geoip enricher, locating client IPs by the networks
+// read from r, CSV records of a network in CIDR notation, its country and
+// optionally its city, e.g. "203.0.113.0/24,AU,Sydney" (fields "country" and
+// "city"). A first record starting with "network" is taken as a header. IPs
+// are located in the most specific network holding them.
+func NewGeoIPEnricher(r io.Reader) (Enricher, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	e := &geoIPEnricher{networks: make(map[string]map[string]string)}
+	prefixes := make(map[int]bool)
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && record[0] == "network" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(strings.TrimSpace(record[0]))
+		if err != nil || len(record) < 2 {
+			return nil, errors.Wrap(errors.New(ErrInvalidGeoIPNetwork), record[0])
+		}
+		fields := map[string]string{"country": strings.TrimSpace(record[1])}
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			fields["city"] = strings.TrimSpace(record[2])
+		}
+		prefix, _ := network.Mask.Size()
+		if network.IP.To4() == nil {
+			// ipv6 networks are told apart from ipv4 ones by their length
+			prefix += 128
+		}
+		prefixes[prefix] = true
+		e.networks[network.String()] = fields
+	}
+	for prefix := range prefixes {
+		e.prefixes = append(e.prefixes, prefix)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(e.prefixes)))
+	return e, nil
+}
+
+func (e *geoIPEnricher) Name() string { return "geoip" }
+
+func (e *geoIPEnricher) Key(line *Line) string {
+	if net.ParseIP(line.RemoteHost) == nil {
+		return ""
+	}
+	return line.RemoteHost
+}
+
+func (e *geoIPEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	ip := net.ParseIP(key)
+	bits, offset := 32, 0
+	if ip.To4() != nil {
+		ip = ip.To4()
+	} else {
+		bits, offset = 128, 128
+	}
+	for _, prefix := range e.prefixes {
+		if prefix < offset || prefix > offset+bits {
+			continue
+		}
+		mask := net.CIDRMask(prefix-offset, bits)
+		network := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		if fields, ok := e.networks[network.String()]; ok {
+			return fields, nil
+		}
+	}
+	return nil, nil
+}
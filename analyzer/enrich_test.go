@@ -0,0 +1,220 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnricher : Derives a field from another one, counting its lookups
+type fakeEnricher struct {
+	name    string
+	from    string
+	delay   time.Duration
+	lookups int
+}
+
+func (e *fakeEnricher) Name() string { return e.name }
+
+func (e *fakeEnricher) Key(line *Line) string {
+	if e.from == "" {
+		return line.RemoteHost
+	}
+	return line.Enrichments[e.from]
+}
+
+func (e *fakeEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	e.lookups++
+	select {
+	case <-time.After(e.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return map[string]string{"value": e.name + "(" + key + ")"}, nil
+}
+
+func Test_logAnalyzer_enrich(t *testing.T) {
+	first := &fakeEnricher{name: "first"}
+	second := &fakeEnricher{name: "second", from: "first.value"}
+	slow := &fakeEnricher{name: "slow", delay: time.Second}
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex: defaultLineRegex,
+		Enrichers: []*EnrichmentStep{
+			{Enricher: first},
+			{Enricher: second},
+			{Enricher: slow, Timeout: 10 * time.Millisecond},
+		},
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.enrich() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	for i := 0; i < 3; i++ {
+		line := &Line{RemoteHost: "10.0.0.1"}
+		l.enrich(line)
+		want := map[string]string{
+			"first.value":  "first(10.0.0.1)",
+			"second.value": "second(first(10.0.0.1))",
+		}
+		if !reflect.DeepEqual(line.Enrichments, want) {
+			t.Errorf("logAnalyzer.enrich() = %v, want %v", line.Enrichments, want)
+		}
+	}
+	if first.lookups != 1 || second.lookups != 1 {
+		t.Errorf("logAnalyzer.enrich() lookups = %d, %d, want cached after the first", first.lookups, second.lookups)
+	}
+	// failed lookups are not cached, each line times out
+	if l.SelfMetrics().EnrichmentErrors != 3 {
+		t.Errorf("logAnalyzer.enrich() timed out lookups = %d, want 3", l.SelfMetrics().EnrichmentErrors)
+	}
+}
+
+// stuckEnricher : Never answers, whatever its context
+type stuckEnricher struct {
+	release chan struct{}
+}
+
+func (e *stuckEnricher) Name() string { return "stuck" }
+
+func (e *stuckEnricher) Key(line *Line) string { return line.RemoteHost }
+
+func (e *stuckEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	<-e.release
+	return map[string]string{"value": key}, nil
+}
+
+func Test_lookup_timeout(t *testing.T) {
+	stuck := &stuckEnricher{release: make(chan struct{})}
+	defer close(stuck.release)
+	done := make(chan error, 1)
+	go func() {
+		_, err := lookup(&EnrichmentStep{Enricher: stuck, Timeout: 10 * time.Millisecond}, "10.0.0.1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != ErrEnrichmentTimeout {
+			t.Errorf("lookup() error = %v, want %v", err, ErrEnrichmentTimeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("lookup() waited for an enricher ignoring its context")
+	}
+}
+
+func Test_geoIPEnricher_Enrich(t *testing.T) {
+	networks := `network,country,city
+# documentation ranges
+203.0.113.0/24,AU,Sydney
+203.0.0.0/16,AU
+2001:db8::/32,NL,Amsterdam
+`
+	e, err := NewGeoIPEnricher(strings.NewReader(networks))
+	if err != nil {
+		t.Fatalf("NewGeoIPEnricher() error = %v", err)
+	}
+	tests := []struct {
+		ip   string
+		want map[string]string
+	}{
+		{"203.0.113.7", map[string]string{"country": "AU", "city": "Sydney"}},
+		{"203.0.1.7", map[string]string{"country": "AU"}},
+		{"2001:db8::1", map[string]string{"country": "NL", "city": "Amsterdam"}},
+		{"198.51.100.1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := e.Enrich(context.Background(), tt.ip)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("geoIPEnricher.Enrich() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := NewGeoIPEnricher(strings.NewReader("203.0.113.0/33,AU\n")); err == nil {
+		t.Errorf("NewGeoIPEnricher() accepted an invalid network")
+	}
+}
+
+func Test_enrichmentCache(t *testing.T) {
+	c := newEnrichmentCache(2)
+	c.put("e", "a", map[string]string{"v": "a"})
+	c.put("e", "b", map[string]string{"v": "b"})
+	c.get("e", "a")
+	c.put("e", "c", map[string]string{"v": "c"})
+	if _, ok := c.get("e", "b"); ok {
+		t.Errorf("enrichmentCache.get() least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.get("e", key); !ok {
+			t.Errorf("enrichmentCache.get() entry %s was evicted", key)
+		}
+	}
+	if _, ok := c.get("other", "a"); ok {
+		t.Errorf("enrichmentCache.get() entries are shared across enrichers")
+	}
+}
+
+func Test_userAgentEnricher_Enrich(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want map[string]string
+	}{
+		{
+			ua:   "Mozilla/5.0 (X11; U; Linux x86_64; fr-FR) AppleWebKit/534.7 (KHTML, like Gecko) Epiphany/2.30.6 Safari/534.7",
+			want: map[string]string{"device": "desktop", "browser": "Epiphany", "os": "Linux"},
+		},
+		{
+			ua:   "Mozilla/5.0 (Linux; U; Android 2.3.5; en-us; HTC Vision Build/GRI40) AppleWebKit/533.1 (KHTML, like Gecko) Version/4.0 Mobile Safari/533.1",
+			want: map[string]string{"device": "mobile", "browser": "Safari", "os": "Android"},
+		},
+		{
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: map[string]string{"device": "bot", "browser": "other", "os": "other"},
+		},
+		{
+			ua:   "Mozilla/5.0 (iPad; CPU OS 12_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0 Mobile/15E148 Safari/604.1",
+			want: map[string]string{"device": "tablet", "browser": "Safari", "os": "iOS"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want["device"], func(t *testing.T) {
+			got, err := (&userAgentEnricher{}).Enrich(context.Background(), tt.ua)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("userAgentEnricher.Enrich() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_Analyze_enriched(t *testing.T) {
+	ua, err := NewBuiltinEnricher("useragent")
+	if err != nil {
+		t.Fatalf("NewBuiltinEnricher() error = %v", err)
+	}
+	if _, err := NewBuiltinEnricher("geoip"); err == nil {
+		t.Errorf("NewBuiltinEnricher() error is expected for an unknown enricher")
+	}
+
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:              defaultLineRegex,
+		Enrichers:              []*EnrichmentStep{{Enricher: ua}},
+		TopEnrichedValuesCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/ipv6-clients.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	want := map[string][]string{
+		"useragent.device":  {"desktop"},
+		"useragent.browser": {"Firefox"},
+		"useragent.os":      {"Linux"},
+	}
+	if !reflect.DeepEqual(got.TopEnrichedValues, want) {
+		t.Errorf("logAnalyzer.Analyze() top enriched values = %v, want %v", got.TopEnrichedValues, want)
+	}
+}
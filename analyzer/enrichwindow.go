@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// enrichmentWindow : Enriches lines, then runs the script on them and passes
+// the lines kept on. With EnrichmentConcurrency set, up to that many lines
+// are enriched at once, so slow lookups (reverse DNS, GeoIP misses) overlap
+// instead of holding up every line behind them. The enriched lines are
+// passed on one at a time, in the order they were added when
+// PreserveEnrichmentOrder is set, as soon as they are enriched otherwise.
+type enrichmentWindow struct {
+	l    *logAnalyzer
+	next func(line *Line, text string)
+	// slots : One per line in flight, bounding the window; nil when lines
+	// are enriched one after the other
+	slots chan struct{}
+	// pending : The lines in flight in the order they were added, when it is
+	// preserved
+	pending chan *windowLine
+	passed  chan struct{}
+	// mu : Passes lines on one at a time when the order is not preserved
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+// windowLine : A line in flight, and its text
+type windowLine struct {
+	line     *Line
+	text     string
+	enriched chan struct{}
+}
+
+// newEnrichmentWindow : A window passing enriched lines, along with their
+// text, on to next. wait must be called once every line was added.
+func (l *logAnalyzer) newEnrichmentWindow(next func(line *Line, text string)) *enrichmentWindow {
+	w := &enrichmentWindow{l: l, next: next}
+	if l.enrichConcurrency <= 1 || len(l.enrichers) == 0 {
+		return w
+	}
+	w.slots = make(chan struct{}, l.enrichConcurrency)
+	if l.enrichInOrder {
+		// never full, as every line pending holds a slot
+		w.pending = make(chan *windowLine, l.enrichConcurrency)
+		w.passed = make(chan struct{})
+		go w.passInOrder()
+	}
+	return w
+}
+
+// add : Enriches the line, waiting for a slot when the window is full
+func (w *enrichmentWindow) add(line *Line, text string) {
+	if w.slots == nil {
+		w.l.enrich(line)
+		w.pass(line, text)
+		return
+	}
+
+	w.slots <- struct{}{}
+	if w.pending != nil {
+		pending := &windowLine{line: line, text: text, enriched: make(chan struct{})}
+		w.pending <- pending
+		go func() {
+			w.l.enrich(line)
+			close(pending.enriched)
+		}()
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.l.enrich(line)
+		w.mu.Lock()
+		w.pass(line, text)
+		w.mu.Unlock()
+		<-w.slots
+	}()
+}
+
+func (w *enrichmentWindow) passInOrder() {
+	defer close(w.passed)
+	for pending := range w.pending {
+		<-pending.enriched
+		w.pass(pending.line, pending.text)
+		<-w.slots
+	}
+}
+
+// pass : Runs the script on the line, passing it on unless it is filtered out
+func (w *enrichmentWindow) pass(line *Line, text string) {
+	if !w.l.runScript(line) {
+		atomic.AddInt64(&w.l.metrics.filteredLines, 1)
+		return
+	}
+	w.next(line, text)
+}
+
+// wait : Waits for the lines in flight to be passed on
+func (w *enrichmentWindow) wait() {
+	if w.pending != nil {
+		close(w.pending)
+		<-w.passed
+		return
+	}
+	w.wg.Wait()
+}
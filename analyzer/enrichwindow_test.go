@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedEnricher : Holds the lookups of one key back until released,
+// counting the lookups started
+type gatedEnricher struct {
+	gated   string
+	release chan struct{}
+	started int64
+}
+
+func (e *gatedEnricher) Name() string { return "gated" }
+
+func (e *gatedEnricher) Key(line *Line) string { return line.RemoteHost }
+
+func (e *gatedEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	atomic.AddInt64(&e.started, 1)
+	if key == e.gated {
+		<-e.release
+	}
+	return map[string]string{"key": key}, nil
+}
+
+func Test_enrichmentWindow(t *testing.T) {
+	hosts := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	tests := []struct {
+		name    string
+		inOrder bool
+		// want : The hosts of the lines passed on, in order, but for the
+		// lines passed on before the gated one when the order is not kept
+		want []string
+	}{
+		{name: "as enriched", want: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.1"}},
+		{name: "in order", inOrder: true, want: hosts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enricher := &gatedEnricher{gated: hosts[0], release: make(chan struct{})}
+			a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+				LineRegex:               defaultLineRegex,
+				Enrichers:               []*EnrichmentStep{{Enricher: enricher}},
+				EnrichmentConcurrency:   len(hosts),
+				PreserveEnrichmentOrder: tt.inOrder,
+			})
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			l := a.(*logAnalyzer)
+
+			var mu sync.Mutex
+			var got []string
+			passed := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string(nil), got...)
+			}
+			window := l.newEnrichmentWindow(func(line *Line, text string) {
+				if line.Enrichments["gated.key"] != line.RemoteHost || text != line.RemoteHost {
+					t.Errorf("enrichmentWindow passed %+v, %q on, want it enriched with its text", line, text)
+				}
+				mu.Lock()
+				got = append(got, line.RemoteHost)
+				mu.Unlock()
+			})
+			for _, host := range hosts {
+				window.add(&Line{RemoteHost: host}, host)
+			}
+
+			// the lookups after the gated one run meanwhile
+			deadline := time.Now().Add(5 * time.Second)
+			for atomic.LoadInt64(&enricher.started) < int64(len(hosts)) || (!tt.inOrder && len(passed()) < len(hosts)-1) {
+				if time.Now().After(deadline) {
+					t.Fatalf("enrichmentWindow started %d lookups and passed %v on, want all lookups at once", atomic.LoadInt64(&enricher.started), passed())
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if tt.inOrder && len(passed()) != 0 {
+				t.Errorf("enrichmentWindow passed %v on before the first line, want them held back", passed())
+			}
+			close(enricher.release)
+			window.wait()
+			if !tt.inOrder {
+				sort.Strings(got[:len(got)-1])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("enrichmentWindow passed %v on, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogAnalyzer_enrichmentConcurrency(t *testing.T) {
+	const filePath = "./test-data/programming-task.log"
+	var enriched []map[string]map[string]int
+	for _, config := range []*LogAnalyzerConfig{
+		{},
+		{EnrichmentConcurrency: 8},
+		{EnrichmentConcurrency: 8, PreserveEnrichmentOrder: true},
+	} {
+		config.LineRegex = defaultLineRegex
+		config.Enrichers = []*EnrichmentStep{{Enricher: &userAgentEnricher{}}}
+		config.TopEnrichedValuesCount = 1
+		a, err := NewLogAnalyzer(config)
+		if err != nil {
+			t.Fatalf("NewLogAnalyzer() error = %v", err)
+		}
+		aggs, err := a.(*logAnalyzer).aggregateFiles([]string{filePath})
+		if err != nil {
+			t.Fatalf("logAnalyzer.aggregateFiles() error = %v", err)
+		}
+		enriched = append(enriched, aggs[filePath].enrichedHits)
+	}
+	for _, got := range enriched[1:] {
+		if !reflect.DeepEqual(got, enriched[0]) {
+			t.Errorf("logAnalyzer.aggregateFiles() enriched concurrently = %v, want %v", got, enriched[0])
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorLine : A line of an Apache or nginx error log
+type ErrorLine struct {
+	Time time.Time
+	// Level : e.g. "error", "warn" or "crit"
+	Level string
+	// Module : The Apache module logging the line, e.g. "core" or "ssl",
+	// empty for nginx and Apache 2.2
+	Module string
+	PID    int
+	// Client : Address of the client of the request in error, when logged
+	Client  string
+	Message string
+}
+
+// ErrorLogAnalytics : What went wrong, out of error logs
+type ErrorLogAnalytics struct {
+	// Lines : Lines of a known error log format
+	Lines int
+	// ParseErrors : Other non-empty lines
+	ParseErrors int
+	// Levels : Lines per level
+	Levels map[string]int
+	// Modules : Lines per Apache module
+	Modules map[string]int `json:",omitempty"`
+	// TopMessages : The most common messages, their numbers, quoted strings
+	// and paths replaced by placeholders, e.g. `open() "*" failed (N: No such
+	// file or directory)`
+	TopMessages []string
+	// TopClients : Clients of the most errors
+	TopClients []string
+	// Timeseries : Lines per level per TimeseriesInterval, the error rate
+	// over time
+	Timeseries []*TimeseriesBucket
+}
+
+// errorLogFormats : Apache 2.4 and 2.2 error lines, then nginx ones
+//
+//	[Wed Oct 11 14:32:52.123456 2023] [core:error] [pid 1234:tid 5678] [client 10.0.0.1:4567] AH00037: message
+//	[Wed Oct 11 14:32:52 2000] [error] [client 127.0.0.1] message
+//	2023/10/11 14:32:52 [error] 1234#5678: *99 message, client: 10.0.0.1, server: localhost
+var (
+	apacheErrorLine = regexp.MustCompile(`^\[([^\]]+)\] \[(?:([^:\]]+):)?([^\]]+)\](?: \[pid (\d+)[^\]]*\])?(?: \[client ([^\]]+)\])? (.*)$`)
+	nginxErrorLine  = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (\d+)#\d+: (?:\*\d+ )?(.*)$`)
+	nginxClient     = regexp.MustCompile(`, client: ([^,]+)`)
+)
+
+// ParseErrorLine : Parses a line of an Apache (2.2 or 2.4) or nginx error
+// log. Times have no time zone, and are read as UTC.
+func ParseErrorLine(text string) (*ErrorLine, error) {
+	if m := apacheErrorLine.FindStringSubmatch(text); m != nil {
+		line := &ErrorLine{Module: m[2], Level: m[3], Client: withoutPort(m[5]), Message: m[6]}
+		line.Time, _ = time.Parse("Mon Jan _2 15:04:05 2006", m[1])
+		line.PID, _ = strconv.Atoi(m[4])
+		return line, nil
+	}
+	if m := nginxErrorLine.FindStringSubmatch(text); m != nil {
+		line := &ErrorLine{Level: m[2], Message: m[4]}
+		line.Time, _ = time.Parse("2006/01/02 15:04:05", m[1])
+		line.PID, _ = strconv.Atoi(m[3])
+		if client := nginxClient.FindStringSubmatch(m[4]); client != nil {
+			line.Client = client[1]
+		}
+		return line, nil
+	}
+	return nil, errors.New(ErrLineNotMatched)
+}
+
+// withoutPort : The address, without the port Apache 2.4 logs clients with
+func withoutPort(address string) string {
+	if net.ParseIP(address) != nil {
+		return address
+	}
+	if i := strings.LastIndexByte(address, ':'); i >= 0 && net.ParseIP(address[:i]) != nil {
+		return address[:i]
+	}
+	return address
+}
+
+var (
+	// errorContext : What nginx and Apache append to messages, the request's
+	// client, server and request line, or the referrer
+	errorContext  = regexp.MustCompile(`, (?:client|referer): .*$`)
+	quotedString  = regexp.MustCompile(`"[^"]*"`)
+	messagePath   = regexp.MustCompile(`(^|[\s(])/[^\s,;)]*`)
+	messageNumber = regexp.MustCompile(`\b\d+\b`)
+)
+
+// messageTemplate : The message without its request context, numbers, quoted
+// strings and paths replaced by placeholders, so that the messages of one
+// error are counted together
+func messageTemplate(message string) string {
+	message = errorContext.ReplaceAllString(message, "")
+	message = quotedString.ReplaceAllString(message, `"*"`)
+	message = messagePath.ReplaceAllString(message, "$1/*")
+	return messageNumber.ReplaceAllString(message, "N")
+}
+
+// AnalyzeErrorLog : Analyzes Apache or nginx error logs, reporting the top
+// TopErrorMessagesCount messages and MostActiveIPsCount clients
+func (l *logAnalyzer) AnalyzeErrorLog(filePaths ...string) (*ErrorLogAnalytics, error) {
+	analytics := &ErrorLogAnalytics{Levels: make(map[string]int)}
+	modules := make(map[string]int)
+	messages := make(map[string]int)
+	clients := make(map[string]int)
+	buckets := make(seriesHits)
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, errors.New(ErrOpeningFile)
+		}
+		records := l.records(bufio.NewScanner(file))
+		for records.Scan() {
+			text := records.Text()
+			if l.syslog {
+				text, _, _, _ = stripSyslog(text)
+			}
+			if text == "" {
+				continue
+			}
+			// the lines continuing a record, e.g. a stack trace, vary too
+			// much to count messages by
+			if i := strings.IndexByte(text, '\n'); i >= 0 {
+				text = text[:i]
+			}
+			line, err := ParseErrorLine(text)
+			if err != nil {
+				analytics.ParseErrors++
+				continue
+			}
+			analytics.Lines++
+			analytics.Levels[line.Level]++
+			if line.Module != "" {
+				modules[line.Module]++
+			}
+			messages[messageTemplate(line.Message)]++
+			if line.Client != "" {
+				clients[line.Client]++
+			}
+			if !line.Time.IsZero() {
+				start := line.Time.UTC().Truncate(l.timeseriesInterval).Unix()
+				if buckets[start] == nil {
+					buckets[start] = make(map[string]int)
+				}
+				buckets[start][line.Level]++
+			}
+		}
+		err = records.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	settings := l.reloadable()
+	if len(modules) > 0 {
+		analytics.Modules = modules
+	}
+	analytics.TopMessages = topMost(messages, settings.topErrorMessagesCount)
+	analytics.TopClients = topMost(clients, settings.mostActiveIPsCount)
+	analytics.Timeseries = timeseriesReport(map[string]seriesHits{"levels": buckets})["levels"]
+	return analytics, nil
+}
@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseErrorLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    *ErrorLine
+		wantErr bool
+	}{
+		{
+			name: "Apache 2.4",
+			text: `[Wed Oct 11 14:32:52.123456 2023] [core:error] [pid 1234:tid 5678] [client 10.0.0.1:4567] AH00037: Symbolic link not allowed or link target not accessible: /var/www/html/a`,
+			want: &ErrorLine{
+				Time:    time.Date(2023, 10, 11, 14, 32, 52, 123456000, time.UTC),
+				Level:   "error",
+				Module:  "core",
+				PID:     1234,
+				Client:  "10.0.0.1",
+				Message: "AH00037: Symbolic link not allowed or link target not accessible: /var/www/html/a",
+			},
+		},
+		{
+			name: "Apache 2.2",
+			text: `[Wed Oct 11 14:32:52 2000] [error] [client 127.0.0.1] client denied by server configuration: /export/home/live/ap/htdocs/test`,
+			want: &ErrorLine{
+				Time:    time.Date(2000, 10, 11, 14, 32, 52, 0, time.UTC),
+				Level:   "error",
+				Client:  "127.0.0.1",
+				Message: "client denied by server configuration: /export/home/live/ap/htdocs/test",
+			},
+		},
+		{
+			name: "nginx",
+			text: `2023/10/11 14:32:52 [error] 1234#5678: *99 open() "/usr/share/nginx/html/favicon.ico" failed (2: No such file or directory), client: 10.0.0.2, server: localhost, request: "GET /favicon.ico HTTP/1.1"`,
+			want: &ErrorLine{
+				Time:    time.Date(2023, 10, 11, 14, 32, 52, 0, time.UTC),
+				Level:   "error",
+				PID:     1234,
+				Client:  "10.0.0.2",
+				Message: `open() "/usr/share/nginx/html/favicon.ico" failed (2: No such file or directory), client: 10.0.0.2, server: localhost, request: "GET /favicon.ico HTTP/1.1"`,
+			},
+		},
+		{
+			name:    "access log line",
+			text:    `10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 3574`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseErrorLine(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseErrorLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseErrorLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_messageTemplate(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{
+			message: `open() "/usr/share/nginx/html/favicon.ico" failed (2: No such file or directory), client: 10.0.0.2, server: localhost`,
+			want:    `open() "*" failed (N: No such file or directory)`,
+		},
+		{
+			message: "AH01630: client denied by server configuration: /var/www/private",
+			want:    "AH01630: client denied by server configuration: /*",
+		},
+		{
+			message: "upstream timed out (110: Connection timed out) while reading response header from upstream, client: 10.0.0.3",
+			want:    "upstream timed out (N: Connection timed out) while reading response header from upstream",
+		},
+	}
+	for _, tt := range tests {
+		if got := messageTemplate(tt.message); got != tt.want {
+			t.Errorf("messageTemplate(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestLogAnalyzer_AnalyzeErrorLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errorlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "error.log")
+	lines := `2023/10/11 14:32:52 [error] 1234#0: *1 open() "/srv/a.png" failed (2: No such file or directory), client: 10.0.0.1, server: localhost
+2023/10/11 14:33:10 [error] 1234#0: *2 open() "/srv/b.png" failed (2: No such file or directory), client: 10.0.0.1, server: localhost
+2023/10/11 15:01:00 [warn] 1234#0: *3 an upstream response is buffered to a temporary file /var/cache/nginx/1/00/0000000001, client: 10.0.0.2, server: localhost
+[Wed Oct 11 15:02:00.000001 2023] [ssl:warn] [pid 99] AH01909: server certificate does NOT include an ID which matches the server name
+not an error line
+`
+	if err := ioutil.WriteFile(filePath, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:             defaultLineRegex,
+		MostActiveIPsCount:    1,
+		TopErrorMessagesCount: 1,
+		TimeseriesInterval:    time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	got, err := l.AnalyzeErrorLog(filePath)
+	if err != nil {
+		t.Fatalf("AnalyzeErrorLog() error = %v", err)
+	}
+	hour := time.Date(2023, 10, 11, 14, 0, 0, 0, time.UTC)
+	want := &ErrorLogAnalytics{
+		Lines:       4,
+		ParseErrors: 1,
+		Levels:      map[string]int{"error": 2, "warn": 2},
+		Modules:     map[string]int{"ssl": 1},
+		TopMessages: []string{`open() "*" failed (N: No such file or directory)`},
+		TopClients:  []string{"10.0.0.1"},
+		Timeseries: []*TimeseriesBucket{
+			{Start: hour, Counts: map[string]int{"error": 2}},
+			{Start: hour.Add(time.Hour), Counts: map[string]int{"warn": 2}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzeErrorLog() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEstimateSampleLines : Lines sampled by a dry run when not specified
+const DefaultEstimateSampleLines = 10000
+
+// CostEstimate : Projected cost of analyzing a file, from a sample of its lines
+type CostEstimate struct {
+	// FileBytes : Size of the file
+	FileBytes int64
+	// SampledLines : Lines actually read and analyzed
+	SampledLines int
+	// Complete : The sample covered the whole file, the figures are exact
+	Complete bool
+	// EstimatedLines : Lines the whole file is expected to hold
+	EstimatedLines int64
+	// LinesPerSecond : Parse and count rate measured on the sample
+	LinesPerSecond float64
+	// ParseErrorRate : Ratio of sampled lines that did not parse
+	ParseErrorRate float64
+	// EstimatedKeys : Distinct IPs, URLs... the aggregates are expected to hold
+	EstimatedKeys int64
+	// EstimatedMemoryBytes : Projected memory of those aggregates
+	EstimatedMemoryBytes int64
+	// EstimatedRuntime : Expected duration of the full analysis
+	EstimatedRuntime time.Duration
+}
+
+// EstimateCost : Analyzes the first sampleLines lines of the file and projects
+// the figures to the whole file. Distinct keys are assumed to grow like
+// Heaps' law, with the exponent fitted on the two halves of the sample. The
+// sample is counted apart, the self-metrics left as they are.
+func (l *logAnalyzer) EstimateCost(filePath string, sampleLines int) (*CostEstimate, error) {
+	if sampleLines <= 0 {
+		sampleLines = DefaultEstimateSampleLines
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.New(ErrOpeningFile)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.New(ErrOpeningFile)
+	}
+
+	estimate := &CostEstimate{FileBytes: info.Size()}
+	agg := newAggregate()
+	reader := bufio.NewReader(file)
+	var sampledBytes int64
+	var parseErrors, halfKeys int
+	// a sample of a single line has no half to fit the exponent on, keys
+	// are then assumed to grow with the lines
+	half := sampleLines / 2
+	parseLine := l.newLineParser()
+	start := time.Now()
+	for estimate.SampledLines < sampleLines {
+		text, err := reader.ReadString('\n')
+		sampledBytes += int64(len(text))
+		if len(text) > 0 {
+			estimate.SampledLines++
+			line, parseErr := parseLine(trimEOL(text))
+			if parseErr != nil {
+				parseErrors++
+			} else {
+				l.countLine(agg, line)
+			}
+			if half > 0 && estimate.SampledLines == half {
+				halfKeys = agg.keys()
+			}
+		}
+		if err == io.EOF {
+			estimate.Complete = true
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, ErrOpeningFile)
+		}
+	}
+	elapsed := time.Since(start)
+
+	if estimate.SampledLines == 0 {
+		return estimate, nil
+	}
+	if elapsed > 0 {
+		estimate.LinesPerSecond = float64(estimate.SampledLines) / elapsed.Seconds()
+	}
+	estimate.ParseErrorRate = float64(parseErrors) / float64(estimate.SampledLines)
+
+	keys := agg.keys()
+	keyBytes := agg.keyBytes()
+	scale := 1.0
+	if !estimate.Complete {
+		scale = float64(estimate.FileBytes) / float64(sampledBytes)
+	}
+	estimate.EstimatedLines = int64(math.Round(float64(estimate.SampledLines) * scale))
+
+	// Heaps' law: keys = k * lines^beta
+	beta := 1.0
+	if halfKeys > 0 && keys > halfKeys {
+		beta = math.Log(float64(keys)/float64(halfKeys)) / math.Log(float64(estimate.SampledLines)/float64(half))
+	} else if halfKeys > 0 {
+		beta = 0
+	}
+	beta = math.Max(0, math.Min(1, beta))
+	keyScale := math.Pow(scale, beta)
+	estimate.EstimatedKeys = int64(math.Round(float64(keys) * keyScale))
+	if keys > 0 {
+		avgKeyBytes := float64(keyBytes)/float64(keys) + aggregateKeyOverhead
+		estimate.EstimatedMemoryBytes = int64(math.Round(avgKeyBytes * float64(estimate.EstimatedKeys)))
+	}
+	if estimate.LinesPerSecond > 0 {
+		estimate.EstimatedRuntime = time.Duration(float64(estimate.EstimatedLines) / estimate.LinesPerSecond * float64(time.Second))
+	}
+
+	return estimate, nil
+}
+
+func trimEOL(text string) string {
+	for len(text) > 0 && (text[len(text)-1] == '\n' || text[len(text)-1] == '\r') {
+		text = text[:len(text)-1]
+	}
+	return text
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_logAnalyzer_EstimateCost(t *testing.T) {
+	tests := []struct {
+		name         string
+		filePath     string
+		sampleLines  int
+		wantComplete bool
+		wantLines    [2]int64
+		wantKeys     [2]int64
+		wantErr      error
+	}{
+		{
+			name:     "error when wrong file path is provided",
+			filePath: "./test-data.log",
+			wantErr:  errors.New(ErrOpeningFile),
+		},
+		{
+			name:         "whole file sampled, exact figures",
+			filePath:     "./test-data/programming-task.log",
+			wantComplete: true,
+			wantLines:    [2]int64{23, 23},
+			// 11 ips, 20 urls
+			wantKeys: [2]int64{31, 31},
+		},
+		{
+			name:        "partial sample projected to the file size",
+			filePath:    "./test-data/programming-task.log",
+			sampleLines: 10,
+			wantLines:   [2]int64{18, 28},
+			wantKeys:    [2]int64{20, 50},
+		},
+		{
+			name:        "single line sample, keys growing with the lines",
+			filePath:    "./test-data/programming-task.log",
+			sampleLines: 1,
+			wantLines:   [2]int64{15, 35},
+			wantKeys:    [2]int64{30, 70},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex})
+			if err != nil {
+				t.Fatalf("logAnalyzer.EstimateCost() error = %v, error creating analyzer", err)
+			}
+			got, err := l.EstimateCost(tt.filePath, tt.sampleLines)
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("logAnalyzer.EstimateCost() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("logAnalyzer.EstimateCost() error = %v", err)
+			}
+			if got.Complete != tt.wantComplete {
+				t.Errorf("logAnalyzer.EstimateCost() complete = %v, want %v", got.Complete, tt.wantComplete)
+			}
+			if got.EstimatedLines < tt.wantLines[0] || got.EstimatedLines > tt.wantLines[1] {
+				t.Errorf("logAnalyzer.EstimateCost() lines = %d, want within %v", got.EstimatedLines, tt.wantLines)
+			}
+			if got.EstimatedKeys < tt.wantKeys[0] || got.EstimatedKeys > tt.wantKeys[1] {
+				t.Errorf("logAnalyzer.EstimateCost() keys = %d, want within %v", got.EstimatedKeys, tt.wantKeys)
+			}
+			if got.EstimatedMemoryBytes <= got.EstimatedKeys*aggregateKeyOverhead {
+				t.Errorf("logAnalyzer.EstimateCost() memory = %d, too low for %d keys", got.EstimatedMemoryBytes, got.EstimatedKeys)
+			}
+			if got.LinesPerSecond <= 0 || got.EstimatedRuntime <= 0 {
+				t.Errorf("logAnalyzer.EstimateCost() rate, runtime = %v, %v", got.LinesPerSecond, got.EstimatedRuntime)
+			}
+			// the sample is not counted as analyzed
+			if metrics := l.SelfMetrics(); metrics.LinesRead != 0 || metrics.QueueDepth != 0 {
+				t.Errorf("logAnalyzer.EstimateCost() self-metrics lines read, queue depth = %d, %d, want 0, 0", metrics.LinesRead, metrics.QueueDepth)
+			}
+		})
+	}
+}
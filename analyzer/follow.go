@@ -0,0 +1,244 @@
+package analyzer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrFollowingFile :
+	ErrFollowingFile = "error following file"
+
+	// DefaultFollowPollInterval : How often a followed file is checked for
+	// appended lines once its end was reached
+	DefaultFollowPollInterval = 250 * time.Millisecond
+	// DefaultSnapshotInterval : How often analytics snapshots are emitted
+	// while following a file
+	DefaultSnapshotInterval = 10 * time.Second
+)
+
+func (l *logAnalyzer) Follow(ctx context.Context, filePath string) (<-chan *LogAnalytics, <-chan error) {
+	snapshotCh := make(chan *LogAnalytics)
+	errCh := make(chan error, 1)
+
+	// following stops on a line not fitting the schema in strict schema mode
+	ctx, stop := context.WithCancel(ctx)
+	lineCh, tailErrCh, err := tailLines(ctx, filePath, l.followPollInterval)
+	if err != nil {
+		stop()
+		errCh <- err
+		close(errCh)
+		close(snapshotCh)
+		return snapshotCh, errCh
+	}
+	if l.multiline != nil {
+		lineCh = assembleRecords(lineCh, l.multiline, l.followPollInterval)
+	}
+
+	agg := l.followedAggregate()
+	// lines are sampled as read, a loaded state replacing what they hold
+	samples := agg.samples
+	snapshot := func() *LogAnalytics {
+		l.stateMu.Lock()
+		defer l.stateMu.Unlock()
+		l.compactTimeseries(agg.timeseries, agg.lastSeen)
+		return l.report(agg)
+	}
+
+	// parsing and counting are decoupled by a bounded queue, so a slow
+	// aggregation stage either slows reading down or drops lines, as per the
+	// queue policy, instead of letting memory grow
+	queue := l.newQueue()
+	var readErr error
+	go func() {
+		defer close(queue)
+		parseLine := l.newLineParser()
+		window := l.newEnrichmentWindow(func(line *Line, text string) {
+			if l.lineIndex != nil {
+				if l.redaction != nil {
+					text = ""
+				}
+				l.lineIndex.add(text, line)
+			}
+			l.enqueue(queue, line)
+		})
+		for text := range lineCh {
+			if readErr != nil {
+				// drained until tailing stops
+				continue
+			}
+			if len(text) >= maxLineBytes {
+				l.warn(&OversizedLine{Bytes: len(text)})
+				continue
+			}
+			if readErr = l.readLine(parseLine, window, samples, text); readErr != nil {
+				stop()
+			}
+		}
+		window.wait()
+	}()
+
+	ingested := make(chan struct{})
+	go func() {
+		defer close(ingested)
+		for line := range queue {
+			l.stateMu.Lock()
+			l.consolidate(agg, line)
+			l.stateMu.Unlock()
+		}
+	}()
+
+	go func() {
+		defer close(errCh)
+		defer close(snapshotCh)
+		defer stop()
+
+		ticker := time.NewTicker(l.snapshotInterval)
+		defer ticker.Stop()
+		signalCh := make(chan os.Signal, 1)
+		if len(l.snapshotSignals) > 0 {
+			signal.Notify(signalCh, l.snapshotSignals...)
+			defer signal.Stop(signalCh)
+		}
+
+		// once everything read was ingested, the final analytics are
+		// emitted before the channels are closed
+		final := func() {
+			snapshotCh <- snapshot()
+			err := <-tailErrCh
+			if readErr != nil {
+				err = readErr
+			}
+			if err != nil {
+				errCh <- err
+			}
+		}
+		for {
+			select {
+			case <-ingested:
+				final()
+				return
+			case <-ticker.C:
+			case <-signalCh:
+			}
+
+			select {
+			case snapshotCh <- snapshot():
+			case <-ingested:
+				final()
+				return
+			}
+		}
+	}()
+
+	return snapshotCh, errCh
+}
+
+// tailLines : Emits the lines of the file, then the ones appended to it, until
+// ctx is done. A truncated file is read again from its start, and a rotated
+// one (a new file at filePath) is reopened. Incomplete lines are held back
+// until they are complete, or ctx is done. The consumer must drain the line
+// channel.
+func tailLines(ctx context.Context, filePath string, pollInterval time.Duration) (<-chan string, <-chan error, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, errors.New(ErrOpeningFile)
+	}
+
+	lineCh := make(chan string)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		defer close(lineCh)
+		defer func() {
+			file.Close()
+		}()
+
+		reader := bufio.NewReader(file)
+		var partial strings.Builder
+		for {
+			chunk, err := reader.ReadString('\n')
+			partial.WriteString(chunk)
+			if err == nil {
+				lineCh <- strings.TrimRight(partial.String(), "\r\n")
+				partial.Reset()
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+			if err != io.EOF {
+				errCh <- errors.Wrap(err, ErrFollowingFile)
+				return
+			}
+
+			select {
+			case <-time.After(pollInterval):
+			case <-ctx.Done():
+				// flush the last line, even if its writer did not end it yet
+				if partial.Len() > 0 {
+					lineCh <- partial.String()
+				}
+				return
+			}
+
+			info, err := os.Stat(filePath)
+			if err != nil {
+				// rotated away and not recreated yet
+				continue
+			}
+			current, err := file.Stat()
+			if err != nil {
+				errCh <- errors.Wrap(err, ErrFollowingFile)
+				return
+			}
+			if !os.SameFile(info, current) {
+				rotated, err := os.Open(filePath)
+				if err != nil {
+					continue
+				}
+				// the lines written before the rotation are read to the end
+				// of the old file, whose last line is ended by the rotation
+				for {
+					chunk, err := reader.ReadString('\n')
+					partial.WriteString(chunk)
+					if err != nil {
+						break
+					}
+					lineCh <- strings.TrimRight(partial.String(), "\r\n")
+					partial.Reset()
+				}
+				if partial.Len() > 0 {
+					lineCh <- strings.TrimRight(partial.String(), "\r\n")
+					partial.Reset()
+				}
+				file.Close()
+				file = rotated
+				reader.Reset(file)
+				continue
+			}
+			offset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				errCh <- errors.Wrap(err, ErrFollowingFile)
+				return
+			}
+			if info.Size() < offset {
+				if _, err := file.Seek(0, io.SeekStart); err != nil {
+					errCh <- errors.Wrap(err, ErrFollowingFile)
+					return
+				}
+				reader.Reset(file)
+				partial.Reset()
+			}
+		}
+	}()
+
+	return lineCh, errCh, nil
+}
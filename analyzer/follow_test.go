@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_Follow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	task, err := ioutil.ReadFile("./test-data/programming-task.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, task, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:          defaultLineRegex,
+		FollowPollInterval: 5 * time.Millisecond,
+		SnapshotInterval:   20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Follow() error = %v, error creating analyzer", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshotCh, errCh := l.Follow(ctx, filePath)
+
+	waitFor := func(uniqueIPCount int) {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case snapshot := <-snapshotCh:
+				if snapshot.UniqueIPCount == uniqueIPCount {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("logAnalyzer.Follow() no snapshot with %d unique ips", uniqueIPCount)
+			}
+		}
+	}
+	waitFor(11)
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a partial line is only counted once complete
+	file.WriteString(`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 1 "-" `)
+	file.Sync()
+	time.Sleep(50 * time.Millisecond)
+	file.WriteString("\"curl\"\n")
+	file.Close()
+	waitFor(12)
+
+	// rotation: a new file replaces the followed one
+	if err := os.Rename(filePath, filePath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	rotated := `10.0.0.2 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 1 "-" "curl"` + "\n"
+	if err := ioutil.WriteFile(filePath, []byte(rotated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(13)
+
+	// an unterminated last line is flushed into the final snapshot
+	file, err = os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`10.0.0.3 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 1 "-" "curl"`)
+	file.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	var final *LogAnalytics
+	for snapshot := range snapshotCh {
+		final = snapshot
+	}
+	if final == nil || final.UniqueIPCount != 14 {
+		t.Errorf("logAnalyzer.Follow() final snapshot = %+v, want 14 unique ips", final)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("logAnalyzer.Follow() error = %v", err)
+	}
+}
+
+func Test_logAnalyzer_Follow_missingFile(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Follow() error = %v, error creating analyzer", err)
+	}
+	snapshotCh, errCh := l.Follow(context.Background(), "./test-data.log")
+	if _, ok := <-snapshotCh; ok {
+		t.Errorf("logAnalyzer.Follow() snapshot emitted for a missing file")
+	}
+	if err := <-errCh; err == nil || err.Error() != ErrOpeningFile {
+		t.Errorf("logAnalyzer.Follow() error = %v, wantErr %v", err, ErrOpeningFile)
+	}
+}
+
+func Test_tailLines_rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lineCh, _, err := tailLines(ctx, filePath, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("tailLines() error = %v", err)
+	}
+	next := func() string {
+		select {
+		case line := <-lineCh:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("tailLines() no line")
+			return ""
+		}
+	}
+	if got := next(); got != "a" {
+		t.Fatalf("tailLines() = %q, want %q", got, "a")
+	}
+
+	// lines written right before the rotation, the last one unterminated,
+	// are still read from the old file
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("b\nc")
+	file.Close()
+	if err := os.Rename(filePath, filePath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filePath, []byte("d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"b", "c", "d"} {
+		if got := next(); got != want {
+			t.Errorf("tailLines() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrInvalidTrustedProxy :
+	ErrInvalidTrustedProxy = "trusted proxy must be an IP address or a network in CIDR notation"
+)
+
+// trustedProxies : The networks of the proxies trusted to report the client
+// they forward requests of in X-Forwarded-For
+type trustedProxies []*net.IPNet
+
+// newTrustedProxies : The networks of the proxies, given as IP addresses or
+// networks in CIDR notation, nil for none
+func newTrustedProxies(proxies []string) (trustedProxies, error) {
+	var trusted trustedProxies
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			_, network, err := net.ParseCIDR(proxy)
+			if err != nil {
+				return nil, errors.Wrap(errors.New(ErrInvalidTrustedProxy), proxy)
+			}
+			trusted = append(trusted, network)
+			continue
+		}
+		ip := net.ParseIP(proxy)
+		if ip == nil {
+			return nil, errors.Wrap(errors.New(ErrInvalidTrustedProxy), proxy)
+		}
+		bits := 128
+		if v4 := ip.To4(); v4 != nil {
+			ip, bits = v4, 32
+		}
+		trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return trusted, nil
+}
+
+// trusts : Whether the address is of a trusted proxy
+func (t trustedProxies) trusts(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientOf : The client of a request from the peer, with the X-Forwarded-For
+// chain: the peer itself, unless it is a trusted proxy. Then the chain is
+// walked from its end, each proxy appending the address it got the request
+// from, up to the first address not trusted. An address that is not an IP
+// ends the walk, as the addresses before it cannot be told apart from forged
+// ones; so does the start of the chain, its first address then being the
+// client.
+func (t trustedProxies) clientOf(peer, forwardedFor string) string {
+	client := peer
+	for t.trusts(client) && forwardedFor != "" {
+		hop := forwardedFor
+		forwardedFor = ""
+		if i := strings.LastIndexByte(hop, ','); i >= 0 {
+			hop, forwardedFor = hop[i+1:], hop[:i]
+		}
+		hop = strings.TrimSpace(hop)
+		// with its port, e.g. 203.0.113.9:52346 or [2001:db8::1]:443
+		if host, _, err := net.SplitHostPort(hop); err == nil {
+			hop = host
+		}
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		client = hop
+	}
+	return client
+}
+
+// resolveClient : Replaces the remote host of the line, when it is a trusted
+// proxy, with the client it forwarded the request of, keeping the proxy as
+// the peer
+func (l *logAnalyzer) resolveClient(line *Line) {
+	if l.trustedProxies == nil || line.ForwardedFor == "" {
+		return
+	}
+	if client := l.trustedProxies.clientOf(line.RemoteHost, line.ForwardedFor); client != line.RemoteHost {
+		line.Peer, line.RemoteHost = line.RemoteHost, client
+	}
+}
@@ -0,0 +1,118 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_newTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		wantErr string
+	}{
+		{name: "addresses and networks", proxies: []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "::1"}},
+		{name: "none"},
+		{name: "invalid address", proxies: []string{"10.0.0"}, wantErr: "10.0.0: " + ErrInvalidTrustedProxy},
+		{name: "invalid network", proxies: []string{"10.0.0.0/33"}, wantErr: "10.0.0.0/33: " + ErrInvalidTrustedProxy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTrustedProxies(tt.proxies)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("newTrustedProxies() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(got) != len(tt.proxies) {
+				t.Errorf("newTrustedProxies() = %v, %v, want %d networks", got, err, len(tt.proxies))
+			}
+		})
+	}
+}
+
+func Test_trustedProxies_clientOf(t *testing.T) {
+	trusted, err := newTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8:1::/48"})
+	if err != nil {
+		t.Fatalf("newTrustedProxies() error = %v", err)
+	}
+	tests := []struct {
+		name         string
+		peer         string
+		forwardedFor string
+		want         string
+	}{
+		{name: "untrusted peer", peer: "203.0.113.5", forwardedFor: "198.51.100.7", want: "203.0.113.5"},
+		{name: "trusted peer", peer: "10.0.0.2", forwardedFor: "198.51.100.7", want: "198.51.100.7"},
+		{name: "trusted chain", peer: "10.0.0.2", forwardedFor: "198.51.100.7, 192.168.1.1", want: "198.51.100.7"},
+		// the client may have sent an X-Forwarded-For of its own
+		{name: "forged start", peer: "10.0.0.2", forwardedFor: "1.2.3.4, 198.51.100.7, 10.1.1.1", want: "198.51.100.7"},
+		{name: "all trusted", peer: "10.0.0.2", forwardedFor: "10.0.0.9, 10.0.0.3", want: "10.0.0.9"},
+		{name: "ports", peer: "10.0.0.2", forwardedFor: "[2001:db8::7]:443, 10.0.0.3:8080", want: "2001:db8::7"},
+		{name: "ipv6 proxy", peer: "2001:db8:1::1", forwardedFor: "198.51.100.7", want: "198.51.100.7"},
+		{name: "not an address", peer: "10.0.0.2", forwardedFor: "198.51.100.7, unknown, 10.0.0.3", want: "10.0.0.3"},
+		{name: "empty hops", peer: "10.0.0.2", forwardedFor: ",198.51.100.7", want: "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trusted.clientOf(tt.peer, tt.forwardedFor); got != tt.want {
+				t.Errorf("trustedProxies.clientOf(%q, %q) = %q, want %q", tt.peer, tt.forwardedFor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogAnalyzer_trustedProxies(t *testing.T) {
+	format, err := formats.Nginx(`$remote_addr [$time_local] "$request" $status $body_bytes_sent "$http_x_forwarded_for"`)
+	if err != nil {
+		t.Fatalf("formats.Nginx() error = %v", err)
+	}
+	lines := []string{
+		`10.0.0.2 [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 120 "198.51.100.7, 10.0.0.3"`,
+		`10.0.0.2 [10/Jul/2018:22:21:29 +0200] "GET / HTTP/1.1" 200 120 "198.51.100.7"`,
+		`203.0.113.5 [10/Jul/2018:22:21:30 +0200] "GET / HTTP/1.1" 200 120 "1.2.3.4"`,
+		`10.0.0.2 [10/Jul/2018:22:21:31 +0200] "GET / HTTP/1.1" 200 120 "-"`,
+	}
+	tests := []struct {
+		name    string
+		proxies []string
+		want    map[string]int
+		// wantPeer : The peer of the first line
+		wantPeer string
+	}{
+		{name: "trusted", proxies: []string{"10.0.0.0/8"}, want: map[string]int{"198.51.100.7": 2, "203.0.113.5": 1, "10.0.0.2": 1}, wantPeer: "10.0.0.2"},
+		{name: "none", want: map[string]int{"10.0.0.2": 3, "203.0.113.5": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewLogAnalyzer(&LogAnalyzerConfig{Format: format, TrustedProxies: tt.proxies})
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			l := a.(*logAnalyzer)
+			agg := newAggregate()
+			var read []*Line
+			window := l.newEnrichmentWindow(func(line *Line, _ string) {
+				read = append(read, line)
+				l.consolidate(agg, line)
+			})
+			parseLine := l.newLineParser()
+			for _, text := range lines {
+				if err := l.readLine(parseLine, window, &lineSamples{}, text); err != nil {
+					t.Fatalf("logAnalyzer.readLine() error = %v", err)
+				}
+			}
+			window.wait()
+
+			if !reflect.DeepEqual(agg.ipHits, tt.want) {
+				t.Errorf("logAnalyzer.readLine() hits per IP = %v, want %v", agg.ipHits, tt.want)
+			}
+			if read[0].Peer != tt.wantPeer || read[0].ForwardedFor != "198.51.100.7, 10.0.0.3" {
+				t.Errorf("logAnalyzer.readLine() peer = %q, forwarded for %q, want %q and the chain as logged", read[0].Peer, read[0].ForwardedFor, tt.wantPeer)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build gofuzz
+// +build gofuzz
+
+package analyzer
+
+// Fuzz : go-fuzz entry point, see https://github.com/dvyukov/go-fuzz
+//
+//	go-fuzz-build github.com/sdileep/http-log-parser/analyzer
+//	go-fuzz -bin=analyzer-fuzz.zip -workdir=analyzer/testdata/fuzz/FuzzParse
+func Fuzz(data []byte) int {
+	if _, err := Parse(data); err != nil {
+		return 0
+	}
+	return 1
+}
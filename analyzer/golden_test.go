@@ -0,0 +1,137 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+//go:generate go run ../cmd/fixturegen -o test-data/golden/combined/pathological.log
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files with the current parser output")
+
+// goldenFormats : Parser presets validated against test-data/golden/<name>/
+var goldenFormats = map[string]func([]byte) (*Line, error){
+	"combined":       Parse,
+	"alb":            parseFormat(formats.ALB),
+	"elb":            parseFormat(formats.ClassicELB),
+	"s3":             parseFormat(formats.S3),
+	"haproxy":        parseFormat(formats.HAProxy),
+	"envoy":          parseFormat(formats.EnvoyDefault),
+	"caddy":          parseFields(newJSONFormat(CaddyJSONFields)),
+	"traefik":        parseFormat(formats.Traefik),
+	"traefik-json":   parseFields(newJSONFormat(TraefikJSONFields)),
+	"cloudflare":     parseFields(newJSONFormat(CloudflareJSONFields)),
+	"squid":          parseFormat(formats.Squid),
+	"varnish":        parseFormat(formats.Varnish),
+	"fastly":         parseFormat(formats.Fastly),
+	"vhost-combined": parseFormat(formats.VHostCombined),
+	"fastly-json":    parseFields(newJSONFormat(FastlyJSONFields)),
+	"cef":            parseFields(newCEFFormat(nil)),
+	// headers declare the fields of the lines after them
+	"cloudfront": parseFields(cloudFrontFormat{}),
+}
+
+// parseFormat : Parses lines of the preset
+func parseFormat(format *formats.Format) func([]byte) (*Line, error) {
+	return func(data []byte) (*Line, error) {
+		return parseLine(format.LineRegex, string(data))
+	}
+}
+
+// parseFields : Parses lines of the field format, one parser for all fixtures
+func parseFields(format fieldFormat) func([]byte) (*Line, error) {
+	parse := format.newParser(nil)
+	return func(data []byte) (*Line, error) {
+		return parse(string(data))
+	}
+}
+
+// goldenResult : The expected outcome of parsing one fixture line
+type goldenResult struct {
+	Line  *Line  `json:"line,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Test_golden : Every test-data/golden/<format>/<fixture>.log is parsed line by
+// line with the <format> preset and compared with <fixture>.golden.json.
+// Run `go test ./analyzer -run Test_golden -update` after adding a fixture.
+func Test_golden(t *testing.T) {
+	dirs, err := ioutil.ReadDir("./test-data/golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		parse, ok := goldenFormats[dir.Name()]
+		if !ok {
+			t.Errorf("no parser preset registered for golden fixtures in %s", dir.Name())
+			continue
+		}
+		fixtures, err := filepath.Glob(filepath.Join("./test-data/golden", dir.Name(), "*.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fixture := range fixtures {
+			fixture := fixture
+			t.Run(dir.Name()+"/"+filepath.Base(fixture), func(t *testing.T) {
+				got, err := goldenParse(fixture, parse)
+				if err != nil {
+					t.Fatal(err)
+				}
+				goldenPath := strings.TrimSuffix(fixture, ".log") + ".golden.json"
+				if *updateGolden {
+					if err := ioutil.WriteFile(goldenPath, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := ioutil.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatalf("%v (run with -update to create it)", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("parsed %s does not match %s:\n%s", fixture, goldenPath, got)
+				}
+			})
+		}
+	}
+}
+
+func goldenParse(fixture string, parse func([]byte) (*Line, error)) ([]byte, error) {
+	file, err := os.Open(fixture)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	results := []*goldenResult{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, err := parse(scanner.Bytes())
+		result := &goldenResult{Line: line}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
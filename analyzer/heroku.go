@@ -0,0 +1,84 @@
+package analyzer
+
+import "regexp"
+
+// HerokuLogfmtFields : The keys of Heroku router logs. On top of
+// DefaultLogfmtFields, the error code (e.g. H12 for request timeouts), the
+// connect time, the host and the request ID are kept in the line's extras.
+var HerokuLogfmtFields = func() map[string]string {
+	fields := map[string]string{
+		"code":       "code",
+		"connect":    "connect",
+		"host":       "host",
+		"request_id": "request_id",
+	}
+	for name, key := range DefaultLogfmtFields {
+		fields[name] = key
+	}
+	return fields
+}()
+
+// herokuHeader : The header of lines drained from Logplex (RFC 5424) or
+// printed by heroku logs, capturing the time, the source and the process:
+//
+//	<158>1 2023-10-11T14:32:52.123456+00:00 host heroku router - at=info method=GET ...
+//	2023-10-11T14:32:52.123456+00:00 heroku[router]: at=info method=GET ...
+var herokuHeader = regexp.MustCompile(`^(?:<\d{1,3}>1 (\S+) \S+ (\S+) (\S+) - |(\S+) (\w+)\[([^\]]+)\]: )`)
+
+// herokuFormat : Reads Heroku router lines, logfmt pairs whose time is the
+// one of their Logplex header, as the router does not log it
+type herokuFormat struct {
+	logfmt *logfmtFormat
+	// time : Whether the time is needed
+	time bool
+}
+
+func newHerokuFormat(fields map[string]string) *herokuFormat {
+	return &herokuFormat{logfmt: newLogfmtFormat(fields), time: true}
+}
+
+func (f *herokuFormat) newParser(batch *lineBatch) lineParser {
+	return func(text string) (*Line, error) {
+		return f.parse(text, batch)
+	}
+}
+
+func (f *herokuFormat) fields() []string {
+	fields := f.logfmt.fields()
+	if !f.time {
+		return fields
+	}
+	for _, field := range fields {
+		if field == "time" {
+			return fields
+		}
+	}
+	return append([]string{"time"}, fields...)
+}
+
+func (f *herokuFormat) project(fields projection) fieldFormat {
+	return &herokuFormat{logfmt: f.logfmt.project(fields).(*logfmtFormat), time: fields.keeps("time")}
+}
+
+// parse : Parses a router line, with or without its header. Like headers,
+// lines of other processes, e.g. of the app's dynos, are skipped without
+// counting as errors.
+func (f *herokuFormat) parse(text string, batch *lineBatch) (*Line, error) {
+	var logged string
+	if m := herokuHeader.FindStringSubmatch(text); m != nil {
+		source, process := m[2]+m[5], m[3]+m[6]
+		if source != "heroku" || process != "router" {
+			return nil, errHeaderLine
+		}
+		logged = m[1] + m[4]
+		text = text[len(m[0]):]
+	}
+	line, err := f.logfmt.parse(text, batch)
+	if err != nil {
+		return nil, err
+	}
+	if f.time && logged != "" && line.Time.IsZero() {
+		line.Time = parseTime(logged)
+	}
+	return line, nil
+}
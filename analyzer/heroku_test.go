@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_herokuFormat_parse(t *testing.T) {
+	logged := time.Date(2023, 10, 11, 14, 32, 52, 123456000, time.UTC)
+	router := &Line{
+		RemoteHost: "1.2.3.4",
+		Time:       logged,
+		Method:     "GET",
+		Path:       "/docs/",
+		Protocol:   "https",
+		URL:        "/docs/",
+		Status:     200,
+		Bytes:      13,
+		Duration:   18 * time.Millisecond,
+		Upstream:   "web.1",
+		Extras:     map[string]string{"code": "", "connect": "1ms", "host": "example.herokuapp.com", "request_id": "8601b555"},
+	}
+	pairs := `at=info method=GET path="/docs/" host=example.herokuapp.com request_id=8601b555 fwd="1.2.3.4" dyno=web.1 connect=1ms service=18ms status=200 bytes=13 protocol=https`
+	tests := []struct {
+		name    string
+		text    string
+		want    *Line
+		wantErr error
+	}{
+		{
+			name: "heroku logs",
+			text: "2023-10-11T14:32:52.123456+00:00 heroku[router]: " + pairs,
+			want: router,
+		},
+		{
+			name: "logplex drain",
+			text: "<158>1 2023-10-11T14:32:52.123456+00:00 host heroku router - " + pairs,
+			want: router,
+		},
+		{
+			name: "request timeout",
+			text: `2023-10-11T14:32:52.123456+00:00 heroku[router]: at=error code=H12 desc="Request timeout" method=GET path="/slow" fwd="1.2.3.4" dyno=web.2 connect=0ms service=30000ms status=503 bytes=0 protocol=https`,
+			want: &Line{
+				RemoteHost: "1.2.3.4",
+				Time:       logged,
+				Method:     "GET",
+				Path:       "/slow",
+				Protocol:   "https",
+				URL:        "/slow",
+				Status:     503,
+				Duration:   30 * time.Second,
+				Upstream:   "web.2",
+				Extras:     map[string]string{"code": "H12", "connect": "0ms", "host": "", "request_id": ""},
+			},
+		},
+		{
+			name:    "app line",
+			text:    `2023-10-11T14:32:52.123456+00:00 app[web.1]: at=info status=200 rendered in 12ms`,
+			wantErr: errHeaderLine,
+		},
+		{
+			name:    "not logfmt",
+			text:    `2023-10-11T14:32:52.123456+00:00 heroku[router]: starting`,
+			wantErr: errNotMatched,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newHerokuFormat(HerokuLogfmtFields).parse(tt.text, nil)
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("herokuFormat.parse() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("herokuFormat.parse() error = %v", err)
+			}
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("herokuFormat.parse() time = %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time = tt.want.Time
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("herokuFormat.parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogAnalyzer_heroku(t *testing.T) {
+	dir, err := ioutil.TempDir("", "heroku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "heroku.log")
+	lines := `2023-10-11T14:32:52+00:00 heroku[router]: at=info method=GET path="/" fwd="1.2.3.4" dyno=web.1 service=10ms status=200 bytes=13
+2023-10-11T14:32:53+00:00 app[web.1]: Completed 200 OK in 8ms
+2023-10-11T14:32:54+00:00 heroku[router]: at=error code=H12 method=GET path="/slow" fwd="1.2.3.5" dyno=web.2 service=30000ms status=503 bytes=0
+`
+	if err := ioutil.WriteFile(filePath, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{Heroku: true, MostActiveIPsCount: 2, UpstreamsCount: 2})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	analytics, err := l.Analyze(filePath)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if analytics.UniqueIPCount != 2 || len(analytics.Upstreams) != 2 {
+		t.Errorf("Analyze() = %d IPs, upstreams %+v, want 2 IPs and 2 dynos", analytics.UniqueIPCount, analytics.Upstreams)
+	}
+	if m := l.SelfMetrics(); m.ParseErrors != 0 || m.LinesRead != 2 {
+		t.Errorf("SelfMetrics() = %d lines read, %d errors, want 2 router lines and no errors", m.LinesRead, m.ParseErrors)
+	}
+	if _, err := NewLogAnalyzer(&LogAnalyzerConfig{Heroku: true, Logfmt: true}); err == nil || err.Error() != ErrConflictingFormats {
+		t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, ErrConflictingFormats)
+	}
+}
@@ -0,0 +1,156 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrInvalidPercentile :
+	ErrInvalidPercentile = "percentiles must be above 0 and at most 100"
+	// ErrInvalidBuckets :
+	ErrInvalidBuckets = "histogram bucket bounds must be positive and increasing"
+)
+
+// DefaultPercentiles : Percentiles reported by default
+var DefaultPercentiles = []float64{50, 95, 99}
+
+// DefaultLatencyBuckets : Upper bounds of the latency histogram buckets
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second,
+	10 * time.Second, 30 * time.Second, time.Minute,
+}
+
+// DefaultSizeBuckets : Upper bounds in bytes of the response size histogram
+// buckets
+var DefaultSizeBuckets = []int{
+	1 << 10, 10 << 10, 100 << 10,
+	1 << 20, 10 << 20, 100 << 20,
+}
+
+// histogram : Counts of observed values per bucket, so percentiles can be
+// estimated in constant memory. Bucket i counts values up to bounds[i], the
+// last bucket the values above all bounds.
+type histogram struct {
+	Counts []int64 `json:"counts"`
+	Sum    float64 `json:"sum"`
+	Max    float64 `json:"max"`
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{Counts: make([]int64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	i := 0
+	for i < len(bounds) && v > bounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Sum += v
+	if v > h.Max {
+		h.Max = v
+	}
+}
+
+func (h *histogram) count() int64 {
+	var n int64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// quantile : Estimates the value below which the q ratio of the values fall,
+// interpolating within the bucket it lands in
+func (h *histogram) quantile(bounds []float64, q float64) float64 {
+	total := h.count()
+	if total == 0 {
+		return 0
+	}
+	rank := q * float64(total)
+	var cumulative int64
+	for i, c := range h.Counts {
+		if c == 0 || float64(cumulative+c) < rank {
+			cumulative += c
+			continue
+		}
+		lower, upper := 0.0, h.Max
+		if i > 0 {
+			lower = bounds[i-1]
+		}
+		if i < len(bounds) && bounds[i] < h.Max {
+			upper = bounds[i]
+		}
+		return lower + (upper-lower)*(rank-float64(cumulative))/float64(c)
+	}
+	return h.Max
+}
+
+func (h *histogram) mean() float64 {
+	if n := h.count(); n > 0 {
+		return h.Sum / float64(n)
+	}
+	return 0
+}
+
+// merge : Adds the observations of other, of the same buckets
+func (h *histogram) merge(other *histogram) {
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	h.Sum += other.Sum
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+}
+
+// checkPercentiles : The percentiles, DefaultPercentiles when not set
+func checkPercentiles(percentiles []float64) ([]float64, error) {
+	if len(percentiles) == 0 {
+		return DefaultPercentiles, nil
+	}
+	for _, p := range percentiles {
+		if p <= 0 || p > 100 {
+			return nil, errors.Wrap(errors.New(ErrInvalidPercentile), percentileName(p))
+		}
+	}
+	return percentiles, nil
+}
+
+// checkBounds : Bucket bounds must be positive and strictly increasing
+func checkBounds(bounds []float64) error {
+	for i, b := range bounds {
+		if b <= 0 || (i > 0 && b <= bounds[i-1]) {
+			return errors.New(ErrInvalidBuckets)
+		}
+	}
+	return nil
+}
+
+// secondsBounds : The bucket bounds as seconds
+func secondsBounds(buckets []time.Duration) []float64 {
+	bounds := make([]float64, len(buckets))
+	for i, b := range buckets {
+		bounds[i] = b.Seconds()
+	}
+	return bounds
+}
+
+// seconds : A duration of float seconds
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// bytesBounds : The bucket bounds as float bytes
+func bytesBounds(buckets []int) []float64 {
+	bounds := make([]float64, len(buckets))
+	for i, b := range buckets {
+		bounds[i] = float64(b)
+	}
+	return bounds
+}
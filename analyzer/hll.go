@@ -0,0 +1,189 @@
+package analyzer
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+const (
+	// ErrInvalidUniqueIPWindow :
+	ErrInvalidUniqueIPWindow = "unique IP windows must be positive"
+
+	// hllPrecision : Bits of the hash picking a register, 2^12 registers for
+	// a standard error of 1.6%
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// UniqueIPWindow : The estimated number of distinct client IPs in the window
+// up to the latest line
+type UniqueIPWindow struct {
+	Window    time.Duration
+	UniqueIPs int
+}
+
+// hllEntry : A rank seen in a register, and when
+type hllEntry struct {
+	Time int64 `json:"t"`
+	Rank uint8 `json:"r"`
+}
+
+// slidingHLL : A HyperLogLog sketch of the keys seen in the last Window,
+// answering for any window up to it (sliding HyperLogLog). Rather than the
+// highest rank seen, each register keeps the ranks that are the highest of
+// some window: a rank is dropped once a later one is as high, or once it is
+// older than Window. Memory is a few entries per register, whatever the
+// number of keys.
+type slidingHLL struct {
+	// Window : The longest window, nanoseconds
+	Window int64 `json:"window"`
+	// Latest : Time of the latest key, Unix nanoseconds; windows end there
+	Latest    int64        `json:"latest"`
+	Registers [][]hllEntry `json:"registers"`
+}
+
+func newSlidingHLL(window time.Duration) *slidingHLL {
+	return &slidingHLL{Window: int64(window), Registers: make([][]hllEntry, hllRegisters)}
+}
+
+// keyHash : A 64-bit hash of the key, for sketches and filters: FNV-1a with
+// its bits mixed, so that the leading ones are as random as the trailing ones
+func keyHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return mix64(h.Sum64())
+}
+
+// mix64 : The splitmix64 finalizer, spreading every bit of x over the result
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// add : Counts the key as seen at now, Unix nanoseconds
+func (s *slidingHLL) add(key string, now int64) {
+	hash := keyHash(key)
+	register := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	s.insert(int(register), hllEntry{Time: now, Rank: rank})
+	if now > s.Latest {
+		s.Latest = now
+	}
+}
+
+// insert : Adds the entry to the register, in time order, dropping the
+// entries it outranks and the expired ones
+func (s *slidingHLL) insert(register int, entry hllEntry) {
+	entries := s.Registers[register]
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Time > entry.Time })
+	if i < len(entries) && entries[i].Rank >= entry.Rank || i > 0 && entries[i-1].Time == entry.Time && entries[i-1].Rank >= entry.Rank {
+		// a rank as high is as recent, the entry never is the highest
+		return
+	}
+	entries = append(entries, hllEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+
+	// entries are kept with ranks decreasing over time
+	kept := entries[:0]
+	expired := s.expiry(entry.Time)
+	for j, e := range entries {
+		if e.Time <= expired || j < i && e.Rank <= entry.Rank {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	for j := len(kept); j < len(entries); j++ {
+		entries[j] = hllEntry{}
+	}
+	s.Registers[register] = kept
+}
+
+// expiry : Time at or before which entries are older than the window
+func (s *slidingHLL) expiry(now int64) int64 {
+	if now < s.Latest {
+		now = s.Latest
+	}
+	return now - s.Window
+}
+
+// estimate : The number of distinct keys seen in the window up to the latest
+// key
+func (s *slidingHLL) estimate(window time.Duration) int {
+	since := s.Latest - int64(window)
+	sum, zeros := 0.0, 0
+	for _, entries := range s.Registers {
+		// ranks decrease over time, the first one in the window is the highest
+		i := sort.Search(len(entries), func(i int) bool { return entries[i].Time > since })
+		if i == len(entries) {
+			zeros++
+			sum++
+			continue
+		}
+		sum += math.Ldexp(1, -int(entries[i].Rank))
+	}
+	m := float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting, more accurate for few keys
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int(math.Round(estimate))
+}
+
+// mergeSlidingHLL : Adds the keys of from to into, as if seen by a single
+// sketch of the longer window
+func mergeSlidingHLL(into, from *slidingHLL) *slidingHLL {
+	if from == nil {
+		return into
+	}
+	if into == nil {
+		into = newSlidingHLL(time.Duration(from.Window))
+	}
+	if from.Window > into.Window {
+		into.Window = from.Window
+	}
+	if from.Latest > into.Latest {
+		into.Latest = from.Latest
+	}
+	for register, entries := range from.Registers {
+		for _, entry := range entries {
+			into.insert(register, entry)
+		}
+	}
+	return into
+}
+
+// uniqueIPWindowsReport : The estimated distinct IPs of every window
+func uniqueIPWindowsReport(sketch *slidingHLL, windows []time.Duration) []*UniqueIPWindow {
+	if sketch == nil {
+		return nil
+	}
+	report := make([]*UniqueIPWindow, 0, len(windows))
+	for _, window := range windows {
+		report = append(report, &UniqueIPWindow{Window: window, UniqueIPs: sketch.estimate(window)})
+	}
+	return report
+}
+
+// longestWindow : The longest of the windows, 0 when there are none, or one
+// is not positive
+func longestWindow(windows []time.Duration) time.Duration {
+	var longest time.Duration
+	for _, window := range windows {
+		if window <= 0 {
+			return 0
+		}
+		if window > longest {
+			longest = window
+		}
+	}
+	return longest
+}
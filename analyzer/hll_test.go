@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// within : Whether the estimate is within 5% of the count
+func within(estimate, count int) bool {
+	return math.Abs(float64(estimate-count)) <= 0.05*float64(count)
+}
+
+func Test_slidingHLL(t *testing.T) {
+	start := time.Date(2018, 7, 10, 22, 0, 0, 0, time.UTC).UnixNano()
+	s := newSlidingHLL(time.Hour)
+	// 1000 new IPs a minute for an hour and a half, and 100 IPs seen every minute
+	for minute := int64(0); minute < 90; minute++ {
+		now := start + minute*int64(time.Minute)
+		for i := 0; i < 1000; i++ {
+			s.add(fmt.Sprintf("10.%d.%d.%d", minute, i/256, i%256), now)
+		}
+		for i := 0; i < 100; i++ {
+			s.add(fmt.Sprintf("192.168.0.%d", i), now)
+		}
+	}
+	for _, tt := range []struct {
+		window time.Duration
+		want   int
+	}{
+		{time.Nanosecond, 1100},
+		{5 * time.Minute, 5100},
+		{time.Hour, 60100},
+	} {
+		if got := s.estimate(tt.window); !within(got, tt.want) {
+			t.Errorf("slidingHLL.estimate(%v) = %d, want about %d", tt.window, got, tt.want)
+		}
+	}
+	if got := newSlidingHLL(time.Hour).estimate(time.Hour); got != 0 {
+		t.Errorf("slidingHLL.estimate() = %d, want 0 for no IPs", got)
+	}
+
+	// registers only keep the ranks highest in some window
+	for register, entries := range s.Registers {
+		for i := 1; i < len(entries); i++ {
+			if entries[i].Time <= entries[i-1].Time || entries[i].Rank >= entries[i-1].Rank {
+				t.Fatalf("register %d = %v, want ranks decreasing over time", register, entries)
+			}
+		}
+	}
+}
+
+func Test_mergeSlidingHLL(t *testing.T) {
+	start := time.Date(2018, 7, 10, 22, 0, 0, 0, time.UTC).UnixNano()
+	all, even, odd := newSlidingHLL(time.Hour), newSlidingHLL(time.Hour), newSlidingHLL(time.Hour)
+	for i := 0; i < 5000; i++ {
+		ip, now := fmt.Sprintf("10.0.%d.%d", i/256, i%256), start+int64(i)*int64(time.Second)
+		all.add(ip, now)
+		if i%2 == 0 {
+			even.add(ip, now)
+		} else {
+			odd.add(ip, now)
+		}
+	}
+	merged := mergeSlidingHLL(mergeSlidingHLL(nil, even), odd)
+	for _, window := range []time.Duration{time.Minute, time.Hour} {
+		if got, want := merged.estimate(window), all.estimate(window); got != want {
+			t.Errorf("mergeSlidingHLL() estimate(%v) = %d, want %d", window, got, want)
+		}
+	}
+
+	data, err := json.Marshal(&aggregate{uniqueIPs: all})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded aggregate
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.uniqueIPs.estimate(time.Hour), all.estimate(time.Hour); got != want {
+		t.Errorf("aggregate unique ips = %d, want %d", got, want)
+	}
+}
+
+func Test_logAnalyzer_Analyze_uniqueIPWindows(t *testing.T) {
+	windows := []time.Duration{time.Second, 7 * 24 * time.Hour}
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, UniqueIPWindows: windows})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	analytics, err := l.Analyze("./test-data/programming-task.log")
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	// the log spans less than a week, and its latest second has a single IP
+	want := []*UniqueIPWindow{{Window: time.Second, UniqueIPs: 1}, {Window: 7 * 24 * time.Hour, UniqueIPs: analytics.UniqueIPCount}}
+	if !reflect.DeepEqual(analytics.UniqueIPWindows, want) {
+		for _, w := range analytics.UniqueIPWindows {
+			t.Errorf("Analyze() unique ip window = %+v", *w)
+		}
+	}
+
+	if _, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, UniqueIPWindows: []time.Duration{time.Hour, 0}}); err == nil || err.Error() != ErrInvalidUniqueIPWindow {
+		t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, ErrInvalidUniqueIPWindow)
+	}
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	// ErrInvalidIPv6Prefix :
+	ErrInvalidIPv6Prefix = "ipv6 aggregation prefix must be between 0 and 128"
+	// ErrInvalidNetworkPrefix :
+	ErrInvalidNetworkPrefix = "network prefix must be between 1 and 32 for ipv4, and 1 and 128 for ipv6"
+)
+
+const (
+	// DefaultIPv4NetworkPrefix : Prefix length IPv4 clients are grouped by into networks
+	DefaultIPv4NetworkPrefix = 24
+	// DefaultIPv6NetworkPrefix : Prefix length IPv6 clients are grouped by into networks
+	DefaultIPv6NetworkPrefix = 48
+)
+
+// normalizeIP : Canonicalizes the textual form of an IP address (lower case,
+// zero compression, IPv4-mapped IPv6 as IPv4), so one address is counted once.
+// When ipv6Prefix is set, IPv6 addresses are reduced to their network in CIDR
+// notation. Hosts that are not IP addresses are returned unchanged.
+func normalizeIP(host string, ipv6Prefix int) string {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip.To4() != nil || ipv6Prefix <= 0 || ipv6Prefix >= 128 {
+		return ip.String()
+	}
+
+	network := ip.Mask(net.CIDRMask(ipv6Prefix, 128))
+	return fmt.Sprintf("%s/%d", network, ipv6Prefix)
+}
+
+// networkOf : Returns the network, in CIDR notation, the host belongs to when
+// IPv4 addresses are masked to ipv4Prefix bits and IPv6 ones to ipv6Prefix
+// bits. ok is false when the host is not an IP address.
+func networkOf(host string, ipv4Prefix, ipv6Prefix int) (network string, ok bool) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", false
+	}
+
+	ipNet := &net.IPNet{IP: ip.Mask(net.CIDRMask(ipv6Prefix, 128)), Mask: net.CIDRMask(ipv6Prefix, 128)}
+	if v4 := ip.To4(); v4 != nil {
+		ipNet = &net.IPNet{IP: v4.Mask(net.CIDRMask(ipv4Prefix, 32)), Mask: net.CIDRMask(ipv4Prefix, 32)}
+	}
+	return ipNet.String(), true
+}
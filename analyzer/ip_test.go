@@ -0,0 +1,50 @@
+package analyzer
+
+import "testing"
+
+func Test_normalizeIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		ipv6Prefix int
+		want       string
+	}{
+		{name: "ipv4 unchanged", host: "177.71.128.21", want: "177.71.128.21"},
+		{name: "ipv4 not aggregated", host: "177.71.128.21", ipv6Prefix: 64, want: "177.71.128.21"},
+		{name: "not an ip", host: "crawl.googlebot.com", want: "crawl.googlebot.com"},
+		{name: "ipv6 lower cased", host: "2001:DB8::1", want: "2001:db8::1"},
+		{name: "ipv6 zero compressed", host: "2001:0db8:0000:0000:0000:0000:0000:0001", want: "2001:db8::1"},
+		{name: "ipv4 mapped ipv6", host: "::ffff:177.71.128.21", want: "177.71.128.21"},
+		{name: "ipv6 aggregated by /64", host: "2001:db8:0:1:a1b2:c3d4:e5f6:1", ipv6Prefix: 64, want: "2001:db8:0:1::/64"},
+		{name: "ipv6 full prefix", host: "2001:db8::1", ipv6Prefix: 128, want: "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeIP(tt.host, tt.ipv6Prefix); got != tt.want {
+				t.Errorf("normalizeIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_networkOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		want   string
+		wantOk bool
+	}{
+		{name: "ipv4 /24", host: "177.71.128.21", want: "177.71.128.0/24", wantOk: true},
+		{name: "ipv4 mapped ipv6 /24", host: "::ffff:177.71.128.21", want: "177.71.128.0/24", wantOk: true},
+		{name: "ipv6 /48", host: "2001:db8:aa:1:a1b2:c3d4:e5f6:1", want: "2001:db8:aa::/48", wantOk: true},
+		{name: "not an ip", host: "crawl.googlebot.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := networkOf(tt.host, DefaultIPv4NetworkPrefix, DefaultIPv6NetworkPrefix)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("networkOf() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
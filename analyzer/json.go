@@ -0,0 +1,271 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultJSONFields : The JSON keys line fields are read from, when
+// JSONFields is not set
+var DefaultJSONFields = map[string]string{
+	"remote_host": "ip",
+	"time":        "time",
+	"method":      "method",
+	"url":         "path",
+	"query":       "query",
+	"protocol":    "protocol",
+	"status":      "status",
+	"bytes":       "bytes",
+	"referer":     "referer",
+	"user_agent":  "ua",
+	"duration":    "duration",
+	"upstream":    "upstream",
+}
+
+// CaddyJSONFields : The keys of Caddy v2 access logs, for JSONFields. Caddy
+// logs the URI with its query, times as Unix seconds unless its time_format
+// is set, and TLS versions and cipher suites as numbers, read as their
+// names; the host is kept in the line's extras.
+var CaddyJSONFields = map[string]string{
+	"remote_host":      "request.remote_ip",
+	"time":             "ts",
+	"method":           "request.method",
+	"url":              "request.uri",
+	"protocol":         "request.proto",
+	"status":           "status",
+	"bytes":            "size",
+	"referer":          "request.headers.Referer",
+	"user_agent":       "request.headers.User-Agent",
+	"duration":         "duration",
+	"content_type":     "resp_headers.Content-Type",
+	"content_encoding": "resp_headers.Content-Encoding",
+	"ssl_protocol":     "request.tls.version",
+	"ssl_cipher":       "request.tls.cipher_suite",
+	"remote_user":      "user_id",
+	"host":             "request.host",
+}
+
+// TraefikJSONFields : The keys of Traefik access logs in JSON mode, for
+// JSONFields. The router is the upstream, so that backend reports are per
+// route; the service, its URL, the entry point and the host are kept in the
+// line's extras. Referrers and user agents are read from the request headers
+// Traefik was configured to keep, which it drops by default.
+var TraefikJSONFields = map[string]string{
+	"remote_host":  "ClientHost",
+	"time":         "StartUTC",
+	"method":       "RequestMethod",
+	"url":          "RequestPath",
+	"protocol":     "RequestProtocol",
+	"status":       "DownstreamStatus",
+	"bytes":        "DownstreamContentSize",
+	"referer":      "request_Referer",
+	"user_agent":   "request_User-Agent",
+	"duration_ns":  "Duration",
+	"upstream":     "RouterName",
+	"ssl_protocol": "TLSVersion",
+	"ssl_cipher":   "TLSCipher",
+	"remote_user":  "ClientUsername",
+	"service":      "ServiceName",
+	"service_url":  "ServiceURL",
+	"entry_point":  "entryPointName",
+	"host":         "RequestHost",
+}
+
+// CloudflareJSONFields : The fields of Cloudflare Logpush HTTP request
+// records, for JSONFields. EdgeStartTimestamp is read as Unix nanoseconds,
+// the default of Logpush jobs, or as RFC 3339 or Unix seconds as per their
+// timestamp_format. The cache status is the cache_status extra, for cache
+// reports; the origin IP is the upstream, empty for requests answered by the
+// edge. The time to first byte is the duration; the host, the Ray ID, the
+// data center (colo), the client's country and the origin's response time
+// in milliseconds are kept in the line's extras. Logpush only sends the
+// fields its job selects, missing ones are left empty.
+var CloudflareJSONFields = map[string]string{
+	"remote_host":        "ClientIP",
+	"time":               "EdgeStartTimestamp",
+	"method":             "ClientRequestMethod",
+	"url":                "ClientRequestURI",
+	"protocol":           "ClientRequestProtocol",
+	"status":             "EdgeResponseStatus",
+	"bytes":              "EdgeResponseBytes",
+	"referer":            "ClientRequestReferer",
+	"user_agent":         "ClientRequestUserAgent",
+	"duration_ms":        "EdgeTimeToFirstByteMs",
+	"upstream":           "OriginIP",
+	"content_type":       "EdgeResponseContentType",
+	"cache_status":       "CacheCacheStatus",
+	"ssl_protocol":       "ClientSSLProtocol",
+	"ssl_cipher":         "ClientSSLCipher",
+	"host":               "ClientRequestHost",
+	"ray_id":             "RayID",
+	"colo":               "EdgeColoCode",
+	"country":            "ClientCountry",
+	"origin_response_ms": "OriginResponseDurationMs",
+}
+
+// FastlyJSONFields : The keys of Fastly real-time log streaming in the JSON
+// layout of Fastly's examples, for JSONFields:
+//
+//	{"timestamp":"%{strftime(\{"%Y-%m-%dT%H:%M:%S%z"\}, time.start)}V",
+//	"client_ip":"%{req.http.Fastly-Client-IP}V","host":"%{req.http.Host}V",
+//	"url":"%{json.escape(req.url)}V","request_method":"%{json.escape(req.method)}V",
+//	"request_protocol":"%{json.escape(req.proto)}V",
+//	"request_referer":"%{json.escape(req.http.referer)}V",
+//	"request_user_agent":"%{json.escape(req.http.User-Agent)}V",
+//	"response_state":"%{json.escape(fastly_info.state)}V",
+//	"response_status":%{resp.status}V,"response_body_size":%{resp.body_bytes_written}V,
+//	"elapsed_ms":%{time.elapsed.msec}V,"datacenter":"%{server.datacenter}V",
+//	"fastly_server":"%{json.escape(server.identity)}V","geo_country":"%{client.geo.country_name}V"}
+//
+// The cache state (fastly_info.state, e.g. HIT, MISS, PASS or HIT-STALE) is
+// the cache_status extra, for cache reports; the host, the datacenter, the
+// cache server and the client's country are kept in the line's extras.
+var FastlyJSONFields = map[string]string{
+	"remote_host":  "client_ip",
+	"time":         "timestamp",
+	"method":       "request_method",
+	"url":          "url",
+	"protocol":     "request_protocol",
+	"status":       "response_status",
+	"bytes":        "response_body_size",
+	"referer":      "request_referer",
+	"user_agent":   "request_user_agent",
+	"duration_ms":  "elapsed_ms",
+	"cache_status": "response_state",
+	"host":         "host",
+	"datacenter":   "datacenter",
+	"server":       "fastly_server",
+	"country":      "geo_country",
+}
+
+// lineParser : Parses the lines of one log, in order
+type lineParser func(text string) (*Line, error)
+
+// fieldFormat : A log format of structured lines, whose fields are read by
+// name rather than matched by a line regex
+type fieldFormat interface {
+	// newParser : A parser of the lines of one log. Formats whose lines
+	// depend on earlier ones, e.g. W3C logs declaring their fields in
+	// headers, keep that state per parser. Lines are allocated from the
+	// batch.
+	newParser(batch *lineBatch) lineParser
+	// fields : The line fields read, named as the groups of line regexes
+	fields() []string
+	// project : The format reading the fields of the projection only
+	project(fields projection) fieldFormat
+}
+
+// jsonFormat : Reads lines logged as JSON objects, one per line
+type jsonFormat struct {
+	// names : Line fields, named as the groups of line regexes
+	names []string
+	// keys : The JSON key of each field, split at dots to reach nested objects
+	keys [][]string
+	// mapped : The keys read into fields, which are not kept as extras
+	mapped map[string]bool
+	// extras : The unmapped keys kept as extras, nil for all of them
+	extras projection
+}
+
+// newJSONFormat : A JSON format reading line fields from the keys they map to,
+// e.g. "remote_host" to "client.ip"
+func newJSONFormat(fields map[string]string) *jsonFormat {
+	f := &jsonFormat{mapped: make(map[string]bool, len(fields))}
+	for name, key := range fields {
+		f.names = append(f.names, name)
+		f.mapped[key] = true
+	}
+	sort.Strings(f.names)
+	for _, name := range f.names {
+		f.keys = append(f.keys, strings.Split(fields[name], "."))
+	}
+	return f
+}
+
+func (f *jsonFormat) newParser(batch *lineBatch) lineParser {
+	return func(text string) (*Line, error) {
+		return f.parse(text, batch)
+	}
+}
+
+func (f *jsonFormat) fields() []string {
+	return f.names
+}
+
+func (f *jsonFormat) project(fields projection) fieldFormat {
+	projected := &jsonFormat{mapped: f.mapped, extras: fields}
+	for i, name := range f.names {
+		if fields.keeps(name) {
+			projected.names = append(projected.names, name)
+			projected.keys = append(projected.keys, f.keys[i])
+		}
+	}
+	return projected
+}
+
+// parse : Parses a line holding a single JSON object. Missing keys leave
+// their fields empty. The top level keys mapped to no field are kept as
+// extras by their key, e.g. a request ID or a cache status, unless a mapped
+// extra has the same name; nested objects are not.
+func (f *jsonFormat) parse(text string, batch *lineBatch) (*Line, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil || object == nil {
+		return nil, errors.New(ErrLineNotMatched)
+	}
+	values := make([]string, len(f.keys))
+	for i, key := range f.keys {
+		values[i] = jsonValue(object, key)
+	}
+	lineItem := batch.next()
+	parseNamedFields(lineItem, f.names, nil, values)
+	for key := range object {
+		if f.mapped[key] || !f.extras.keeps(key) {
+			continue
+		}
+		if _, ok := lineItem.Extras[key]; ok {
+			continue
+		}
+		value := object[key]
+		if _, ok := value.(map[string]interface{}); ok {
+			continue
+		}
+		if lineItem.Extras == nil {
+			lineItem.Extras = make(map[string]string)
+		}
+		lineItem.Extras[key] = jsonValue(object, []string{key})
+	}
+	return lineItem, nil
+}
+
+// jsonValue : The value at the key path as logged text, empty when missing or
+// not a scalar. Of an array, e.g. of header values, the first is read.
+func jsonValue(object map[string]interface{}, key []string) string {
+	var value interface{} = object
+	for _, k := range key {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = nested[k]
+	}
+	if values, ok := value.([]interface{}); ok && len(values) > 0 {
+		value = values[0]
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
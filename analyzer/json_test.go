@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_jsonFormat_parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		text    string
+		want    *Line
+		wantErr string
+	}{
+		{
+			name:   "default fields",
+			fields: DefaultJSONFields,
+			text: `{"ip":"177.71.128.21","time":"10/Jul/2018:22:21:28 +0200","method":"GET","path":"/intranet-analytics/",` +
+				`"protocol":"HTTP/1.1","status":200,"bytes":3574,"referer":"-","ua":"curl/7.58.0","duration":0.25}`,
+			want: &Line{
+				RemoteHost: "177.71.128.21",
+				Time:       time.Date(2018, 7, 10, 22, 21, 28, 0, time.FixedZone("", 2*60*60)),
+				Method:     "GET",
+				Path:       "/intranet-analytics/",
+				Protocol:   "HTTP/1.1",
+				URL:        "/intranet-analytics/",
+				Status:     200,
+				Bytes:      3574,
+				Referer:    "-",
+				UserAgent:  "curl/7.58.0",
+				Duration:   250 * time.Millisecond,
+			},
+		},
+		{
+			name: "nested keys, RFC 3339 time and string numbers",
+			fields: map[string]string{
+				"remote_host": "client.ip",
+				"time":        "timestamp",
+				"method":      "request.method",
+				"url":         "request.uri",
+				"status":      "response.status",
+				"bytes":       "response.bytes",
+				"request_id":  "id",
+			},
+			text: `{"client":{"ip":"10.0.0.1"},"timestamp":"2018-07-10T20:21:28Z","request":{"method":"POST","uri":"/login"},` +
+				`"response":{"status":"401","bytes":"-"},"id":"abc123","ignored":true}`,
+			want: &Line{
+				RemoteHost: "10.0.0.1",
+				Time:       time.Date(2018, 7, 10, 20, 21, 28, 0, time.UTC),
+				Method:     "POST",
+				Path:       "/login",
+				URL:        "/login",
+				Status:     401,
+				Extras:     map[string]string{"request_id": "abc123", "ignored": "true"},
+			},
+		},
+		{
+			name:   "query without its '?'",
+			fields: DefaultJSONFields,
+			text:   `{"ip":"10.0.0.1","time":"10/Jul/2018:22:21:28 +0200","method":"GET","path":"/search","query":"q=go","status":200}`,
+			want: &Line{
+				RemoteHost: "10.0.0.1",
+				Time:       time.Date(2018, 7, 10, 22, 21, 28, 0, time.FixedZone("", 2*60*60)),
+				Method:     "GET",
+				Path:       "/search",
+				RawQuery:   "q=go",
+				URL:        "/search?q=go",
+				Status:     200,
+			},
+		},
+		{
+			name:    "not an object",
+			fields:  DefaultJSONFields,
+			text:    `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 3574`,
+			wantErr: ErrLineNotMatched,
+		},
+		{
+			name:    "null",
+			fields:  DefaultJSONFields,
+			text:    `null`,
+			wantErr: ErrLineNotMatched,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newJSONFormat(tt.fields).parse(tt.text, nil)
+			if err != nil {
+				if err.Error() != tt.wantErr {
+					t.Errorf("jsonFormat.parse() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("jsonFormat.parse() error = nil, wantErr %v", tt.wantErr)
+			}
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("jsonFormat.parse() time = %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time, tt.want.Time = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonFormat.parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_Analyze_json(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		JSON:                 true,
+		MostActiveIPsCount:   1,
+		MostVisitedURLsCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/access.json")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	if got.UniqueIPCount != 2 || !reflect.DeepEqual(got.MostActiveIPs, []string{"168.41.191.40"}) ||
+		!reflect.DeepEqual(got.MostVisitedURLs, []string{"/docs/manage-websites/"}) {
+		t.Errorf("logAnalyzer.Analyze() = %d %v %v, want 2 [168.41.191.40] [/docs/manage-websites/]",
+			got.UniqueIPCount, got.MostActiveIPs, got.MostVisitedURLs)
+	}
+}
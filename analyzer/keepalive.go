@@ -0,0 +1,66 @@
+package analyzer
+
+// KeepaliveStats : How much clients reuse their connections, for formats
+// logging connections
+type KeepaliveStats struct {
+	// Requests : Requests whose connection is known
+	Requests int
+	// Connections : Connections these requests were made on
+	Connections int
+	// RequestsPerConnection : Average number of requests per connection
+	RequestsPerConnection float64
+	// ReuseRate : Ratio of requests made on an already used connection
+	ReuseRate float64
+	// NonReusingClients : Client IPs making the most requests, never on an
+	// already used connection
+	NonReusingClients []string `json:",omitempty"`
+}
+
+// consolidateKeepalive : Counts the line into the connection reuse metrics.
+// Without a logged request number, requests are numbered per connection ID.
+func (l *logAnalyzer) consolidateKeepalive(agg *aggregate, ip string, line *Line) {
+	n := line.ConnectionRequests
+	if n <= 0 {
+		if line.Connection == "" {
+			return
+		}
+		agg.connectionHits[line.Connection]++
+		n = agg.connectionHits[line.Connection]
+	}
+
+	agg.keepaliveRequests++
+	reused := n > 1
+	if reused {
+		agg.reusedRequests++
+	}
+	if l.reloadable().nonReusingClientsCount > 0 {
+		agg.keepaliveClientHits[ip]++
+		if reused {
+			agg.reusingClientHits[ip]++
+		}
+	}
+}
+
+func (l *logAnalyzer) keepaliveReport(agg *aggregate, top int) *KeepaliveStats {
+	if agg.keepaliveRequests == 0 {
+		return nil
+	}
+	stats := &KeepaliveStats{
+		Requests:    agg.keepaliveRequests,
+		Connections: agg.keepaliveRequests - agg.reusedRequests,
+		ReuseRate:   float64(agg.reusedRequests) / float64(agg.keepaliveRequests),
+	}
+	if stats.Connections > 0 {
+		stats.RequestsPerConnection = float64(stats.Requests) / float64(stats.Connections)
+	}
+	if top > 0 {
+		nonReusing := make(map[string]int)
+		for ip, hits := range agg.keepaliveClientHits {
+			if _, reused := agg.reusingClientHits[ip]; !reused {
+				nonReusing[ip] = hits
+			}
+		}
+		stats.NonReusingClients = topMost(nonReusing, top)
+	}
+	return stats
+}
@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_logAnalyzer_report_keepalive(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []*Line
+		want  *KeepaliveStats
+	}{
+		{
+			name:  "connections not logged",
+			lines: []*Line{{RemoteHost: "177.71.128.21"}},
+		},
+		{
+			name: "request numbers",
+			lines: []*Line{
+				{RemoteHost: "177.71.128.21", ConnectionRequests: 1},
+				{RemoteHost: "177.71.128.21", ConnectionRequests: 2},
+				{RemoteHost: "177.71.128.21", ConnectionRequests: 3},
+				{RemoteHost: "168.41.191.40", ConnectionRequests: 1},
+				{RemoteHost: "168.41.191.40", ConnectionRequests: 1},
+				{RemoteHost: "50.112.00.11", ConnectionRequests: 1},
+			},
+			want: &KeepaliveStats{
+				Requests:              6,
+				Connections:           4,
+				RequestsPerConnection: 1.5,
+				ReuseRate:             2.0 / 6,
+				NonReusingClients:     []string{"168.41.191.40"},
+			},
+		},
+		{
+			name: "connection ids",
+			lines: []*Line{
+				{RemoteHost: "177.71.128.21", Connection: "41"},
+				{RemoteHost: "177.71.128.21", Connection: "41"},
+				{RemoteHost: "168.41.191.40", Connection: "42"},
+				{RemoteHost: "168.41.191.40", Connection: "43"},
+			},
+			want: &KeepaliveStats{
+				Requests:              4,
+				Connections:           3,
+				RequestsPerConnection: 4.0 / 3,
+				ReuseRate:             0.25,
+				NonReusingClients:     []string{"168.41.191.40"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+				LineRegex:              defaultLineRegex,
+				NonReusingClientsCount: 1,
+			})
+			if err != nil {
+				t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+			}
+			l := a.(*logAnalyzer)
+			agg := newAggregate()
+			for _, line := range tt.lines {
+				l.consolidate(agg, line)
+			}
+			if got := l.report(agg).Keepalive; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logAnalyzer.report() keepalive = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
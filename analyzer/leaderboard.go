@@ -0,0 +1,96 @@
+package analyzer
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// RequestSample : An individual request, for drill-down
+type RequestSample struct {
+	Time       time.Time
+	RemoteHost string
+	URL        string
+	Status     int
+	Bytes      int
+	Duration   time.Duration `json:",omitempty"`
+}
+
+func newRequestSample(line *Line) *RequestSample {
+	return &RequestSample{
+		Time:       line.Time,
+		RemoteHost: line.RemoteHost,
+		URL:        line.URL,
+		Status:     line.Status,
+		Bytes:      line.Bytes,
+		Duration:   line.Duration,
+	}
+}
+
+// rankedSample : A request and the value it is ranked by
+type rankedSample struct {
+	Value  float64        `json:"value"`
+	Sample *RequestSample `json:"sample"`
+}
+
+// leaderboard : The requests of highest value seen so far, as a min-heap so
+// the lowest ranked one is replaced first
+type leaderboard []*rankedSample
+
+func (b leaderboard) Len() int            { return len(b) }
+func (b leaderboard) Less(i, j int) bool  { return b[i].Value < b[j].Value }
+func (b leaderboard) Swap(i, j int)       { b[i], b[j] = b[j], b[i] }
+func (b *leaderboard) Push(x interface{}) { *b = append(*b, x.(*rankedSample)) }
+func (b *leaderboard) Pop() interface{} {
+	old := *b
+	last := old[len(old)-1]
+	*b = old[:len(old)-1]
+	return last
+}
+
+// offer : Keeps the request when it ranks within the top n. On equal values,
+// the request already kept stays.
+func (b *leaderboard) offer(n int, value float64, sample func() *RequestSample) {
+	if n <= 0 {
+		return
+	}
+	if len(*b) < n {
+		heap.Push(b, &rankedSample{Value: value, Sample: sample()})
+		return
+	}
+	for len(*b) > n {
+		heap.Pop(b)
+	}
+	if value > (*b)[0].Value {
+		(*b)[0] = &rankedSample{Value: value, Sample: sample()}
+		heap.Fix(b, 0)
+	}
+}
+
+// merge : Offers the requests of other, keeping as many requests as the
+// larger of the two boards
+func (b *leaderboard) merge(other leaderboard) {
+	n := len(*b)
+	if len(other) > n {
+		n = len(other)
+	}
+	for _, r := range other {
+		sample := r.Sample
+		b.offer(n, r.Value, func() *RequestSample { return sample })
+	}
+}
+
+// top : The n highest ranked requests, highest first
+func (b leaderboard) top(n int) []*RequestSample {
+	ranked := make([]*rankedSample, len(b))
+	copy(ranked, b)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Value > ranked[j].Value })
+	if n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	samples := make([]*RequestSample, len(ranked))
+	for i, r := range ranked {
+		samples[i] = r.Sample
+	}
+	return samples
+}
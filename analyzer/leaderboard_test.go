@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_leaderboard(t *testing.T) {
+	type offer struct {
+		value float64
+		url   string
+	}
+	tests := []struct {
+		name   string
+		n      int
+		offers []offer
+		want   []string
+	}{
+		{name: "fewer than n", n: 3, offers: []offer{{2, "/b"}, {1, "/a"}}, want: []string{"/b", "/a"}},
+		{name: "highest kept", n: 2, offers: []offer{{1, "/a"}, {5, "/e"}, {3, "/c"}, {4, "/d"}, {2, "/b"}}, want: []string{"/e", "/d"}},
+		{name: "ties keep the first", n: 1, offers: []offer{{3, "/first"}, {3, "/second"}}, want: []string{"/first"}},
+		{name: "none", n: 0, offers: []offer{{1, "/a"}}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b leaderboard
+			for _, o := range tt.offers {
+				url := o.url
+				b.offer(tt.n, o.value, func() *RequestSample { return &RequestSample{URL: url} })
+			}
+			got := []string{}
+			for _, sample := range b.top(tt.n) {
+				got = append(got, sample.URL)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("leaderboard.top() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_Analyze_slowestRequests(t *testing.T) {
+	lineRegex := regexp.MustCompile(strings.TrimSuffix(defaultLineRegex.String(), "$") +
+		`\s(?P<upstream>\S+(?:, \S+)*)\s(?P<duration>\S+)$`)
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:            lineRegex,
+		SlowestRequestsCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/upstreams.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	want := []RequestSample{
+		{RemoteHost: "168.41.191.40", URL: "/api/orders", Status: 502, Duration: 1800 * time.Millisecond},
+		{RemoteHost: "168.41.191.40", URL: "/api/orders", Status: 200, Bytes: 120, Duration: 40 * time.Millisecond},
+	}
+	wantTimes := []string{"2018-07-10T22:21:31+02:00", "2018-07-10T22:21:32+02:00"}
+	if len(got.SlowestRequests) != len(want) {
+		t.Fatalf("logAnalyzer.Analyze() slowest requests = %d, want %d", len(got.SlowestRequests), len(want))
+	}
+	for i, sample := range got.SlowestRequests {
+		if at := sample.Time.Format(time.RFC3339); at != wantTimes[i] {
+			t.Errorf("logAnalyzer.Analyze() slowest request %d time = %s, want %s", i, at, wantTimes[i])
+		}
+		s := *sample
+		s.Time = want[i].Time
+		if !reflect.DeepEqual(s, want[i]) {
+			t.Errorf("logAnalyzer.Analyze() slowest request %d = %+v, want %+v", i, s, want[i])
+		}
+	}
+}
+
+func Test_logAnalyzer_Analyze_largestResponses(t *testing.T) {
+	lineRegex := regexp.MustCompile(strings.TrimSuffix(defaultLineRegex.String(), "$") +
+		`\s(?P<upstream>\S+(?:, \S+)*)\s(?P<duration>\S+)$`)
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:             lineRegex,
+		LargestResponsesCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/upstreams.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	var urls []string
+	var bytes []int
+	for _, sample := range got.LargestResponses {
+		urls = append(urls, sample.RemoteHost+" "+sample.URL)
+		bytes = append(bytes, sample.Bytes)
+	}
+	wantURLs := []string{"50.112.00.11 /static/app.js", "177.71.128.21 /api/users"}
+	if !reflect.DeepEqual(urls, wantURLs) || !reflect.DeepEqual(bytes, []int{9000, 3574}) {
+		t.Errorf("logAnalyzer.Analyze() largest responses = %v %v, want %v %v", urls, bytes, wantURLs, []int{9000, 3574})
+	}
+	if got.SlowestRequests != nil {
+		t.Errorf("logAnalyzer.Analyze() slowest requests = %v, want none", got.SlowestRequests)
+	}
+}
@@ -0,0 +1,46 @@
+package analyzer
+
+// DefaultLineBatchSize : Lines allocated at once when reading a log
+const DefaultLineBatchSize = 256
+
+// lineBatch : Allocates the lines parsed from a log a block at a time,
+// instead of one by one. A block lives as long as any of its lines, so lines
+// are freed along with the rest of their block once consolidated; consumers
+// keeping a few lines for long keep copies instead. A nil batch allocates
+// lines one by one.
+type lineBatch struct {
+	size int
+	// lines : The lines of the current block not handed out yet
+	lines []Line
+}
+
+// newLineBatch : A batch of size lines per block, nil for blocks of a line
+func newLineBatch(size int) *lineBatch {
+	if size <= 1 {
+		return nil
+	}
+	return &lineBatch{size: size}
+}
+
+// next : An empty line
+func (b *lineBatch) next() *Line {
+	if b == nil {
+		return &Line{}
+	}
+	if len(b.lines) == 0 {
+		b.lines = make([]Line, b.size)
+	}
+	line := &b.lines[0]
+	b.lines = b.lines[1:]
+	return line
+}
+
+// Copy : A copy of the line in an allocation of its own. Lines read by the
+// analyzer share their allocation with the other lines of their block
+// (LineBatchSize), so a line kept after it was handed over, e.g. by an
+// Enricher caching lines, keeps its whole block in memory; a copy does not.
+// Extras and enrichments are shared with the line.
+func (line *Line) Copy() *Line {
+	copied := *line
+	return &copied
+}
@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func Test_lineBatch(t *testing.T) {
+	batch := newLineBatch(3)
+	lines := make([]*Line, 4)
+	for i := range lines {
+		lines[i] = batch.next()
+		lines[i].Status = 200 + i
+	}
+	for i, line := range lines {
+		if line.Status != 200+i {
+			t.Errorf("line %d status = %d, want %d", i, line.Status, 200+i)
+		}
+	}
+	if &lines[0].Status == &lines[1].Status {
+		t.Errorf("next() handed out the same line twice")
+	}
+	if newLineBatch(1) != nil || (*lineBatch)(nil).next() == nil {
+		t.Errorf("a batch of single lines is not nil, or does not allocate")
+	}
+
+	copied := lines[0].Copy()
+	copied.Status = 500
+	if lines[0].Status != 200 || copied.URL != lines[0].URL {
+		t.Errorf("Copy() = %+v, of %+v", copied, lines[0])
+	}
+}
+
+func Test_lineBatch_allocs(t *testing.T) {
+	text := `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574 "-" "Mozilla/5.0"`
+	allocs := func(batch *lineBatch) float64 {
+		parse := newSplitParser(defaultLineRegex, nil, batch)
+		return testing.AllocsPerRun(1000, func() {
+			parse(text)
+		})
+	}
+	if single, batched := allocs(nil), allocs(newLineBatch(DefaultLineBatchSize)); batched > single-0.9 {
+		t.Errorf("allocations per line = %v batched, %v one by one", batched, single)
+	}
+}
+
+func Test_logAnalyzer_Analyze_lineBatchSize(t *testing.T) {
+	var analytics []*LogAnalytics
+	for _, size := range []int{1, 2, DefaultLineBatchSize} {
+		l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, MostActiveIPsCount: 100, MostVisitedURLsCount: 100, LineBatchSize: size})
+		if err != nil {
+			t.Fatalf("NewLogAnalyzer() error = %v", err)
+		}
+		got, err := l.Analyze("./test-data/programming-task.log")
+		if err != nil {
+			t.Fatalf("Analyze() error = %v", err)
+		}
+		// ties are listed in any order
+		sort.Strings(got.MostActiveIPs)
+		sort.Strings(got.MostVisitedURLs)
+		analytics = append(analytics, got)
+	}
+	for _, got := range analytics[1:] {
+		if !reflect.DeepEqual(got, analytics[0]) {
+			t.Errorf("Analyze() = %+v in batches, want %+v", got, analytics[0])
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLogfmtFields : The logfmt keys line fields are read from, when
+// LogfmtFields is not set. They are the keys of Heroku router logs, with the
+// dyno as the upstream and the service time as the duration.
+var DefaultLogfmtFields = map[string]string{
+	"remote_host": "fwd",
+	"time":        "time",
+	"method":      "method",
+	"url":         "path",
+	"protocol":    "protocol",
+	"status":      "status",
+	"bytes":       "bytes",
+	"referer":     "referer",
+	"user_agent":  "user_agent",
+	"duration":    "service",
+	"upstream":    "dyno",
+}
+
+// logfmtFormat : Reads lines logged as logfmt, e.g.
+// at=info method=GET path="/" fwd="1.2.3.4" status=200 bytes=13
+type logfmtFormat struct {
+	names []string
+	keys  []string
+}
+
+// newLogfmtFormat : A logfmt format reading line fields from the keys they map
+// to, e.g. "remote_host" to "fwd"
+func newLogfmtFormat(fields map[string]string) *logfmtFormat {
+	f := &logfmtFormat{}
+	for name := range fields {
+		f.names = append(f.names, name)
+	}
+	sort.Strings(f.names)
+	for _, name := range f.names {
+		f.keys = append(f.keys, fields[name])
+	}
+	return f
+}
+
+func (f *logfmtFormat) newParser(batch *lineBatch) lineParser {
+	return func(text string) (*Line, error) {
+		return f.parse(text, batch)
+	}
+}
+
+func (f *logfmtFormat) fields() []string {
+	return f.names
+}
+
+func (f *logfmtFormat) project(fields projection) fieldFormat {
+	projected := &logfmtFormat{}
+	for i, name := range f.names {
+		if fields.keeps(name) {
+			projected.names = append(projected.names, name)
+			projected.keys = append(projected.keys, f.keys[i])
+		}
+	}
+	return projected
+}
+
+// parse : Parses a line of key=value pairs. Lines without a single mapped key
+// do not match; missing keys leave their fields empty.
+func (f *logfmtFormat) parse(text string, batch *lineBatch) (*Line, error) {
+	return f.parsePairs(parseLogfmt(text), batch)
+}
+
+// parsePairs : Reads the line fields from the keys they map to, whatever the
+// syntax the pairs were read from
+func (f *logfmtFormat) parsePairs(pairs map[string]string, batch *lineBatch) (*Line, error) {
+	values := make([]string, len(f.keys))
+	matched := false
+	for i, key := range f.keys {
+		if value, ok := pairs[key]; ok {
+			values[i] = value
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, errors.New(ErrLineNotMatched)
+	}
+	lineItem := batch.next()
+	parseNamedFields(lineItem, f.names, nil, values)
+	return lineItem, nil
+}
+
+// parseLogfmt : The key=value pairs of the text. Values may be double quoted,
+// with backslash escapes; keys without a value, and words without a key, e.g.
+// a syslog prefix, are skipped.
+func parseLogfmt(text string) map[string]string {
+	pairs := make(map[string]string)
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\t' {
+			i++
+			continue
+		}
+		// the key, up to = or the end of the word
+		start := i
+		for i < len(text) && text[i] != '=' && text[i] != ' ' && text[i] != '\t' {
+			i++
+		}
+		if i == start {
+			// a word starting with =, skipped whole
+			for i < len(text) && text[i] != ' ' && text[i] != '\t' {
+				i++
+			}
+			continue
+		}
+		if i == len(text) || text[i] != '=' {
+			continue
+		}
+		key := text[start:i]
+		i++
+
+		if i < len(text) && text[i] == '"' {
+			var value strings.Builder
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				value.WriteByte(text[i])
+			}
+			i++
+			pairs[key] = value.String()
+			continue
+		}
+		start = i
+		for i < len(text) && text[i] != ' ' && text[i] != '\t' {
+			i++
+		}
+		pairs[key] = text[start:i]
+	}
+	return pairs
+}
@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_parseLogfmt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]string
+	}{
+		{
+			name: "heroku router",
+			text: `2018-07-10T20:21:28.677020+00:00 heroku[router]: at=info method=GET path="/docs?q=a b" fwd="1.2.3.4" dyno=web.1 service=18ms status=200`,
+			want: map[string]string{"at": "info", "method": "GET", "path": "/docs?q=a b", "fwd": "1.2.3.4", "dyno": "web.1", "service": "18ms", "status": "200"},
+		},
+		{
+			name: "escaped quotes and empty values",
+			text: `msg="say \"hi\"" empty= flag =bare`,
+			want: map[string]string{"msg": `say "hi"`, "empty": ""},
+		},
+		{
+			name: "unterminated quote",
+			text: `ua="curl`,
+			want: map[string]string{"ua": "curl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLogfmt(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLogfmt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logfmtFormat_parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    *Line
+		wantErr string
+	}{
+		{
+			name: "heroku router",
+			text: `at=info method=GET path="/docs/" host=example.herokuapp.com fwd="1.2.3.4" dyno=web.1 connect=1ms service=18ms status=200 bytes=13 protocol=https`,
+			want: &Line{
+				RemoteHost: "1.2.3.4",
+				Method:     "GET",
+				Path:       "/docs/",
+				Protocol:   "https",
+				URL:        "/docs/",
+				Status:     200,
+				Bytes:      13,
+				Duration:   18 * time.Millisecond,
+				Upstream:   "web.1",
+			},
+		},
+		{
+			name:    "no mapped key",
+			text:    `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 3574`,
+			wantErr: ErrLineNotMatched,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newLogfmtFormat(DefaultLogfmtFields).parse(tt.text, nil)
+			if err != nil {
+				if err.Error() != tt.wantErr {
+					t.Errorf("logfmtFormat.parse() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logfmtFormat.parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
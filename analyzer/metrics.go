@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// aggregateKeyOverhead : Estimated bytes a map entry takes besides its key,
+// (string header, count, and map bucket share)
+const aggregateKeyOverhead = 48
+
+// SelfMetrics : Internal metrics of an analyzer, to monitor the analyzer itself
+type SelfMetrics struct {
+	// StartedAt : When the analyzer was created
+	StartedAt time.Time
+	// LinesRead : Non-empty lines read, whether they parsed or not
+	LinesRead int64
+	// ParseErrors : Lines that did not match the log format
+	ParseErrors int64
+	// TimeParseErrors : Lines of a format logging times whose time did not
+	// parse, counted with a zero time
+	TimeParseErrors int64
+	// LinesPerSecond : Average read throughput since the analyzer started
+	LinesPerSecond float64
+	// ParseErrorRate : Ratio of lines read that did not parse
+	ParseErrorRate float64
+	// QueueDepth : Lines parsed but not counted into analytics yet
+	QueueDepth int64
+	// DroppedLines : Lines dropped by the queue policy because the
+	// aggregation stage could not keep up
+	DroppedLines int64
+	// EnrichmentErrors : Enrichment lookups that failed or timed out
+	EnrichmentErrors int64
+	// ScriptErrors : Script hook calls that failed or returned the wrong type
+	ScriptErrors int64
+	// OverflowedURLs : URL hits counted in the overflow bucket because the
+	// distinct URL cap was reached
+	OverflowedURLs int64
+	// AggregateKeys : Distinct keys (IPs, URLs, networks...) held by the
+	// aggregate Follow counts into, as the metrics are taken
+	AggregateKeys int64
+	// AggregateBytes : Estimated memory held by those keys
+	AggregateBytes int64
+}
+
+// selfMetrics : The counters behind SelfMetrics, updated atomically
+type selfMetrics struct {
+	startedAt         time.Time
+	linesRead         int64
+	parseErrors       int64
+	timeParseErrors   int64
+	linesConsolidated int64
+	droppedLines      int64
+	enrichmentErrors  int64
+	scriptErrors      int64
+	filteredLines     int64
+	overflowedURLs    int64
+}
+
+func (l *logAnalyzer) SelfMetrics() *SelfMetrics {
+	m := &SelfMetrics{
+		StartedAt:        l.metrics.startedAt,
+		LinesRead:        atomic.LoadInt64(&l.metrics.linesRead),
+		ParseErrors:      atomic.LoadInt64(&l.metrics.parseErrors),
+		TimeParseErrors:  atomic.LoadInt64(&l.metrics.timeParseErrors),
+		DroppedLines:     atomic.LoadInt64(&l.metrics.droppedLines),
+		EnrichmentErrors: atomic.LoadInt64(&l.metrics.enrichmentErrors),
+		ScriptErrors:     atomic.LoadInt64(&l.metrics.scriptErrors),
+		OverflowedURLs:   atomic.LoadInt64(&l.metrics.overflowedURLs),
+	}
+	l.stateMu.Lock()
+	if l.state != nil {
+		m.AggregateKeys = int64(l.state.keys())
+		m.AggregateBytes = int64(l.state.keyBytes() + l.state.keys()*aggregateKeyOverhead)
+	}
+	l.stateMu.Unlock()
+	m.QueueDepth = m.LinesRead - m.ParseErrors - m.DroppedLines - atomic.LoadInt64(&l.metrics.filteredLines) - atomic.LoadInt64(&l.metrics.linesConsolidated)
+	if uptime := time.Since(m.StartedAt).Seconds(); uptime > 0 {
+		m.LinesPerSecond = float64(m.LinesRead) / uptime
+	}
+	if m.LinesRead > 0 {
+		m.ParseErrorRate = float64(m.ParseErrors) / float64(m.LinesRead)
+	}
+	return m
+}
+
+// parse : Parses a line read from a log with the parser of the log, keeping
+// track of parse errors and warning of them, and of times that do not parse.
+// Header lines are neither counted as read nor as errors.
+func (l *logAnalyzer) parse(parseLine lineParser, text string) (*Line, error) {
+	if text == "" {
+		return nil, errNotMatched
+	}
+	line, err := parseLine(text)
+	if err == errHeaderLine {
+		return nil, err
+	}
+	atomic.AddInt64(&l.metrics.linesRead, 1)
+	if err != nil {
+		atomic.AddInt64(&l.metrics.parseErrors, 1)
+		l.warn(&ParseFailure{Line: text, Reason: err.Error()})
+	} else if l.logsTime && line.Time.IsZero() {
+		atomic.AddInt64(&l.metrics.timeParseErrors, 1)
+		l.warn(&TimeParseFailure{Line: text})
+	}
+	return line, err
+}
+
+// newLineParser : A parser of the lines of one log, in the analyzer's log
+// format
+func (l *logAnalyzer) newLineParser() lineParser {
+	var parse lineParser
+	batch := newLineBatch(l.lineBatchSize)
+	if l.lineFields != nil {
+		parse = l.lineFields.newParser(batch)
+	} else if split := newSplitParser(l.lineRegex, l.projection, batch); split != nil && l.tokenizer == TokenizerScan && l.schema == nil && l.timeLayouts == nil {
+		parse = split
+	} else {
+		names := l.projection.names(groupNames(l.lineRegex))
+		parse = func(text string) (*Line, error) {
+			result := l.lineRegex.FindStringSubmatch(text)
+			if l.schema != nil && result != nil {
+				if err := l.schema.check(result, text); err != nil {
+					return nil, err
+				}
+			}
+			return parseMatch(l.lineRegex, names, l.projection, l.timeLayouts, result, batch)
+		}
+	}
+	if l.syslog {
+		parse = l.syslogParser(parse)
+	}
+	return parse
+}
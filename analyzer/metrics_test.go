@@ -0,0 +1,42 @@
+package analyzer
+
+import "testing"
+
+func Test_logAnalyzer_SelfMetrics(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex})
+	if err != nil {
+		t.Fatalf("logAnalyzer.SelfMetrics() error = %v, error creating analyzer", err)
+	}
+	if _, err := l.Analyze("./test-data/golden/combined/malformed.log"); err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	got := l.SelfMetrics()
+	// the blank line is not counted
+	if got.LinesRead != 3 || got.ParseErrors != 2 {
+		t.Errorf("logAnalyzer.SelfMetrics() lines read, parse errors = %d, %d, want 3, 2", got.LinesRead, got.ParseErrors)
+	}
+	if got.ParseErrorRate < 0.66 || got.ParseErrorRate > 0.67 {
+		t.Errorf("logAnalyzer.SelfMetrics() parse error rate = %v, want 2/3", got.ParseErrorRate)
+	}
+	if got.QueueDepth != 0 {
+		t.Errorf("logAnalyzer.SelfMetrics() queue depth = %d, want 0", got.QueueDepth)
+	}
+	// the aggregates of batch analyses are gone once they report
+	if got.AggregateKeys != 0 || got.AggregateBytes != 0 {
+		t.Errorf("logAnalyzer.SelfMetrics() aggregate keys, bytes = %d, %d, want 0, 0", got.AggregateKeys, got.AggregateBytes)
+	}
+
+	// one ip, one url in the followed aggregate
+	la := l.(*logAnalyzer)
+	agg := la.followedAggregate()
+	line, err := la.parse(la.newLineParser(), `50.112.00.11 - admin [11/Jul/2018:17:31:56 +0200] "GET /asset.css HTTP/1.1" 200 3574 "-" "curl/7.58.0"`)
+	if err != nil {
+		t.Fatalf("logAnalyzer.parse() error = %v", err)
+	}
+	la.consolidate(agg, line)
+	got = l.SelfMetrics()
+	if got.AggregateKeys != 2 || got.AggregateBytes != int64(len("50.112.00.11")+len("/asset.css")+2*aggregateKeyOverhead) {
+		t.Errorf("logAnalyzer.SelfMetrics() aggregate keys, bytes = %d, %d", got.AggregateKeys, got.AggregateBytes)
+	}
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import "sort"
+
+// DefaultTopMoversCount : Risers and fallers reported when TopMoversCount is
+// not set
+const DefaultTopMoversCount = 10
+
+// Mover : A URL or client IP whose traffic changed between two periods
+type Mover struct {
+	Key      string
+	Previous int
+	Current  int
+	// Change : Relative change of the hits, with a hit added to both periods
+	// so keys new to or gone from the current period rank by their traffic,
+	// e.g. 1.5 for +150%
+	Change float64
+}
+
+// Movers : The largest relative traffic increases and decreases
+type Movers struct {
+	// Risers : The largest increases first
+	Risers []*Mover
+	// Fallers : The largest decreases first
+	Fallers []*Mover
+}
+
+// PeriodComparison : The analytics of two periods, and the URLs and client
+// IPs whose traffic moved the most between them
+type PeriodComparison struct {
+	Previous *LogAnalytics
+	Current  *LogAnalytics
+	URLs     *Movers
+	IPs      *Movers
+}
+
+func (l *logAnalyzer) ComparePeriods(previous, current []string) (*PeriodComparison, error) {
+	before, err := l.mergedAggregate(previous)
+	if err != nil {
+		return nil, err
+	}
+	after, err := l.mergedAggregate(current)
+	if err != nil {
+		return nil, err
+	}
+
+	top := l.reloadable().topMoversCount
+	if top <= 0 {
+		top = DefaultTopMoversCount
+	}
+	return &PeriodComparison{
+		Previous: l.report(before),
+		Current:  l.report(after),
+		URLs:     topMovers(before.urlHits.counts(), after.urlHits.counts(), top),
+		IPs:      topMovers(before.ipHits, after.ipHits, top),
+	}, nil
+}
+
+// mergedAggregate : The counts of the files together
+func (l *logAnalyzer) mergedAggregate(filePaths []string) (*aggregate, error) {
+	aggs, err := l.aggregateFiles(filePaths)
+	if err != nil {
+		return nil, err
+	}
+	merged := newAggregate()
+	for _, filePath := range filePaths {
+		merged.merge(aggs[filePath])
+	}
+	return merged, nil
+}
+
+// topMovers : The top keys of the largest relative increases and decreases
+// of hits from before to after
+func topMovers(before, after map[string]int, top int) *Movers {
+	var risers, fallers []*Mover
+	add := func(key string) {
+		m := &Mover{Key: key, Previous: before[key], Current: after[key]}
+		m.Change = float64(m.Current+1)/float64(m.Previous+1) - 1
+		switch {
+		case m.Change > 0:
+			risers = append(risers, m)
+		case m.Change < 0:
+			fallers = append(fallers, m)
+		}
+	}
+	for key := range after {
+		add(key)
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			add(key)
+		}
+	}
+
+	// the largest changes first, then by key
+	sort.Slice(risers, func(i, j int) bool {
+		if risers[i].Change != risers[j].Change {
+			return risers[i].Change > risers[j].Change
+		}
+		return risers[i].Key < risers[j].Key
+	})
+	sort.Slice(fallers, func(i, j int) bool {
+		if fallers[i].Change != fallers[j].Change {
+			return fallers[i].Change < fallers[j].Change
+		}
+		return fallers[i].Key < fallers[j].Key
+	})
+	if top < len(risers) {
+		risers = risers[:top]
+	}
+	if top < len(fallers) {
+		fallers = fallers[:top]
+	}
+	return &Movers{Risers: risers, Fallers: fallers}
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_logAnalyzer_ComparePeriods(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:      defaultLineRegex,
+		TopMoversCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.ComparePeriods() error = %v, error creating analyzer", err)
+	}
+	got, err := l.ComparePeriods([]string{"./test-data/period-previous.log"}, []string{"./test-data/period-current.log"})
+	if err != nil {
+		t.Fatalf("logAnalyzer.ComparePeriods() error = %v", err)
+	}
+
+	wantURLs := &Movers{
+		Risers: []*Mover{{Key: "/export.csv", Previous: 0, Current: 3, Change: 3}},
+		// /home, unchanged, is neither
+		Fallers: []*Mover{{Key: "/pricing", Previous: 3, Current: 0, Change: -0.75}},
+	}
+	if !reflect.DeepEqual(got.URLs, wantURLs) {
+		t.Errorf("logAnalyzer.ComparePeriods() urls = %s, want %s", moversString(got.URLs), moversString(wantURLs))
+	}
+	wantIPs := &Movers{
+		Risers: []*Mover{
+			{Key: "10.0.0.3", Previous: 0, Current: 3, Change: 3},
+			{Key: "10.0.0.1", Previous: 2, Current: 3, Change: float64(3+1)/float64(2+1) - 1},
+		},
+		Fallers: []*Mover{{Key: "10.0.0.2", Previous: 4, Current: 0, Change: -0.8}},
+	}
+	if !reflect.DeepEqual(got.IPs, wantIPs) {
+		t.Errorf("logAnalyzer.ComparePeriods() ips = %s, want %s", moversString(got.IPs), moversString(wantIPs))
+	}
+	if got.Previous.UniqueIPCount != 2 || got.Current.UniqueIPCount != 2 {
+		t.Errorf("logAnalyzer.ComparePeriods() unique ips = %d, %d, want 2, 2", got.Previous.UniqueIPCount, got.Current.UniqueIPCount)
+	}
+}
+
+func moversString(movers *Movers) string {
+	s := "risers:"
+	for _, m := range movers.Risers {
+		s += " " + m.Key
+	}
+	s += " fallers:"
+	for _, m := range movers.Fallers {
+		s += " " + m.Key
+	}
+	return s
+}
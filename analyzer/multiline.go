@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultMultilineMaxLines : Lines a record is cut at, when MultilineRules
+// sets no MaxLines
+const DefaultMultilineMaxLines = 500
+
+// indentedLine : What continuation lines start with when MultilineRules sets
+// neither pattern, as the frames of most stack traces do
+var indentedLine = regexp.MustCompile(`^[ \t]`)
+
+// MultilineRules : How the lines continuing a record, e.g. the stack trace of
+// an error or the rest of a line a shipper wrapped, are joined into it before
+// it is parsed
+type MultilineRules struct {
+	// Start : Lines matching start a record, the others continue the one
+	// before them, e.g. `^\d{4}/\d\d/\d\d ` for nginx error logs
+	Start *regexp.Regexp
+	// Continuation : Lines matching continue the record before them, when
+	// Start is not set; lines starting with a space or tab when neither is
+	Continuation *regexp.Regexp
+	// Unwrap : Join continuation lines without a separator, for lines a
+	// shipper wrapped, rather than with a newline
+	Unwrap bool
+	// MaxLines : Lines a record is cut at, the next one starting a record
+	// whatever it looks like; DefaultMultilineMaxLines when not set
+	MaxLines int
+}
+
+// continues : Whether the line continues the record before it
+func (r *MultilineRules) continues(line string) bool {
+	switch {
+	case r.Start != nil:
+		return !r.Start.MatchString(line)
+	case r.Continuation != nil:
+		return r.Continuation.MatchString(line)
+	default:
+		return indentedLine.MatchString(line)
+	}
+}
+
+// recordAssembler : Joins lines into records, as per the rules
+type recordAssembler struct {
+	rules  *MultilineRules
+	record strings.Builder
+	// lines : Lines of the record being assembled
+	lines int
+}
+
+func newRecordAssembler(rules *MultilineRules) *recordAssembler {
+	return &recordAssembler{rules: rules}
+}
+
+// add : Adds the line, returning the record it ends, if any. Lines before
+// the first start of a record make records of their own.
+func (a *recordAssembler) add(line string) (string, bool) {
+	maxLines := a.rules.MaxLines
+	if maxLines <= 0 {
+		maxLines = DefaultMultilineMaxLines
+	}
+	if a.lines > 0 && a.lines < maxLines && a.rules.continues(line) {
+		if !a.rules.Unwrap {
+			a.record.WriteByte('\n')
+		}
+		a.record.WriteString(line)
+		a.lines++
+		return "", false
+	}
+	record, ended := a.flush()
+	a.record.WriteString(line)
+	a.lines = 1
+	return record, ended
+}
+
+// flush : Ends the record being assembled, returning it, if any
+func (a *recordAssembler) flush() (string, bool) {
+	if a.lines == 0 {
+		return "", false
+	}
+	record := a.record.String()
+	a.record.Reset()
+	a.lines = 0
+	return record, true
+}
+
+// lineScanner : What lines and records are read through, a bufio.Scanner or
+// a recordScanner
+type lineScanner interface {
+	Scan() bool
+	Text() string
+	Err() error
+}
+
+// recordScanner : Scans the records of the lines of a scanner
+type recordScanner struct {
+	scanner   *bufio.Scanner
+	assembler *recordAssembler
+	text      string
+}
+
+func (s *recordScanner) Scan() bool {
+	for s.scanner.Scan() {
+		if record, ended := s.assembler.add(s.scanner.Text()); ended {
+			s.text = record
+			return true
+		}
+	}
+	var ended bool
+	s.text, ended = s.assembler.flush()
+	return ended
+}
+
+func (s *recordScanner) Text() string {
+	return s.text
+}
+
+func (s *recordScanner) Err() error {
+	return s.scanner.Err()
+}
+
+// records : Scans the records of the scanner's lines, or its lines when no
+// multiline rules are set
+func (l *logAnalyzer) records(scanner *bufio.Scanner) lineScanner {
+	if l.multiline == nil {
+		return scanner
+	}
+	return &recordScanner{scanner: scanner, assembler: newRecordAssembler(l.multiline)}
+}
+
+// assembleRecords : Joins followed lines into records, ending the last one
+// when no line came for idle, since whether another line continues it is
+// not known until one does
+func assembleRecords(lineCh <-chan string, rules *MultilineRules, idle time.Duration) <-chan string {
+	recordCh := make(chan string)
+	go func() {
+		defer close(recordCh)
+		assembler := newRecordAssembler(rules)
+		for {
+			var idleCh <-chan time.Time
+			if assembler.lines > 0 {
+				idleCh = time.After(idle)
+			}
+			select {
+			case line, ok := <-lineCh:
+				if !ok {
+					if record, ended := assembler.flush(); ended {
+						recordCh <- record
+					}
+					return
+				}
+				if record, ended := assembler.add(line); ended {
+					recordCh <- record
+				}
+			case <-idleCh:
+				if record, ended := assembler.flush(); ended {
+					recordCh <- record
+				}
+			}
+		}
+	}()
+	return recordCh
+}
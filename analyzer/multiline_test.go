@@ -0,0 +1,152 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_recordAssembler(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules *MultilineRules
+		lines []string
+		want  []string
+	}{
+		{
+			name:  "indented lines",
+			rules: &MultilineRules{},
+			lines: []string{"  orphan", "Exception: boom", "\tat a()", "  at b()", "next"},
+			want:  []string{"  orphan", "Exception: boom\n\tat a()\n  at b()", "next"},
+		},
+		{
+			name:  "start",
+			rules: &MultilineRules{Start: regexp.MustCompile(`^\d{4}/`)},
+			lines: []string{"before", "2023/10/11 a", "trace", "", "2023/10/11 b"},
+			want:  []string{"before", "2023/10/11 a\ntrace\n", "2023/10/11 b"},
+		},
+		{
+			name:  "continuation",
+			rules: &MultilineRules{Continuation: regexp.MustCompile(`^\.\.\.`)},
+			lines: []string{"a", "...b", "c"},
+			want:  []string{"a\n...b", "c"},
+		},
+		{
+			name:  "unwrap",
+			rules: &MultilineRules{Start: regexp.MustCompile(`^10\.`), Unwrap: true},
+			lines: []string{`10.0.0.1 - - "GET /a`, `/b HTTP/1.1" 200`, `10.0.0.2 - -`},
+			want:  []string{`10.0.0.1 - - "GET /a/b HTTP/1.1" 200`, `10.0.0.2 - -`},
+		},
+		{
+			name:  "max lines",
+			rules: &MultilineRules{MaxLines: 2},
+			lines: []string{"a", " 1", " 2", " 3"},
+			want:  []string{"a\n 1", " 2\n 3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newRecordAssembler(tt.rules)
+			var got []string
+			for _, line := range tt.lines {
+				if record, ended := a.add(line); ended {
+					got = append(got, record)
+				}
+			}
+			if record, ended := a.flush(); ended {
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_assembleRecords(t *testing.T) {
+	lineCh := make(chan string)
+	recordCh := assembleRecords(lineCh, &MultilineRules{}, 10*time.Millisecond)
+	lineCh <- "a"
+	lineCh <- " 1"
+	// no line follows for long enough to end the record
+	if got := <-recordCh; got != "a\n 1" {
+		t.Errorf("record = %q, want %q", got, "a\n 1")
+	}
+	lineCh <- "b"
+	close(lineCh)
+	if got := <-recordCh; got != "b" {
+		t.Errorf("record = %q, want %q", got, "b")
+	}
+	if _, ok := <-recordCh; ok {
+		t.Error("records not closed after the lines")
+	}
+}
+
+func TestLogAnalyzerConfig_multiline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	jsonPath := filepath.Join(dir, "access.json")
+	records := `{"ip": "10.0.0.1",
+  "method": "GET", "path": "/a", "status": 200}
+{"ip": "10.0.0.2",
+  "method": "GET", "path": "/b", "status": 404}
+`
+	if err := ioutil.WriteFile(jsonPath, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{JSON: true, Multiline: &MultilineRules{}})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	analytics, err := l.Analyze(jsonPath)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if parseErrors := l.SelfMetrics().ParseErrors; analytics.UniqueIPCount != 2 || parseErrors != 0 {
+		t.Errorf("Analyze() = %d IPs and %d parse errors, want 2 and 0", analytics.UniqueIPCount, parseErrors)
+	}
+
+	errorPath := filepath.Join(dir, "error.log")
+	lines := `2023/10/11 14:32:52 [error] 1234#0: *1 FastCGI sent in stderr: "PHP message: PHP Fatal error: boom"
+Stack trace:
+#0 /srv/index.php(12): main()
+#1 {main}
+2023/10/11 14:33:10 [error] 1234#0: *2 FastCGI sent in stderr: "PHP message: PHP Fatal error: bang"
+Stack trace:
+#0 {main}
+`
+	if err := ioutil.WriteFile(errorPath, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err = NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:             defaultLineRegex,
+		Multiline:             &MultilineRules{Start: regexp.MustCompile(`^\d{4}/\d\d/\d\d `)},
+		TopErrorMessagesCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	got, err := l.AnalyzeErrorLog(errorPath)
+	if err != nil {
+		t.Fatalf("AnalyzeErrorLog() error = %v", err)
+	}
+	if got.Lines != 2 || got.ParseErrors != 0 || !reflect.DeepEqual(got.TopMessages, []string{`FastCGI sent in stderr: "*"`}) {
+		t.Errorf("AnalyzeErrorLog() = %+v, want 2 records by their first line", got)
+	}
+
+	_, err = NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex: defaultLineRegex,
+		Multiline: &MultilineRules{Start: regexp.MustCompile(`^\[`), Continuation: regexp.MustCompile(`^\s`)},
+	})
+	if err == nil || err.Error() != ErrConflictingMultiline {
+		t.Errorf("NewLogAnalyzer() error = %v, want %s", err, ErrConflictingMultiline)
+	}
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultAssetPaths : URL paths of static assets and crawler files, which are
+// not pages
+var DefaultAssetPaths = regexp.MustCompile(`(?i)(\.(css|js|mjs|map|json|xml|txt|png|jpe?g|gif|webp|avif|svg|ico|bmp|woff2?|ttf|otf|eot|mp4|webm|mp3|pdf|zip|gz))$|^/favicon\.ico$|^/robots\.txt$`)
+
+// PageviewRules : What counts as a pageview, so pageviews compare with those
+// of web analytics tools. A pageview is a successful (2xx) request for a page
+// by a person: requests with other methods, for assets, or by bots are not
+// pageviews.
+type PageviewRules struct {
+	// Methods : Request methods of pageviews, GET when empty
+	Methods []string
+	// AssetPaths : URL paths, without the query, that are assets rather than
+	// pages. DefaultAssetPaths when nil.
+	AssetPaths *regexp.Regexp
+	// BotUserAgents : User agents of bots. When nil, the bots known to the
+	// useragent enricher.
+	BotUserAgents *regexp.Regexp
+}
+
+// defaultPageviewRules : Pageviews sessions are made of, when no rules are set
+var defaultPageviewRules = &PageviewRules{}
+
+// pageviewRules : The rules pageviews are told apart by
+func (l *logAnalyzer) pageviewRules() *PageviewRules {
+	if l.pageviews != nil {
+		return l.pageviews
+	}
+	return defaultPageviewRules
+}
+
+// isPageview : Whether the line is a pageview according to the rules
+func (r *PageviewRules) isPageview(line *Line) bool {
+	if line.Status < 200 || line.Status > 299 {
+		return false
+	}
+
+	if len(r.Methods) == 0 {
+		if line.Method != "GET" {
+			return false
+		}
+	} else if !containsString(r.Methods, line.Method) {
+		return false
+	}
+
+	assetPaths := r.AssetPaths
+	if assetPaths == nil {
+		assetPaths = DefaultAssetPaths
+	}
+	if assetPaths.MatchString(line.Path) {
+		return false
+	}
+
+	if r.BotUserAgents != nil {
+		return !r.BotUserAgents.MatchString(line.UserAgent)
+	}
+	return classify(strings.ToLower(line.UserAgent), deviceTokens, "") != "bot"
+}
+
+// urlPath : The URL without its query and fragment
+func urlPath(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestPageviewRules_isPageview(t *testing.T) {
+	browser := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/71.0.3578.98 Safari/537.36"
+	tests := []struct {
+		name  string
+		rules *PageviewRules
+		line  *Line
+		want  bool
+	}{
+		{
+			name:  "page",
+			rules: &PageviewRules{},
+			line:  &Line{Method: "GET", Path: "/docs/", RawQuery: "page=2", Protocol: "HTTP/1.1", URL: "/docs/?page=2", Status: 200, UserAgent: browser},
+			want:  true,
+		},
+		{
+			name:  "not found",
+			rules: &PageviewRules{},
+			line:  &Line{Method: "GET", Path: "/docs/", Protocol: "HTTP/1.1", URL: "/docs/", Status: 404, UserAgent: browser},
+		},
+		{
+			name:  "post",
+			rules: &PageviewRules{},
+			line:  &Line{Method: "POST", Path: "/login", Protocol: "HTTP/1.1", URL: "/login", Status: 200, UserAgent: browser},
+		},
+		{
+			name:  "asset",
+			rules: &PageviewRules{},
+			line:  &Line{Method: "GET", Path: "/static/app.CSS", RawQuery: "v=3", Protocol: "HTTP/1.1", URL: "/static/app.CSS?v=3", Status: 200, UserAgent: browser},
+		},
+		{
+			name:  "robots.txt",
+			rules: &PageviewRules{},
+			line:  &Line{Method: "GET", Path: "/robots.txt", Protocol: "HTTP/1.1", URL: "/robots.txt", Status: 200, UserAgent: browser},
+		},
+		{
+			name:  "bot",
+			rules: &PageviewRules{},
+			line:  &Line{Method: "GET", Path: "/docs/", Protocol: "HTTP/1.1", URL: "/docs/", Status: 200, UserAgent: "Googlebot/2.1 (+http://www.google.com/bot.html)"},
+		},
+		{
+			name:  "custom rules",
+			rules: &PageviewRules{Methods: []string{"GET", "POST"}, AssetPaths: regexp.MustCompile(`^/static/`), BotUserAgents: regexp.MustCompile(`^Monitor`)},
+			line:  &Line{Method: "POST", Path: "/report.pdf", Protocol: "HTTP/1.1", URL: "/report.pdf", Status: 201, UserAgent: "curl/7.64.0"},
+			want:  true,
+		},
+		{
+			name:  "custom bot",
+			rules: &PageviewRules{BotUserAgents: regexp.MustCompile(`^Monitor`)},
+			line:  &Line{Method: "GET", Path: "/", Protocol: "HTTP/1.1", URL: "/", Status: 200, UserAgent: "Monitor/1.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.isPageview(tt.line); got != tt.want {
+				t.Errorf("PageviewRules.isPageview() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
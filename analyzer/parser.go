@@ -0,0 +1,432 @@
+package analyzer
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+const (
+	// ErrLineNotMatched :
+	ErrLineNotMatched = "line does not match the log format"
+)
+
+var errNotMatched = errors.New(ErrLineNotMatched)
+
+// Parser : Parses lines in place of a line regex, e.g. code generated by
+// cmd/parsergen for a fixed format
+type Parser struct {
+	Parse func(text string) (*Line, error)
+	// Fields : The line fields Parse fills, named as the groups of line
+	// regexes, e.g. "duration" for latency reports
+	Fields []string
+}
+
+// parserFormat : Reads lines with a Parser
+type parserFormat struct {
+	parser *Parser
+}
+
+// newParser : Parser implementations allocate their own lines
+func (f parserFormat) newParser(batch *lineBatch) lineParser {
+	return f.parser.Parse
+}
+
+func (f parserFormat) fields() []string {
+	return f.parser.Fields
+}
+
+// project : The parser fills all its fields
+func (f parserFormat) project(fields projection) fieldFormat {
+	return f
+}
+
+// defaultLineRegex : NCSA combined log format, as used in the task logs
+var defaultLineRegex = formats.CombinedLog.LineRegex
+
+// defaultParser : Reads combined log format lines without regex matching
+// when it can
+var defaultParser = newSplitParser(defaultLineRegex, nil, nil)
+
+// Parse : Parses a single combined log format line. It has no side effects
+// and never panics, whatever the input.
+func Parse(data []byte) (*Line, error) {
+	return defaultParser(string(data))
+}
+
+func parseLine(lineRegex *regexp.Regexp, line string) (*Line, error) {
+	return parseProjectedLine(lineRegex, groupNames(lineRegex), nil, line, nil)
+}
+
+// groupAliases : Fields of the groups of custom line regexes named as they
+// commonly are in other tools, e.g. (?P<ip>\S+) or (?P<status_code>\d+)
+var groupAliases = map[string]string{
+	"ip":          "remote_host",
+	"client_ip":   "remote_host",
+	"remote_addr": "remote_host",
+	"remote_ip":   "remote_host",
+	"timestamp":   "time",
+	"ts":          "time",
+	"verb":        "method",
+	"path":        "url",
+	"uri":         "url",
+	"proto":       "protocol",
+	"status_code": "status",
+	"size":        "bytes",
+	"referrer":    "referer",
+	"ua":          "user_agent",
+	"agent":       "user_agent",
+	"useragent":   "user_agent",
+}
+
+// groupNames : The names of the groups of the line regex, by number, aliases
+// replaced by the field they fill
+func groupNames(lineRegex *regexp.Regexp) []string {
+	names := append([]string(nil), lineRegex.SubexpNames()...)
+	for i, name := range names {
+		if field, ok := groupAliases[name]; ok {
+			names[i] = field
+		}
+	}
+	return names
+}
+
+// namesGroups : Whether the groups of the line regex, as named by
+// SubexpNames, map to fields by name rather than by position
+func namesGroups(names []string) bool {
+	_, positional := positionalGroups(names)
+	return !positional
+}
+
+// positionalGroups : The groups of the positional fields, by field number,
+// e.g. that of the status at 7. Positional line regexes have ten unnamed
+// groups, the positional ones, and may name groups among them too, e.g.
+// (?P<remote_user>\S+) before the time, which are read by name. Line regexes
+// with fewer unnamed groups and named ones map all their groups by name,
+// unnamed groups being skipped; positional is false for them.
+func positionalGroups(names []string) (groups [11]int, positional bool) {
+	field, named := 1, false
+	for i := 1; i < len(names); i++ {
+		if names[i] != "" {
+			named = true
+		} else if field < len(groups) {
+			groups[field] = i
+			field++
+		}
+	}
+	return groups, field == len(groups) || !named
+}
+
+// positionalNames : The names of the groups of the line regex, by number,
+// those of the positional groups of positional line regexes being their field
+func positionalNames(names []string) []string {
+	fields := append([]string(nil), names...)
+	if groups, positional := positionalGroups(names); positional {
+		for field := 1; field < len(groups) && groups[field] > 0; field++ {
+			fields[groups[field]] = positionalFields[field]
+		}
+	}
+	return fields
+}
+
+// parseProjectedLine : Parses the line, filling the fields of the projection
+// only. names are the group names of the line regex, as projected. The line
+// is allocated from the batch.
+func parseProjectedLine(lineRegex *regexp.Regexp, names []string, fields projection, line string, batch *lineBatch) (*Line, error) {
+	return parseMatch(lineRegex, names, fields, nil, lineRegex.FindStringSubmatch(line), batch)
+}
+
+// parseMatch : parseProjectedLine, of the submatches of the line regex in
+// the line, nil when it does not match. Times are parsed with the layouts,
+// the default ones when nil.
+func parseMatch(lineRegex *regexp.Regexp, names []string, fields projection, layouts timeLayouts, result []string, batch *lineBatch) (*Line, error) {
+	// a line regex with named groups and fewer than ten unnamed ones, e.g.
+	// compiled from a log format, maps all its groups by name
+	groups, positional := positionalGroups(lineRegex.SubexpNames())
+	if !positional {
+		if result == nil {
+			return nil, errors.New(ErrLineNotMatched)
+		}
+		lineItem := batch.next()
+		parseNamedFields(lineItem, names, layouts, result)
+		return lineItem, nil
+	}
+	// the positional lookups below need all ten groups
+	if len(result) < 11 {
+		return nil, errors.New(ErrLineNotMatched)
+	}
+
+	lineItem := batch.next()
+	if fields.keeps("remote_host") {
+		lineItem.RemoteHost = result[groups[1]]
+	}
+	if fields.keeps("time") {
+		lineItem.Time = layouts.parse(result[groups[2]])
+	}
+	if fields == nil || fields["request"] || fields["url"] {
+		url := result[groups[4]]
+		altURL := result[groups[6]]
+		if url == "" && altURL != "" {
+			url = altURL
+		}
+		if fields == nil || fields["request"] {
+			lineItem.setRequest(result[groups[3]], url, result[groups[5]])
+		}
+		if fields == nil || fields["url"] {
+			lineItem.URL = url
+		}
+	}
+	if fields.keeps("status") {
+		lineItem.Status = parseInt(result[groups[7]])
+	}
+	if fields.keeps("bytes") {
+		lineItem.Bytes = parseInt(result[groups[8]])
+	}
+	if fields.keeps("referer") {
+		lineItem.Referer = result[groups[9]]
+	}
+	if fields.keeps("user_agent") {
+		lineItem.UserAgent = result[groups[10]]
+	}
+
+	parseNamedFields(lineItem, names, layouts, result)
+
+	return lineItem, nil
+}
+
+// parseNamedFields : Fills the fields captured by named groups of the line
+// regex. Positional line regexes place the optional ones after their ten
+// positional groups, e.g. (?P<upstream>\S+), or among them:
+//
+//	remote_user          authenticated user, "-" for none (Apache %u, nginx $remote_user)
+//	vhost                virtual host that served the request (Apache %v, nginx $server_name)
+//	x_forwarded_for      X-Forwarded-For chain, the client resolved from it with TrustedProxies
+//	upstream             backend that served the request (nginx $upstream_addr)
+//	duration             response time in float seconds (nginx $request_time)
+//	duration_ms          response time in milliseconds (S3 total time)
+//	duration_us          response time in microseconds (Apache %D)
+//	duration_ns          response time in nanoseconds (Traefik's JSON Duration)
+//	connection           connection ID (nginx $connection)
+//	connection_requests  number of the request on its connection (nginx $connection_requests)
+//	content_type         response content type (nginx $sent_http_content_type)
+//	content_encoding     response content encoding (nginx $sent_http_content_encoding)
+//	original_bytes       response size before compression
+//	gzip_ratio           compression ratio, giving the original size (nginx $gzip_ratio)
+//	cache_status         cache result, e.g. HIT, MISS or TCP_MEM_HIT, kept in the extras for the cache report
+//	ssl_protocol         TLS protocol version, e.g. TLSv1.2 (nginx $ssl_protocol, Apache %{SSL_PROTOCOL}x)
+//	ssl_cipher           TLS cipher suite, e.g. ECDHE-RSA-AES128-GCM-SHA256 (nginx $ssl_cipher, Apache %{SSL_CIPHER}x)
+//
+// Line regexes mapping all their groups by name also capture the core fields:
+//
+//	remote_host          client address
+//	time                 request time, as 02/Jan/2006:15:04:05 -0700, RFC 3339, 2006-01-02T15:04:05-0700, 02/Jan/2006:15:04:05.000, Unix seconds or Unix milliseconds to nanoseconds
+//	request              request line, e.g. "GET /index.html HTTP/1.1"
+//	method, url, query, protocol  parts of the request line, when it is not logged whole
+//	status               response status
+//	bytes                response body size, "-" for none
+//	referer              referrer
+//	user_agent           user agent
+//
+// They may be named as in other tools too, e.g. ip, timestamp, path,
+// status_code, size, referrer or ua (see groupAliases). Other named groups
+// are kept in the line's extras.
+func parseNamedFields(lineItem *Line, names []string, layouts timeLayouts, result []string) {
+	var request, method, url, query, protocol, gzipRatio string
+	for i, name := range names {
+		switch name {
+		case "remote_host":
+			lineItem.RemoteHost = result[i]
+		case "time":
+			lineItem.Time = layouts.parse(result[i])
+		case "request":
+			request = result[i]
+		case "method":
+			method = result[i]
+		case "url":
+			url = result[i]
+		case "query":
+			// as logged by nginx's $args, without its '?'
+			query = result[i]
+			if query == "-" {
+				query = ""
+			} else if query != "" && !strings.HasPrefix(query, "?") {
+				query = "?" + query
+			}
+		case "protocol":
+			protocol = result[i]
+		case "status":
+			lineItem.Status = parseInt(result[i])
+		case "bytes":
+			lineItem.Bytes = parseInt(result[i])
+		case "referer":
+			lineItem.Referer = result[i]
+		case "user_agent":
+			lineItem.UserAgent = result[i]
+		case "remote_user":
+			if result[i] != "-" {
+				lineItem.RemoteUser = result[i]
+			}
+		case "vhost":
+			lineItem.VHost = result[i]
+		case "x_forwarded_for":
+			if result[i] != "-" {
+				lineItem.ForwardedFor = result[i]
+			}
+		case "upstream":
+			lineItem.Upstream = lastUpstream(result[i])
+		case "duration":
+			lineItem.Duration = parseSeconds(result[i])
+		case "duration_ms":
+			if ms, err := strconv.ParseFloat(result[i], 64); err == nil && ms >= 0 {
+				lineItem.Duration = seconds(ms / 1000)
+			}
+		case "duration_us":
+			if us, err := strconv.ParseInt(result[i], 10, 64); err == nil && us >= 0 {
+				lineItem.Duration = time.Duration(us) * time.Microsecond
+			}
+		case "duration_ns":
+			if ns, err := strconv.ParseInt(result[i], 10, 64); err == nil && ns >= 0 {
+				lineItem.Duration = time.Duration(ns)
+			}
+		case "connection":
+			if result[i] != "-" {
+				lineItem.Connection = result[i]
+			}
+		case "connection_requests":
+			lineItem.ConnectionRequests, _ = strconv.Atoi(result[i])
+		case "content_type":
+			lineItem.ContentType = result[i]
+		case "content_encoding":
+			lineItem.ContentEncoding = result[i]
+		case "original_bytes":
+			lineItem.OriginalBytes, _ = strconv.Atoi(result[i])
+		case "gzip_ratio":
+			gzipRatio = result[i]
+		case "ssl_protocol":
+			lineItem.TLSProtocol = tlsProtocol(result[i])
+		case "ssl_cipher":
+			lineItem.TLSCipher = tlsCipher(result[i])
+		case "":
+		default:
+			if lineItem.Extras == nil {
+				lineItem.Extras = make(map[string]string)
+			}
+			lineItem.Extras[name] = result[i]
+		}
+	}
+	if request != "" {
+		method, url, protocol = splitRequest(request)
+		query = ""
+	}
+	if method != "" || url != "" || query != "" || protocol != "" {
+		lineItem.URL = url + query
+		lineItem.setRequest(method, lineItem.URL, protocol)
+	}
+	if gzipRatio != "" {
+		lineItem.OriginalBytes = originalBytes(lineItem.Bytes, gzipRatio)
+	}
+}
+
+// FillFields : Fills the line with the values of the fields named as the
+// groups of line regexes, converted as the groups of line regexes are, e.g.
+// for parsers generated by cmd/parsergen. Empty names are skipped.
+func FillFields(line *Line, names []string, values []string) {
+	parseNamedFields(line, names, nil, values)
+}
+
+// splitRequest : The method, URL and protocol of a request line, e.g.
+// "GET /index.html HTTP/1.1". The protocol is empty when not logged.
+func splitRequest(request string) (method, url, protocol string) {
+	i := strings.IndexByte(request, ' ')
+	if i < 0 {
+		return request, "", ""
+	}
+	method, url = request[:i], request[i+1:]
+	if j := strings.LastIndexByte(url, ' '); j >= 0 && strings.HasPrefix(url[j+1:], "HTTP/") {
+		url, protocol = url[:j], url[j+1:]
+	}
+	return method, url, protocol
+}
+
+// setRequest : Sets the parts of the request line, splitting the target into
+// its path and query
+func (line *Line) setRequest(method, target, protocol string) {
+	line.Method, line.Protocol = method, protocol
+	line.Path, line.RawQuery = splitTarget(target)
+}
+
+// splitTarget : The unescaped path and the raw query of a request target, as
+// url.ParseRequestURI reads them. Targets it rejects, e.g. with invalid
+// escapes, are split at their '?' as logged.
+func splitTarget(target string) (path, rawQuery string) {
+	// paths with nothing to unescape are split without allocating, the same
+	// way
+	if strings.HasPrefix(target, "/") && strings.IndexByte(target, '%') < 0 {
+		return cutQuery(target)
+	}
+	if u, err := url.ParseRequestURI(target); err == nil {
+		return u.Path, u.RawQuery
+	}
+	return cutQuery(target)
+}
+
+func cutQuery(target string) (path, rawQuery string) {
+	if i := strings.IndexByte(target, '?'); i >= 0 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
+
+// parseTime : A time logged as 02/Jan/2006:15:04:05 -0700, as RFC 3339
+// (e.g. nginx $time_iso8601), as ISO 8601 with an offset without colon
+// (e.g. strftime's %Y-%m-%dT%H:%M:%S%z), without a time zone as HAProxy does,
+// then in UTC, or as Unix seconds (e.g. Caddy's ts) or nanoseconds.
+// Fractional seconds are read in all of them. Zero when invalid.
+func parseTime(value string) time.Time {
+	return timeLayouts(nil).parse(value)
+}
+
+// parseUnixSeconds : A time logged as Unix seconds, e.g. 1646861401.5241024,
+// its fraction read digit by digit rather than rounded as a float. Whole
+// numbers longer than the 10 digits of seconds are Unix milliseconds,
+// microseconds or nanoseconds, e.g. Cloudflare's 1646861401524102400, their
+// digits past the tenth the fraction. Zero when invalid.
+func parseUnixSeconds(value string) time.Time {
+	whole, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		whole, fraction = value[:i], value[i+1:]
+	} else if len(value) > 12 {
+		whole, fraction = value[:10], value[10:]
+	}
+	seconds, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	if len(fraction) > 9 {
+		fraction = fraction[:9]
+	}
+	nanos := 0
+	if fraction != "" {
+		if fraction[0] < '0' || fraction[0] > '9' {
+			return time.Time{}
+		}
+		if nanos, err = strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction))); err != nil {
+			return time.Time{}
+		}
+	}
+	return time.Unix(seconds, int64(nanos)).UTC()
+}
+
+// parseInt : A logged number, zero when missing ("-") or invalid
+func parseInt(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
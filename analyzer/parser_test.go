@@ -0,0 +1,436 @@
+package analyzer
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Line
+		wantErr error
+	}{
+		{
+			name:    "error: empty line",
+			data:    "",
+			wantErr: errors.New(ErrLineNotMatched),
+		},
+		{
+			name:    "error: garbage",
+			data:    "not a log line",
+			wantErr: errors.New(ErrLineNotMatched),
+		},
+		{
+			name: "combined log format line",
+			data: `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574 "-" "Mozilla/5.0"`,
+			want: &Line{
+				RemoteHost: "177.71.128.21",
+				Time:       time.Date(2018, time.July, 10, 22, 21, 28, 0, time.FixedZone("", 2*60*60)),
+				Method:     "GET",
+				Path:       "/intranet-analytics/",
+				Protocol:   "HTTP/1.1",
+				Status:     200,
+				Bytes:      3574,
+				Referer:    "-",
+				UserAgent:  "Mozilla/5.0",
+				URL:        "/intranet-analytics/",
+			},
+		},
+		{
+			name: "request with no protocol and no bytes",
+			data: `50.112.00.11 - admin [11/Jul/2018:17:33:01 +0200] "GET /asset.css" 200 - "-" "curl/7.1"`,
+			want: &Line{
+				RemoteHost: "50.112.00.11",
+				Time:       time.Date(2018, time.July, 11, 17, 33, 1, 0, time.FixedZone("", 2*60*60)),
+				Method:     "GET",
+				Path:       "/asset.css",
+				Status:     200,
+				Referer:    "-",
+				UserAgent:  "curl/7.1",
+				URL:        "/asset.css",
+				RemoteUser: "admin",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Errorf("Parse() error is expected")
+					return
+				}
+				if tt.wantErr.Error() != err.Error() {
+					t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Parse() unexpected error = %v", err)
+				return
+			}
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("Parse() time = %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time = tt.want.Time
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	// seed with every line of the task logs, on top of testdata/fuzz/FuzzParse
+	file, err := os.Open("./test-data/programming-task.log")
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		f.Add([]byte(scanner.Text()))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		line, err := Parse(data)
+		if err != nil && line != nil {
+			t.Errorf("Parse() returned a line along with error %v", err)
+		}
+		if err == nil && line == nil {
+			t.Errorf("Parse() returned neither a line nor an error")
+		}
+		// the delimiter scan must read lines exactly as the regex does
+		if want, _ := parseLine(defaultLineRegex, string(data)); !reflect.DeepEqual(line, want) {
+			t.Errorf("Parse() = %+v, regex %+v", line, want)
+		}
+	})
+}
+
+func Test_parseLine_namedGroups(t *testing.T) {
+	format, err := formats.Apache(`%>s %h "%m %U%q %H" %b "%{User-agent}i" %t`)
+	if err != nil {
+		t.Fatalf("formats.Apache() error = %v", err)
+	}
+	got, err := parseLine(format.LineRegex, `404 10.0.0.1 "GET /search?q=go HTTP/1.1" - "curl/7.64.0" [10/Jul/2018:22:21:28 +0200]`)
+	if err != nil {
+		t.Fatalf("parseLine() error = %v", err)
+	}
+	want := &Line{
+		RemoteHost: "10.0.0.1",
+		Time:       time.Date(2018, time.July, 10, 22, 21, 28, 0, time.FixedZone("", 2*60*60)),
+		Method:     "GET",
+		Path:       "/search",
+		RawQuery:   "q=go",
+		Protocol:   "HTTP/1.1",
+		Status:     404,
+		UserAgent:  "curl/7.64.0",
+		URL:        "/search?q=go",
+	}
+	if !got.Time.Equal(want.Time) {
+		t.Errorf("parseLine() time = %v, want %v", got.Time, want.Time)
+	}
+	got.Time = want.Time
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLine() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseLine(format.LineRegex, "not a log line"); err == nil || err.Error() != ErrLineNotMatched {
+		t.Errorf("parseLine() error = %v, wantErr %v", err, ErrLineNotMatched)
+	}
+}
+
+func Test_parseLine_groupAliases(t *testing.T) {
+	// named as in other tools, after an unnamed optional prefix
+	lineRegex := regexp.MustCompile(`^(\[\w+\] )?(?P<ip>\S+) (?P<timestamp>\S+) "(?P<verb>\S+) (?P<path>\S+)" (?P<status_code>\d+) (?P<size>\d+) (?P<ua>.*)$`)
+	got, err := parseLine(lineRegex, `[edge] 10.0.0.1 2018-07-10T22:21:28+02:00 "GET /search?q=go" 404 120 curl/8.4.0`)
+	if err != nil {
+		t.Fatalf("parseLine() error = %v", err)
+	}
+	want := &Line{
+		RemoteHost: "10.0.0.1",
+		Time:       time.Date(2018, time.July, 10, 22, 21, 28, 0, time.FixedZone("", 2*60*60)),
+		Method:     "GET",
+		Path:       "/search",
+		RawQuery:   "q=go",
+		Status:     404,
+		Bytes:      120,
+		UserAgent:  "curl/8.4.0",
+		URL:        "/search?q=go",
+	}
+	if !got.Time.Equal(want.Time) {
+		t.Errorf("parseLine() time = %v, want %v", got.Time, want.Time)
+	}
+	got.Time = want.Time
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLine() = %+v, want %+v", got, want)
+	}
+
+	// projected by the fields they fill
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: lineRegex, Fields: []string{"remote_host", "status"}})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	got, err = a.(*logAnalyzer).newLineParser()(`10.0.0.1 2018-07-10T22:21:28+02:00 "GET /a" 200 120 curl/8.4.0`)
+	if err != nil {
+		t.Fatalf("lineParser() error = %v", err)
+	}
+	if got.RemoteHost != "10.0.0.1" || got.Status != 200 || got.URL != "" || got.Bytes != 0 {
+		t.Errorf("lineParser() = %+v, want the client and status only", got)
+	}
+}
+
+func Test_parseLine_extras(t *testing.T) {
+	format, err := formats.Nginx(`$remote_addr [$time_local] "$request" $status $body_bytes_sent cache=$upstream_cache_status`)
+	if err != nil {
+		t.Fatalf("formats.Nginx() error = %v", err)
+	}
+	got, err := parseLine(format.LineRegex, `10.0.0.1 [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 120 cache=HIT`)
+	if err != nil {
+		t.Fatalf("parseLine() error = %v", err)
+	}
+	if want := map[string]string{"upstream_cache_status": "HIT"}; !reflect.DeepEqual(got.Extras, want) {
+		t.Errorf("parseLine() extras = %v, want %v", got.Extras, want)
+	}
+	if got.URL != "/a" || got.Status != 200 || got.Bytes != 120 {
+		t.Errorf("parseLine() = %+v, want URL /a, status 200 and 120 bytes", got)
+	}
+}
+
+func Test_parseLine_durations(t *testing.T) {
+	const line = `10.0.0.1 [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 120 `
+	tests := []struct {
+		name      string
+		logFormat string
+		took      string
+		want      time.Duration
+	}{
+		{name: "apache microseconds", logFormat: `%D`, took: "1234567", want: 1234567 * time.Microsecond},
+		{name: "apache seconds", logFormat: `%T`, took: "2", want: 2 * time.Second},
+		{name: "apache milliseconds", logFormat: `%{ms}T`, took: "1234", want: 1234 * time.Millisecond},
+		{name: "apache unit microseconds", logFormat: `%{us}T`, took: "1500", want: 1500 * time.Microsecond},
+		{name: "unparsable", logFormat: `%D`, took: "-", want: 0},
+		{name: "negative", logFormat: `%D`, took: "-1", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := formats.Apache(`%h %t "%r" %>s %b ` + tt.logFormat)
+			if err != nil {
+				t.Fatalf("formats.Apache() error = %v", err)
+			}
+			got, err := parseLine(format.LineRegex, line+tt.took)
+			if err != nil {
+				t.Fatalf("parseLine() error = %v", err)
+			}
+			if got.Duration != tt.want {
+				t.Errorf("parseLine() duration = %v, want %v", got.Duration, tt.want)
+			}
+		})
+	}
+
+	// nginx $request_time is in seconds, with milliseconds
+	format, err := formats.Nginx(`$remote_addr [$time_local] "$request" $status $body_bytes_sent $request_time`)
+	if err != nil {
+		t.Fatalf("formats.Nginx() error = %v", err)
+	}
+	got, err := parseLine(format.LineRegex, line+"0.250")
+	if err != nil {
+		t.Fatalf("parseLine() error = %v", err)
+	}
+	if want := 250 * time.Millisecond; got.Duration != want {
+		t.Errorf("parseLine() duration = %v, want %v", got.Duration, want)
+	}
+}
+
+func Test_splitTarget(t *testing.T) {
+	tests := []struct {
+		target       string
+		wantPath     string
+		wantRawQuery string
+	}{
+		{target: "/search?q=go&page=2", wantPath: "/search", wantRawQuery: "q=go&page=2"},
+		{target: "/docs/", wantPath: "/docs/"},
+		{target: "/caf%C3%A9?q=a%20b", wantPath: "/café", wantRawQuery: "q=a%20b"},
+		{target: "http://example.com/a?b", wantPath: "/a", wantRawQuery: "b"},
+		{target: "/a%zz?b", wantPath: "/a%zz", wantRawQuery: "b"},
+		{target: "*", wantPath: "*"},
+		{target: ""},
+	}
+	for _, tt := range tests {
+		gotPath, gotRawQuery := splitTarget(tt.target)
+		if gotPath != tt.wantPath || gotRawQuery != tt.wantRawQuery {
+			t.Errorf("splitTarget(%q) = %q, %q, want %q, %q", tt.target, gotPath, gotRawQuery, tt.wantPath, tt.wantRawQuery)
+		}
+	}
+}
+
+func TestLine_Request(t *testing.T) {
+	tests := []struct {
+		line *Line
+		want string
+	}{
+		{line: &Line{Method: "GET", Path: "/search", RawQuery: "q=go", Protocol: "HTTP/1.1", URL: "/search?q=go"}, want: "GET /search?q=go HTTP/1.1"},
+		{line: &Line{Method: "GET", Path: "/a", URL: "/a"}, want: "GET /a"},
+		{line: &Line{Method: "-"}, want: "-"},
+		{line: &Line{}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := tt.line.Request(); got != tt.want {
+			t.Errorf("Line.Request() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNewLogAnalyzer_parser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, []byte("10.0.0.1 /a 0.5\n10.0.0.2 /b 1.5\n10.0.0.1 /b 0.1\nnot\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := []string{"remote_host", "url", "duration"}
+	parser := &Parser{
+		Parse: func(text string) (*Line, error) {
+			values := strings.Fields(text)
+			if len(values) != len(fields) {
+				return nil, errNotMatched
+			}
+			line := &Line{}
+			FillFields(line, fields, values)
+			return line, nil
+		},
+		Fields: fields,
+	}
+	if _, err := NewLogAnalyzer(&LogAnalyzerConfig{Parser: parser, JSON: true}); err == nil || err.Error() != ErrConflictingFormats {
+		t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, ErrConflictingFormats)
+	}
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{Parser: parser, MostActiveIPsCount: 1, SlowestRequestsCount: 1})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	analytics, err := l.Analyze(filePath)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if !reflect.DeepEqual(analytics.MostActiveIPs, []string{"10.0.0.1"}) || analytics.UniqueIPCount != 2 {
+		t.Errorf("Analyze() IPs = %v (%d unique), want [10.0.0.1] (2 unique)", analytics.MostActiveIPs, analytics.UniqueIPCount)
+	}
+	if len(analytics.SlowestRequests) != 1 || analytics.SlowestRequests[0].URL != "/b" {
+		t.Errorf("Analyze() slowest = %+v, want /b", analytics.SlowestRequests)
+	}
+}
+
+func Test_splitLine(t *testing.T) {
+	texts := []string{
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 3574 "-" "Mozilla/5.0 (X11; Linux x86_64)"`,
+		`10.0.0.1 - frank [10/Jul/2018:22:21:28 +0200] "GET /a b HTTP/1.1" 200 - "http://example.com/" ""`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a" 200 1 "-" "curl"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "-" 400 0 "-" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "" 400 0 "-" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET  /a HTTP/1.1" 200 1 "-" "a "quoted" agent"`,
+		`10.0.0.1 - a b [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "-" "-"`,
+		`10.0.0.1 -  - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "-" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a\" HTTP/1.1" 200 1 "-" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "x\"y" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "-" "FBAN/FBIOS [FBAN]"`,
+		`10.0.0.1 - - [x] [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "-" "-"`,
+		"10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] \"GET /a\tHTTP/1.1\" 200 1 \"-\" \"-\"",
+		`10.0.0.1 - - [] "GET /a HTTP/1.1" 200 1 "-" "-"`,
+		`10.0.0.1  - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "-" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200  1 "-" "-"`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 "-" "-" trailing`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1`,
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 1 extra`,
+		`not a log line`,
+		``,
+	}
+	for _, name := range []string{"programming-task.log", "common.log", "encoded-urls.log", "ipv6-clients.log"} {
+		data, err := ioutil.ReadFile(filepath.Join("test-data", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, strings.Split(string(data), "\n")...)
+	}
+	for _, format := range []*formats.Format{formats.CombinedLog, formats.CommonLog} {
+		for _, fields := range []projection{nil, newProjection([]string{"remote_host", "url"}), newProjection([]string{"request", "bytes"})} {
+			parse := newSplitParser(format.LineRegex, fields, nil)
+			names := fields.names(format.LineRegex.SubexpNames())
+			for _, text := range texts {
+				got, gotErr := parse(text)
+				want, wantErr := parseProjectedLine(format.LineRegex, names, fields, text, nil)
+				if !reflect.DeepEqual(got, want) || (gotErr == nil) != (wantErr == nil) {
+					t.Errorf("%s %v: parse(%q) = %+v, %v, regex %+v, %v", format.Name, fields, text, got, gotErr, want, wantErr)
+				}
+			}
+		}
+	}
+	if newSplitParser(formats.Traefik.LineRegex, nil, nil) != nil {
+		t.Errorf("newSplitParser() of a named groups regex is not nil")
+	}
+}
+
+func TestNewLogAnalyzer_tokenizer(t *testing.T) {
+	var reports []*LogAnalytics
+	for _, tokenizer := range []Tokenizer{TokenizerScan, TokenizerRegex} {
+		l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, Tokenizer: tokenizer, MostActiveIPsCount: 1, MostVisitedURLsCount: 3})
+		if err != nil {
+			t.Fatalf("NewLogAnalyzer() error = %v", err)
+		}
+		analytics, err := l.Analyze("./test-data/programming-task.log")
+		if err != nil {
+			t.Fatalf("Analyze() error = %v", err)
+		}
+		reports = append(reports, analytics)
+	}
+	if !reflect.DeepEqual(reports[0], reports[1]) {
+		t.Errorf("Analyze() with the regex = %+v, want %+v as scanned", reports[1], reports[0])
+	}
+	if _, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, Tokenizer: TokenizerRegex + 1}); err == nil || err.Error() != ErrInvalidTokenizer {
+		t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, ErrInvalidTokenizer)
+	}
+}
+
+// benchmarkTokenizer : Parses combined log lines with the tokenizer
+func benchmarkTokenizer(b *testing.B, tokenizer Tokenizer) {
+	data, err := ioutil.ReadFile(filepath.Join("test-data", "programming-task.log"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	texts := strings.Split(strings.TrimSpace(string(data)), "\n")
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, Tokenizer: tokenizer})
+	if err != nil {
+		b.Fatal(err)
+	}
+	parse := l.(*logAnalyzer).newLineParser()
+	b.SetBytes(int64(len(data) / len(texts)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// the log has malformed lines, parsed as they are
+		parse(texts[i%len(texts)])
+	}
+}
+
+func BenchmarkTokenizerScan(b *testing.B) {
+	benchmarkTokenizer(b, TokenizerScan)
+}
+
+func BenchmarkTokenizerRegex(b *testing.B) {
+	benchmarkTokenizer(b, TokenizerRegex)
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import "encoding/json"
+
+// Partial : The mergeable counts of a shard of logs, before they are reported,
+// e.g. analyzed by one instance of several. Top-N reports cannot be merged
+// exactly, counts can: partials of analyzers of the same config merge into
+// the analytics of all their logs. Sessions spanning shards are counted once
+// per shard.
+type Partial struct {
+	agg *aggregate
+}
+
+func (l *logAnalyzer) AnalyzePartial(filePaths ...string) (*Partial, error) {
+	filePaths = uniquePaths(filePaths)
+	aggs, err := l.aggregateFiles(filePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := newAggregate()
+	for _, filePath := range filePaths {
+		merged.merge(aggs[filePath])
+	}
+	return &Partial{agg: merged}, nil
+}
+
+func (l *logAnalyzer) MergePartials(partials ...*Partial) *LogAnalytics {
+	merged := newAggregate()
+	for _, p := range partials {
+		if p != nil && p.agg != nil {
+			merged.merge(p.agg)
+		}
+	}
+	return l.report(merged)
+}
+
+// MarshalJSON : Implements json.Marshaler
+func (p *Partial) MarshalJSON() ([]byte, error) {
+	if p.agg == nil {
+		return json.Marshal(newAggregate())
+	}
+	return json.Marshal(p.agg)
+}
+
+// UnmarshalJSON : Implements json.Unmarshaler
+func (p *Partial) UnmarshalJSON(data []byte) error {
+	agg := newAggregate()
+	if err := json.Unmarshal(data, agg); err != nil {
+		return err
+	}
+	p.agg = agg
+	return nil
+}
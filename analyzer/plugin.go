@@ -0,0 +1,39 @@
+package analyzer
+
+import (
+	"regexp"
+)
+
+const (
+	// ErrLoadingPlugin :
+	ErrLoadingPlugin = "error loading plugin"
+	// ErrInvalidPlugin :
+	ErrInvalidPlugin = "plugin does not export a Plugin variable"
+	// ErrPluginsUnsupported :
+	ErrPluginsUnsupported = "plugins are not supported on this platform"
+)
+
+// PluginSymbol : Name of the variable, of type analyzer.Plugin, a plugin exports
+const PluginSymbol = "Plugin"
+
+// Plugin : Parsers, enrichers and sinks distributed separately from the
+// analyzer. A plugin is a main package built with -buildmode=plugin against
+// the same analyzer version, exporting
+//
+//	var Plugin = analyzer.Plugin{...}
+type Plugin struct {
+	Name string
+	// LineRegexes : Line formats by name, each with the ten groups of
+	// LogAnalyzerConfig.LineRegex
+	LineRegexes map[string]*regexp.Regexp
+	Enrichers   []Enricher
+	Sinks       []Sink
+}
+
+// Sink : Destination the analytics reports are delivered to
+type Sink interface {
+	// Name : Unique name the sink is selected by
+	Name() string
+	// Write : Delivers a report. It is called from a single goroutine.
+	Write(analytics *LogAnalytics) error
+}
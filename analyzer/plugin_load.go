@@ -0,0 +1,29 @@
+//go:build (linux || darwin || freebsd) && cgo
+// +build linux darwin freebsd
+// +build cgo
+
+package analyzer
+
+import (
+	"plugin"
+
+	"github.com/pkg/errors"
+)
+
+// LoadPlugin : Opens the plugin file and returns the Plugin it exports.
+// Opening a plugin again returns the already loaded one.
+func LoadPlugin(path string) (*Plugin, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrLoadingPlugin)
+	}
+	symbol, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, errors.Wrap(errors.New(ErrInvalidPlugin), path)
+	}
+	exported, ok := symbol.(*Plugin)
+	if !ok {
+		return nil, errors.Wrap(errors.New(ErrInvalidPlugin), path)
+	}
+	return exported, nil
+}
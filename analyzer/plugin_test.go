@@ -0,0 +1,23 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestLoadPlugin(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "error: missing file", path: "./test-data/missing.so"},
+		{name: "error: not a plugin", path: "./test-data/programming-task.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadPlugin(tt.path)
+			if err == nil {
+				t.Errorf("LoadPlugin() = %v, want an error", got)
+			}
+		})
+	}
+}
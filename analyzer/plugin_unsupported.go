@@ -0,0 +1,13 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+// +build !linux,!darwin,!freebsd !cgo
+
+package analyzer
+
+import (
+	"github.com/pkg/errors"
+)
+
+// LoadPlugin : Go plugins need cgo on Linux, macOS or FreeBSD
+func LoadPlugin(path string) (*Plugin, error) {
+	return nil, errors.Wrap(errors.New(ErrPluginsUnsupported), path)
+}
@@ -0,0 +1,72 @@
+package analyzer
+
+// projection : The line fields a run needs, named as the groups of line
+// regexes. Groups filling none of them are neither extracted nor converted,
+// e.g. times are not parsed when "time" is not needed. nil for all fields.
+type projection map[string]bool
+
+// groupFields : The line fields of the groups filling other fields than the
+// one of their name
+var groupFields = map[string][]string{
+	"request":     {"request", "url"},
+	"method":      {"request"},
+	"url":         {"request", "url"},
+	"query":       {"request", "url"},
+	"protocol":    {"request"},
+	"duration_ms": {"duration"},
+	"duration_us": {"duration"},
+	"duration_ns": {"duration"},
+	"gzip_ratio":  {"original_bytes"},
+}
+
+// newProjection : The projection of the fields, nil when none are set
+func newProjection(fields []string) projection {
+	if len(fields) == 0 {
+		return nil
+	}
+	p := make(projection, len(fields)+1)
+	for _, field := range fields {
+		p[field] = true
+	}
+	// original sizes are derived from the compressed size and gzip ratio
+	if p["original_bytes"] {
+		p["bytes"] = true
+	}
+	// clients may be resolved from the X-Forwarded-For chain
+	if p["remote_host"] {
+		p["x_forwarded_for"] = true
+	}
+	return p
+}
+
+// keeps : Whether the group fills a needed field
+func (p projection) keeps(group string) bool {
+	if p == nil {
+		return group != ""
+	}
+	fields, ok := groupFields[group]
+	if !ok {
+		return p[group]
+	}
+	for _, field := range fields {
+		if p[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// names : The group names, blank for the groups not kept, so that
+// parseNamedFields skips them
+func (p projection) names(names []string) []string {
+	if p == nil {
+		return names
+	}
+	projected := make([]string, len(names))
+	for i, name := range names {
+		if p.keeps(name) {
+			projected[i] = name
+		}
+	}
+	return projected
+}
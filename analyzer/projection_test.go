@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_projection(t *testing.T) {
+	nginx, err := formats.Nginx(`$remote_addr [$time_local] "$request" $status $body_bytes_sent "$http_user_agent" cache=$upstream_cache_status`)
+	if err != nil {
+		t.Fatalf("formats.Nginx() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		config *LogAnalyzerConfig
+		text   string
+		want   *Line
+	}{
+		{
+			name:   "positional groups",
+			config: &LogAnalyzerConfig{Format: formats.CombinedLog, Fields: []string{"remote_host", "url"}},
+			text:   `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574 "-" "Mozilla/5.0"`,
+			want:   &Line{RemoteHost: "177.71.128.21", URL: "/intranet-analytics/"},
+		},
+		{
+			name:   "named groups",
+			config: &LogAnalyzerConfig{Format: nginx, Fields: []string{"url", "status", "upstream_cache_status"}},
+			text:   `10.0.0.1 [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 120 "curl/7.64.0" cache=HIT`,
+			want: &Line{
+				Method:   "GET",
+				Path:     "/a",
+				Protocol: "HTTP/1.1",
+				URL:      "/a",
+				Status:   200,
+				Extras:   map[string]string{"upstream_cache_status": "HIT"},
+			},
+		},
+		{
+			name:   "JSON",
+			config: &LogAnalyzerConfig{JSON: true, Fields: []string{"remote_host", "original_bytes", "request_id"}},
+			text:   `{"ip": "10.0.0.1", "path": "/a", "status": 200, "bytes": 120, "ua": "curl/7.64.0", "request_id": "r1", "pid": 7}`,
+			want:   &Line{RemoteHost: "10.0.0.1", Bytes: 120, Extras: map[string]string{"request_id": "r1"}},
+		},
+		{
+			name:   "logfmt",
+			config: &LogAnalyzerConfig{Logfmt: true, Fields: []string{"request", "duration"}},
+			text:   `at=info method=GET path="/a" fwd="10.0.0.1" dyno=web.1 service=25ms status=200 bytes=120`,
+			want:   &Line{Method: "GET", Path: "/a", URL: "/a", Duration: 25e6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogAnalyzer(tt.config)
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			got, err := l.(*logAnalyzer).newLineParser()(tt.text)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_projection_w3c(t *testing.T) {
+	parse := w3cFormat{}.project(newProjection([]string{"remote_host", "time"})).newParser(nil)
+	if _, err := parse("#Fields: date time c-ip cs-method cs-uri-stem sc-status cs(User-Agent)"); err != errHeaderLine {
+		t.Fatalf("parse() header error = %v", err)
+	}
+	got, err := parse("2018-07-10 20:21:28 10.0.0.1 GET /a 200 curl/7.64.0")
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if got.RemoteHost != "10.0.0.1" || got.Time.IsZero() || got.URL != "" || got.Status != 0 || got.UserAgent != "" {
+		t.Errorf("parse() = %+v, want the client and time only", got)
+	}
+}
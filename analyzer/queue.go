@@ -0,0 +1,57 @@
+package analyzer
+
+import "sync/atomic"
+
+const (
+	// ErrInvalidQueuePolicy :
+	ErrInvalidQueuePolicy = "invalid queue policy"
+
+	// DefaultQueueSize : Lines buffered between reading and counting
+	DefaultQueueSize = 1024
+)
+
+// QueuePolicy : What happens to a parsed line when the queue to the
+// aggregation stage is full
+type QueuePolicy int
+
+const (
+	// QueueBlock : Wait for room, slowing reading down. Nothing is lost.
+	QueueBlock QueuePolicy = iota
+	// QueueDropNewest : Drop the incoming line
+	QueueDropNewest
+	// QueueDropOldest : Drop the oldest queued line to make room
+	QueueDropOldest
+)
+
+// newQueue : Returns a queue of the configured size
+func (l *logAnalyzer) newQueue() chan *Line {
+	return make(chan *Line, l.queueSize)
+}
+
+// enqueue : Queues a followed line, applying the queue policy when the queue
+// is full. Dropped lines are counted in the self-metrics.
+func (l *logAnalyzer) enqueue(queue chan *Line, line *Line) {
+	switch l.queuePolicy {
+	case QueueDropNewest:
+		select {
+		case queue <- line:
+		default:
+			atomic.AddInt64(&l.metrics.droppedLines, 1)
+		}
+	case QueueDropOldest:
+		for {
+			select {
+			case queue <- line:
+				return
+			default:
+			}
+			select {
+			case <-queue:
+				atomic.AddInt64(&l.metrics.droppedLines, 1)
+			default:
+			}
+		}
+	default:
+		queue <- line
+	}
+}
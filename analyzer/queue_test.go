@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_logAnalyzer_enqueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      QueuePolicy
+		wantQueued  []string
+		wantDropped int64
+	}{
+		{name: "drop newest", policy: QueueDropNewest, wantQueued: []string{"/1", "/2"}, wantDropped: 2},
+		{name: "drop oldest", policy: QueueDropOldest, wantQueued: []string{"/3", "/4"}, wantDropped: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+				LineRegex:   defaultLineRegex,
+				QueueSize:   2,
+				QueuePolicy: tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("logAnalyzer.enqueue() error = %v, error creating analyzer", err)
+			}
+			l := a.(*logAnalyzer)
+
+			queue := l.newQueue()
+			for _, url := range []string{"/1", "/2", "/3", "/4"} {
+				l.enqueue(queue, &Line{URL: url})
+			}
+			close(queue)
+
+			var got []string
+			for line := range queue {
+				got = append(got, line.URL)
+			}
+			if !reflect.DeepEqual(got, tt.wantQueued) {
+				t.Errorf("logAnalyzer.enqueue() queued = %v, want %v", got, tt.wantQueued)
+			}
+			if dropped := l.SelfMetrics().DroppedLines; dropped != tt.wantDropped {
+				t.Errorf("logAnalyzer.enqueue() dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestNewLogAnalyzer_queuePolicy(t *testing.T) {
+	_, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:   defaultLineRegex,
+		QueuePolicy: QueueDropOldest + 1,
+	})
+	if err == nil || err.Error() != ErrInvalidQueuePolicy {
+		t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, ErrInvalidQueuePolicy)
+	}
+}
+
+func Test_logAnalyzer_Analyze_queuePolicy(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:   defaultLineRegex,
+		QueueSize:   1,
+		QueuePolicy: QueueDropNewest,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/programming-task.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	// batch analyses wait for room whatever the policy
+	if dropped := l.SelfMetrics().DroppedLines; got.UniqueIPCount != 11 || dropped != 0 {
+		t.Errorf("logAnalyzer.Analyze() = %d unique ips, %d dropped, want 11, 0", got.UniqueIPCount, dropped)
+	}
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import "io"
+
+// readAheadBlock : Bytes read ahead, and the error that ended the read
+type readAheadBlock struct {
+	data []byte
+	err  error
+}
+
+// readAheadReader : Reads a source block by block in a goroutine of its own,
+// ahead of the consumer: while one block is being parsed, the next one is
+// read into the other buffer (double buffering), so disk and network latency
+// overlap parsing instead of adding to it.
+type readAheadReader struct {
+	blocks chan readAheadBlock
+	// free : Buffers consumed, to be filled again
+	free chan []byte
+	done chan struct{}
+	// buffer, pending : The block being consumed, and its unread bytes
+	buffer  []byte
+	pending []byte
+	err     error
+}
+
+// newReadAheadReader : Reads src ahead, size bytes at a time. Close stops
+// reading.
+func newReadAheadReader(src io.Reader, size int) *readAheadReader {
+	r := &readAheadReader{
+		blocks: make(chan readAheadBlock),
+		free:   make(chan []byte, 2),
+		done:   make(chan struct{}),
+	}
+	r.free <- make([]byte, size)
+	r.free <- make([]byte, size)
+	go r.readAhead(src)
+	return r
+}
+
+func (r *readAheadReader) readAhead(src io.Reader) {
+	defer close(r.blocks)
+	for {
+		var buffer []byte
+		select {
+		case buffer = <-r.free:
+		case <-r.done:
+			return
+		}
+		// whole blocks, for large sequential reads
+		n, err := io.ReadFull(src, buffer)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		select {
+		case r.blocks <- readAheadBlock{data: buffer[:n], err: err}:
+		case <-r.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read : Implements io.Reader
+func (r *readAheadReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.buffer != nil {
+			r.free <- r.buffer[:cap(r.buffer)]
+			r.buffer = nil
+		}
+		block, ok := <-r.blocks
+		if !ok {
+			return 0, io.EOF
+		}
+		r.buffer, r.pending, r.err = block.data, block.data, block.err
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Close : Stops reading ahead; the source is left open
+func (r *readAheadReader) Close() error {
+	close(r.done)
+	return nil
+}
+
+// reader : The file, read ahead when ReadAheadSize is set. The returned
+// function stops reading ahead.
+func (l *logAnalyzer) reader(file io.Reader) (io.Reader, func()) {
+	if l.readAheadSize <= 0 {
+		return file, func() {}
+	}
+	r := newReadAheadReader(file, l.readAheadSize)
+	return r, func() { r.Close() }
+}
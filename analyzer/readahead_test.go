@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_readAheadReader(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef\n", 1000))
+	tests := []struct {
+		name string
+		data []byte
+		size int
+		// half : The source returns half the bytes asked for
+		half bool
+	}{
+		{name: "blocks of a few bytes", data: data, size: 7},
+		{name: "one block", data: data, size: 1 << 20},
+		{name: "short reads", data: data, size: 4096, half: true},
+		{name: "empty", data: []byte{}, size: 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src io.Reader = bytes.NewReader(tt.data)
+			if tt.half {
+				src = iotest.HalfReader(src)
+			}
+			r := newReadAheadReader(src, tt.size)
+			defer r.Close()
+			got, err := ioutil.ReadAll(iotest.OneByteReader(r))
+			if err != nil {
+				t.Fatalf("readAheadReader.Read() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("readAheadReader.Read() = %d bytes, want %d", len(got), len(tt.data))
+			}
+		})
+	}
+
+	// bytes read before an error come first
+	failure := errors.New("disk failure")
+	r := newReadAheadReader(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(failure)), 2)
+	got, err := ioutil.ReadAll(r)
+	if string(got) != "abc" || err != failure {
+		t.Errorf("readAheadReader.Read() = %q, %v, want abc, %v", got, err, failure)
+	}
+	r.Close()
+
+	// stopping early does not wait for the source to be read
+	r = newReadAheadReader(bytes.NewReader(data), 16)
+	if _, err := io.ReadFull(r, make([]byte, 20)); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+}
+
+func TestNewLogAnalyzer_readAhead(t *testing.T) {
+	var reports []*LogAnalytics
+	for _, size := range []int{0, 100} {
+		l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, ReadAheadSize: size, MostActiveIPsCount: 1, MostVisitedURLsCount: 3})
+		if err != nil {
+			t.Fatalf("NewLogAnalyzer() error = %v", err)
+		}
+		analytics, err := l.Analyze("./test-data/programming-task.log")
+		if err != nil {
+			t.Fatalf("Analyze() error = %v", err)
+		}
+		reports = append(reports, analytics)
+	}
+	if !reflect.DeepEqual(reports[0], reports[1]) {
+		t.Errorf("Analyze() read ahead = %+v, want %+v", reports[1], reports[0])
+	}
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactedQueryParams : Query parameters commonly carrying secrets
+const DefaultRedactedQueryParams = `(?i)token|password|session`
+
+// RedactionPolicy : Rules keeping secrets out of the URLs, referrers and
+// users the analyzer counts, so they never reach reports, sinks or batch state
+type RedactionPolicy struct {
+	// DropQueryParams : Query parameters whose (decoded) name matches are removed
+	DropQueryParams *regexp.Regexp
+	// HashEmails : Replace email addresses, plain or percent-encoded, by a hash
+	// of them, so different addresses still count apart, in URLs, referrers
+	// and remote users
+	HashEmails bool
+}
+
+var emailRegex = regexp.MustCompile(`[A-Za-z0-9._%+-]+(?:@|%40)[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// redact : Applies the redaction policy to the line, before anything else sees it
+func (l *logAnalyzer) redact(line *Line) {
+	if l.redaction == nil {
+		return
+	}
+	redacted := l.redaction.redactURL(line.URL)
+	if redacted != line.URL {
+		if line.Path != "" || line.RawQuery != "" {
+			line.Path, line.RawQuery = splitTarget(redacted)
+		}
+		line.URL = redacted
+	}
+	line.Referer = l.redaction.redactURL(line.Referer)
+	if l.redaction.HashEmails {
+		line.RemoteUser = emailRegex.ReplaceAllStringFunc(line.RemoteUser, hashEmail)
+	}
+}
+
+func (p *RedactionPolicy) redactURL(raw string) string {
+	if p.DropQueryParams != nil {
+		raw = dropQueryParams(raw, p.DropQueryParams)
+	}
+	if p.HashEmails {
+		raw = emailRegex.ReplaceAllStringFunc(raw, hashEmail)
+	}
+	return raw
+}
+
+// dropQueryParams : Removes the matching parameters, keeping the order and
+// spelling of the others
+func dropQueryParams(raw string, names *regexp.Regexp) string {
+	start := strings.IndexByte(raw, '?')
+	if start < 0 {
+		return raw
+	}
+	query, fragment := raw[start+1:], ""
+	if end := strings.IndexByte(query, '#'); end >= 0 {
+		query, fragment = query[:end], query[end:]
+	}
+
+	var kept []string
+	for _, param := range strings.Split(query, "&") {
+		name := param
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			name = param[:i]
+		}
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if name != "" && names.MatchString(name) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+
+	redacted := raw[:start]
+	if len(kept) > 0 {
+		redacted += "?" + strings.Join(kept, "&")
+	}
+	return redacted + fragment
+}
+
+func hashEmail(email string) string {
+	email = strings.ToLower(strings.Replace(email, "%40", "@", 1))
+	sum := sha256.Sum256([]byte(email))
+	return "email-" + hex.EncodeToString(sum[:6])
+}
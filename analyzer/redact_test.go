@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func Test_logAnalyzer_redact(t *testing.T) {
+	policy := &RedactionPolicy{
+		DropQueryParams: regexp.MustCompile(DefaultRedactedQueryParams),
+		HashEmails:      true,
+	}
+	tests := []struct {
+		name   string
+		policy *RedactionPolicy
+		line   *Line
+		want   *Line
+	}{
+		{
+			name:   "no policy",
+			policy: nil,
+			line:   &Line{Method: "GET", Path: "/login", RawQuery: "token=abc", Protocol: "HTTP/1.1", URL: "/login?token=abc"},
+			want:   &Line{Method: "GET", Path: "/login", RawQuery: "token=abc", Protocol: "HTTP/1.1", URL: "/login?token=abc"},
+		},
+		{
+			name:   "nothing to redact",
+			policy: policy,
+			line:   &Line{Method: "GET", Path: "/search", RawQuery: "q=go", Protocol: "HTTP/1.1", URL: "/search?q=go", Referer: "-"},
+			want:   &Line{Method: "GET", Path: "/search", RawQuery: "q=go", Protocol: "HTTP/1.1", URL: "/search?q=go", Referer: "-"},
+		},
+		{
+			name:   "secret params dropped",
+			policy: policy,
+			line:   &Line{Method: "GET", Path: "/a", RawQuery: "access_token=x&page=2&Password=y", Protocol: "HTTP/1.1", URL: "/a?access_token=x&page=2&Password=y"},
+			want:   &Line{Method: "GET", Path: "/a", RawQuery: "page=2", Protocol: "HTTP/1.1", URL: "/a?page=2"},
+		},
+		{
+			name:   "only secret params, encoded name and fragment",
+			policy: policy,
+			line:   &Line{URL: "/a?%73ession=1#top", Referer: "http://example.net/?sessionid=2"},
+			want:   &Line{URL: "/a#top", Referer: "http://example.net/"},
+		},
+		{
+			name:   "emails hashed, case insensitive and percent-encoded",
+			policy: policy,
+			line:   &Line{URL: "/users/Jo.Doe@Example.com", Referer: "/unsubscribe?to=jo.doe%40example.com"},
+			want:   &Line{URL: "/users/email-67f823351899", Referer: "/unsubscribe?to=email-67f823351899"},
+		},
+		{
+			name:   "remote users hashed when emails",
+			policy: policy,
+			line:   &Line{URL: "/", RemoteUser: "Jo.Doe@example.com"},
+			want:   &Line{URL: "/", RemoteUser: "email-67f823351899"},
+		},
+		{
+			name:   "other remote users kept",
+			policy: policy,
+			line:   &Line{URL: "/", RemoteUser: "jdoe"},
+			want:   &Line{URL: "/", RemoteUser: "jdoe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &logAnalyzer{redaction: tt.policy}
+			l.redact(tt.line)
+			if !reflect.DeepEqual(tt.line, tt.want) {
+				t.Errorf("logAnalyzer.redact() = %+v, want %+v", tt.line, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// DefaultReferrerSpam : Well known referrer spam domains
+var DefaultReferrerSpam = []string{
+	"semalt.com", "semalt.semalt.com", "buttons-for-website.com", "buttons-for-your-website.com",
+	"darodar.com", "ilovevitaly.com", "ilovevitaly.ru", "priceg.com", "hulfingtonpost.com",
+	"best-seo-offer.com", "best-seo-solution.com", "free-share-buttons.com", "get-free-traffic-now.com",
+	"7makemoneyonline.com", "blackhatworth.com", "social-buttons.com", "simple-share-buttons.com",
+	"trafficmonetize.org", "webmonetizer.net", "floating-share-buttons.com", "event-tracking.com",
+}
+
+// ReadReferrerBlocklist : Reads spam domains, one per line. Blank lines and
+// lines starting with # are skipped.
+func ReadReferrerBlocklist(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		domain := strings.TrimSpace(scanner.Text())
+		if domain == "" || strings.HasPrefix(domain, "#") {
+			continue
+		}
+		domains = append(domains, domain)
+	}
+	return domains, scanner.Err()
+}
+
+// referrerBlocklist : Domains whose referrals are spam, subdomains included
+type referrerBlocklist map[string]bool
+
+func newReferrerBlocklist(domains []string) referrerBlocklist {
+	blocklist := make(referrerBlocklist, len(domains))
+	for _, domain := range domains {
+		blocklist[strings.ToLower(strings.TrimSuffix(domain, "."))] = true
+	}
+	return blocklist
+}
+
+// blocks : Whether the host or one of its parent domains is listed
+func (b referrerBlocklist) blocks(host string) bool {
+	for host != "" {
+		if b[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return false
+}
+
+// referrerHost : The lower case host a referrer URL points at, "" when there
+// is no referrer
+func referrerHost(referer string) string {
+	if referer == "" || referer == "-" {
+		return ""
+	}
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
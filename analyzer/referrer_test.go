@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadReferrerBlocklist(t *testing.T) {
+	got, err := ReadReferrerBlocklist(strings.NewReader("# spam\nspam.example\n\n  Other.Example.  \n"))
+	if err != nil {
+		t.Fatalf("ReadReferrerBlocklist() error = %v", err)
+	}
+	want := []string{"spam.example", "Other.Example."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadReferrerBlocklist() = %v, want %v", got, want)
+	}
+}
+
+func Test_logAnalyzer_report_referrers(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:         defaultLineRegex,
+		TopReferrersCount: 2,
+		ReferrerBlocklist: append([]string{"Other.Example."}, DefaultReferrerSpam...),
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+	agg := newAggregate()
+	referrers := []string{
+		"https://www.Google.com/search?q=logs",
+		"https://www.google.com/",
+		"http://example.net/faq/",
+		"-",
+		"",
+		"http://semalt.semalt.com/crawler.php?u=http://example.net",
+		"http://www.other.example/",
+		"http://darodar.com/",
+		"not a url",
+	}
+	for _, referer := range referrers {
+		l.consolidate(agg, &Line{RemoteHost: "177.71.128.21", Referer: referer})
+	}
+
+	want := &LogAnalytics{
+		UniqueIPCount: 1,
+		TopReferrers:  []string{"www.google.com", "example.net"},
+		SpamReferrals: 3,
+	}
+	if got := l.report(agg); !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() = %+v, want %+v", got, want)
+	}
+}
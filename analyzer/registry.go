@@ -0,0 +1,208 @@
+package analyzer
+
+import (
+	"regexp/syntax"
+	"sort"
+	"sync"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+// Format : A log format parsing lines in place of a line regex, e.g. of a
+// third party, set as LogAnalyzerConfig.LineFormat or registered by name with
+// RegisterFormat. ParseLine returns ErrLineNotMatched, or an error of its own,
+// for lines it cannot read, which are skipped. It may be called from several
+// goroutines at once, e.g. by AnalyzeBatch, and the lines it returns must not
+// be reused.
+type Format interface {
+	ParseLine(text string) (*Line, error)
+}
+
+// FormatFields : Implemented by formats telling the line fields their
+// ParseLine fills, named as the groups of line regexes, e.g. "duration" for
+// latency reports. Reports needing other fields are left out.
+type FormatFields interface {
+	LineFields() []string
+}
+
+// FormatExample : Implemented by formats giving a line of theirs, e.g. to
+// document them with cmd/formatdoc
+type FormatExample interface {
+	ExampleLine() string
+}
+
+// ParseLine : Implements Format
+func (p *Parser) ParseLine(text string) (*Line, error) {
+	return p.Parse(text)
+}
+
+// LineFields : Implements FormatFields
+func (p *Parser) LineFields() []string {
+	return p.Fields
+}
+
+// formatParser : The parser of a format
+func formatParser(format Format) *Parser {
+	if parser, ok := format.(*Parser); ok {
+		return parser
+	}
+	parser := &Parser{Parse: format.ParseLine}
+	if fields, ok := format.(FormatFields); ok {
+		parser.Fields = fields.LineFields()
+	}
+	return parser
+}
+
+// regexFormat : A format of the formats package, read with its line regex
+type regexFormat struct {
+	format *formats.Format
+}
+
+// RegexFormat : The format reading lines with the line regex of format, as
+// registered for the presets of the formats package under their names
+func RegexFormat(format *formats.Format) Format {
+	return regexFormat{format}
+}
+
+// ParseLine : Implements Format
+func (f regexFormat) ParseLine(text string) (*Line, error) {
+	return parseLine(f.format.LineRegex, text)
+}
+
+// LineFields : Implements FormatFields, the fields of the groups of the line
+// regex, less those it always captures empty, e.g. the referrer and user
+// agent of the Common Log Format
+func (f regexFormat) LineFields() []string {
+	empty := emptyGroups(f.format.LineRegex.String())
+	var fields []string
+	seen := make(map[string]bool)
+	for i, name := range positionalNames(groupNames(f.format.LineRegex)) {
+		if name == "" || empty[i] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// ExampleLine : Implements FormatExample
+func (f regexFormat) ExampleLine() string {
+	return f.format.Example
+}
+
+// emptyGroups : The groups of the regex that can only capture empty text,
+// by number, e.g. () standing in for fields a format does not log
+func emptyGroups(expr string) map[int]bool {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	empty := make(map[int]bool)
+	var walk func(re *syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		if re.Op == syntax.OpCapture && len(re.Sub) == 1 && re.Sub[0].Op == syntax.OpEmptyMatch {
+			empty[re.Cap] = true
+		}
+		for _, sub := range re.Sub {
+			walk(sub)
+		}
+	}
+	walk(re)
+	return empty
+}
+
+func init() {
+	for _, format := range formats.Presets {
+		RegisterFormat(format.Name, RegexFormat(format))
+	}
+}
+
+// FormatRegistry : Formats by name, e.g. proprietary ones of an
+// application, to select as LogAnalyzerConfig.FormatName. Lookups in a
+// registry of NewFormatRegistry fall back to the global registry of
+// RegisterFormat, so a registry of an analyzer adds formats to the global
+// ones, or replaces some. It is safe for concurrent use.
+type FormatRegistry struct {
+	mu      sync.RWMutex
+	formats map[string]Format
+	// parent : The registry lookups fall back to, nil for the global one
+	parent *FormatRegistry
+}
+
+// globalFormats : The formats of RegisterFormat, the presets among them
+var globalFormats = &FormatRegistry{formats: make(map[string]Format)}
+
+// NewFormatRegistry : An empty registry, falling back to the global one
+func NewFormatRegistry() *FormatRegistry {
+	return &FormatRegistry{formats: make(map[string]Format), parent: globalFormats}
+}
+
+// Register : Makes the format available by name. It panics when the name is
+// empty or already registered in r, as two formats claiming one name is a
+// programming error; a name of the global registry is replaced in r.
+func (r *FormatRegistry) Register(name string, format Format) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name == "" || format == nil {
+		panic("analyzer: RegisterFormat of an empty name or nil format")
+	}
+	if _, ok := r.formats[name]; ok {
+		panic("analyzer: RegisterFormat called twice for format " + name)
+	}
+	r.formats[name] = format
+}
+
+// Lookup : The format registered under name, in r or else the global
+// registry, nil when none is. A nil registry looks up the global one.
+func (r *FormatRegistry) Lookup(name string) Format {
+	if r == nil {
+		r = globalFormats
+	}
+	r.mu.RLock()
+	format := r.formats[name]
+	r.mu.RUnlock()
+	if format == nil && r.parent != nil {
+		return r.parent.Lookup(name)
+	}
+	return format
+}
+
+// Names : The names of the formats r looks up, sorted
+func (r *FormatRegistry) Names() []string {
+	if r == nil {
+		r = globalFormats
+	}
+	var names []string
+	if r.parent != nil {
+		names = r.parent.Names()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name := range r.formats {
+		if r.parent == nil || r.parent.Lookup(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterFormat : Makes the format available by name to all analyzers,
+// e.g. to the "format" of config files, typically from the init function of
+// the package (or plugin) implementing it. It panics when the name is empty
+// or already registered, the presets' names included, as two formats
+// claiming one name is a programming error.
+func RegisterFormat(name string, format Format) {
+	globalFormats.Register(name, format)
+}
+
+// LookupFormat : The format registered globally under name, nil when none is
+func LookupFormat(name string) Format {
+	return globalFormats.Lookup(name)
+}
+
+// RegisteredFormats : The names of the globally registered formats, sorted
+func RegisteredFormats() []string {
+	return globalFormats.Names()
+}
@@ -0,0 +1,195 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+// pipeFormat : Lines of client|unix seconds|method|path|status|milliseconds
+type pipeFormat struct{}
+
+func (pipeFormat) ParseLine(text string) (*Line, error) {
+	parts := strings.Split(text, "|")
+	if len(parts) != 6 {
+		return nil, errors.New(ErrLineNotMatched)
+	}
+	line := &Line{RemoteHost: parts[0], Time: parseTime(parts[1]), Status: parseInt(parts[4])}
+	line.URL = parts[3]
+	line.setRequest(parts[2], parts[3], "")
+	if ms, err := strconv.Atoi(parts[5]); err == nil {
+		line.Duration = time.Duration(ms) * time.Millisecond
+	}
+	return line, nil
+}
+
+func (pipeFormat) LineFields() []string {
+	return []string{"remote_host", "time", "request", "url", "status", "duration"}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("test-pipe", pipeFormat{})
+	defer func() {
+		globalFormats.mu.Lock()
+		delete(globalFormats.formats, "test-pipe")
+		globalFormats.mu.Unlock()
+	}()
+
+	if got := LookupFormat("test-pipe"); got != (pipeFormat{}) {
+		t.Errorf("LookupFormat() = %v, want the registered format", got)
+	}
+	if got := LookupFormat("unknown"); got != nil {
+		t.Errorf("LookupFormat() = %v, want nil for an unknown format", got)
+	}
+	if got := RegisteredFormats(); !containsString(got, "test-pipe") {
+		t.Errorf("RegisteredFormats() = %v, want test-pipe among them", got)
+	}
+
+	for name, register := range map[string]func(){
+		"twice":      func() { RegisterFormat("test-pipe", pipeFormat{}) },
+		"empty name": func() { RegisterFormat("", pipeFormat{}) },
+		"nil format": func() { RegisterFormat("test-nil", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterFormat() %s did not panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestNewLogAnalyzer_lineFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "access.log")
+	lines := []string{
+		"10.0.0.1|1531254088|GET|/a|200|120",
+		"10.0.0.2|1531254089|GET|/b|500|2500",
+		"not a pipe line",
+		"10.0.0.1|1531254090|POST|/a|201|40",
+	}
+	if err := ioutil.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{LineFormat: pipeFormat{}, MostVisitedURLsCount: 1, SlowestRequestsCount: 1})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	analytics, err := a.Analyze(filePath)
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	if analytics.UniqueIPCount != 2 || !reflect.DeepEqual(analytics.MostVisitedURLs, []string{"/a"}) {
+		t.Errorf("logAnalyzer.Analyze() = %d IPs and %v, want 2 IPs and /a", analytics.UniqueIPCount, analytics.MostVisitedURLs)
+	}
+	// the format logs durations, as its fields tell
+	if len(analytics.SlowestRequests) != 1 || analytics.SlowestRequests[0].URL != "/b" {
+		t.Errorf("logAnalyzer.Analyze() slowest requests = %v, want /b", analytics.SlowestRequests)
+	}
+	if got := a.SelfMetrics().ParseErrors; got != 1 {
+		t.Errorf("logAnalyzer.SelfMetrics() parse errors = %d, want 1", got)
+	}
+
+	if _, err := NewLogAnalyzer(&LogAnalyzerConfig{LineFormat: pipeFormat{}, JSON: true}); err == nil || err.Error() != ErrConflictingFormats {
+		t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, ErrConflictingFormats)
+	}
+}
+
+func TestRegexFormat_presets(t *testing.T) {
+	for _, preset := range formats.Presets {
+		format := LookupFormat(preset.Name)
+		if format == nil {
+			t.Errorf("LookupFormat(%q) = nil, want the preset", preset.Name)
+			continue
+		}
+		if _, err := format.ParseLine(format.(FormatExample).ExampleLine()); err != nil {
+			t.Errorf("%s ParseLine() of its example error = %v", preset.Name, err)
+		}
+	}
+
+	// groups always captured empty fill no field
+	want := []string{"remote_host", "remote_user", "time", "method", "url", "protocol", "status", "bytes"}
+	if got := RegexFormat(formats.CommonLog).(FormatFields).LineFields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RegexFormat(CommonLog).LineFields() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatRegistry(t *testing.T) {
+	r := NewFormatRegistry()
+	r.Register("test-pipe", pipeFormat{})
+	r.Register("common", pipeFormat{})
+
+	if got := r.Lookup("test-pipe"); got != (pipeFormat{}) {
+		t.Errorf("FormatRegistry.Lookup() = %v, want the registered format", got)
+	}
+	// the registry replaces the global formats of its names, and falls
+	// back to the others
+	if got := r.Lookup("common"); got != (pipeFormat{}) {
+		t.Errorf("FormatRegistry.Lookup() = %v, want the format replacing the preset", got)
+	}
+	if got := r.Lookup("combined"); got == nil {
+		t.Errorf("FormatRegistry.Lookup() = nil, want the global format")
+	}
+	if got := LookupFormat("test-pipe"); got != nil {
+		t.Errorf("LookupFormat() = %v, want nil for a format of another registry", got)
+	}
+	names := r.Names()
+	if !containsString(names, "test-pipe") || !containsString(names, "combined") || len(names) != len(RegisteredFormats())+1 {
+		t.Errorf("FormatRegistry.Names() = %v, want test-pipe and the global formats", names)
+	}
+
+	dir, err := ioutil.TempDir("", "format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, []byte("10.0.0.1|1531254088|GET|/a|200|120\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{FormatName: "test-pipe", Formats: r})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	if analytics, err := a.Analyze(filePath); err != nil || analytics.UniqueIPCount != 1 {
+		t.Errorf("logAnalyzer.Analyze() = %v, %v, want the line of the registered format", analytics, err)
+	}
+
+	// presets are read with their line regex
+	a, err = NewLogAnalyzer(&LogAnalyzerConfig{FormatName: "combined"})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	if l := a.(*logAnalyzer); l.lineRegex != formats.CombinedLog.LineRegex || l.lineFields != nil {
+		t.Errorf("NewLogAnalyzer() of the combined preset reads lines with %v, want its line regex", l.lineRegex)
+	}
+
+	for _, tt := range []struct {
+		config  *LogAnalyzerConfig
+		wantErr string
+	}{
+		{config: &LogAnalyzerConfig{FormatName: "test-pipe"}, wantErr: "test-pipe: " + ErrUnknownFormat},
+		{config: &LogAnalyzerConfig{FormatName: "combined", LineFormat: pipeFormat{}}, wantErr: ErrConflictingFormats},
+		{config: &LogAnalyzerConfig{FormatName: "combined", JSON: true}, wantErr: ErrConflictingFormats},
+	} {
+		if _, err := NewLogAnalyzer(tt.config); err == nil || err.Error() != tt.wantErr {
+			t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import "github.com/pkg/errors"
+
+// reloadableSettings : Settings that only shape reports, so they can change
+// while an analyzer runs without invalidating what it accumulated
+type reloadableSettings struct {
+	mostActiveIPsCount      int
+	mostVisitedURLsCount    int
+	mostActiveNetworksCount int
+	topEnrichedValuesCount  int
+	topScoredIPsCount       int
+	topLandingPagesCount    int
+	topExitPagesCount       int
+	topReferrersCount       int
+	topCampaignsCount       int
+	topErrorMessagesCount   int
+	upstreamsCount          int
+	topUsersCount           int
+	nonReusingClientsCount  int
+	uncompressedURLsCount   int
+	slowestRequestsCount    int
+	largestResponsesCount   int
+	annotations             []*Annotation
+	topMoversCount          int
+}
+
+func newReloadableSettings(config *LogAnalyzerConfig) *reloadableSettings {
+	return &reloadableSettings{
+		mostActiveIPsCount:      config.MostActiveIPsCount,
+		mostVisitedURLsCount:    config.MostVisitedURLsCount,
+		mostActiveNetworksCount: config.MostActiveNetworksCount,
+		topEnrichedValuesCount:  config.TopEnrichedValuesCount,
+		topScoredIPsCount:       config.TopScoredIPsCount,
+		topLandingPagesCount:    config.TopLandingPagesCount,
+		topExitPagesCount:       config.TopExitPagesCount,
+		topReferrersCount:       config.TopReferrersCount,
+		topCampaignsCount:       config.TopCampaignsCount,
+		topErrorMessagesCount:   config.TopErrorMessagesCount,
+		upstreamsCount:          config.UpstreamsCount,
+		topUsersCount:           config.TopUsersCount,
+		nonReusingClientsCount:  config.NonReusingClientsCount,
+		uncompressedURLsCount:   config.UncompressedURLsCount,
+		slowestRequestsCount:    config.SlowestRequestsCount,
+		largestResponsesCount:   config.LargestResponsesCount,
+		annotations:             config.Annotations,
+		topMoversCount:          config.TopMoversCount,
+	}
+}
+
+func (l *logAnalyzer) reloadable() *reloadableSettings {
+	return l.settings.Load().(*reloadableSettings)
+}
+
+func (l *logAnalyzer) Reconfigure(config *LogAnalyzerConfig) error {
+	if config == nil {
+		return errors.New(ErrConfigIsRequired)
+	}
+
+	l.settings.Store(newReloadableSettings(config))
+	return nil
+}
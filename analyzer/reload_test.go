@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_logAnalyzer_Reconfigure(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:          defaultLineRegex,
+		MostActiveIPsCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Reconfigure() error = %v, error creating analyzer", err)
+	}
+
+	if err := l.Reconfigure(nil); err == nil || err.Error() != errors.New(ErrConfigIsRequired).Error() {
+		t.Errorf("logAnalyzer.Reconfigure() error = %v, wantErr %v", err, ErrConfigIsRequired)
+	}
+
+	err = l.Reconfigure(&LogAnalyzerConfig{
+		MostVisitedURLsCount:    1,
+		MostActiveNetworksCount: 1,
+		// not reloadable, ignored
+		KeepRawURLs: true,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Reconfigure() error = %v", err)
+	}
+	got, err := l.Analyze("./test-data/encoded-urls.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	want := &LogAnalytics{
+		UniqueIPCount:      2,
+		MostVisitedURLs:    []string{"/café"},
+		MostActiveNetworks: []string{"168.41.191.0/24"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.Analyze() after Reconfigure() = %+v, want %+v", got, want)
+	}
+}
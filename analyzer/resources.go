@@ -0,0 +1,52 @@
+package analyzer
+
+// ResourceStats : What the aggregate behind a report held, to reason about
+// the memory a run needs and how approximate its analytics are. Unlike the
+// self-metrics, they only depend on the lines analyzed, so the same logs
+// always report the same stats.
+type ResourceStats struct {
+	// Keys : Distinct keys held per table, e.g. "ips", "urls", "referrers",
+	// or "enriched.<field>" for the values of an enrichment field. Tables
+	// without keys are left out. Tables only grow during a run, so these are
+	// also their peak sizes, except for open sessions.
+	Keys map[string]int
+	// PeakOpenSessions : The most sessions open at once, summed over the
+	// aggregates merged into the report, which are held at the same time
+	PeakOpenSessions int
+	// EstimatedMemoryBytes : Estimated memory held by the keys, as
+	// SelfMetrics.AggregateBytes
+	EstimatedMemoryBytes int64
+	// OverflowedURLs : Hits of URLs beyond MaxURLs, counted under OverflowURL
+	// instead of their own URL
+	OverflowedURLs int
+}
+
+// keyCounts : Distinct keys per table of the aggregate, the empty tables
+// left out
+func (a *aggregate) keyCounts() map[string]int {
+	counts := map[string]int{
+		"urls":          a.urlHits.len(),
+		"upstreams":     len(a.upstreams),
+		"content_types": len(a.compression),
+		"open_sessions": len(a.openSessions),
+	}
+	for name, hits := range a.hitTables() {
+		counts[name] = len(hits)
+	}
+	for name, n := range counts {
+		if n == 0 {
+			delete(counts, name)
+		}
+	}
+	return counts
+}
+
+// resourceReport : The resource stats of the aggregate
+func resourceReport(agg *aggregate) *ResourceStats {
+	return &ResourceStats{
+		Keys:                 agg.keyCounts(),
+		PeakOpenSessions:     agg.peakOpenSessions,
+		EstimatedMemoryBytes: int64(agg.keyBytes() + agg.keys()*aggregateKeyOverhead),
+		OverflowedURLs:       agg.overflowedURLs,
+	}
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_report_resources(t *testing.T) {
+	start := time.Date(2018, 7, 10, 10, 0, 0, 0, time.UTC)
+	pageview := func(ip, ua, url string, minutes int) *Line {
+		return &Line{RemoteHost: ip, Time: start.Add(time.Duration(minutes) * time.Minute), Method: "GET", Path: url, URL: url, Status: 200, UserAgent: ua}
+	}
+	lines := []*Line{
+		pageview("1.1.1.1", "Firefox", "/", 0),
+		pageview("1.1.1.1", "Chrome", "/", 1),
+		pageview("2.2.2.2", "Firefox", "/docs/", 2),
+		pageview("2.2.2.2", "Firefox", "/blog/", 3),
+		pageview("2.2.2.2", "Firefox", "/pricing", 4),
+		pageview("1.1.1.1", "Firefox", "/docs/", 5),
+		// more than 30 minutes later, the other sessions are closed
+		pageview("1.1.1.1", "Firefox", "/pricing", 50),
+	}
+
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:     defaultLineRegex,
+		MaxURLs:       3,
+		Sessions:      &SessionRules{},
+		ResourceStats: true,
+	})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	l := a.(*logAnalyzer)
+	agg := newAggregate()
+	for _, line := range lines {
+		l.consolidate(agg, line)
+	}
+
+	want := &ResourceStats{
+		// "/", "/docs/", "/blog/" and OverflowURL
+		Keys:             map[string]int{"ips": 2, "urls": 4, "landing_pages": 2, "exit_pages": 3, "open_sessions": 1},
+		PeakOpenSessions: 3,
+		OverflowedURLs:   2,
+	}
+	got := l.report(agg).Resources
+	if got == nil {
+		t.Fatalf("logAnalyzer.report() resources = nil, want %+v", want)
+	}
+	if got.EstimatedMemoryBytes <= int64(agg.keys()*aggregateKeyOverhead) {
+		t.Errorf("logAnalyzer.report() estimated memory = %d, want more than the overhead of %d keys", got.EstimatedMemoryBytes, agg.keys())
+	}
+	want.EstimatedMemoryBytes = got.EstimatedMemoryBytes
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() resources = %+v, want %+v", got, want)
+	}
+
+	// stored aggregates keep the peak and the overflow, their open sessions
+	// stored as closed ones
+	data, err := json.Marshal(agg)
+	if err != nil {
+		t.Fatalf("aggregate.MarshalJSON() error = %v", err)
+	}
+	restored := newAggregate()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("aggregate.UnmarshalJSON() error = %v", err)
+	}
+	got = l.report(restored).Resources
+	if got.PeakOpenSessions != want.PeakOpenSessions || got.OverflowedURLs != want.OverflowedURLs {
+		t.Errorf("logAnalyzer.report() of a restored aggregate = %+v, want %+v", got, want)
+	}
+
+	// merged aggregates are held at once
+	merged := newAggregate()
+	merged.merge(agg)
+	merged.merge(restored)
+	got = l.report(merged).Resources
+	if got.PeakOpenSessions != 6 || got.OverflowedURLs != 4 {
+		t.Errorf("logAnalyzer.report() of merged aggregates = %+v, want 6 peak open sessions and 4 overflowed URLs", got)
+	}
+
+	l.resourceStats = false
+	if got := l.report(agg).Resources; got != nil {
+		t.Errorf("logAnalyzer.report() resources = %+v, want none when not set", got)
+	}
+}
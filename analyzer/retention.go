@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrInvalidRetention :
+	ErrInvalidRetention = "retention tiers must have increasing ages, and intervals multiple of the previous one"
+)
+
+// RetentionTier : Time series buckets younger than Age are kept at the
+// resolution of Interval, e.g. {time.Minute, 24 * time.Hour}
+type RetentionTier struct {
+	Interval time.Duration
+	Age      time.Duration
+}
+
+// checkRetention : Tiers go from the finest resolution, kept the shortest, to
+// the coarsest, each interval a multiple of the previous one, starting with
+// the time series interval
+func checkRetention(tiers []*RetentionTier, interval time.Duration) error {
+	var age time.Duration
+	for _, tier := range tiers {
+		if tier.Interval < interval || tier.Interval%interval != 0 || tier.Age <= age {
+			return errors.New(ErrInvalidRetention)
+		}
+		interval, age = tier.Interval, tier.Age
+	}
+	return nil
+}
+
+// bucketInterval : The interval of the bucket starting at start, as per the
+// tier of its age relative to now, Unix nanoseconds
+func (l *logAnalyzer) bucketInterval(start time.Time, now int64) time.Duration {
+	if tier := l.retentionTier(start, now); tier != nil {
+		return tier.Interval
+	}
+	return l.timeseriesInterval
+}
+
+// retentionTier : The tier of a bucket starting at start, nil when it is older
+// than all tiers, or when no retention is set
+func (l *logAnalyzer) retentionTier(start time.Time, now int64) *RetentionTier {
+	age := time.Unix(0, now).Sub(start)
+	for _, tier := range l.retention {
+		if age < tier.Age {
+			return tier
+		}
+	}
+	return nil
+}
+
+// compactTimeseries : Rolls the buckets of every series up into the interval
+// of the tier of their age, dropping the ones older than all tiers. Ages are
+// relative to now, the time of the latest line in Unix nanoseconds, so logs
+// replayed long after they were written are compacted alike.
+func (l *logAnalyzer) compactTimeseries(timeseries map[string]seriesHits, now int64) {
+	if len(l.retention) == 0 || now == 0 {
+		return
+	}
+	for series, buckets := range timeseries {
+		starts := make([]int64, 0, len(buckets))
+		for start := range buckets {
+			starts = append(starts, start)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+		for _, start := range starts {
+			counts := buckets[start]
+			startTime := time.Unix(start, 0).UTC()
+			tier := l.retentionTier(startTime, now)
+			if tier == nil {
+				delete(buckets, start)
+				continue
+			}
+			rolled := startTime.Truncate(tier.Interval).Unix()
+			if rolled == start {
+				continue
+			}
+			delete(buckets, start)
+			if _, ok := buckets[rolled]; !ok {
+				buckets[rolled] = counts
+				continue
+			}
+			mergeHits(buckets[rolled], counts)
+		}
+		if len(buckets) == 0 {
+			delete(timeseries, series)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_checkRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		tiers   []*RetentionTier
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "minutes then hours", tiers: []*RetentionTier{{time.Minute, 24 * time.Hour}, {time.Hour, 720 * time.Hour}}},
+		{name: "finer than the series", tiers: []*RetentionTier{{time.Second, time.Hour}}, wantErr: true},
+		{name: "not a multiple", tiers: []*RetentionTier{{time.Minute, time.Hour}, {90 * time.Second, 2 * time.Hour}}, wantErr: true},
+		{name: "ages not increasing", tiers: []*RetentionTier{{time.Minute, time.Hour}, {time.Hour, time.Hour}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRetention(tt.tiers, time.Minute); (err != nil) != tt.wantErr {
+				t.Errorf("checkRetention() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_compactTimeseries(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:          defaultLineRegex,
+		TimeseriesInterval: time.Minute,
+		TimeseriesRetention: []*RetentionTier{
+			{Interval: time.Minute, Age: 24 * time.Hour},
+			{Interval: time.Hour, Age: 30 * 24 * time.Hour},
+		},
+		DeviceTimeseries: true,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.compactTimeseries() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	now := time.Date(2018, 7, 10, 12, 0, 0, 0, time.UTC)
+	agg := newAggregate()
+	for _, at := range []time.Time{
+		now,
+		now.Add(-time.Minute),
+		// over a day old, rolled up per hour
+		now.Add(-25*time.Hour + 10*time.Minute),
+		now.Add(-25*time.Hour + 20*time.Minute),
+		// over 30 days old, dropped
+		now.Add(-31 * 24 * time.Hour),
+	} {
+		l.consolidate(agg, &Line{RemoteHost: "177.71.128.21", Time: at, UserAgent: "curl/7.58.0"})
+	}
+
+	l.compactTimeseries(agg.timeseries, agg.lastSeen)
+	want := seriesHits{
+		now.Add(-25 * time.Hour).Unix(): {"bot": 2},
+		now.Add(-time.Minute).Unix():    {"bot": 1},
+		now.Unix():                      {"bot": 1},
+	}
+	if got := agg.timeseries[DeviceSeries]; !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.compactTimeseries() = %v, want %v", got, want)
+	}
+}
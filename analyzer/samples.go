@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"container/heap"
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// lineSamples : Uniform samples of the lines read that matched the log format
+// and of those that did not, as logged. Lines are sampled as they are read,
+// before the aggregation stage, so the samples have a lock of their own.
+type lineSamples struct {
+	mu        sync.Mutex
+	matched   reservoir
+	unmatched reservoir
+	// matchedSize, unmatchedSize : The lines the samples keep, as configured
+	// when they were taken
+	matchedSize   int
+	unmatchedSize int
+}
+
+// sampledLine : A line and the random key it was sampled by
+type sampledLine struct {
+	Key  int64  `json:"key"`
+	Text string `json:"text"`
+}
+
+// reservoir : The lines of the highest random keys seen so far, as a
+// min-heap so the lowest one is replaced first. Every line being as likely
+// to draw a high key, they are a uniform sample, and reservoirs of different
+// sources merge into a sample of them all.
+type reservoir []*sampledLine
+
+func (r reservoir) Len() int            { return len(r) }
+func (r reservoir) Less(i, j int) bool  { return r[i].Key < r[j].Key }
+func (r reservoir) Swap(i, j int)       { r[i], r[j] = r[j], r[i] }
+func (r *reservoir) Push(x interface{}) { *r = append(*r, x.(*sampledLine)) }
+func (r *reservoir) Pop() interface{} {
+	old := *r
+	last := old[len(old)-1]
+	*r = old[:len(old)-1]
+	return last
+}
+
+// offer : Keeps the line when its key is among the n highest
+func (r *reservoir) offer(n int, key int64, text string) {
+	if n <= 0 {
+		return
+	}
+	if len(*r) < n {
+		heap.Push(r, &sampledLine{Key: key, Text: text})
+		return
+	}
+	for len(*r) > n {
+		heap.Pop(r)
+	}
+	if key > (*r)[0].Key {
+		(*r)[0] = &sampledLine{Key: key, Text: text}
+		heap.Fix(r, 0)
+	}
+}
+
+// merge : Offers the lines of other, keeping up to n lines
+func (r *reservoir) merge(n int, other reservoir) {
+	for _, s := range other {
+		r.offer(n, s.Key, s.Text)
+	}
+}
+
+// lines : The n sampled lines of highest keys, in no meaningful order
+func (r reservoir) lines(n int) []string {
+	sampled := make(reservoir, len(r))
+	copy(sampled, r)
+	sort.Slice(sampled, func(i, j int) bool { return sampled[i].Key > sampled[j].Key })
+	if n < len(sampled) {
+		sampled = sampled[:n]
+	}
+	lines := make([]string, len(sampled))
+	for i, s := range sampled {
+		lines[i] = s.Text
+	}
+	return lines
+}
+
+// sample : Offers the line read to the samples of the lines matched, or not
+// when err is set. Header lines are neither; nor are lines of a redacted log,
+// as they are sampled as logged.
+func (l *logAnalyzer) sample(samples *lineSamples, text string, err error) {
+	if l.redaction != nil || text == "" || err == errHeaderLine {
+		return
+	}
+	n, r, size := l.matchedSamples, &samples.matched, &samples.matchedSize
+	if err != nil {
+		n, r, size = l.unmatchedSamples, &samples.unmatched, &samples.unmatchedSize
+	}
+	if n <= 0 {
+		return
+	}
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	*size = n
+	r.offer(n, rand.Int63(), text)
+}
+
+// merge : Adds the lines sampled of other to s, keeping as many lines as
+// the larger of the two samples
+func (s *lineSamples) merge(other *lineSamples) {
+	other.mu.Lock()
+	v := other.serialized()
+	other.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mergeSerialized(v)
+}
+
+// mergeSerialized : Adds the serialized samples to s, the lock held
+func (s *lineSamples) mergeSerialized(v *lineSamplesJSON) {
+	if v.MatchedSize > s.matchedSize {
+		s.matchedSize = v.MatchedSize
+	}
+	if v.UnmatchedSize > s.unmatchedSize {
+		s.unmatchedSize = v.UnmatchedSize
+	}
+	s.matched.merge(s.matchedSize, v.Matched)
+	s.unmatched.merge(s.unmatchedSize, v.Unmatched)
+}
+
+// replace : Replaces the lines sampled with those of other
+func (s *lineSamples) replace(other *lineSamples) {
+	s.mu.Lock()
+	s.matched, s.unmatched = nil, nil
+	s.matchedSize, s.unmatchedSize = 0, 0
+	s.mu.Unlock()
+	s.merge(other)
+}
+
+// report : The lines sampled, up to the configured sizes
+func (s *lineSamples) report(matched, unmatched int) (matchedLines, unmatchedLines []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if matched > 0 && len(s.matched) > 0 {
+		matchedLines = s.matched.lines(matched)
+	}
+	if unmatched > 0 && len(s.unmatched) > 0 {
+		unmatchedLines = s.unmatched.lines(unmatched)
+	}
+	return matchedLines, unmatchedLines
+}
+
+// empty : Whether no line was sampled
+func (s *lineSamples) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.matched) == 0 && len(s.unmatched) == 0
+}
+
+// lineSamplesJSON : The serialized form of line samples
+type lineSamplesJSON struct {
+	Matched       reservoir `json:"matched,omitempty"`
+	Unmatched     reservoir `json:"unmatched,omitempty"`
+	MatchedSize   int       `json:"matchedSize,omitempty"`
+	UnmatchedSize int       `json:"unmatchedSize,omitempty"`
+}
+
+// serialized : A copy of the samples, the lock held
+func (s *lineSamples) serialized() *lineSamplesJSON {
+	return &lineSamplesJSON{
+		Matched:       append(reservoir(nil), s.matched...),
+		Unmatched:     append(reservoir(nil), s.unmatched...),
+		MatchedSize:   s.matchedSize,
+		UnmatchedSize: s.unmatchedSize,
+	}
+}
+
+// MarshalJSON : Implements json.Marshaler
+func (s *lineSamples) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.serialized())
+}
+
+// UnmarshalJSON : Implements json.Unmarshaler
+func (s *lineSamples) UnmarshalJSON(data []byte) error {
+	var v lineSamplesJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matched, s.unmatched = nil, nil
+	s.matchedSize, s.unmatchedSize = 0, 0
+	s.mergeSerialized(&v)
+	return nil
+}
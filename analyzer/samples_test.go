@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_reservoir(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		keys []int64
+		want []string
+	}{
+		{name: "fewer than n", n: 3, keys: []int64{2, 1}, want: []string{"2", "1"}},
+		{name: "highest keys kept", n: 2, keys: []int64{1, 5, 3, 4, 2}, want: []string{"5", "4"}},
+		{name: "none", n: 0, keys: []int64{1}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r reservoir
+			for _, key := range tt.keys {
+				r.offer(tt.n, key, string(rune('0'+key)))
+			}
+			if got := r.lines(tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reservoir.lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var a, b reservoir
+	for _, key := range []int64{1, 4, 6} {
+		a.offer(3, key, string(rune('0'+key)))
+	}
+	for _, key := range []int64{2, 5, 3} {
+		b.offer(3, key, string(rune('0'+key)))
+	}
+	a.merge(3, b)
+	if got, want := a.lines(3), []string{"6", "5", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reservoir.merge() lines = %v, want %v", got, want)
+	}
+}
+
+func TestNewLogAnalyzer_lineSamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	matched := []string{
+		`10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 3574 "-" "curl/8.4.0"`,
+		`10.0.0.2 - - [10/Jul/2018:22:21:29 +0200] "GET /a HTTP/1.1" 200 3574 "-" "curl/8.4.0"`,
+		`10.0.0.3 - - [10/Jul/2018:22:21:30 +0200] "GET /b HTTP/1.1" 404 0 "-" "curl/8.4.0"`,
+	}
+	unmatched := []string{`not a log line`, `10.0.0.4 GET /c`}
+	filePaths := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+	for i, lines := range [][]string{{matched[0], unmatched[0], matched[1]}, {unmatched[1], "", matched[2]}} {
+		if err := ioutil.WriteFile(filePaths[i], []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sorted := func(lines []string) []string {
+		lines = append([]string(nil), lines...)
+		sort.Strings(lines)
+		return lines
+	}
+
+	tests := []struct {
+		name          string
+		config        *LogAnalyzerConfig
+		wantMatched   []string
+		wantUnmatched []string
+	}{
+		{name: "all", config: &LogAnalyzerConfig{MatchedSamplesCount: 5, UnmatchedSamplesCount: 5}, wantMatched: matched, wantUnmatched: unmatched},
+		{name: "unmatched only", config: &LogAnalyzerConfig{UnmatchedSamplesCount: 5}, wantUnmatched: unmatched},
+		{name: "none"},
+		{name: "redacted", config: &LogAnalyzerConfig{MatchedSamplesCount: 5, UnmatchedSamplesCount: 5, Redaction: &RedactionPolicy{HashEmails: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == nil {
+				config = &LogAnalyzerConfig{}
+			}
+			config.LineRegex = defaultLineRegex
+			a, err := NewLogAnalyzer(config)
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			for name, analyze := range map[string]func() (*LogAnalytics, error){
+				"AnalyzeFiles": func() (*LogAnalytics, error) { return a.AnalyzeFiles(filePaths...) },
+				"AnalyzeBatch": func() (*LogAnalytics, error) { return a.AnalyzeBatch(filePaths, WithBatchParallelism(2)) },
+			} {
+				analytics, err := analyze()
+				if err != nil {
+					t.Fatalf("%s() error = %v", name, err)
+				}
+				if got := sorted(analytics.MatchedSamples); !reflect.DeepEqual(got, sorted(tt.wantMatched)) {
+					t.Errorf("%s() matched samples = %q, want %q", name, got, tt.wantMatched)
+				}
+				if got := sorted(analytics.UnmatchedSamples); !reflect.DeepEqual(got, sorted(tt.wantUnmatched)) {
+					t.Errorf("%s() unmatched samples = %q, want %q", name, got, tt.wantUnmatched)
+				}
+			}
+		})
+	}
+
+	// a sample of the lines, of the files together
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, MatchedSamplesCount: 2, UnmatchedSamplesCount: 1})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	analytics, err := a.AnalyzeFiles(filePaths...)
+	if err != nil {
+		t.Fatalf("AnalyzeFiles() error = %v", err)
+	}
+	if len(analytics.MatchedSamples) != 2 || len(analytics.UnmatchedSamples) != 1 {
+		t.Errorf("AnalyzeFiles() samples = %q and %q, want 2 and 1 lines", analytics.MatchedSamples, analytics.UnmatchedSamples)
+	}
+	for _, line := range analytics.MatchedSamples {
+		if !containsString(matched, line) {
+			t.Errorf("AnalyzeFiles() matched sample %q was not read", line)
+		}
+	}
+}
+
+func Test_lineSamples_json(t *testing.T) {
+	agg := newAggregate()
+	agg.samples.matched.offer(2, 7, "matched")
+	agg.samples.unmatched.offer(2, 3, "unmatched")
+	agg.samples.matchedSize, agg.samples.unmatchedSize = 2, 2
+	data, err := json.Marshal(agg)
+	if err != nil {
+		t.Fatalf("aggregate.MarshalJSON() error = %v", err)
+	}
+	got := newAggregate()
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("aggregate.UnmarshalJSON() error = %v", err)
+	}
+	matched, unmatched := got.samples.report(2, 2)
+	if !reflect.DeepEqual(matched, []string{"matched"}) || !reflect.DeepEqual(unmatched, []string{"unmatched"}) {
+		t.Errorf("aggregate.UnmarshalJSON() samples = %q and %q, want them as marshaled", matched, unmatched)
+	}
+
+	// none sampled, none serialized
+	data, err = json.Marshal(newAggregate())
+	if err != nil {
+		t.Fatalf("aggregate.MarshalJSON() error = %v", err)
+	}
+	if strings.Contains(string(data), "samples") {
+		t.Errorf("aggregate.MarshalJSON() = %s, want no samples", data)
+	}
+}
@@ -0,0 +1,128 @@
+package analyzer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrSchemaMismatch :
+	ErrSchemaMismatch = "line does not fit the schema of the log format"
+	// ErrPositionalGroups :
+	ErrPositionalGroups = "positional line regexes have ten unnamed groups, the others are named"
+)
+
+var errSchemaMismatch = errors.New(ErrSchemaMismatch)
+
+// positionalFields : The fields of the groups of positional line regexes,
+// by group number
+var positionalFields = []string{1: "remote_host", 2: "time", 3: "method", 4: "url", 5: "protocol", 6: "url", 7: "status", 8: "bytes", 9: "referer", 10: "user_agent"}
+
+// fieldTypes : The values captured for typed fields, by group name. A value
+// of "-" logs the absence of a status, count or duration. Time and status are
+// required, the others may be left out by optional groups.
+var fieldTypes = map[string]*fieldType{
+	"time":                {"a time", func(v string) bool { return !parseTime(v).IsZero() }},
+	"status":              {"a status code", isStatus},
+	"bytes":               {"a count", isCount},
+	"original_bytes":      {"a count", isCount},
+	"connection_requests": {"a count", isCount},
+	"duration":            {"a number", isNumber},
+	"duration_ms":         {"a number", isNumber},
+	"duration_us":         {"a number", isNumber},
+	"duration_ns":         {"a number", isNumber},
+	"gzip_ratio":          {"a number", isNumber},
+}
+
+// fieldType : What the values of a field are, and a check of them
+type fieldType struct {
+	name  string
+	check func(value string) bool
+}
+
+// lineSchema : The typed groups of a line regex, whose captures are checked
+// in strict schema mode
+type lineSchema struct {
+	// groups : The names of the groups, by number
+	groups []string
+	types  []*fieldType
+}
+
+// newLineSchema : The schema of the line regex. Positional line regexes must
+// have ten unnamed groups, and any other group named, as captures are
+// otherwise read from the wrong groups. Times must parse with the layouts,
+// the default ones when nil.
+func newLineSchema(lineRegex *regexp.Regexp, layouts timeLayouts) (*lineSchema, error) {
+	names := groupNames(lineRegex)
+	schema := &lineSchema{groups: make([]string, len(names)), types: make([]*fieldType, len(names))}
+	_, positional := positionalGroups(names)
+	for i, name := range positionalNames(names)[1:] {
+		i++
+		if positional && name == "" {
+			return nil, errors.Wrapf(errors.New(ErrPositionalGroups), "group %d is unnamed", i)
+		}
+		schema.groups[i] = name
+		schema.types[i] = fieldTypes[name]
+		if name == "time" && layouts != nil {
+			schema.types[i] = &fieldType{"a time", func(v string) bool { return !layouts.parse(v).IsZero() }}
+		}
+	}
+	if positional && len(names) < len(positionalFields) {
+		return nil, errors.Wrapf(errors.New(ErrPositionalGroups), "%d groups", len(names)-1)
+	}
+	return schema, nil
+}
+
+// check : Whether every typed capture of the match is of its type, the
+// mismatch otherwise
+func (s *lineSchema) check(result []string, line string) error {
+	for i, value := range result {
+		t := s.types[i]
+		if t == nil || value == "" && s.groups[i] != "time" && s.groups[i] != "status" {
+			continue
+		}
+		if !t.check(value) {
+			return errors.Wrapf(errSchemaMismatch, "%s %q is not %s, in %q", s.groups[i], value, t.name, line)
+		}
+	}
+	return nil
+}
+
+// isSchemaMismatch : Whether the error is a line not fitting the schema in
+// strict schema mode, which fails the analysis
+func isSchemaMismatch(err error) bool {
+	return errors.Cause(err) == errSchemaMismatch
+}
+
+// isStatus : Whether the value is a status code, or none: "-" or -1, e.g.
+// of HAProxy for aborted requests, or 000, e.g. of Squid
+func isStatus(value string) bool {
+	return len(value) == 3 && isDigits(value) || value == "-" || value == "-1"
+}
+
+// isCount : Whether the value is a count, or none; HAProxy prefixes the
+// bytes read so far with a +, with option logasap
+func isCount(value string) bool {
+	value = strings.TrimPrefix(value, "+")
+	return value == "-" || value != "" && isDigits(value)
+}
+
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumber(value string) bool {
+	if value == "-" {
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
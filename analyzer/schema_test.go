@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_newLineSchema(t *testing.T) {
+	tests := []struct {
+		name      string
+		lineRegex string
+		wantErr   string
+	}{
+		{name: "positional", lineRegex: defaultLineRegex.String()},
+		{name: "positional with named groups after", lineRegex: defaultLineRegex.String() + ` (?P<upstream>\S+)`},
+		{name: "named", lineRegex: `(?P<remote_host>\S+) (\S+) (?P<status>\d+)`},
+		{name: "missing group", lineRegex: `(\S+) \[([^]]+)\] "(\S+) (\S+) (\S+)"() (\d+) (\d+) "(.*?)"`, wantErr: "9 groups: " + ErrPositionalGroups},
+		{name: "extra unnamed group", lineRegex: defaultLineRegex.String() + ` (\S+)`, wantErr: "group 12 is unnamed: " + ErrPositionalGroups},
+		{name: "named with unnamed groups", lineRegex: `(\S+) (?P<time>\S+)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLineSchema(regexp.MustCompile(tt.lineRegex), nil)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("newLineSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_lineSchema_check(t *testing.T) {
+	lineRegex := regexp.MustCompile(`(?P<time>\S*) (?P<status>\S*) (?P<bytes>\S*) (?P<duration>\S*) (?P<user_agent>.*)`)
+	schema, err := newLineSchema(lineRegex, nil)
+	if err != nil {
+		t.Fatalf("newLineSchema() error = %v", err)
+	}
+	tests := []struct {
+		line    string
+		wantErr string
+	}{
+		{line: "2018-07-10T22:21:28+02:00 200 3574 0.25 curl/8.4.0"},
+		{line: "1697034774.533 -1 +0 - curl/8.4.0"},
+		{line: "2018-07-10T22:21:28+02:00 000 - -1 -"},
+		// optional groups
+		{line: "2018-07-10T22:21:28+02:00 200   curl/8.4.0"},
+		{line: "10/Jul/2018 200 3574 0.25 curl/8.4.0", wantErr: `time "10/Jul/2018" is not a time`},
+		{line: "2018-07-10T22:21:28+02:00 3574 200 0.25 curl/8.4.0", wantErr: `status "3574" is not a status code`},
+		{line: "2018-07-10T22:21:28+02:00  3574 0.25 curl/8.4.0", wantErr: `status "" is not a status code`},
+		{line: "2018-07-10T22:21:28+02:00 200 3.5k 0.25 curl/8.4.0", wantErr: `bytes "3.5k" is not a count`},
+		{line: "2018-07-10T22:21:28+02:00 200 3574 250ms curl/8.4.0", wantErr: `duration "250ms" is not a number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			err := schema.check(lineRegex.FindStringSubmatch(tt.line), tt.line)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("lineSchema.check() error = %v, want none", err)
+				}
+				return
+			}
+			if want := tt.wantErr + ", in \"" + tt.line + "\": " + ErrSchemaMismatch; err == nil || err.Error() != want || !isSchemaMismatch(err) {
+				t.Errorf("lineSchema.check() error = %v, wantErr %v", err, want)
+			}
+		})
+	}
+}
+
+// Test_lineSchema_presets : The preset formats fit their schema, on their
+// golden fixtures
+func Test_lineSchema_presets(t *testing.T) {
+	for name, format := range map[string]*formats.Format{
+		"combined":       formats.CombinedLog,
+		"alb":            formats.ALB,
+		"elb":            formats.ClassicELB,
+		"s3":             formats.S3,
+		"haproxy":        formats.HAProxy,
+		"envoy":          formats.EnvoyDefault,
+		"traefik":        formats.Traefik,
+		"squid":          formats.Squid,
+		"varnish":        formats.Varnish,
+		"fastly":         formats.Fastly,
+		"vhost-combined": formats.VHostCombined,
+	} {
+		schema, err := newLineSchema(format.LineRegex, nil)
+		if err != nil {
+			t.Errorf("newLineSchema(%s) error = %v", name, err)
+			continue
+		}
+		fixtures, err := filepath.Glob(filepath.Join("test-data", "golden", name, "*.log"))
+		if err != nil || len(fixtures) == 0 {
+			t.Fatalf("no %s fixtures: %v", name, err)
+		}
+		for _, fixture := range fixtures {
+			file, err := os.Open(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				if result := format.LineRegex.FindStringSubmatch(scanner.Text()); result != nil {
+					if err := schema.check(result, scanner.Text()); err != nil {
+						t.Errorf("lineSchema.check(%s) error = %v", name, err)
+					}
+				}
+			}
+			file.Close()
+		}
+	}
+}
+
+func TestNewLogAnalyzer_strictSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	task, err := ioutil.ReadFile("./test-data/programming-task.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, task, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the bytes and status the wrong way round
+	swapped := regexp.MustCompile(`^(?P<remote_host>\S+) \S+ \S+ \[(?P<time>[^]]+)\] "(?P<request>[^"]*)" (?P<bytes>\S+) (?P<status>\S+)`)
+
+	newAnalyzer := func(lineRegex *regexp.Regexp, strict bool) LogAnalyzer {
+		a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+			LineRegex:          lineRegex,
+			StrictSchema:       strict,
+			FollowPollInterval: 5 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("NewLogAnalyzer() error = %v", err)
+		}
+		return a
+	}
+	isMismatch := func(err error) bool {
+		return err != nil && strings.HasSuffix(err.Error(), ErrSchemaMismatch)
+	}
+
+	if _, err := newAnalyzer(swapped, false).Analyze(filePath); err != nil {
+		t.Errorf("logAnalyzer.Analyze() error = %v, want garbage counted when not strict", err)
+	}
+	if _, err := newAnalyzer(defaultLineRegex, true).Analyze(filePath); err != nil {
+		t.Errorf("logAnalyzer.Analyze() error = %v, want none for a fitting regex", err)
+	}
+	if _, err := newAnalyzer(swapped, true).Analyze(filePath); !isMismatch(err) {
+		t.Errorf("logAnalyzer.Analyze() error = %v, want %v", err, ErrSchemaMismatch)
+	}
+	if _, err := newAnalyzer(swapped, true).AnalyzeBatch([]string{filePath}); !isMismatch(err) {
+		t.Errorf("logAnalyzer.AnalyzeBatch() error = %v, want %v", err, ErrSchemaMismatch)
+	}
+
+	strict, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: swapped, StrictSchema: true, TimeOrdered: true})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	if _, err := strict.Analyze(filePath); !isMismatch(err) {
+		t.Errorf("logAnalyzer.Analyze() time ordered error = %v, want %v", err, ErrSchemaMismatch)
+	}
+
+	// following stops at the line
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	snapshotCh, errCh := newAnalyzer(swapped, true).Follow(ctx, filePath)
+	for range snapshotCh {
+	}
+	if err := <-errCh; !isMismatch(err) {
+		t.Errorf("logAnalyzer.Follow() error = %v, want %v", err, ErrSchemaMismatch)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("logAnalyzer.Follow() followed until the timeout, want it stopped at the line")
+	}
+}
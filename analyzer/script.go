@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+const (
+	// ErrLoadingScript :
+	ErrLoadingScript = "error loading script"
+
+	// scriptMaxSteps : Execution steps a script function may take per line,
+	// so a runaway script cannot hang the analysis
+	scriptMaxSteps = 100000
+)
+
+// Script : A Starlark (https://github.com/bazelbuild/starlark) script extending
+// the analysis without recompiling. It may define any of
+//
+//	filter(line) -> bool     lines it returns False for are skipped
+//	derive(line) -> dict     string fields added to the line's enrichments as "script.<field>"
+//	score(line)  -> number   summed per client IP, reported as TopScoredIPs
+//
+// where line is a struct with remote_host, time (RFC 3339), request, method,
+// path, query, protocol, status, bytes, referer, user_agent, url and
+// enrichments (a dict) attributes.
+// Scripts failing on a line are counted as script errors in the self-metrics,
+// and the line is kept as is.
+type Script struct {
+	filter *starlark.Function
+	derive *starlark.Function
+	score  *starlark.Function
+}
+
+// LoadScript : Compiles and runs the top level of a script, looking up the
+// hook functions it defines
+func LoadScript(filename string, src []byte) (*Script, error) {
+	thread := &starlark.Thread{Name: "load " + filename}
+	globals, err := starlark.ExecFile(thread, filename, src, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrLoadingScript)
+	}
+
+	script := &Script{}
+	hooks := map[string]**starlark.Function{
+		"filter": &script.filter,
+		"derive": &script.derive,
+		"score":  &script.score,
+	}
+	for name, hook := range hooks {
+		v, ok := globals[name]
+		if !ok {
+			continue
+		}
+		fn, ok := v.(*starlark.Function)
+		if !ok {
+			return nil, errors.Wrap(fmt.Errorf("%s is a %s, not a function", name, v.Type()), ErrLoadingScript)
+		}
+		*hook = fn
+	}
+	if script.filter == nil && script.derive == nil && script.score == nil {
+		return nil, errors.Wrap(errors.New("script defines none of filter, derive or score"), ErrLoadingScript)
+	}
+
+	return script, nil
+}
+
+// runScript : Runs the filter and derive hooks on the line, reporting whether
+// the line is kept
+func (l *logAnalyzer) runScript(line *Line) bool {
+	if l.script == nil {
+		return true
+	}
+
+	if l.script.filter != nil {
+		v, err := l.callScript(l.script.filter, line)
+		if err == nil && v.Truth() == starlark.False {
+			return false
+		}
+	}
+
+	if l.script.derive != nil {
+		v, err := l.callScript(l.script.derive, line)
+		if err != nil {
+			return true
+		}
+		dict, ok := v.(*starlark.Dict)
+		if !ok {
+			if v != starlark.None {
+				atomic.AddInt64(&l.metrics.scriptErrors, 1)
+			}
+			return true
+		}
+		for _, item := range dict.Items() {
+			field, ok := starlark.AsString(item[0])
+			if !ok {
+				continue
+			}
+			value, ok := starlark.AsString(item[1])
+			if !ok {
+				value = item[1].String()
+			}
+			if line.Enrichments == nil {
+				line.Enrichments = make(map[string]string)
+			}
+			line.Enrichments["script."+field] = value
+		}
+	}
+
+	return true
+}
+
+// scriptScore : Runs the score hook on the line
+func (l *logAnalyzer) scriptScore(line *Line) (float64, bool) {
+	if l.script == nil || l.script.score == nil {
+		return 0, false
+	}
+	v, err := l.callScript(l.script.score, line)
+	if err != nil {
+		return 0, false
+	}
+	score, ok := starlark.AsFloat(v)
+	if !ok {
+		atomic.AddInt64(&l.metrics.scriptErrors, 1)
+		return 0, false
+	}
+	return score, true
+}
+
+func (l *logAnalyzer) callScript(fn *starlark.Function, line *Line) (starlark.Value, error) {
+	thread := &starlark.Thread{Name: fn.Name()}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	v, err := starlark.Call(thread, fn, starlark.Tuple{scriptLine(line)}, nil)
+	if err != nil {
+		atomic.AddInt64(&l.metrics.scriptErrors, 1)
+	}
+	return v, err
+}
+
+// scriptLine : The line, as seen by scripts
+func scriptLine(line *Line) *starlarkstruct.Struct {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"remote_host": starlark.String(line.RemoteHost),
+		"time":        starlark.String(line.Time.Format(time.RFC3339)),
+		"request":     starlark.String(line.Request()),
+		"method":      starlark.String(line.Method),
+		"path":        starlark.String(line.Path),
+		"query":       starlark.String(line.RawQuery),
+		"protocol":    starlark.String(line.Protocol),
+		"status":      starlark.MakeInt(line.Status),
+		"bytes":       starlark.MakeInt(line.Bytes),
+		"referer":     starlark.String(line.Referer),
+		"user_agent":  starlark.String(line.UserAgent),
+		"url":         starlark.String(line.URL),
+		"extras":      frozenDict(line.Extras),
+		"enrichments": frozenDict(line.Enrichments),
+	})
+}
+
+// frozenDict : The map as a read-only dict, in key order
+func frozenDict(m map[string]string) *starlark.Dict {
+	dict := starlark.NewDict(len(m))
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		dict.SetKey(starlark.String(k), starlark.String(m[k]))
+	}
+	dict.Freeze()
+	return dict
+}
+
+// topScored : The keys with the highest scores
+func topScored(scores map[string]float64, top int) []string {
+	keys := make([]string, 0, len(scores))
+	for k := range scores {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] == scores[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return scores[keys[i]] > scores[keys[j]]
+	})
+	if top > len(keys) {
+		top = len(keys)
+	}
+	return keys[:top]
+}
@@ -0,0 +1,117 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{name: "error: syntax", src: "def filter(line)\n", wantErr: true},
+		{name: "error: no hooks", src: "x = 1\n", wantErr: true},
+		{name: "error: hook is not a function", src: "filter = True\n", wantErr: true},
+		{name: "filter only", src: "def filter(line):\n    return True\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadScript("test.star", []byte(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadScript() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_Analyze_script(t *testing.T) {
+	script, err := LoadScript("test.star", []byte(`
+def filter(line):
+    return not line.url.startswith("/menu")
+
+def derive(line):
+    return {"encoded": str("%" in line.url).lower()}
+
+def score(line):
+    if line.status >= 400:
+        return 10
+    return 1
+
+def unused(line):
+    return fail("never called")
+`))
+	if err != nil {
+		t.Fatalf("LoadScript() error = %v", err)
+	}
+
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:              defaultLineRegex,
+		TopEnrichedValuesCount: 2,
+		TopScoredIPsCount:      2,
+		Script:                 script,
+		KeepRawURLs:            true,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/encoded-urls.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	want := &LogAnalytics{
+		UniqueIPCount:     2,
+		TopEnrichedValues: map[string][]string{"script.encoded": {"true", "false"}},
+		TopScoredIPs:      []string{"177.71.128.21", "168.41.191.40"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.Analyze() = %+v, want %+v", got, want)
+	}
+	if errs := l.SelfMetrics().ScriptErrors; errs != 0 {
+		t.Errorf("logAnalyzer.SelfMetrics() script errors = %d, want 0", errs)
+	}
+}
+
+func Test_logAnalyzer_runScript_runaway(t *testing.T) {
+	script, err := LoadScript("test.star", []byte(`
+def filter(line):
+    for i in range(1000000000):
+        pass
+    return False
+`))
+	if err != nil {
+		t.Fatalf("LoadScript() error = %v", err)
+	}
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, Script: script})
+	if err != nil {
+		t.Fatalf("logAnalyzer.runScript() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	if !l.runScript(&Line{}) {
+		t.Errorf("logAnalyzer.runScript() a failing filter drops the line")
+	}
+	if errs := l.SelfMetrics().ScriptErrors; errs != 1 {
+		t.Errorf("logAnalyzer.SelfMetrics() script errors = %d, want 1", errs)
+	}
+}
+
+func Test_scriptLine_extras(t *testing.T) {
+	script, err := LoadScript("extras.star", []byte(`
+def filter(line):
+    return line.extras.get("upstream_cache_status") != "HIT"
+`))
+	if err != nil {
+		t.Fatalf("LoadScript() error = %v", err)
+	}
+	l := &logAnalyzer{script: script}
+	hit := &Line{Extras: map[string]string{"upstream_cache_status": "HIT"}}
+	if l.runScript(hit) {
+		t.Errorf("runScript() keeps a cache hit")
+	}
+	if !l.runScript(&Line{}) {
+		t.Errorf("runScript() drops a line without extras")
+	}
+}
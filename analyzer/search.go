@@ -0,0 +1,198 @@
+package analyzer
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSearchLimit : Lines a search returns when its limit is not set
+	DefaultSearchLimit = 100
+	// maxIndexedPrefixDepth : Path segments URL prefixes are indexed to, e.g.
+	// /api/v1/items; deeper prefixes are searched from it
+	maxIndexedPrefixDepth = 4
+)
+
+// IndexedLine : A line kept by a LineIndex
+type IndexedLine struct {
+	// Raw : The line as logged, left out when a redaction policy is set, as
+	// only the parsed fields are redacted
+	Raw  string `json:",omitempty"`
+	Line *Line
+	seq  uint64
+	// added : When the line was indexed, for the index's max age
+	added time.Time
+}
+
+// SearchQuery : The lines a search matches, all of its set criteria
+type SearchQuery struct {
+	RemoteHost string
+	Status     int
+	// URLPrefix : e.g. /api/ or /api/v1/items
+	URLPrefix string
+	// Since : Lines logged at or after, e.g. an hour ago
+	Since time.Time
+	// Limit : DefaultSearchLimit when not set
+	Limit int
+}
+
+// LineIndex : The most recent lines of a followed log, indexed by client IP,
+// status and URL path prefix, answering e.g. "the requests of 1.2.3.4 in the
+// last hour". Set it as LogAnalyzerConfig.LineIndex.
+type LineIndex struct {
+	maxLines int
+	maxAge   time.Duration
+
+	mu sync.RWMutex
+	// lines : Oldest first; lines[0] is of sequence number first
+	lines    []*IndexedLine
+	first    uint64
+	byIP     map[string][]uint64
+	byStatus map[int][]uint64
+	byPrefix map[string][]uint64
+}
+
+// NewLineIndex : An index keeping up to maxLines lines, none older than
+// maxAge when set
+func NewLineIndex(maxLines int, maxAge time.Duration) *LineIndex {
+	return &LineIndex{
+		maxLines: maxLines,
+		maxAge:   maxAge,
+		byIP:     make(map[string][]uint64),
+		byStatus: make(map[int][]uint64),
+		byPrefix: make(map[string][]uint64),
+	}
+}
+
+// add : Indexes a copy of the line, evicting the oldest ones over the
+// limits. Copies keep the blocks of the other lines from being held.
+func (x *LineIndex) add(raw string, line *Line) {
+	line = line.Copy()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := time.Now()
+	seq := x.first + uint64(len(x.lines))
+	x.lines = append(x.lines, &IndexedLine{Raw: raw, Line: line, seq: seq, added: now})
+	x.byIP[line.RemoteHost] = append(x.byIP[line.RemoteHost], seq)
+	x.byStatus[line.Status] = append(x.byStatus[line.Status], seq)
+	for _, prefix := range urlPrefixes(line.URL) {
+		x.byPrefix[prefix] = append(x.byPrefix[prefix], seq)
+	}
+
+	for len(x.lines) > 0 && (len(x.lines) > x.maxLines || x.maxAge > 0 && now.Sub(x.lines[0].added) > x.maxAge) {
+		x.evictOldest()
+	}
+}
+
+// evictOldest : Drops the oldest line, which is first in each of its posting
+// lists
+func (x *LineIndex) evictOldest() {
+	oldest := x.lines[0]
+	x.lines[0] = nil
+	x.lines = x.lines[1:]
+	x.first++
+
+	if x.byIP[oldest.Line.RemoteHost] = x.byIP[oldest.Line.RemoteHost][1:]; len(x.byIP[oldest.Line.RemoteHost]) == 0 {
+		delete(x.byIP, oldest.Line.RemoteHost)
+	}
+	if x.byStatus[oldest.Line.Status] = x.byStatus[oldest.Line.Status][1:]; len(x.byStatus[oldest.Line.Status]) == 0 {
+		delete(x.byStatus, oldest.Line.Status)
+	}
+	for _, prefix := range urlPrefixes(oldest.Line.URL) {
+		if x.byPrefix[prefix] = x.byPrefix[prefix][1:]; len(x.byPrefix[prefix]) == 0 {
+			delete(x.byPrefix, prefix)
+		}
+	}
+}
+
+// Search : The lines matching the query, newest first
+func (x *LineIndex) Search(query *SearchQuery) []*IndexedLine {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// the shortest posting list of the criteria, all lines without any
+	var candidates []uint64
+	indexed := false
+	narrow := func(seqs []uint64) {
+		if !indexed || len(seqs) < len(candidates) {
+			candidates, indexed = seqs, true
+		}
+	}
+	if query.RemoteHost != "" {
+		narrow(x.byIP[query.RemoteHost])
+	}
+	if query.Status != 0 {
+		narrow(x.byStatus[query.Status])
+	}
+	if strings.HasPrefix(query.URLPrefix, "/") {
+		narrow(x.byPrefix[indexedPrefix(query.URLPrefix)])
+	}
+
+	var found []*IndexedLine
+	match := func(indexedLine *IndexedLine) bool {
+		line := indexedLine.Line
+		if query.RemoteHost != "" && line.RemoteHost != query.RemoteHost ||
+			query.Status != 0 && line.Status != query.Status ||
+			query.URLPrefix != "" && !strings.HasPrefix(urlPath(line.URL), query.URLPrefix) ||
+			!query.Since.IsZero() && line.Time.Before(query.Since) {
+			return false
+		}
+		found = append(found, indexedLine)
+		return len(found) == limit
+	}
+	if indexed {
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if match(x.lines[candidates[i]-x.first]) {
+				break
+			}
+		}
+	} else {
+		for i := len(x.lines) - 1; i >= 0; i-- {
+			if match(x.lines[i]) {
+				break
+			}
+		}
+	}
+	return found
+}
+
+// urlPrefixes : The path prefixes the URL is indexed under, up to
+// maxIndexedPrefixDepth segments, e.g. /, /api and /api/items for
+// /api/items?page=2
+func urlPrefixes(url string) []string {
+	path := urlPath(url)
+	if !strings.HasPrefix(path, "/") {
+		return nil
+	}
+	prefixes := []string{"/"}
+	for i, depth := 1, 0; i < len(path) && depth < maxIndexedPrefixDepth; depth++ {
+		next := strings.IndexByte(path[i:], '/')
+		if next < 0 {
+			prefixes = append(prefixes, path)
+			break
+		}
+		if next > 0 {
+			prefixes = append(prefixes, path[:i+next])
+		}
+		i += next + 1
+	}
+	return prefixes
+}
+
+// indexedPrefix : The indexed prefix of every URL starting with the prefix.
+// The last segment of a prefix without a trailing slash may be incomplete,
+// e.g. /api/v of /api/v1, so it is searched from its parent.
+func indexedPrefix(prefix string) string {
+	prefixes := urlPrefixes(prefix)
+	last := prefixes[len(prefixes)-1]
+	if len(prefixes) > 1 && !strings.HasSuffix(prefix, "/") && last == urlPath(prefix) {
+		last = prefixes[len(prefixes)-2]
+	}
+	return last
+}
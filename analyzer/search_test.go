@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLineIndex_Search(t *testing.T) {
+	at := time.Date(2018, 7, 10, 22, 0, 0, 0, time.UTC)
+	index := NewLineIndex(5, 0)
+	for i, line := range []*Line{
+		{RemoteHost: "10.0.0.1", Status: 200, URL: "/evicted", Time: at},
+		{RemoteHost: "10.0.0.1", Status: 200, URL: "/api/v1/items?page=2", Time: at.Add(time.Minute)},
+		{RemoteHost: "10.0.0.2", Status: 404, URL: "/api/v2/items", Time: at.Add(2 * time.Minute)},
+		{RemoteHost: "10.0.0.1", Status: 500, URL: "/apidocs", Time: at.Add(3 * time.Minute)},
+		{RemoteHost: "10.0.0.3", Status: 200, URL: "/", Time: at.Add(4 * time.Minute)},
+		{RemoteHost: "10.0.0.1", Status: 404, URL: "/a/b/c/d/e/f", Time: at.Add(5 * time.Minute)},
+	} {
+		index.add(string(rune('a'+i)), line)
+	}
+
+	tests := []struct {
+		name  string
+		query *SearchQuery
+		want  string
+	}{
+		{"all, newest first", &SearchQuery{}, "fedcb"},
+		{"limit", &SearchQuery{Limit: 2}, "fe"},
+		{"ip", &SearchQuery{RemoteHost: "10.0.0.1"}, "fdb"},
+		{"status", &SearchQuery{Status: 404}, "fc"},
+		{"ip and status", &SearchQuery{RemoteHost: "10.0.0.1", Status: 404}, "f"},
+		{"segment prefix", &SearchQuery{URLPrefix: "/api/"}, "cb"},
+		{"string prefix", &SearchQuery{URLPrefix: "/api"}, "dcb"},
+		{"incomplete segment", &SearchQuery{URLPrefix: "/api/v1"}, "b"},
+		{"deeper than indexed", &SearchQuery{URLPrefix: "/a/b/c/d/e/"}, "f"},
+		{"since", &SearchQuery{Since: at.Add(3 * time.Minute)}, "fed"},
+		{"unknown ip", &SearchQuery{RemoteHost: "10.0.0.9"}, ""},
+		{"evicted", &SearchQuery{URLPrefix: "/evicted"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			for _, line := range index.Search(tt.query) {
+				got += line.Raw
+			}
+			if got != tt.want {
+				t.Errorf("LineIndex.Search() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineIndex_evictOldest(t *testing.T) {
+	index := NewLineIndex(1, 0)
+	index.add("a", &Line{RemoteHost: "10.0.0.1", Status: 200, URL: "/a"})
+	index.add("b", &Line{RemoteHost: "10.0.0.2", Status: 404, URL: "/b"})
+	if want := map[string][]uint64{"10.0.0.2": {1}}; !reflect.DeepEqual(index.byIP, want) {
+		t.Errorf("LineIndex byIP = %v, want %v", index.byIP, want)
+	}
+	if want := map[string][]uint64{"/": {1}, "/b": {1}}; !reflect.DeepEqual(index.byPrefix, want) {
+		t.Errorf("LineIndex byPrefix = %v, want %v", index.byPrefix, want)
+	}
+}
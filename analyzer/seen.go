@@ -0,0 +1,155 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrReadingSeenIPs :
+	ErrReadingSeenIPs = "error reading seen IPs"
+	// ErrWritingSeenIPs :
+	ErrWritingSeenIPs = "error writing seen IPs"
+
+	// DefaultSeenIPsCapacity : IPs a new seen IPs filter is sized for, 1.2 MB
+	DefaultSeenIPsCapacity = 1000000
+	// seenIPsFalsePositiveRate : Share of new IPs a filter holding its
+	// capacity takes for seen ones
+	seenIPsFalsePositiveRate = 0.01
+)
+
+// seenIPsMagic : The first bytes of a saved filter
+var seenIPsMagic = [4]byte{'S', 'E', 'E', 'N'}
+
+// SeenIPs : A Bloom filter of the client IPs seen by previous runs, so that
+// reports mark the most active IPs seen for the first time
+// (LogAnalytics.FirstTimeIPs). IPs counted by a run are added to the filter,
+// but only IPs of previous runs count as seen, until it is written and read
+// back by the next run. Seen IPs are never taken for new ones; new IPs are
+// taken for seen ones about 1% of the time, more once the filter holds more
+// IPs than it was sized for.
+type SeenIPs struct {
+	mu     sync.Mutex
+	hashes uint32
+	// seen : The bits of the IPs of previous runs; added : of this run
+	seen  []uint64
+	added []uint64
+}
+
+// NewSeenIPs : An empty filter sized for capacity IPs
+func NewSeenIPs(capacity int) *SeenIPs {
+	if capacity <= 0 {
+		capacity = DefaultSeenIPsCapacity
+	}
+	bits := math.Ceil(-float64(capacity) * math.Log(seenIPsFalsePositiveRate) / (math.Ln2 * math.Ln2))
+	words := int(math.Ceil(bits / 64))
+	hashes := uint32(math.Round(float64(words*64) / float64(capacity) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &SeenIPs{hashes: hashes, seen: make([]uint64, words), added: make([]uint64, words)}
+}
+
+// ReadSeenIPs : The filter written by SeenIPs.Write
+func ReadSeenIPs(r io.Reader) (*SeenIPs, error) {
+	var header struct {
+		Magic  [4]byte
+		Hashes uint32
+		Words  uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, errors.Wrap(err, ErrReadingSeenIPs)
+	}
+	if header.Magic != seenIPsMagic || header.Hashes == 0 || header.Words == 0 {
+		return nil, errors.New(ErrReadingSeenIPs)
+	}
+	s := &SeenIPs{hashes: header.Hashes, seen: make([]uint64, header.Words), added: make([]uint64, header.Words)}
+	if err := binary.Read(r, binary.LittleEndian, s.seen); err != nil {
+		return nil, errors.Wrap(err, ErrReadingSeenIPs)
+	}
+	return s, nil
+}
+
+// Write : Writes the filter, with the IPs of previous runs and of this one
+func (s *SeenIPs) Write(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	words := make([]uint64, len(s.seen))
+	for i := range words {
+		words[i] = s.seen[i] | s.added[i]
+	}
+	header := struct {
+		Magic  [4]byte
+		Hashes uint32
+		Words  uint64
+	}{seenIPsMagic, s.hashes, uint64(len(words))}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return errors.Wrap(err, ErrWritingSeenIPs)
+	}
+	if err := binary.Write(w, binary.LittleEndian, words); err != nil {
+		return errors.Wrap(err, ErrWritingSeenIPs)
+	}
+	return nil
+}
+
+// positions : Calls set with the bit positions of the IP, derived from two
+// hashes (double hashing)
+func (s *SeenIPs) positions(ip string, set func(word int, bit uint64) bool) bool {
+	h1 := keyHash(ip)
+	h2 := mix64(h1) | 1
+	bits := uint64(len(s.seen)) * 64
+	for i := uint64(0); i < uint64(s.hashes); i++ {
+		position := (h1 + i*h2) % bits
+		if !set(int(position/64), 1<<(position%64)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Seen : Whether a previous run saw the IP
+func (s *SeenIPs) Seen(ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.positions(ip, func(word int, bit uint64) bool {
+		return s.seen[word]&bit != 0
+	})
+}
+
+// Add : Adds an IP seen by this run
+func (s *SeenIPs) Add(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(ip)
+}
+
+func (s *SeenIPs) add(ip string) {
+	s.positions(ip, func(word int, bit uint64) bool {
+		s.added[word] |= bit
+		return true
+	})
+}
+
+// record : Adds the IPs counted by the run
+func (s *SeenIPs) record(ipHits map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ip := range ipHits {
+		s.add(ip)
+	}
+}
+
+// firstTime : The IPs no previous run saw, in order
+func (s *SeenIPs) firstTime(ips []string) []string {
+	var first []string
+	for _, ip := range ips {
+		if !s.Seen(ip) {
+			first = append(first, ip)
+		}
+	}
+	return first
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSeenIPs(t *testing.T) {
+	s := NewSeenIPs(10000)
+	for i := 0; i < 10000; i++ {
+		s.Add(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	// IPs of this run are not seen until the filter is written and read back
+	if s.Seen("10.0.0.1") {
+		t.Errorf("SeenIPs.Seen() = true for an IP of this run")
+	}
+
+	var saved bytes.Buffer
+	if err := s.Write(&saved); err != nil {
+		t.Fatalf("SeenIPs.Write() error = %v", err)
+	}
+	loaded, err := ReadSeenIPs(&saved)
+	if err != nil {
+		t.Fatalf("ReadSeenIPs() error = %v", err)
+	}
+	for i := 0; i < 10000; i++ {
+		if ip := fmt.Sprintf("10.0.%d.%d", i/256, i%256); !loaded.Seen(ip) {
+			t.Fatalf("SeenIPs.Seen(%s) = false for a saved IP", ip)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if loaded.Seen(fmt.Sprintf("192.168.%d.%d", i/256, i%256)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 200 {
+		t.Errorf("SeenIPs.Seen() = true for %d of 10000 new IPs, want about 1%%", falsePositives)
+	}
+
+	if _, err := ReadSeenIPs(bytes.NewReader([]byte("not a filter"))); err == nil {
+		t.Errorf("ReadSeenIPs() error = nil for a file that is not a filter")
+	}
+}
+
+func Test_logAnalyzer_report_firstTimeIPs(t *testing.T) {
+	seenIPs := NewSeenIPs(1000)
+	seenIPs.Add("168.41.191.40")
+	var saved bytes.Buffer
+	if err := seenIPs.Write(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() []string {
+		seenIPs, err := ReadSeenIPs(bytes.NewReader(saved.Bytes()))
+		if err != nil {
+			t.Fatalf("ReadSeenIPs() error = %v", err)
+		}
+		l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex, MostActiveIPsCount: 3, SeenIPs: seenIPs})
+		if err != nil {
+			t.Fatalf("NewLogAnalyzer() error = %v", err)
+		}
+		analytics, err := l.Analyze("./test-data/top-3-most-active-ips.log")
+		if err != nil {
+			t.Fatalf("Analyze() error = %v", err)
+		}
+		saved.Reset()
+		if err := seenIPs.Write(&saved); err != nil {
+			t.Fatal(err)
+		}
+		return analytics.FirstTimeIPs
+	}
+	if got, want := run(), []string{"177.71.128.21", "50.112.00.11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Analyze() first-time ips = %v, want %v", got, want)
+	}
+	if got := run(); got != nil {
+		t.Errorf("Analyze() first-time ips = %v on the second run, want none", got)
+	}
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import "time"
+
+// DefaultSessionTimeout : Inactivity after which a visitor's next pageview
+// starts a new session, as in web analytics tools
+const DefaultSessionTimeout = 30 * time.Minute
+
+// SessionRules : How pageviews are reconstructed into sessions. A visitor is
+// a client IP and user agent pair; their pageviews belong to one session
+// until they stay inactive for longer than the timeout.
+type SessionRules struct {
+	// Timeout : DefaultSessionTimeout when not set
+	Timeout time.Duration
+}
+
+// session : A session still open, which further pageviews can extend
+type session struct {
+	landing   string
+	exit      string
+	pageviews int
+	last      time.Time
+}
+
+// sessionTotals : Sessions of an aggregate, closed or not
+type sessionTotals struct {
+	sessions    int
+	bounces     int
+	landingHits map[string]int
+	exitHits    map[string]int
+}
+
+// trackSession : Adds the pageview of url to the visitor's open session, or
+// starts a new one. Sessions left inactive are closed as time advances, so
+// only active visitors are held in memory.
+func (l *logAnalyzer) trackSession(agg *aggregate, visitor, url string, at time.Time) {
+	s, ok := agg.openSessions[visitor]
+	if ok && at.Sub(s.last) > l.sessionTimeout {
+		l.closeSession(agg, s)
+		ok = false
+	}
+	if !ok {
+		s = &session{landing: url}
+		agg.openSessions[visitor] = s
+		if len(agg.openSessions) > agg.peakOpenSessions {
+			agg.peakOpenSessions = len(agg.openSessions)
+		}
+	}
+	s.exit = url
+	s.pageviews++
+	if at.After(s.last) {
+		s.last = at
+	}
+
+	if at.Sub(agg.sessionsSwept) > l.sessionTimeout {
+		for v, open := range agg.openSessions {
+			if at.Sub(open.last) > l.sessionTimeout {
+				l.closeSession(agg, open)
+				delete(agg.openSessions, v)
+			}
+		}
+		agg.sessionsSwept = at
+	}
+}
+
+func (l *logAnalyzer) closeSession(agg *aggregate, s *session) {
+	agg.sessions++
+	if s.pageviews == 1 {
+		agg.bounces++
+	}
+	agg.landingHits[s.landing]++
+	agg.exitHits[s.exit]++
+}
+
+// sessionTotals : The closed sessions, and the open ones as if they ended now
+func (a *aggregate) sessionTotals() *sessionTotals {
+	totals := &sessionTotals{
+		sessions:    a.sessions + len(a.openSessions),
+		bounces:     a.bounces,
+		landingHits: make(map[string]int, len(a.landingHits)),
+		exitHits:    make(map[string]int, len(a.exitHits)),
+	}
+	mergeHits(totals.landingHits, a.landingHits)
+	mergeHits(totals.exitHits, a.exitHits)
+	for _, s := range a.openSessions {
+		if s.pageviews == 1 {
+			totals.bounces++
+		}
+		totals.landingHits[s.landing]++
+		totals.exitHits[s.exit]++
+	}
+	return totals
+}
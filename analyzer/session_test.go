@@ -0,0 +1,76 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_report_sessions(t *testing.T) {
+	start := time.Date(2018, 7, 10, 10, 0, 0, 0, time.UTC)
+	pageview := func(ip, ua, url string, minutes int) *Line {
+		return &Line{
+			RemoteHost: ip,
+			Time:       start.Add(time.Duration(minutes) * time.Minute),
+			Method:     "GET",
+			Path:       url,
+			Protocol:   "HTTP/1.1",
+			URL:        url,
+			Status:     200,
+			UserAgent:  ua,
+		}
+	}
+	lines := []*Line{
+		pageview("1.1.1.1", "Firefox", "/", 0),
+		pageview("1.1.1.1", "Chrome", "/", 1),
+		pageview("2.2.2.2", "Firefox", "/docs/", 2),
+		pageview("2.2.2.2", "Firefox", "/a.css", 2),
+		pageview("2.2.2.2", "Firefox", "/pricing", 3),
+		pageview("1.1.1.1", "Firefox", "/docs/", 5),
+		// more than 30 minutes later, a new session
+		pageview("1.1.1.1", "Firefox", "/pricing", 50),
+	}
+
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:            defaultLineRegex,
+		Sessions:             &SessionRules{},
+		TopLandingPagesCount: 1,
+		TopExitPagesCount:    1,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+	agg := newAggregate()
+	for _, line := range lines {
+		l.consolidate(agg, line)
+	}
+
+	want := &LogAnalytics{
+		UniqueIPCount:   2,
+		Sessions:        4,
+		BounceRate:      0.5,
+		TopLandingPages: []string{"/"},
+		TopExitPages:    []string{"/pricing"},
+	}
+	if got := l.report(agg); !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() = %+v, want %+v", got, want)
+	}
+	if open := len(agg.openSessions); open != 1 {
+		t.Errorf("logAnalyzer.consolidate() left %d sessions open, want the inactive ones closed", open)
+	}
+
+	// stored aggregates keep the sessions
+	data, err := json.Marshal(agg)
+	if err != nil {
+		t.Fatalf("aggregate.MarshalJSON() error = %v", err)
+	}
+	restored := newAggregate()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("aggregate.UnmarshalJSON() error = %v", err)
+	}
+	if got := l.report(restored); !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() of a restored aggregate = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrSinkFailed :
+	ErrSinkFailed = "sink write failed"
+	// ErrWritingDeadLetter :
+	ErrWritingDeadLetter = "error writing dead letter file"
+
+	// DefaultSinkBackoff : Wait before the first retry of a failed sink write
+	DefaultSinkBackoff = time.Second
+	// DefaultSinkMaxBackoff : Longest wait between retries
+	DefaultSinkMaxBackoff = time.Minute
+)
+
+// BatchSink : A sink delivering several reports in one write, e.g. as a bulk
+// request, used when a SinkPolicy batches reports
+type BatchSink interface {
+	Sink
+	WriteBatch(batch []*LogAnalytics) error
+}
+
+// SinkPolicy : How the writes of a sink, e.g. to a remote store or webhook,
+// are retried, batched and rate limited
+type SinkPolicy struct {
+	// MaxRetries : Retries of a failed write before its reports are given up
+	MaxRetries int
+	// Backoff : Wait before the first retry, doubled for every next one up to
+	// MaxBackoff. DefaultSinkBackoff and DefaultSinkMaxBackoff when not set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// BatchSize : Reports written together, in one WriteBatch for batch
+	// sinks. Reports are held until a batch is full, or Flush. 1 when not set.
+	BatchSize int
+	// MinInterval : Shortest time between two writes, rate limiting the sink
+	MinInterval time.Duration
+	// DeadLetterPath : File the reports given up are appended to, one JSON
+	// object per line, to be replayed or inspected. They are dropped when not
+	// set.
+	DeadLetterPath string
+}
+
+// ReliableSink : A sink writing through another as per a SinkPolicy
+type ReliableSink struct {
+	sink    Sink
+	policy  SinkPolicy
+	pending []*LogAnalytics
+	// lastWrite : Time of the last write, for the rate limit
+	lastWrite time.Time
+	// sleep, now : time.Sleep and time.Now, but in tests
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+// NewReliableSink : Returns the sink wrapped with the policy
+func NewReliableSink(sink Sink, policy *SinkPolicy) *ReliableSink {
+	s := &ReliableSink{sink: sink, policy: *policy, sleep: time.Sleep, now: time.Now}
+	if s.policy.Backoff <= 0 {
+		s.policy.Backoff = DefaultSinkBackoff
+	}
+	if s.policy.MaxBackoff <= 0 {
+		s.policy.MaxBackoff = DefaultSinkMaxBackoff
+	}
+	if s.policy.BatchSize < 1 {
+		s.policy.BatchSize = 1
+	}
+	return s
+}
+
+// Name : The wrapped sink's name
+func (s *ReliableSink) Name() string {
+	return s.sink.Name()
+}
+
+// Write : Adds the report to the batch, writing the batch once it is full
+func (s *ReliableSink) Write(analytics *LogAnalytics) error {
+	s.pending = append(s.pending, analytics)
+	if len(s.pending) < s.policy.BatchSize {
+		return nil
+	}
+	return s.Flush()
+}
+
+// Flush : Writes the reports held, e.g. before exiting. Reports still
+// failing once retries are exhausted go to the dead letter file.
+func (s *ReliableSink) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	batch := s.pending
+	s.pending = nil
+
+	backoff := s.policy.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		s.waitRateLimit()
+		if batch, err = s.write(batch); err == nil {
+			return nil
+		}
+		if attempt == s.policy.MaxRetries {
+			break
+		}
+		s.sleep(backoff)
+		if backoff *= 2; backoff > s.policy.MaxBackoff {
+			backoff = s.policy.MaxBackoff
+		}
+	}
+
+	err = errors.Wrap(errors.Wrap(err, s.sink.Name()), ErrSinkFailed)
+	if deadErr := s.deadLetter(batch); deadErr != nil {
+		return errors.Wrap(err, deadErr.Error())
+	}
+	return err
+}
+
+// write : Writes the batch, returning the reports not written on failure
+func (s *ReliableSink) write(batch []*LogAnalytics) ([]*LogAnalytics, error) {
+	if batchSink, ok := s.sink.(BatchSink); ok && len(batch) > 1 {
+		if err := batchSink.WriteBatch(batch); err != nil {
+			return batch, err
+		}
+		return nil, nil
+	}
+	for i, analytics := range batch {
+		if err := s.sink.Write(analytics); err != nil {
+			return batch[i:], err
+		}
+	}
+	return nil, nil
+}
+
+func (s *ReliableSink) waitRateLimit() {
+	if s.policy.MinInterval > 0 && !s.lastWrite.IsZero() {
+		if wait := s.lastWrite.Add(s.policy.MinInterval).Sub(s.now()); wait > 0 {
+			s.sleep(wait)
+		}
+	}
+	s.lastWrite = s.now()
+}
+
+// deadLetter : Appends the reports to the dead letter file, if any
+func (s *ReliableSink) deadLetter(batch []*LogAnalytics) error {
+	if s.policy.DeadLetterPath == "" {
+		return nil
+	}
+	file, err := os.OpenFile(s.policy.DeadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, ErrWritingDeadLetter)
+	}
+	encoder := json.NewEncoder(file)
+	for _, analytics := range batch {
+		if err := encoder.Encode(analytics); err != nil {
+			file.Close()
+			return errors.Wrap(err, ErrWritingDeadLetter)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrap(err, ErrWritingDeadLetter)
+	}
+	return nil
+}
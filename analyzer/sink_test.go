@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// flakySink : Fails its first failures writes
+type flakySink struct {
+	failures int
+	batch    bool
+	writes   [][]int
+}
+
+func (s *flakySink) Name() string {
+	return "flaky"
+}
+
+func (s *flakySink) Write(analytics *LogAnalytics) error {
+	return s.WriteBatch([]*LogAnalytics{analytics})
+}
+
+func (s *flakySink) WriteBatch(batch []*LogAnalytics) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	var write []int
+	for _, analytics := range batch {
+		write = append(write, analytics.UniqueIPCount)
+	}
+	s.writes = append(s.writes, write)
+	return nil
+}
+
+// plainFlakySink : A flakySink without WriteBatch
+type plainFlakySink struct {
+	sink *flakySink
+}
+
+func (s *plainFlakySink) Name() string {
+	return s.sink.Name()
+}
+
+func (s *plainFlakySink) Write(analytics *LogAnalytics) error {
+	return s.sink.Write(analytics)
+}
+
+func TestReliableSink(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      SinkPolicy
+		failures    int
+		batchWrites bool
+		reports     int
+		wantWrites  [][]int
+		wantSleeps  []time.Duration
+		wantErr     bool
+		wantDead    int
+	}{
+		{
+			name:       "retried with backoff",
+			policy:     SinkPolicy{MaxRetries: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second},
+			failures:   3,
+			reports:    1,
+			wantWrites: [][]int{{0}},
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:       "given up to the dead letter file",
+			policy:     SinkPolicy{MaxRetries: 1, Backoff: time.Second},
+			failures:   5,
+			reports:    2,
+			wantSleeps: []time.Duration{time.Second, time.Second},
+			wantErr:    true,
+			wantDead:   2,
+		},
+		{
+			name:        "batched",
+			policy:      SinkPolicy{BatchSize: 2},
+			batchWrites: true,
+			reports:     3,
+			wantWrites:  [][]int{{0, 1}, {2}},
+		},
+		{
+			name:       "batched, written one by one",
+			policy:     SinkPolicy{BatchSize: 2},
+			reports:    3,
+			wantWrites: [][]int{{0}, {1}, {2}},
+		},
+		{
+			name:       "rate limited",
+			policy:     SinkPolicy{MinInterval: time.Minute},
+			reports:    2,
+			wantWrites: [][]int{{0}, {1}},
+			wantSleeps: []time.Duration{time.Minute},
+		},
+	}
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakySink{failures: tt.failures}
+			var sink Sink = &plainFlakySink{sink: flaky}
+			if tt.batchWrites {
+				sink = flaky
+			}
+			policy := tt.policy
+			policy.DeadLetterPath = filepath.Join(dir, fmt.Sprintf("dead-%d.jsonl", i))
+			s := NewReliableSink(sink, &policy)
+			var sleeps []time.Duration
+			s.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+			s.now = func() time.Time { return time.Unix(0, 0) }
+
+			var err error
+			for i := 0; i < tt.reports; i++ {
+				if writeErr := s.Write(&LogAnalytics{UniqueIPCount: i}); writeErr != nil {
+					err = writeErr
+				}
+			}
+			if flushErr := s.Flush(); flushErr != nil {
+				err = flushErr
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(flaky.writes, tt.wantWrites) {
+				t.Errorf("writes = %v, want %v", flaky.writes, tt.wantWrites)
+			}
+			if !reflect.DeepEqual(sleeps, tt.wantSleeps) {
+				t.Errorf("sleeps = %v, want %v", sleeps, tt.wantSleeps)
+			}
+			if dead := countLines(t, policy.DeadLetterPath); dead != tt.wantDead {
+				t.Errorf("dead letters = %d, want %d", dead, tt.wantDead)
+			}
+		})
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	return lines
+}
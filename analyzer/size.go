@@ -0,0 +1,41 @@
+package analyzer
+
+// SizeStats : Response sizes in bytes, estimated from a histogram
+type SizeStats struct {
+	Mean int
+	// Percentiles : Keyed by name, e.g. "p95"
+	Percentiles map[string]int
+}
+
+// consolidateSize : Counts the line's response size into the size histogram
+func (l *logAnalyzer) consolidateSize(agg *aggregate, line *Line) {
+	if agg.responseSizes == nil {
+		agg.responseSizes = newHistogram(l.sizeBounds)
+	}
+	agg.responseSizes.observe(l.sizeBounds, float64(line.Bytes))
+}
+
+// sizeReport : The mean and percentiles of the response sizes
+func (l *logAnalyzer) sizeReport(sizes *histogram) *SizeStats {
+	stats := &SizeStats{
+		Mean:        int(sizes.mean()),
+		Percentiles: make(map[string]int, len(l.percentiles)),
+	}
+	for _, p := range l.percentiles {
+		stats.Percentiles[percentileName(p)] = int(sizes.quantile(l.sizeBounds, p/100))
+	}
+	return stats
+}
+
+// mergeHistogram : Adds from into a histogram of the same buckets, either
+// possibly nil
+func mergeHistogram(into, from *histogram) *histogram {
+	if from == nil {
+		return into
+	}
+	if into == nil {
+		into = &histogram{Counts: make([]int64, len(from.Counts))}
+	}
+	into.merge(from)
+	return into
+}
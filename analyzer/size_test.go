@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_Analyze_responseSizes(t *testing.T) {
+	lineRegex := regexp.MustCompile(strings.TrimSuffix(defaultLineRegex.String(), "$") +
+		`\s(?P<upstream>\S+(?:, \S+)*)\s(?P<duration>\S+)$`)
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:   lineRegex,
+		Percentiles: []float64{50, 90},
+		SizeBuckets: []int{1000, 5000, 10000},
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/upstreams.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	want := &SizeStats{Mean: 2731, Percentiles: map[string]int{"p50": 1000, "p90": 6600}}
+	if !reflect.DeepEqual(got.ResponseSizes, want) {
+		t.Errorf("logAnalyzer.Analyze() response sizes = %+v, want %+v", got.ResponseSizes, want)
+	}
+}
+
+func TestNewLogAnalyzer_histogramSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *LogAnalyzerConfig
+		wantErr string
+	}{
+		{
+			name:    "percentile out of range",
+			config:  &LogAnalyzerConfig{Percentiles: []float64{99, 0}},
+			wantErr: "p0: " + ErrInvalidPercentile,
+		},
+		{
+			name:    "latency buckets not increasing",
+			config:  &LogAnalyzerConfig{LatencyBuckets: []time.Duration{time.Second, time.Millisecond}},
+			wantErr: "latency buckets: " + ErrInvalidBuckets,
+		},
+		{
+			name:    "size buckets not positive",
+			config:  &LogAnalyzerConfig{SizeBuckets: []int{0, 1024}},
+			wantErr: "size buckets: " + ErrInvalidBuckets,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.LineRegex = defaultLineRegex
+			_, err := NewLogAnalyzer(tt.config)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewLogAnalyzer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package analyzer
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrSortSpill :
+	ErrSortSpill = "error spilling sorted lines to disk"
+
+	// DefaultSortChunkSize : Lines held in memory by the time-ordering stage
+	// before a sorted chunk is spilled to disk
+	DefaultSortChunkSize = 100000
+
+	// sortFanIn : Spilled chunks merged at once, each holding an open file
+	sortFanIn = 64
+)
+
+// sourcedLine : A line along with the source it was read from
+type sourcedLine struct {
+	Source string
+	Line   *Line
+}
+
+// externalSort : Emits the lines of in ordered by time. Up to chunkSize lines
+// are sorted in memory; larger inputs are spilled to disk as sorted chunks in
+// tempDir (the OS default when empty) and k-way merged, so inputs larger than
+// memory can be time-ordered. At most fanIn chunks are merged at once: more
+// chunks are first merged fanIn at a time into larger ones, as many times as
+// needed, so the open files stay bounded however large the input. Lines with
+// equal times keep their input order. The error channel yields at most one
+// error, once the line channel is closed.
+func externalSort(in <-chan *sourcedLine, chunkSize, fanIn int, tempDir string) (<-chan *sourcedLine, <-chan error) {
+	outCh := make(chan *sourcedLine)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		defer close(outCh)
+
+		// the paths of the spilled chunks, in input order
+		var spills []string
+		defer func() {
+			for _, spill := range spills {
+				os.Remove(spill)
+			}
+		}()
+
+		chunk := make([]*sourcedLine, 0, chunkSize)
+		for line := range in {
+			chunk = append(chunk, line)
+			if len(chunk) < chunkSize {
+				continue
+			}
+			spill, err := spillChunk(chunk, tempDir)
+			if err != nil {
+				errCh <- err
+				drain(in)
+				return
+			}
+			spills = append(spills, spill)
+			chunk = chunk[:0]
+		}
+
+		sortChunk(chunk)
+		if len(spills) == 0 {
+			for _, line := range chunk {
+				outCh <- line
+			}
+			return
+		}
+
+		for len(spills) > fanIn {
+			var err error
+			if spills, err = mergePass(spills, fanIn, tempDir); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		err := mergeChunks(spills, chunk, func(line *sourcedLine) error {
+			outCh <- line
+			return nil
+		})
+		if err != nil {
+			errCh <- err
+		}
+	}()
+
+	return outCh, errCh
+}
+
+func sortChunk(chunk []*sourcedLine) {
+	sort.SliceStable(chunk, func(i, j int) bool {
+		return chunk[i].Line.Time.Before(chunk[j].Line.Time)
+	})
+}
+
+func spillChunk(chunk []*sourcedLine, tempDir string) (string, error) {
+	sortChunk(chunk)
+	return spill(tempDir, func(encoder *gob.Encoder) error {
+		for _, line := range chunk {
+			if err := encoder.Encode(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// spill : Writes the lines encoded by write to a new file of tempDir, closed
+// once written, and returns its path
+func spill(tempDir string, write func(encoder *gob.Encoder) error) (string, error) {
+	f, err := ioutil.TempFile(tempDir, "http-log-parser-sort-")
+	if err != nil {
+		return "", errors.Wrap(err, ErrSortSpill)
+	}
+	err = write(gob.NewEncoder(f))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", errors.Wrap(err, ErrSortSpill)
+	}
+	return f.Name(), nil
+}
+
+// mergePass : Merges the spilled chunks fanIn at a time, each run of
+// consecutive chunks into one, which keeps equal times in input order. It
+// returns the merged chunks, the chunks merged are removed.
+func mergePass(spills []string, fanIn int, tempDir string) ([]string, error) {
+	var merged []string
+	for len(spills) > 0 {
+		n := fanIn
+		if n > len(spills) {
+			n = len(spills)
+		}
+		path, err := spill(tempDir, func(encoder *gob.Encoder) error {
+			return mergeChunks(spills[:n], nil, func(line *sourcedLine) error {
+				return encoder.Encode(line)
+			})
+		})
+		if err != nil {
+			for _, path := range append(merged, spills...) {
+				os.Remove(path)
+			}
+			return nil, err
+		}
+		for _, path := range spills[:n] {
+			os.Remove(path)
+		}
+		merged = append(merged, path)
+		spills = spills[n:]
+	}
+	return merged, nil
+}
+
+// chunkCursor : The next line of a sorted chunk, on disk or in memory
+type chunkCursor struct {
+	index   int
+	line    *sourcedLine
+	decoder *gob.Decoder
+	memory  []*sourcedLine
+}
+
+func (c *chunkCursor) advance() error {
+	if c.decoder == nil {
+		if len(c.memory) == 0 {
+			c.line = nil
+			return nil
+		}
+		c.line, c.memory = c.memory[0], c.memory[1:]
+		return nil
+	}
+
+	line := &sourcedLine{}
+	err := c.decoder.Decode(line)
+	if err == io.EOF {
+		c.line = nil
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, ErrSortSpill)
+	}
+	c.line = line
+	return nil
+}
+
+// cursorHeap : Orders chunk cursors by the time of their next line, then by
+// chunk, which keeps the merge stable
+type cursorHeap []*chunkCursor
+
+func (h cursorHeap) Len() int { return len(h) }
+func (h cursorHeap) Less(i, j int) bool {
+	if h[i].line.Line.Time.Equal(h[j].line.Line.Time) {
+		return h[i].index < h[j].index
+	}
+	return h[i].line.Line.Time.Before(h[j].line.Line.Time)
+}
+func (h cursorHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x interface{}) { *h = append(*h, x.(*chunkCursor)) }
+func (h *cursorHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// mergeChunks : Emits the lines of the spilled chunks and of the last chunk,
+// held in memory, k-way merged
+func mergeChunks(spills []string, last []*sourcedLine, emit func(line *sourcedLine) error) error {
+	cursors := make(cursorHeap, 0, len(spills)+1)
+	for i, spill := range spills {
+		f, err := os.Open(spill)
+		if err != nil {
+			return errors.Wrap(err, ErrSortSpill)
+		}
+		defer f.Close()
+		cursors = append(cursors, &chunkCursor{index: i, decoder: gob.NewDecoder(f)})
+	}
+	cursors = append(cursors, &chunkCursor{index: len(spills), memory: last})
+
+	h := cursorHeap{}
+	for _, c := range cursors {
+		if err := c.advance(); err != nil {
+			return err
+		}
+		if c.line != nil {
+			h = append(h, c)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		c := h[0]
+		if err := emit(c.line); err != nil {
+			return err
+		}
+		if err := c.advance(); err != nil {
+			return err
+		}
+		if c.line == nil {
+			heap.Pop(&h)
+			continue
+		}
+		heap.Fix(&h, 0)
+	}
+
+	return nil
+}
+
+func drain(in <-chan *sourcedLine) {
+	for range in {
+	}
+}
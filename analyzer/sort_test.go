@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_externalSort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	at := func(minute int) time.Time {
+		return time.Date(2018, time.July, 10, 22, minute, 0, 0, time.UTC)
+	}
+	input := []*sourcedLine{
+		{Source: "a.log", Line: &Line{URL: "/5", Time: at(5)}},
+		{Source: "a.log", Line: &Line{URL: "/1", Time: at(1)}},
+		{Source: "b.log", Line: &Line{URL: "/3-first", Time: at(3)}},
+		{Source: "a.log", Line: &Line{URL: "/4", Time: at(4)}},
+		{Source: "b.log", Line: &Line{URL: "/3-second", Time: at(3)}},
+		{Source: "b.log", Line: &Line{URL: "/2", Time: at(2)}},
+		{Source: "a.log", Line: &Line{URL: "/3-third", Time: at(3)}},
+	}
+	want := []string{"/1", "/2", "/3-first", "/3-second", "/3-third", "/4", "/5"}
+
+	tests := []struct {
+		name      string
+		chunkSize int
+		fanIn     int
+	}{
+		{name: "sorted in memory", chunkSize: DefaultSortChunkSize, fanIn: sortFanIn},
+		{name: "spilled to disk and merged", chunkSize: 2, fanIn: sortFanIn},
+		{name: "one line per chunk", chunkSize: 1, fanIn: sortFanIn},
+		{name: "merged in several passes", chunkSize: 1, fanIn: 2},
+		{name: "merged in a single extra pass", chunkSize: 2, fanIn: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := make(chan *sourcedLine)
+			go func() {
+				defer close(in)
+				for _, line := range input {
+					in <- line
+				}
+			}()
+
+			outCh, errCh := externalSort(in, tt.chunkSize, tt.fanIn, dir)
+			var got []string
+			for line := range outCh {
+				got = append(got, line.Line.URL)
+			}
+			if err := <-errCh; err != nil {
+				t.Fatalf("externalSort() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("externalSort() = %v, want %v", got, want)
+			}
+
+			spills, _ := ioutil.ReadDir(dir)
+			if len(spills) != 0 {
+				t.Errorf("externalSort() left %d spill files behind", len(spills))
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+// ErrInvalidTokenizer :
+const ErrInvalidTokenizer = "invalid tokenizer"
+
+// Tokenizer : How lines of the Common and Combined Log Formats are split into
+// their fields
+type Tokenizer int
+
+const (
+	// TokenizerScan : Scan lines for their spaces, brackets and quotes,
+	// matching the line regex only against lines of an unusual shape
+	TokenizerScan Tokenizer = iota
+	// TokenizerRegex : Match every line against the line regex, e.g. to rule
+	// the tokenizer out when results look wrong
+	TokenizerRegex
+)
+
+// splitFormats : The line regexes of the formats read by scanning for their
+// delimiters instead of matching, and whether they log the referrer and user
+// agent
+var splitFormats = map[*regexp.Regexp]bool{
+	formats.CommonLog.LineRegex:   false,
+	formats.CombinedLog.LineRegex: true,
+}
+
+// newSplitParser : Parses lines of the Common or Combined Log Format with
+// strings.IndexByte, which scans with the same vectorized routine as
+// bytes.IndexByte, instead of regexp submatches. nil for other line regexes,
+// e.g. custom ones. Lines the scan cannot read exactly as the regex does,
+// e.g. with escaped quotes or tabs, are matched against the regex.
+func newSplitParser(lineRegex *regexp.Regexp, fields projection, batch *lineBatch) lineParser {
+	combined, ok := splitFormats[lineRegex]
+	if !ok {
+		return nil
+	}
+	names := fields.names(groupNames(lineRegex))
+	return func(text string) (*Line, error) {
+		if line, ok := splitLine(text, combined, fields, batch); ok {
+			return line, nil
+		}
+		return parseProjectedLine(lineRegex, names, fields, text, batch)
+	}
+}
+
+// splitLine : Reads a line of the Common or Combined Log Format, e.g.
+//
+//	177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 3574 "-" "Mozilla/5.0"
+//
+// filling the fields as parseProjectedLine does. ok is false when the line
+// is not of this plain shape, with single spaces between fields and no
+// escaped quotes, in which case the regex decides.
+func splitLine(text string, combined bool, fields projection, batch *lineBatch) (line *Line, ok bool) {
+	// \s also matches these, and unquoted fields would be split differently
+	if strings.IndexAny(text, "\t\n\f\r") >= 0 {
+		return nil, false
+	}
+
+	// the client, then at least the remote logname and user, before the date
+	host := strings.IndexByte(text, ' ')
+	if host <= 0 {
+		return nil, false
+	}
+	dateStart := host + 1 + strings.IndexByte(text[host+1:], '[')
+	if dateStart <= host+1 || !twoWords(text[host+1:dateStart]) {
+		return nil, false
+	}
+	dateEnd := strings.IndexByte(text[dateStart:], ']')
+	if dateEnd <= 1 {
+		return nil, false
+	}
+	dateEnd += dateStart
+	// the regex takes the last bracket followed by a matching rest of line
+	if strings.IndexByte(text[dateEnd:], '[') >= 0 {
+		return nil, false
+	}
+	rest := text[dateEnd+1:]
+	if !strings.HasPrefix(rest, ` "`) {
+		return nil, false
+	}
+	request, rest, ok := quoted(rest[2:])
+	if !ok || !strings.HasPrefix(rest, " ") {
+		return nil, false
+	}
+	status, rest, ok := word(rest[1:])
+	if !ok {
+		return nil, false
+	}
+
+	var bytes, referer, userAgent string
+	if combined {
+		if bytes, rest, ok = word(rest); !ok || !strings.HasPrefix(rest, `"`) {
+			return nil, false
+		}
+		if referer, rest, ok = quoted(rest[1:]); !ok || !strings.HasPrefix(rest, ` "`) || !strings.HasSuffix(rest, `"`) || len(rest) < 3 {
+			return nil, false
+		}
+		userAgent = rest[2 : len(rest)-1]
+	} else {
+		bytes = rest
+		if bytes == "" || strings.IndexByte(bytes, ' ') >= 0 {
+			return nil, false
+		}
+	}
+
+	line = batch.next()
+	if fields.keeps("remote_host") {
+		line.RemoteHost = text[:host]
+	}
+	if fields.keeps("remote_user") {
+		// the words after the remote logname, the user possibly having spaces
+		words := strings.TrimRight(text[host+1:dateStart], " ")
+		if user := strings.TrimLeft(words[strings.IndexByte(words, ' '):], " "); user != "-" {
+			line.RemoteUser = user
+		}
+	}
+	if fields.keeps("time") {
+		line.Time = parseTime(text[dateStart+1 : dateEnd])
+	}
+	if fields == nil || fields["request"] || fields["url"] {
+		method, url, protocol, altURL := requestGroups(request)
+		if url == "" && altURL != "" {
+			url = altURL
+		}
+		if fields == nil || fields["request"] {
+			line.setRequest(method, url, protocol)
+		}
+		if fields == nil || fields["url"] {
+			line.URL = url
+		}
+	}
+	if fields.keeps("status") {
+		line.Status = parseInt(status)
+	}
+	if fields.keeps("bytes") {
+		line.Bytes = parseInt(bytes)
+	}
+	if fields.keeps("referer") {
+		line.Referer = referer
+	}
+	if fields.keeps("user_agent") {
+		line.UserAgent = userAgent
+	}
+	return line, true
+}
+
+// twoWords : Whether the text is two or more words, each followed by a
+// space, as the remote logname and user are
+func twoWords(text string) bool {
+	if text == "" || text[0] == ' ' || text[len(text)-1] != ' ' {
+		return false
+	}
+	for i := 1; i < len(text)-1; i++ {
+		if text[i] == ' ' && text[i+1] != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// word : The text up to the next space, which is skipped, as `(\S+)\s`
+func word(text string) (value, rest string, ok bool) {
+	end := strings.IndexByte(text, ' ')
+	if end <= 0 {
+		return "", "", false
+	}
+	return text[:end], text[end+1:], true
+}
+
+// quoted : The text up to the next quote, which is skipped. Quotes escaped
+// with a backslash may or may not end the value for the regex, so are left
+// to it.
+func quoted(text string) (value, rest string, ok bool) {
+	end := strings.IndexByte(text, '"')
+	if end < 0 || (end > 0 && text[end-1] == '\\') {
+		return "", "", false
+	}
+	return text[:end], text[end+1:], true
+}
+
+// requestGroups : The groups of the request line as the regex captures them:
+// the method up to the first space, the URL up to the last one and the
+// protocol after it, or, with a single space, the URL alone in altURL
+func requestGroups(request string) (method, url, protocol, altURL string) {
+	first := strings.IndexByte(request, ' ')
+	if first < 0 {
+		return request, "", "", ""
+	}
+	last := strings.LastIndexByte(request, ' ')
+	if last == first {
+		return request[:first], "", "", request[first+1:]
+	}
+	return request[:first], request[first+1 : last], request[last+1:], ""
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrReadingState :
+	ErrReadingState = "error reading analyzer state"
+	// ErrWritingState :
+	ErrWritingState = "error writing analyzer state"
+	// ErrUnsupportedStateVersion :
+	ErrUnsupportedStateVersion = "unsupported analyzer state version"
+)
+
+// stateVersion : Version of the saved state layout. States of older versions
+// are read as far as they go; newer ones are refused.
+const stateVersion = 1
+
+// analyzerState : The state of a followed log, as saved by SaveState. Unlike
+// stored aggregates, open sessions are kept open, so visitors active across
+// a migration are not counted twice.
+type analyzerState struct {
+	Version int `json:"version"`
+	// Aggregate : The counts, of the closed sessions only
+	Aggregate     *aggregate               `json:"aggregate"`
+	OpenSessions  map[string]*sessionState `json:"openSessions,omitempty"`
+	SessionsSwept time.Time                `json:"sessionsSwept"`
+}
+
+// sessionState : The serialized form of an open session
+type sessionState struct {
+	Landing   string    `json:"landing"`
+	Exit      string    `json:"exit"`
+	Pageviews int       `json:"pageviews"`
+	Last      time.Time `json:"last"`
+}
+
+func (l *logAnalyzer) SaveState(w io.Writer) error {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+
+	agg := l.state
+	if agg == nil {
+		agg = newAggregate()
+	}
+	closed := *agg
+	closed.openSessions = nil
+	state := &analyzerState{
+		Version:       stateVersion,
+		Aggregate:     &closed,
+		OpenSessions:  make(map[string]*sessionState, len(agg.openSessions)),
+		SessionsSwept: agg.sessionsSwept,
+	}
+	for visitor, s := range agg.openSessions {
+		state.OpenSessions[visitor] = &sessionState{Landing: s.landing, Exit: s.exit, Pageviews: s.pageviews, Last: s.last}
+	}
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		return errors.Wrap(err, ErrWritingState)
+	}
+	return nil
+}
+
+func (l *logAnalyzer) LoadState(r io.Reader) error {
+	var state analyzerState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return errors.Wrap(err, ErrReadingState)
+	}
+	if state.Version > stateVersion {
+		return errors.Wrap(errors.New(ErrUnsupportedStateVersion), strconv.Itoa(state.Version))
+	}
+	agg := state.Aggregate
+	if agg == nil {
+		agg = newAggregate()
+	}
+	for visitor, s := range state.OpenSessions {
+		agg.openSessions[visitor] = &session{landing: s.Landing, exit: s.Exit, pageviews: s.Pageviews, last: s.Last}
+	}
+	agg.sessionsSwept = state.SessionsSwept
+
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	if l.state != nil {
+		// a running Follow keeps ranking by decayed hits
+		l.trackDecay(agg)
+	}
+	if l.state == nil {
+		l.state = agg
+	} else {
+		// a running Follow keeps counting into the loaded state, and sampling
+		// into its samples
+		samples := l.state.samples
+		samples.replace(agg.samples)
+		*l.state = *agg
+		l.state.samples = samples
+	}
+	return nil
+}
+
+// followedAggregate : The aggregate Follow counts into, the loaded state if any
+func (l *logAnalyzer) followedAggregate() *aggregate {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	if l.state == nil {
+		l.state = newAggregate()
+	}
+	l.trackDecay(l.state)
+	return l.state
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_SaveState(t *testing.T) {
+	config := &LogAnalyzerConfig{
+		LineRegex:            defaultLineRegex,
+		MostActiveIPsCount:   1,
+		Sessions:             &SessionRules{},
+		TopLandingPagesCount: 1,
+		TopExitPagesCount:    1,
+	}
+	newAnalyzer := func() *logAnalyzer {
+		a, err := NewLogAnalyzer(config)
+		if err != nil {
+			t.Fatalf("logAnalyzer.SaveState() error = %v, error creating analyzer", err)
+		}
+		return a.(*logAnalyzer)
+	}
+	start := time.Date(2018, 7, 10, 10, 0, 0, 0, time.UTC)
+	pageview := func(url string, minutes int) *Line {
+		return &Line{
+			RemoteHost: "1.1.1.1",
+			Time:       start.Add(time.Duration(minutes) * time.Minute),
+			Method:     "GET",
+			Path:       url,
+			Protocol:   "HTTP/1.1",
+			URL:        url,
+			Status:     200,
+			UserAgent:  "Firefox",
+		}
+	}
+
+	before := newAnalyzer()
+	before.consolidate(before.followedAggregate(), pageview("/", 0))
+	var state bytes.Buffer
+	if err := before.SaveState(&state); err != nil {
+		t.Fatalf("logAnalyzer.SaveState() error = %v", err)
+	}
+
+	// the session open when the state was saved goes on after it is loaded
+	after := newAnalyzer()
+	if err := after.LoadState(&state); err != nil {
+		t.Fatalf("logAnalyzer.LoadState() error = %v", err)
+	}
+	after.consolidate(after.followedAggregate(), pageview("/pricing", 5))
+	want := &LogAnalytics{
+		UniqueIPCount:   1,
+		MostActiveIPs:   []string{"1.1.1.1"},
+		Sessions:        1,
+		TopLandingPages: []string{"/"},
+		TopExitPages:    []string{"/pricing"},
+	}
+	if got := after.report(after.followedAggregate()); !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() of a loaded state = %+v, want %+v", got, want)
+	}
+}
+
+func Test_logAnalyzer_LoadState(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		wantErr string
+	}{
+		{name: "empty state", state: `{"version":1}`},
+		{name: "newer version", state: `{"version":2}`, wantErr: "2: " + ErrUnsupportedStateVersion},
+		{name: "not a state", state: `[]`, wantErr: ErrReadingState + ": json: cannot unmarshal array into Go value of type analyzer.analyzerState"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: defaultLineRegex})
+			if err != nil {
+				t.Fatalf("logAnalyzer.LoadState() error = %v, error creating analyzer", err)
+			}
+			err = l.LoadState(strings.NewReader(tt.state))
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("logAnalyzer.LoadState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package analyzer
+
+import "regexp"
+
+// syslogHeaders : The headers syslog daemons prefix messages with, RFC 5424
+// first, capturing the host name, the tag (app name) and the message:
+//
+//	<165>1 2003-10-11T22:14:15.003Z web1 nginx 1234 - [meta x="1"] message
+//	<34>Oct 11 22:14:15 web1 nginx[1234]: message
+//	2023-10-11T22:14:15.003+02:00 web1 nginx: message
+//
+// The priority is optional, as files written by rsyslog or syslog-ng leave it
+// out, and so are the process IDs of RFC 3164 tags.
+var syslogHeaders = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:<\d{1,3}>)?1 \S+ (\S+) (\S+) \S+ \S+ (?:-|(?:\[(?:[^\]"\\]|\\.|"(?:[^"\\]|\\.)*")*\])+)(?: (?:\x{FEFF})?(.*))?$`),
+	regexp.MustCompile(`^(?:<\d{1,3}>)?(?:[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\S+) (\S+) ([^:\s\[]+)(?:\[\d+\])?: ?(.*)$`),
+}
+
+// stripSyslog : The message of a line with a syslog header, and the host and
+// tag of the header. Lines without one are left as they are.
+func stripSyslog(text string) (message, host, tag string, ok bool) {
+	for _, header := range syslogHeaders {
+		if m := header.FindStringSubmatch(text); m != nil {
+			return m[3], m[1], m[2], true
+		}
+	}
+	return text, "", "", false
+}
+
+// syslogParser : The parser, of lines stripped of their syslog header. The
+// host and tag are kept as the syslog_host and syslog_tag extras, when needed.
+func (l *logAnalyzer) syslogParser(parse lineParser) lineParser {
+	keepHost, keepTag := l.projection.keeps("syslog_host"), l.projection.keeps("syslog_tag")
+	return func(text string) (*Line, error) {
+		message, host, tag, ok := stripSyslog(text)
+		line, err := parse(message)
+		if !ok || err != nil || !keepHost && !keepTag {
+			return line, err
+		}
+		if line.Extras == nil {
+			line.Extras = make(map[string]string, 2)
+		}
+		if keepHost {
+			line.Extras["syslog_host"] = host
+		}
+		if keepTag {
+			line.Extras["syslog_tag"] = tag
+		}
+		return line, nil
+	}
+}
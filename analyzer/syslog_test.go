@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+const syslogMessage = `10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET /a HTTP/1.1" 200 120 "-" "curl/7.64.0"`
+
+func Test_stripSyslog(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantMessage string
+		wantHost    string
+		wantTag     string
+		wantOK      bool
+	}{
+		{
+			name:        "RFC 3164",
+			text:        "<190>Jul 10 22:21:28 web1 nginx[1234]: " + syslogMessage,
+			wantMessage: syslogMessage,
+			wantHost:    "web1",
+			wantTag:     "nginx",
+			wantOK:      true,
+		},
+		{
+			name:        "RFC 3164 without priority, as written by rsyslog",
+			text:        "Jul  1 22:21:28 web1 nginx: " + syslogMessage,
+			wantMessage: syslogMessage,
+			wantHost:    "web1",
+			wantTag:     "nginx",
+			wantOK:      true,
+		},
+		{
+			name:        "high precision timestamp",
+			text:        "2018-07-10T22:21:28.003+02:00 web1 haproxy[99]: " + syslogMessage,
+			wantMessage: syslogMessage,
+			wantHost:    "web1",
+			wantTag:     "haproxy",
+			wantOK:      true,
+		},
+		{
+			name:        "RFC 5424",
+			text:        "<165>1 2018-07-10T22:21:28.003Z web1.example.com nginx 1234 access - " + syslogMessage,
+			wantMessage: syslogMessage,
+			wantHost:    "web1.example.com",
+			wantTag:     "nginx",
+			wantOK:      true,
+		},
+		{
+			name:        "RFC 5424 with structured data",
+			text:        `<165>1 2018-07-10T22:21:28Z web1 nginx - - [meta sequenceId="1" note="a \"quoted\" ]"][origin ip="10.0.0.9"] ` + syslogMessage,
+			wantMessage: syslogMessage,
+			wantHost:    "web1",
+			wantTag:     "nginx",
+			wantOK:      true,
+		},
+		{
+			name:        "no header",
+			text:        syslogMessage,
+			wantMessage: syslogMessage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, host, tag, ok := stripSyslog(tt.text)
+			if message != tt.wantMessage || host != tt.wantHost || tag != tt.wantTag || ok != tt.wantOK {
+				t.Errorf("stripSyslog() = %q, %q, %q, %v, want %q, %q, %q, %v",
+					message, host, tag, ok, tt.wantMessage, tt.wantHost, tt.wantTag, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLogAnalyzerConfig_syslog(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{Format: formats.CombinedLog, Syslog: true})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	parse := l.(*logAnalyzer).newLineParser()
+	got, err := parse("Jul 10 22:21:28 web1 nginx: " + syslogMessage)
+	if err != nil {
+		t.Fatalf("parse error = %v", err)
+	}
+	if want := map[string]string{"syslog_host": "web1", "syslog_tag": "nginx"}; !reflect.DeepEqual(got.Extras, want) {
+		t.Errorf("parse extras = %v, want %v", got.Extras, want)
+	}
+	if got.RemoteHost != "10.0.0.1" || got.URL != "/a" {
+		t.Errorf("parse = %+v, want the client and URL of the message", got)
+	}
+	if got, err := parse(syslogMessage); err != nil || got.Extras != nil {
+		t.Errorf("parse without header = %+v, %v, want the line without extras", got, err)
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// TimeLayoutUnix : The layout of Unix times in seconds, e.g. 1646861401
+	// or 1646861401.524, and of whole Unix milliseconds, microseconds or
+	// nanoseconds, told apart by their number of digits
+	TimeLayoutUnix = "unix"
+	// TimeLayoutUnixMillis : The layout of Unix times in milliseconds, e.g.
+	// 1646861401524, whatever their number of digits
+	TimeLayoutUnixMillis = "unix_ms"
+)
+
+// DefaultTimeLayouts : The layouts of the logged times tried in order when
+// TimeLayouts is not set: the Common Log Format's, ISO 8601 with and
+// without fractional seconds, the Common Log Format's without a zone, and
+// Unix times
+var DefaultTimeLayouts = []string{"02/Jan/2006:15:04:05 -0700", time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "02/Jan/2006:15:04:05", TimeLayoutUnix}
+
+// timeLayouts : Layouts of the logged times, as in time.Parse or one of
+// TimeLayoutUnix and TimeLayoutUnixMillis, tried in order. nil for
+// DefaultTimeLayouts.
+type timeLayouts []string
+
+// parse : The time of the first layout the value parses with, zero when none
+func (layouts timeLayouts) parse(value string) time.Time {
+	if layouts == nil {
+		layouts = DefaultTimeLayouts
+	}
+	for _, layout := range layouts {
+		var t time.Time
+		switch layout {
+		case TimeLayoutUnix:
+			t = parseUnixSeconds(value)
+		case TimeLayoutUnixMillis:
+			t = parseUnixMillis(value)
+		default:
+			t, _ = time.Parse(layout, value)
+		}
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseUnixMillis : A time logged as Unix milliseconds, e.g. 1646861401524
+// or 1646861401524.1024. Zero when invalid.
+func parseUnixMillis(value string) time.Time {
+	whole, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		whole, fraction = value[:i], value[i+1:]
+	}
+	if len(whole) <= 3 || fraction != "" && (fraction[0] < '0' || fraction[0] > '9') {
+		return time.Time{}
+	}
+	return parseUnixSeconds(whole[:len(whole)-3] + "." + whole[len(whole)-3:] + fraction)
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_timeLayouts_parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		layouts timeLayouts
+		value   string
+		want    time.Time
+	}{
+		{name: "common log format", value: "10/Jul/2018:22:21:28 +0200", want: time.Date(2018, 7, 10, 20, 21, 28, 0, time.UTC)},
+		{name: "iso 8601", value: "2018-07-10T20:21:28.5Z", want: time.Date(2018, 7, 10, 20, 21, 28, 5e8, time.UTC)},
+		{name: "unix seconds", value: "1531254088", want: time.Date(2018, 7, 10, 20, 21, 28, 0, time.UTC)},
+		{name: "unix milliseconds by digits", value: "1531254088250", want: time.Date(2018, 7, 10, 20, 21, 28, 25e7, time.UTC)},
+		{name: "invalid", value: "yesterday"},
+		{name: "custom", layouts: timeLayouts{"2006-01-02 15:04:05"}, value: "2018-07-10 20:21:28", want: time.Date(2018, 7, 10, 20, 21, 28, 0, time.UTC)},
+		{name: "custom only", layouts: timeLayouts{"2006-01-02 15:04:05"}, value: "10/Jul/2018:22:21:28 +0200"},
+		{name: "fallback", layouts: timeLayouts{"2006-01-02 15:04:05", TimeLayoutUnix}, value: "1531254088", want: time.Date(2018, 7, 10, 20, 21, 28, 0, time.UTC)},
+		{name: "unix_ms", layouts: timeLayouts{TimeLayoutUnixMillis}, value: "1531254088250", want: time.Date(2018, 7, 10, 20, 21, 28, 25e7, time.UTC)},
+		{name: "unix_ms fraction", layouts: timeLayouts{TimeLayoutUnixMillis}, value: "1531254088250.5", want: time.Date(2018, 7, 10, 20, 21, 28, 2505e5, time.UTC)},
+		{name: "unix_ms invalid", layouts: timeLayouts{TimeLayoutUnixMillis}, value: "153125408x250"},
+		{name: "unix_ms too short", layouts: timeLayouts{TimeLayoutUnixMillis}, value: "250"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.layouts.parse(tt.value); !got.Equal(tt.want) {
+				t.Errorf("timeLayouts.parse(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogAnalyzer_timeLayouts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "times")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "access.log")
+	lines := []string{
+		`10.0.0.1 [2018-07-10 20:21:28] "GET /a HTTP/1.1" 200`,
+		`10.0.0.2 [1531254089000] "GET /b HTTP/1.1" 200`,
+		`10.0.0.3 [10/Jul/2018:22:21:30 +0200] "GET /c HTTP/1.1" 200`,
+	}
+	if err := ioutil.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lineRegex := regexp.MustCompile(`^(?P<remote_host>\S+) \[(?P<time>[^]]+)\] "(?P<request>[^"]*)" (?P<status>\d+)$`)
+
+	tests := []struct {
+		name    string
+		layouts []string
+		want    int64
+	}{
+		{name: "default", want: 1},
+		{name: "custom", layouts: []string{"2006-01-02 15:04:05", TimeLayoutUnixMillis}, want: 1},
+		{name: "all", layouts: append([]string{"2006-01-02 15:04:05", TimeLayoutUnixMillis}, DefaultTimeLayouts...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warned []Warning
+			a, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: lineRegex, TimeLayouts: tt.layouts, OnWarning: func(w Warning) { warned = append(warned, w) }})
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			if _, err := a.Analyze(filePath); err != nil {
+				t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+			}
+			if got := a.SelfMetrics().TimeParseErrors; got != tt.want || int64(len(warned)) != tt.want {
+				t.Errorf("logAnalyzer.Analyze() time parse errors = %d, %d warnings, want %d", got, len(warned), tt.want)
+			}
+		})
+	}
+
+	// strict schema mode checks times with the layouts
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{LineRegex: lineRegex, StrictSchema: true, TimeLayouts: []string{"2006-01-02 15:04:05"}})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	if _, err := a.Analyze(filePath); err == nil || !strings.Contains(err.Error(), ErrSchemaMismatch) {
+		t.Errorf("logAnalyzer.Analyze() error = %v, wantErr %v", err, ErrSchemaMismatch)
+	}
+}
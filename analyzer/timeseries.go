@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeseriesInterval : Time series are daily by default
+const DefaultTimeseriesInterval = 24 * time.Hour
+
+// DeviceSeries : Name of the time series of requests per device type
+const DeviceSeries = "device"
+
+// StatusSeriesPrefix : Prefix of the names of the time series of requests
+// per status class ("2xx", "4xx"...) of an endpoint group, e.g. "status.api"
+const StatusSeriesPrefix = "status."
+
+// EndpointGroup : URLs reported together, e.g. {"api", ^/api/}
+type EndpointGroup struct {
+	Name string
+	// Path : Matches the URL paths, without the query, of the group
+	Path *regexp.Regexp
+}
+
+// TimeseriesBucket : Counts of one time interval, per label
+type TimeseriesBucket struct {
+	// Start : Start of the interval, aligned on UTC
+	Start  time.Time
+	Counts map[string]int
+	// Annotations : Labels of the annotations of the interval
+	Annotations []string `json:",omitempty"`
+}
+
+// seriesHits : Counts per label, per bucket start (Unix seconds)
+type seriesHits map[int64]map[string]int
+
+// count : Counts one more line of the label in the series, in the bucket of
+// the line time. Lines without a time are left out.
+func (l *logAnalyzer) count(agg *aggregate, series string, at time.Time, label string) {
+	if at.IsZero() {
+		return
+	}
+	buckets, ok := agg.timeseries[series]
+	if !ok {
+		buckets = make(seriesHits)
+		agg.timeseries[series] = buckets
+	}
+	start := at.UTC().Truncate(l.timeseriesInterval).Unix()
+	counts, ok := buckets[start]
+	if !ok {
+		counts = make(map[string]int)
+		buckets[start] = counts
+	}
+	counts[label]++
+}
+
+// endpointGroupOf : The name of the first group the URL belongs to, "" when
+// it belongs to none
+func endpointGroupOf(groups []*EndpointGroup, url string) string {
+	path := urlPath(url)
+	for _, group := range groups {
+		if group.Path.MatchString(path) {
+			return group.Name
+		}
+	}
+	return ""
+}
+
+// statusClass : e.g. "4xx" for 404, "other" for statuses out of range
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// deviceOf : The device type of the line's user agent, as classified by the
+// useragent enricher
+func deviceOf(line *Line) string {
+	if device, ok := line.Enrichments["useragent.device"]; ok {
+		return device
+	}
+	return classify(strings.ToLower(line.UserAgent), deviceTokens, "desktop")
+}
+
+// timeseriesReport : The series, with buckets in time order
+func timeseriesReport(timeseries map[string]seriesHits) map[string][]*TimeseriesBucket {
+	if len(timeseries) == 0 {
+		return nil
+	}
+	report := make(map[string][]*TimeseriesBucket, len(timeseries))
+	for series, buckets := range timeseries {
+		starts := make([]int64, 0, len(buckets))
+		for start := range buckets {
+			starts = append(starts, start)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+		report[series] = make([]*TimeseriesBucket, 0, len(starts))
+		for _, start := range starts {
+			counts := make(map[string]int, len(buckets[start]))
+			mergeHits(counts, buckets[start])
+			report[series] = append(report[series], &TimeseriesBucket{
+				Start:  time.Unix(start, 0).UTC(),
+				Counts: counts,
+			})
+		}
+	}
+	return report
+}
+
+func mergeTimeseries(into, from map[string]seriesHits) {
+	for series, buckets := range from {
+		if _, ok := into[series]; !ok {
+			into[series] = make(seriesHits, len(buckets))
+		}
+		for start, counts := range buckets {
+			if _, ok := into[series][start]; !ok {
+				into[series][start] = make(map[string]int, len(counts))
+			}
+			mergeHits(into[series][start], counts)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_logAnalyzer_report_deviceTimeseries(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:        defaultLineRegex,
+		DeviceTimeseries: true,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	cest := time.FixedZone("CEST", 2*60*60)
+	lines := []*Line{
+		{RemoteHost: "177.71.128.21", Time: time.Date(2018, 7, 10, 1, 0, 0, 0, cest), UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 11_0 like Mac OS X) Mobile/15A372"},
+		{RemoteHost: "177.71.128.21", Time: time.Date(2018, 7, 10, 9, 0, 0, 0, cest), UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Chrome/71.0.3578.98"},
+		{RemoteHost: "177.71.128.21", Time: time.Date(2018, 7, 10, 10, 0, 0, 0, cest), UserAgent: "Googlebot/2.1"},
+		{RemoteHost: "177.71.128.21", Time: time.Date(2018, 7, 10, 11, 0, 0, 0, cest), Enrichments: map[string]string{"useragent.device": "tablet"}},
+		// no time, not in the series
+		{RemoteHost: "177.71.128.21", UserAgent: "Googlebot/2.1"},
+	}
+	agg := newAggregate()
+	for _, line := range lines {
+		l.consolidate(agg, line)
+	}
+
+	want := map[string][]*TimeseriesBucket{
+		DeviceSeries: {
+			{Start: time.Date(2018, 7, 9, 0, 0, 0, 0, time.UTC), Counts: map[string]int{"mobile": 1}},
+			{Start: time.Date(2018, 7, 10, 0, 0, 0, 0, time.UTC), Counts: map[string]int{"desktop": 1, "bot": 1, "tablet": 1}},
+		},
+	}
+	if got := l.report(agg).Timeseries; !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() timeseries = %v, want %v", got, want)
+	}
+}
+
+func Test_logAnalyzer_report_statusTimeseries(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:          defaultLineRegex,
+		TimeseriesInterval: time.Hour,
+		EndpointGroups: []*EndpointGroup{
+			{Name: "api", Path: regexp.MustCompile(`^/api/`)},
+			{Name: "docs", Path: regexp.MustCompile(`^/docs/`)},
+			{Name: "everything", Path: regexp.MustCompile(`.`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	at := func(minutes int) time.Time {
+		return time.Date(2018, 7, 10, 10, minutes, 0, 0, time.UTC)
+	}
+	lines := []*Line{
+		{RemoteHost: "177.71.128.21", Time: at(0), URL: "/api/users", Status: 200},
+		{RemoteHost: "177.71.128.21", Time: at(10), URL: "/api/users?page=/docs/", Status: 503},
+		{RemoteHost: "177.71.128.21", Time: at(20), URL: "/api/users", Status: 201},
+		{RemoteHost: "177.71.128.21", Time: at(70), URL: "/docs/", Status: 404},
+		{RemoteHost: "177.71.128.21", Time: at(80), URL: "/", Status: 0},
+	}
+	agg := newAggregate()
+	for _, line := range lines {
+		l.consolidate(agg, line)
+	}
+
+	want := map[string][]*TimeseriesBucket{
+		"status.api": {
+			{Start: at(0), Counts: map[string]int{"2xx": 2, "5xx": 1}},
+		},
+		"status.docs": {
+			{Start: at(60), Counts: map[string]int{"4xx": 1}},
+		},
+		"status.everything": {
+			{Start: at(60), Counts: map[string]int{"other": 1}},
+		},
+	}
+	if got := l.report(agg).Timeseries; !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() timeseries = %v, want %v", got, want)
+	}
+}
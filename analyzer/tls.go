@@ -0,0 +1,51 @@
+package analyzer
+
+import (
+	"crypto/tls"
+	"strconv"
+	"strings"
+)
+
+// tlsVersions : The TLS versions some logs give as their protocol number,
+// e.g. Caddy's 772 for TLS 1.3
+var tlsVersions = map[string]string{
+	strconv.Itoa(tls.VersionTLS10): "TLSv1",
+	strconv.Itoa(tls.VersionTLS11): "TLSv1.1",
+	strconv.Itoa(tls.VersionTLS12): "TLSv1.2",
+	strconv.Itoa(tls.VersionTLS13): "TLSv1.3",
+}
+
+// tlsProtocol : The logged TLS protocol version as nginx and OpenSSL name it,
+// e.g. TLSv1.2, whether it was logged so, as S3's TLSV1.2, Traefik's 1.2 or
+// Caddy's 771. Empty when not logged, e.g. "-" over plain HTTP.
+func tlsProtocol(value string) string {
+	if value == "" || value == "-" || strings.EqualFold(value, "none") {
+		return ""
+	}
+	if version, ok := tlsVersions[value]; ok {
+		return version
+	}
+	version := value
+	if len(version) > 3 && strings.EqualFold(version[:3], "TLS") {
+		version = strings.TrimLeft(version[3:], "vV ")
+	}
+	switch version {
+	case "1", "1.0":
+		return "TLSv1"
+	case "1.1", "1.2", "1.3":
+		return "TLSv" + version
+	}
+	return value
+}
+
+// tlsCipher : The logged TLS cipher suite, by its IANA name when logged as
+// its number, e.g. Caddy's 4865. Empty when not logged.
+func tlsCipher(value string) string {
+	if value == "" || value == "-" || strings.EqualFold(value, "none") {
+		return ""
+	}
+	if id, err := strconv.ParseUint(value, 10, 16); err == nil {
+		return tls.CipherSuiteName(uint16(id))
+	}
+	return value
+}
@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_tlsProtocol(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "TLSv1.2", want: "TLSv1.2"},
+		{value: "TLSV1.2", want: "TLSv1.2"},
+		{value: "TLSv1", want: "TLSv1"},
+		{value: "TLS 1.0", want: "TLSv1"},
+		{value: "1.3", want: "TLSv1.3"},
+		{value: "772", want: "TLSv1.3"},
+		{value: "SSLv3", want: "SSLv3"},
+		{value: "-"},
+		{value: "none"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		if got := tlsProtocol(tt.value); got != tt.want {
+			t.Errorf("tlsProtocol(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func Test_tlsCipher(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "ECDHE-RSA-AES128-GCM-SHA256", want: "ECDHE-RSA-AES128-GCM-SHA256"},
+		{value: "4865", want: "TLS_AES_128_GCM_SHA256"},
+		{value: "-"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		if got := tlsCipher(tt.value); got != tt.want {
+			t.Errorf("tlsCipher(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewLogAnalyzer_tls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "access.log")
+	lines := []string{
+		`10.0.0.1 [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 TLSv1.3 TLS_AES_128_GCM_SHA256`,
+		`10.0.0.2 [10/Jul/2018:22:21:29 +0200] "GET / HTTP/1.1" 200 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256`,
+		`10.0.0.3 [10/Jul/2018:22:21:30 +0200] "GET / HTTP/1.1" 200 TLSv1 ECDHE-RSA-AES128-SHA`,
+		`10.0.0.4 [10/Jul/2018:22:21:31 +0200] "GET / HTTP/1.1" 200 TLSv1.3 TLS_AES_128_GCM_SHA256`,
+		`10.0.0.5 [10/Jul/2018:22:21:32 +0200] "GET / HTTP/1.1" 301 - -`,
+	}
+	if err := ioutil.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	format, err := formats.Nginx(`$remote_addr [$time_local] "$request" $status $ssl_protocol $ssl_cipher`)
+	if err != nil {
+		t.Fatalf("formats.Nginx() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		config        *LogAnalyzerConfig
+		wantProtocols map[string]int
+		wantCiphers   map[string]int
+	}{
+		{
+			name:          "logged",
+			config:        &LogAnalyzerConfig{Format: format},
+			wantProtocols: map[string]int{"TLSv1.3": 2, "TLSv1.2": 1, "TLSv1": 1},
+			wantCiphers:   map[string]int{"TLS_AES_128_GCM_SHA256": 2, "ECDHE-RSA-AES128-GCM-SHA256": 1, "ECDHE-RSA-AES128-SHA": 1},
+		},
+		{name: "disabled", config: &LogAnalyzerConfig{Format: format, DisabledCollectors: []Collector{CollectTLS}}},
+		{name: "not logged", config: &LogAnalyzerConfig{LineRegex: defaultLineRegex}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewLogAnalyzer(tt.config)
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			analytics, err := a.Analyze(filePath)
+			if err != nil {
+				t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+			}
+			if !reflect.DeepEqual(analytics.TLSProtocols, tt.wantProtocols) || !reflect.DeepEqual(analytics.TLSCiphers, tt.wantCiphers) {
+				t.Errorf("logAnalyzer.Analyze() TLS = %v and %v, want %v and %v", analytics.TLSProtocols, analytics.TLSCiphers, tt.wantProtocols, tt.wantCiphers)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpstreamStats : How a backend served the requests proxied to it
+type UpstreamStats struct {
+	Upstream string
+	Requests int
+	// ErrorRate : Ratio of 5xx responses
+	ErrorRate float64
+	// MeanLatency, LatencyPercentiles : Response times, estimated from a
+	// histogram, when the log format has durations. Percentiles are keyed by
+	// name, e.g. "p95".
+	MeanLatency        time.Duration            `json:",omitempty"`
+	LatencyPercentiles map[string]time.Duration `json:",omitempty"`
+}
+
+// upstreamHits : Requests of a backend
+type upstreamHits struct {
+	Requests int        `json:"requests"`
+	Errors   int        `json:"errors"`
+	Latency  *histogram `json:"latency,omitempty"`
+}
+
+// lastUpstream : The backend that served the response, out of an nginx
+// $upstream_addr listing every backend tried ("a:80, b:80 : c:80")
+func lastUpstream(upstreamAddr string) string {
+	if i := strings.LastIndex(upstreamAddr, ", "); i >= 0 {
+		upstreamAddr = upstreamAddr[i+len(", "):]
+	}
+	if i := strings.LastIndex(upstreamAddr, " : "); i >= 0 {
+		upstreamAddr = upstreamAddr[i+len(" : "):]
+	}
+	if upstreamAddr == "-" {
+		return ""
+	}
+	return upstreamAddr
+}
+
+// parseSeconds : A duration logged as float seconds, e.g. "0.125", or with
+// its unit, e.g. "18ms", zero when missing, negative or invalid
+func parseSeconds(value string) time.Duration {
+	s, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return 0
+		}
+		return d
+	}
+	if s < 0 {
+		return 0
+	}
+	return seconds(s)
+}
+
+// percentileName : e.g. "p99.9" for 99.9
+func percentileName(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// consolidateUpstream : Counts the line into its backend's requests
+func (l *logAnalyzer) consolidateUpstream(agg *aggregate, line *Line) {
+	hits, ok := agg.upstreams[line.Upstream]
+	if !ok {
+		hits = &upstreamHits{}
+		agg.upstreams[line.Upstream] = hits
+	}
+	hits.Requests++
+	if line.Status >= 500 && line.Status <= 599 {
+		hits.Errors++
+	}
+	if l.logsDurations {
+		if hits.Latency == nil {
+			hits.Latency = newHistogram(l.latencyBounds)
+		}
+		hits.Latency.observe(l.latencyBounds, line.Duration.Seconds())
+	}
+}
+
+// upstreamsReport : The busiest backends first
+func (l *logAnalyzer) upstreamsReport(upstreams map[string]*upstreamHits, top int) []*UpstreamStats {
+	stats := make([]*UpstreamStats, 0, len(upstreams))
+	for upstream, hits := range upstreams {
+		s := &UpstreamStats{
+			Upstream:  upstream,
+			Requests:  hits.Requests,
+			ErrorRate: float64(hits.Errors) / float64(hits.Requests),
+		}
+		if hits.Latency != nil {
+			s.MeanLatency = seconds(hits.Latency.mean())
+			s.LatencyPercentiles = make(map[string]time.Duration, len(l.percentiles))
+			for _, p := range l.percentiles {
+				s.LatencyPercentiles[percentileName(p)] = seconds(hits.Latency.quantile(l.latencyBounds, p/100))
+			}
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Requests != stats[j].Requests {
+			return stats[i].Requests > stats[j].Requests
+		}
+		return stats[i].Upstream < stats[j].Upstream
+	})
+	if top < len(stats) {
+		stats = stats[:top]
+	}
+	return stats
+}
+
+func mergeUpstreams(into, from map[string]*upstreamHits) {
+	for upstream, hits := range from {
+		merged, ok := into[upstream]
+		if !ok {
+			merged = &upstreamHits{}
+			into[upstream] = merged
+		}
+		merged.Requests += hits.Requests
+		merged.Errors += hits.Errors
+		merged.Latency = mergeHistogram(merged.Latency, hits.Latency)
+	}
+}
@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_lastUpstream(t *testing.T) {
+	tests := []struct {
+		upstreamAddr string
+		want         string
+	}{
+		{upstreamAddr: "10.0.0.1:8080", want: "10.0.0.1:8080"},
+		{upstreamAddr: "10.0.0.1:8080, 10.0.0.2:8080", want: "10.0.0.2:8080"},
+		{upstreamAddr: "10.0.0.1:8080, 10.0.0.2:8080 : unix:/tmp/app.sock", want: "unix:/tmp/app.sock"},
+		{upstreamAddr: "-", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upstreamAddr, func(t *testing.T) {
+			if got := lastUpstream(tt.upstreamAddr); got != tt.want {
+				t.Errorf("lastUpstream() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_Analyze_upstreams(t *testing.T) {
+	lineRegex := regexp.MustCompile(strings.TrimSuffix(defaultLineRegex.String(), "$") +
+		`\s(?P<upstream>\S+(?:, \S+)*)\s(?P<duration>\S+)$`)
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex:      lineRegex,
+		UpstreamsCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/upstreams.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+
+	want := []*UpstreamStats{
+		{
+			Upstream:    "10.0.0.1:8080",
+			Requests:    4,
+			MeanLatency: 25 * time.Millisecond,
+			LatencyPercentiles: map[string]time.Duration{
+				"p50": 25 * time.Millisecond,
+				"p95": 38500 * time.Microsecond,
+				"p99": 39700 * time.Microsecond,
+			},
+		},
+		{
+			Upstream:    "10.0.0.2:8080",
+			Requests:    1,
+			ErrorRate:   1,
+			MeanLatency: 1800 * time.Millisecond,
+			LatencyPercentiles: map[string]time.Duration{
+				"p50": 1400 * time.Millisecond,
+				"p95": 1760 * time.Millisecond,
+				"p99": 1792 * time.Millisecond,
+			},
+		},
+	}
+	if !reflect.DeepEqual(got.Upstreams, want) {
+		t.Errorf("logAnalyzer.Analyze() upstreams = %s, want %s", upstreamsString(got.Upstreams), upstreamsString(want))
+	}
+}
+
+func upstreamsString(stats []*UpstreamStats) string {
+	s := make([]string, len(stats))
+	for i, stat := range stats {
+		s[i] = fmt.Sprintf("%+v", *stat)
+	}
+	return strings.Join(s, ", ")
+}
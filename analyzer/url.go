@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"net/url"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// DefaultMaxURLs : Distinct URLs tracked per source by default
+	DefaultMaxURLs = 100000
+	// OverflowURL : The bucket URLs beyond MaxURLs are counted in
+	OverflowURL = "(other)"
+)
+
+// normalizeURL : Percent-decodes the URL and puts it in Unicode normalization
+// form C, so `/caf%C3%A9`, `/café` and its decomposed spelling are counted as
+// one URL, and encoded payloads show up decoded in reports. URLs with invalid
+// escapes are only Unicode normalized.
+func normalizeURL(raw string) string {
+	decoded, err := url.PathUnescape(raw)
+	if err != nil {
+		decoded = raw
+	}
+	return norm.NFC.String(decoded)
+}
+
+// countedURL : The URL of the line as it is counted
+func (l *logAnalyzer) countedURL(line *Line) string {
+	if l.keepRawURLs {
+		return line.URL
+	}
+	return normalizeURL(line.URL)
+}
+
+// capURL : Returns the overflow bucket instead of the URL when the aggregate
+// already tracks MaxURLs other URLs
+func (l *logAnalyzer) capURL(agg *aggregate, url string) string {
+	if _, tracked := agg.urlHits.get(url); tracked || agg.urlHits.len() < l.maxURLs {
+		return url
+	}
+	agg.overflowedURLs++
+	return OverflowURL
+}
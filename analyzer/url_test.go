@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_normalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "plain", raw: "/intranet-analytics/", want: "/intranet-analytics/"},
+		{name: "percent encoded utf-8", raw: "/caf%C3%A9", want: "/café"},
+		{name: "decomposed unicode", raw: "/café", want: "/café"},
+		{name: "percent encoded decomposed unicode", raw: "/cafe%CC%81", want: "/café"},
+		{name: "encoded payload", raw: "/search?q=%3Cscript%3Ealert(1)%3C%2Fscript%3E", want: "/search?q=<script>alert(1)</script>"},
+		{name: "plus kept", raw: "/search?q=a+b", want: "/search?q=a+b"},
+		{name: "invalid escape kept", raw: "/100%off", want: "/100%off"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeURL(tt.raw); got != tt.want {
+				t.Errorf("normalizeURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_capURL(t *testing.T) {
+	l := &logAnalyzer{maxURLs: 2}
+	agg := newAggregate()
+	urls := []string{"/a", "/b", "/c", "/a", "/d"}
+	for _, url := range urls {
+		agg.urlHits.add(l.capURL(agg, url), 1)
+	}
+
+	want := map[string]int{"/a": 2, "/b": 1, OverflowURL: 2}
+	if got := agg.urlHits.counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.capURL() counted %v, want %v", got, want)
+	}
+	// consolidate adds them to the self-metrics
+	if agg.overflowedURLs != 2 {
+		t.Errorf("logAnalyzer.capURL() overflowed urls = %d, want 2", agg.overflowedURLs)
+	}
+
+	var warnings []Warning
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		LineRegex: defaultLineRegex,
+		MaxURLs:   2,
+		OnWarning: func(w Warning) { warnings = append(warnings, w) },
+	})
+	if err != nil {
+		t.Fatalf("NewLogAnalyzer() error = %v", err)
+	}
+	l = a.(*logAnalyzer)
+	agg = newAggregate()
+	for _, url := range urls {
+		l.consolidate(agg, &Line{RemoteHost: "10.0.0.1", URL: url})
+	}
+	if got := l.SelfMetrics().OverflowedURLs; got != 2 {
+		t.Errorf("logAnalyzer.SelfMetrics() overflowed urls = %d, want 2", got)
+	}
+	// once per aggregate
+	if want := []Warning{&URLOverflow{MaxURLs: 2}}; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("logAnalyzer.consolidate() warnings = %v, want %v", warnings, want)
+	}
+}
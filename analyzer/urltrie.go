@@ -0,0 +1,173 @@
+package analyzer
+
+import "sort"
+
+// urlTrie : Hits per URL, the URLs stored in a radix tree rather than as map
+// keys: URLs sharing a prefix, e.g. /api/v1/users/, store it once, which cuts
+// the memory of sites with deep, repetitive paths. The children of a node
+// differ by their first byte, so a node has at most 256, kept sorted. The
+// zero value is an empty tree.
+type urlTrie struct {
+	root urlNode
+	// urls : Distinct URLs counted; bytes : Bytes of the node labels, what
+	// the URLs take once their prefixes are shared
+	urls  int
+	bytes int
+}
+
+// urlNode : The URLs starting with the labels from the root to the node
+type urlNode struct {
+	label    string
+	children []*urlNode
+	// counted : Whether a URL ends at the node, hits are its count
+	counted bool
+	hits    int
+}
+
+// add : Adds hits to the URL, reporting whether it was not counted yet
+func (t *urlTrie) add(url string, hits int) bool {
+	node := &t.root
+	for url != "" {
+		i, child := node.child(url[0])
+		if child == nil {
+			// a copy, not to keep the rest of the line the URL was read from
+			leaf := &urlNode{label: string([]byte(url))}
+			node.children = append(node.children, nil)
+			copy(node.children[i+1:], node.children[i:])
+			node.children[i] = leaf
+			t.bytes += len(url)
+			node = leaf
+			break
+		}
+		common := commonPrefixLen(child.label, url)
+		if common < len(child.label) {
+			// the child is split where the URL leaves its label
+			split := &urlNode{label: child.label[:common], children: []*urlNode{child}}
+			child.label = child.label[common:]
+			node.children[i] = split
+			child = split
+		}
+		url = url[common:]
+		node = child
+	}
+	node.hits += hits
+	if node.counted {
+		return false
+	}
+	node.counted = true
+	t.urls++
+	return true
+}
+
+// child : The index of the child starting with b, and the child, nil when
+// there is none and the index is where it would be
+func (n *urlNode) child(b byte) (int, *urlNode) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].label[0] >= b })
+	if i < len(n.children) && n.children[i].label[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// get : The hits of the URL, and whether it is counted
+func (t *urlTrie) get(url string) (int, bool) {
+	node := &t.root
+	for url != "" {
+		_, child := node.child(url[0])
+		if child == nil || len(url) < len(child.label) || url[:len(child.label)] != child.label {
+			return 0, false
+		}
+		url = url[len(child.label):]
+		node = child
+	}
+	return node.hits, node.counted
+}
+
+// len : The number of distinct URLs counted
+func (t *urlTrie) len() int {
+	return t.urls
+}
+
+// each : Calls fn with every URL and its hits, in byte order
+func (t *urlTrie) each(fn func(url string, hits int)) {
+	t.eachBytes(func(url []byte, hits int) {
+		fn(string(url), hits)
+	})
+}
+
+// eachBytes : Calls fn with every URL and its hits, in byte order, the URL
+// only valid during the call
+func (t *urlTrie) eachBytes(fn func(url []byte, hits int)) {
+	t.root.each(make([]byte, 0, 64), fn)
+}
+
+func (n *urlNode) each(prefix []byte, fn func(url []byte, hits int)) {
+	prefix = append(prefix, n.label...)
+	if n.counted {
+		fn(prefix, n.hits)
+	}
+	for _, child := range n.children {
+		child.each(prefix, fn)
+	}
+}
+
+// merge : Adds the hits of other
+func (t *urlTrie) merge(other *urlTrie) {
+	other.each(func(url string, hits int) {
+		t.add(url, hits)
+	})
+}
+
+// mergeCounts : Adds the hits per URL
+func (t *urlTrie) mergeCounts(counts map[string]int) {
+	for url, hits := range counts {
+		t.add(url, hits)
+	}
+}
+
+// counts : The hits per URL, as a map
+func (t *urlTrie) counts() map[string]int {
+	counts := make(map[string]int, t.urls)
+	t.each(func(url string, hits int) {
+		counts[url] = hits
+	})
+	return counts
+}
+
+// top : The n most visited URLs, ties in byte order. Only the URLs ranking
+// among them as they are walked are copied out of the tree.
+func (t *urlTrie) top(n int) []string {
+	type stat struct {
+		url  string
+		hits int
+	}
+	if n <= 0 {
+		return nil
+	}
+	stats := make([]stat, 0, n)
+	t.eachBytes(func(url []byte, hits int) {
+		if len(stats) == n && hits <= stats[n-1].hits {
+			return
+		}
+		// URLs are walked in byte order, so ties stay behind earlier URLs
+		i := sort.Search(len(stats), func(i int) bool { return stats[i].hits < hits })
+		if len(stats) < n {
+			stats = append(stats, stat{})
+		}
+		copy(stats[i+1:], stats[i:])
+		stats[i] = stat{string(url), hits}
+	})
+	var top []string
+	for _, s := range stats {
+		top = append(top, s.url)
+	}
+	return top
+}
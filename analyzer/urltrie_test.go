@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func Test_urlTrie(t *testing.T) {
+	// prefixes of one another, splits inside labels, and the empty URL
+	urls := []string{"/api/v1/users/1", "/api/v1/users/12", "/api/v1/users", "/api/v2/", "/", "", "/api/v1/users/1", "/about", "/api/v1/users/12"}
+	var trie urlTrie
+	want := make(map[string]int)
+	for _, url := range urls {
+		_, seen := want[url]
+		if added := trie.add(url, 1); added == seen {
+			t.Errorf("urlTrie.add(%q) = %v, want %v", url, added, !seen)
+		}
+		want[url]++
+	}
+	if got := trie.counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("urlTrie.counts() = %v, want %v", got, want)
+	}
+	if trie.len() != len(want) {
+		t.Errorf("urlTrie.len() = %d, want %d", trie.len(), len(want))
+	}
+	for url, hits := range want {
+		if got, ok := trie.get(url); !ok || got != hits {
+			t.Errorf("urlTrie.get(%q) = %d, %v, want %d", url, got, ok, hits)
+		}
+	}
+	for _, url := range []string{"/api", "/api/v1/users/", "/api/v1/users/123", "/x"} {
+		if _, ok := trie.get(url); ok {
+			t.Errorf("urlTrie.get(%q) counted, want not counted", url)
+		}
+	}
+
+	var walked []string
+	trie.each(func(url string, hits int) {
+		walked = append(walked, url)
+	})
+	if !sort.StringsAreSorted(walked) {
+		t.Errorf("urlTrie.each() = %q, want byte order", walked)
+	}
+	if got, want := trie.top(3), []string{"/api/v1/users/1", "/api/v1/users/12", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("urlTrie.top() = %q, want %q", got, want)
+	}
+
+	var merged urlTrie
+	merged.mergeCounts(map[string]int{"/api/v1/users/1": 3, "/new": 1})
+	merged.merge(&trie)
+	want["/api/v1/users/1"] += 3
+	want["/new"] = 1
+	if got := merged.counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("urlTrie.merge() = %v, want %v", got, want)
+	}
+}
+
+func Test_urlTrie_bytes(t *testing.T) {
+	var trie urlTrie
+	keyBytes := 0
+	for i := 0; i < 1000; i++ {
+		url := fmt.Sprintf("/api/v1/customers/%d/orders/%d", i%10, i)
+		trie.add(url, 1)
+		keyBytes += len(url)
+	}
+	// the shared prefixes are stored once
+	if trie.bytes*3 > keyBytes {
+		t.Errorf("urlTrie.bytes = %d, want under a third of the %d bytes of the URLs", trie.bytes, keyBytes)
+	}
+}
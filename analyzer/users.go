@@ -0,0 +1,70 @@
+package analyzer
+
+import "sort"
+
+// UserStats : The requests of an authenticated user
+type UserStats struct {
+	User     string
+	Requests int
+	Bytes    int64
+	// ErrorRate : Ratio of 4xx and 5xx responses, e.g. of a user denied
+	// access or failing to authenticate
+	ErrorRate float64
+}
+
+// userHits : Requests of an authenticated user
+type userHits struct {
+	Requests int   `json:"requests"`
+	Bytes    int64 `json:"bytes"`
+	Errors   int   `json:"errors"`
+}
+
+// consolidateUser : Counts the line into its user's requests
+func (l *logAnalyzer) consolidateUser(agg *aggregate, line *Line) {
+	hits, ok := agg.users[line.RemoteUser]
+	if !ok {
+		hits = &userHits{}
+		agg.users[line.RemoteUser] = hits
+	}
+	hits.Requests++
+	hits.Bytes += int64(line.Bytes)
+	if line.Status >= 400 && line.Status <= 599 {
+		hits.Errors++
+	}
+}
+
+// usersReport : The users of the most requests first
+func usersReport(users map[string]*userHits, top int) []*UserStats {
+	stats := make([]*UserStats, 0, len(users))
+	for user, hits := range users {
+		stats = append(stats, &UserStats{
+			User:      user,
+			Requests:  hits.Requests,
+			Bytes:     hits.Bytes,
+			ErrorRate: float64(hits.Errors) / float64(hits.Requests),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Requests != stats[j].Requests {
+			return stats[i].Requests > stats[j].Requests
+		}
+		return stats[i].User < stats[j].User
+	})
+	if top < len(stats) {
+		stats = stats[:top]
+	}
+	return stats
+}
+
+func mergeUsers(into, from map[string]*userHits) {
+	for user, hits := range from {
+		merged, ok := into[user]
+		if !ok {
+			merged = &userHits{}
+			into[user] = merged
+		}
+		merged.Requests += hits.Requests
+		merged.Bytes += hits.Bytes
+		merged.Errors += hits.Errors
+	}
+}
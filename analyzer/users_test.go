@@ -0,0 +1,40 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_logAnalyzer_report_users(t *testing.T) {
+	a, err := NewLogAnalyzer(&LogAnalyzerConfig{Format: formats.CombinedLog, TopUsersCount: 2})
+	if err != nil {
+		t.Fatalf("logAnalyzer.report() error = %v, error creating analyzer", err)
+	}
+	l := a.(*logAnalyzer)
+
+	lines := []string{
+		`10.0.0.1 - alice [11/Oct/2023:14:32:52 +0000] "GET /a HTTP/1.1" 200 1000 "-" "curl/8.0"`,
+		`10.0.0.1 - alice [11/Oct/2023:14:32:53 +0000] "GET /b HTTP/1.1" 403 200 "-" "curl/8.0"`,
+		`10.0.0.2 - bob [11/Oct/2023:14:32:54 +0000] "GET /a HTTP/1.1" 200 500 "-" "curl/8.0"`,
+		`10.0.0.3 - carol [11/Oct/2023:14:32:55 +0000] "GET /a HTTP/1.1" 401 100 "-" "curl/8.0"`,
+		`10.0.0.4 - - [11/Oct/2023:14:32:56 +0000] "GET /a HTTP/1.1" 200 100 "-" "curl/8.0"`,
+	}
+	agg := newAggregate()
+	for _, text := range lines {
+		line, err := l.parse(l.newLineParser(), text)
+		if err != nil {
+			t.Fatalf("logAnalyzer.parse() error = %v", err)
+		}
+		l.consolidate(agg, line)
+	}
+
+	want := []*UserStats{
+		{User: "alice", Requests: 2, Bytes: 1200, ErrorRate: 0.5},
+		{User: "bob", Requests: 1, Bytes: 500},
+	}
+	if got := l.report(agg).TopUsers; !reflect.DeepEqual(got, want) {
+		t.Errorf("logAnalyzer.report() users = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrHeaderLine :
+	ErrHeaderLine = "header line"
+)
+
+var errHeaderLine = errors.New(ErrHeaderLine)
+
+// w3cFields : The line fields, named as the groups of line regexes, of the
+// W3C extended log fields mapped to them. Other fields are kept in the line's
+// extras, under their W3C names, e.g. "s-ip" or "sc-substatus".
+var w3cFields = map[string]string{
+	"c-ip":         "remote_host",
+	"cs-username":  "remote_user",
+	"cs-method":    "method",
+	"cs-uri-stem":  "url",
+	"cs-uri":       "url",
+	"cs-uri-query": "query",
+	"cs-version":   "protocol",
+	// cs-protocol-version : Of CloudFront, whose cs-protocol is the scheme
+	"cs-protocol-version": "protocol",
+	"sc-status":           "status",
+	"sc-bytes":            "bytes",
+	"cs(Referer)":         "referer",
+	"cs(User-Agent)":      "user_agent",
+	"time-taken":          "duration",
+	"sc-content-type":     "content_type",
+}
+
+// w3cFormat : Reads W3C extended log files, e.g. of IIS, whose #Fields header
+// declares the fields of the lines following it
+type w3cFormat struct {
+	// needed : The fields read, nil for all
+	needed projection
+}
+
+func (f w3cFormat) newParser(batch *lineBatch) lineParser {
+	return (&w3cParser{needed: f.needed, batch: batch}).parse
+}
+
+func (f w3cFormat) fields() []string {
+	var fields []string
+	for _, name := range append([]string{"time"}, mappedW3CFields()...) {
+		if f.needed.keeps(name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func (f w3cFormat) project(fields projection) fieldFormat {
+	return w3cFormat{needed: fields}
+}
+
+// mappedW3CFields : The line fields W3C fields are mapped to
+func mappedW3CFields() []string {
+	var fields []string
+	for _, name := range w3cFields {
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// cloudFrontFormat : Reads CloudFront standard logs, a W3C extended log
+// format of tab separated values. Edge location, result type and the other
+// CloudFront fields are kept in the line's extras, e.g. "x-edge-location" or
+// "x-edge-result-type".
+type cloudFrontFormat struct {
+	needed projection
+}
+
+func (f cloudFrontFormat) newParser(batch *lineBatch) lineParser {
+	return (&w3cParser{cloudFront: true, needed: f.needed, batch: batch}).parse
+}
+
+func (f cloudFrontFormat) fields() []string {
+	return w3cFormat{needed: f.needed}.fields()
+}
+
+func (f cloudFrontFormat) project(fields projection) fieldFormat {
+	return cloudFrontFormat{needed: fields}
+}
+
+// w3cParser : The fields declared by the last headers of a log
+type w3cParser struct {
+	// cloudFront : Values are tab separated, time-taken is in seconds and
+	// user agents are URL-encoded, as logged by CloudFront
+	cloudFront bool
+	// needed : The fields read, nil for all
+	needed projection
+	fields []string
+	// date : Date of the #Date header, for logs of a time field only
+	date  string
+	batch *lineBatch
+}
+
+// parse : Parses a line of space separated values, "-" for none, in the order
+// of the #Fields header. Dates and times are in UTC, time-taken is in
+// milliseconds as logged by IIS, and spaces of user agents are logged as +.
+func (p *w3cParser) parse(text string) (*Line, error) {
+	if strings.HasPrefix(text, "#") {
+		p.header(text)
+		return nil, errHeaderLine
+	}
+	var values []string
+	if p.cloudFront {
+		values = strings.Split(text, "\t")
+	} else {
+		values = strings.Fields(text)
+	}
+	if len(p.fields) == 0 || len(values) != len(p.fields) {
+		return nil, errors.New(ErrLineNotMatched)
+	}
+
+	names := make([]string, 0, len(values)+1)
+	fieldValues := make([]string, 0, len(values)+1)
+	date, clock := p.date, ""
+	for i, field := range p.fields {
+		name, ok := w3cFields[field]
+		if !ok {
+			name = field
+		}
+		if field != "date" && field != "time" && !p.needed.keeps(name) {
+			continue
+		}
+		value := values[i]
+		switch field {
+		case "date":
+			date = value
+			continue
+		case "time":
+			clock = value
+			continue
+		case "cs-uri-query":
+			if value == "-" {
+				value = ""
+			} else {
+				value = "?" + value
+			}
+		case "cs(User-Agent)":
+			if !p.cloudFront {
+				value = strings.Replace(value, "+", " ", -1)
+			} else if unescaped, err := url.PathUnescape(value); err == nil {
+				value = unescaped
+			}
+		case "time-taken":
+			if !p.cloudFront {
+				value += "ms"
+			}
+		}
+		names = append(names, name)
+		fieldValues = append(fieldValues, value)
+	}
+	if clock != "" && p.needed.keeps("time") {
+		names = append(names, "time")
+		fieldValues = append(fieldValues, date+"T"+clock+"Z")
+	}
+
+	lineItem := p.batch.next()
+	parseNamedFields(lineItem, names, nil, fieldValues)
+	return lineItem, nil
+}
+
+// header : Keeps the fields and date declared by a #Fields or #Date header
+func (p *w3cParser) header(text string) {
+	switch {
+	case strings.HasPrefix(text, "#Fields:"):
+		p.fields = strings.Fields(strings.TrimPrefix(text, "#Fields:"))
+	case strings.HasPrefix(text, "#Date:"):
+		if date := strings.Fields(strings.TrimPrefix(text, "#Date:")); len(date) > 0 {
+			p.date = date[0]
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_w3cParser_parse(t *testing.T) {
+	parse := w3cFormat{}.newParser(nil)
+	tests := []struct {
+		name    string
+		text    string
+		want    *Line
+		wantErr string
+	}{
+		{
+			name:    "before any header",
+			text:    `2018-07-10 20:21:28 GET /`,
+			wantErr: ErrLineNotMatched,
+		},
+		{
+			name:    "date header",
+			text:    `#Date: 2018-07-10 20:00:00`,
+			wantErr: ErrHeaderLine,
+		},
+		{
+			name:    "fields header",
+			text:    `#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query c-ip cs(User-Agent) sc-status sc-bytes time-taken`,
+			wantErr: ErrHeaderLine,
+		},
+		{
+			name: "line",
+			text: `2018-07-10 20:21:28 10.0.0.1 GET /docs/ q=logs 168.41.191.40 Mozilla/5.0+(Windows+NT+10.0) 200 3574 15`,
+			want: &Line{
+				RemoteHost: "168.41.191.40",
+				Time:       time.Date(2018, 7, 10, 20, 21, 28, 0, time.UTC),
+				Method:     "GET",
+				Path:       "/docs/",
+				RawQuery:   "q=logs",
+				URL:        "/docs/?q=logs",
+				Status:     200,
+				Bytes:      3574,
+				UserAgent:  "Mozilla/5.0 (Windows NT 10.0)",
+				Duration:   15 * time.Millisecond,
+				Extras:     map[string]string{"s-ip": "10.0.0.1"},
+			},
+		},
+		{
+			name:    "missing field",
+			text:    `2018-07-10 20:21:28 10.0.0.1 GET /docs/`,
+			wantErr: ErrLineNotMatched,
+		},
+		{
+			name:    "new fields header",
+			text:    `#Fields: time c-ip sc-status`,
+			wantErr: ErrHeaderLine,
+		},
+		{
+			name: "time of the date header",
+			text: `21:05:00 50.112.00.11 404`,
+			want: &Line{
+				RemoteHost: "50.112.00.11",
+				Time:       time.Date(2018, 7, 10, 21, 5, 0, 0, time.UTC),
+				Status:     404,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.text)
+			if err != nil {
+				if err.Error() != tt.wantErr {
+					t.Errorf("w3cParser.parse() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if !got.Time.Equal(tt.want.Time) {
+				t.Errorf("w3cParser.parse() time = %v, want %v", got.Time, tt.want.Time)
+			}
+			got.Time, tt.want.Time = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("w3cParser.parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_Analyze_w3c(t *testing.T) {
+	l, err := NewLogAnalyzer(&LogAnalyzerConfig{
+		W3C:                  true,
+		MostActiveIPsCount:   1,
+		MostVisitedURLsCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v, error creating analyzer", err)
+	}
+	got, err := l.Analyze("./test-data/iis.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.Analyze() error = %v", err)
+	}
+	if got.UniqueIPCount != 2 || !reflect.DeepEqual(got.MostActiveIPs, []string{"168.41.191.40"}) ||
+		!reflect.DeepEqual(got.MostVisitedURLs, []string{"/docs/?q=logs"}) {
+		t.Errorf("logAnalyzer.Analyze() = %d %v %v, want 2 [168.41.191.40] [/docs/?q=logs]",
+			got.UniqueIPCount, got.MostActiveIPs, got.MostVisitedURLs)
+	}
+	if metrics := l.SelfMetrics(); metrics.ParseErrors != 0 || metrics.LinesRead != 3 {
+		t.Errorf("logAnalyzer.SelfMetrics() = %d lines read, %d parse errors, want 3 lines and no errors", metrics.LinesRead, metrics.ParseErrors)
+	}
+}
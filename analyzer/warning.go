@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+// maxLineBytes : Length from which lines are skipped as oversized rather
+// than read, bounding the memory a line takes
+const maxLineBytes = bufio.MaxScanTokenSize
+
+// Warning : A problem with a line that does not stop the analysis, passed to
+// LogAnalyzerConfig.OnWarning: a *ParseFailure, *TimeParseFailure,
+// *OversizedLine or *URLOverflow
+type Warning interface {
+	// String : The warning, as logged
+	String() string
+}
+
+// ParseFailure : A line that does not parse, skipped
+type ParseFailure struct {
+	Line string
+	// Reason : Why it does not parse, e.g. ErrLineNotMatched
+	Reason string
+}
+
+func (w *ParseFailure) String() string {
+	return fmt.Sprintf("line skipped, %s: %q", w.Reason, w.Line)
+}
+
+// TimeParseFailure : A line of a format logging times, whose time does not
+// parse. The line is counted, with a zero Time.
+type TimeParseFailure struct {
+	Line string
+}
+
+func (w *TimeParseFailure) String() string {
+	return fmt.Sprintf("time of line not parsed: %q", w.Line)
+}
+
+// OversizedLine : A line longer than the 64 KiB lines are read up to,
+// skipped
+type OversizedLine struct {
+	// Bytes : Its length, without the line ending
+	Bytes int
+}
+
+func (w *OversizedLine) String() string {
+	return fmt.Sprintf("line of %d bytes skipped, longer than %d bytes", w.Bytes, maxLineBytes)
+}
+
+// URLOverflow : An aggregate tracking MaxURLs URLs, whose further URLs are
+// counted as OverflowURL. It is warned about once per aggregate.
+type URLOverflow struct {
+	MaxURLs int
+}
+
+func (w *URLOverflow) String() string {
+	return fmt.Sprintf("more than %d distinct URLs, further URLs are counted as %s", w.MaxURLs, OverflowURL)
+}
+
+// warn : Passes the warning to OnWarning, when set
+func (l *logAnalyzer) warn(w Warning) {
+	if l.onWarning != nil {
+		l.onWarning(w)
+	}
+}
+
+// scanLines : Splits lines as bufio.ScanLines does, but skips the lines
+// longer than maxLineBytes with an OversizedLine warning, where a Scanner
+// would stop at them with bufio.ErrTooLong
+func (l *logAnalyzer) scanLines() bufio.SplitFunc {
+	// skipped : Bytes of the oversized line being skipped so far
+	skipped := 0
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if skipped == 0 {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			if advance > 0 || token != nil || err != nil || len(data) < maxLineBytes {
+				return advance, token, err
+			}
+		}
+
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 && !atEOF {
+			skipped += len(data)
+			return len(data), nil, nil
+		}
+		advance := end + 1
+		if end < 0 {
+			end, advance = len(data), len(data)
+		}
+		line := skipped + end
+		if end > 0 && data[end-1] == '\r' {
+			line--
+		}
+		l.warn(&OversizedLine{Bytes: line})
+		skipped = 0
+		return advance, nil, nil
+	}
+}
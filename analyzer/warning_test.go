@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewLogAnalyzer_warnings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const (
+		valid     = `10.0.0.1 - - [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 3574 "-" "curl/8.4.0"`
+		malformed = `not a log line`
+		badTime   = `10.0.0.2 - - [10/July/2018 22:21:29] "GET / HTTP/1.1" 200 3574 "-" "curl/8.4.0"`
+	)
+	oversized := `10.0.0.3 - - [10/Jul/2018:22:21:30 +0200] "GET /` + strings.Repeat("a", maxLineBytes) + ` HTTP/1.1" 200 3574 "-" "curl/8.4.0"`
+	filePath := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(filePath, []byte(strings.Join([]string{valid, malformed, badTime, oversized, valid}, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Warning{
+		&ParseFailure{Line: malformed, Reason: ErrLineNotMatched},
+		&TimeParseFailure{Line: badTime},
+		&OversizedLine{Bytes: len(oversized)},
+	}
+	for name, analyze := range map[string]func(a LogAnalyzer) (*LogAnalytics, error){
+		"Analyze":      func(a LogAnalyzer) (*LogAnalytics, error) { return a.Analyze(filePath) },
+		"AnalyzeBatch": func(a LogAnalyzer) (*LogAnalytics, error) { return a.AnalyzeBatch([]string{filePath}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []Warning
+			a, err := NewLogAnalyzer(&LogAnalyzerConfig{
+				LineRegex: defaultLineRegex,
+				OnWarning: func(w Warning) {
+					mu.Lock()
+					defer mu.Unlock()
+					got = append(got, w)
+				},
+			})
+			if err != nil {
+				t.Fatalf("NewLogAnalyzer() error = %v", err)
+			}
+			// the oversized line is skipped rather than ending the read
+			analytics, err := analyze(a)
+			if err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s() warnings = %v, want %v", name, got, want)
+			}
+			if analytics.UniqueIPCount != 2 {
+				t.Errorf("%s() unique IPs = %d, want 2, of the lines kept", name, analytics.UniqueIPCount)
+			}
+		})
+	}
+}
+
+func Test_logAnalyzer_scanLines(t *testing.T) {
+	long := strings.Repeat("a", maxLineBytes+10)
+	tests := []struct {
+		name string
+		text string
+		want []string
+		// wantBytes : The lengths of the oversized lines skipped
+		wantBytes []int
+	}{
+		{name: "lines", text: "a\r\nb\nc", want: []string{"a", "b", "c"}},
+		{name: "oversized", text: "a\n" + long + "\r\nb\n", want: []string{"a", "b"}, wantBytes: []int{len(long)}},
+		{name: "oversized last line", text: "a\n" + long, want: []string{"a"}, wantBytes: []int{len(long)}},
+		{name: "oversized lines", text: long + "\n" + long + "\n", wantBytes: []int{len(long), len(long)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBytes []int
+			l := &logAnalyzer{onWarning: func(w Warning) {
+				gotBytes = append(gotBytes, w.(*OversizedLine).Bytes)
+			}}
+			scanner := bufio.NewScanner(strings.NewReader(tt.text))
+			scanner.Split(l.scanLines())
+			var got []string
+			for scanner.Scan() {
+				got = append(got, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("Scanner.Err() = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logAnalyzer.scanLines() lines = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotBytes, tt.wantBytes) {
+				t.Errorf("logAnalyzer.scanLines() oversized lines = %v, want %v", gotBytes, tt.wantBytes)
+			}
+		})
+	}
+}
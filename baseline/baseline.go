@@ -0,0 +1,199 @@
+// Package baseline stores daily snapshots of analytics metrics and compares
+// a run against the trailing days, flagging significant deviations.
+package baseline
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+const (
+	// ErrReadingSnapshot :
+	ErrReadingSnapshot = "error reading baseline snapshot"
+	// ErrWritingSnapshot :
+	ErrWritingSnapshot = "error writing baseline snapshot"
+	// ErrPruningSnapshots :
+	ErrPruningSnapshots = "error pruning baseline snapshots"
+)
+
+const (
+	// DefaultDays : Trailing days of the baseline
+	DefaultDays = 7
+	// DefaultThreshold : Relative change from the baseline that is flagged
+	DefaultThreshold = 0.5
+)
+
+// dayLayout : Snapshots are stored per day, as <day>.json
+const dayLayout = "2006-01-02"
+
+// Snapshot : The metrics of a day's run
+type Snapshot struct {
+	Day     string             `json:"day"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// Deviation : A metric departing from its baseline
+type Deviation struct {
+	Metric string
+	Value  float64
+	// Baseline : Mean of the metric over the trailing days
+	Baseline float64
+	// Change : Relative change from the baseline, e.g. -0.6 for a 60% drop
+	Change float64
+}
+
+// Metrics : The numbers of the analytics that are compared across days.
+// Metrics not applying to a run, e.g. pageviews without pageview rules, are
+// left out.
+func Metrics(analytics *analyzer.LogAnalytics) map[string]float64 {
+	metrics := map[string]float64{
+		"unique_ips": float64(analytics.UniqueIPCount),
+	}
+	set := func(name string, v float64) {
+		if v != 0 {
+			metrics[name] = v
+		}
+	}
+	set("pageviews", float64(analytics.Pageviews))
+	set("spam_referrals", float64(analytics.SpamReferrals))
+	set("sessions", float64(analytics.Sessions))
+	if analytics.Sessions > 0 {
+		metrics["bounce_rate"] = analytics.BounceRate
+	}
+	for _, u := range analytics.Upstreams {
+		metrics["upstream."+u.Upstream+".requests"] = float64(u.Requests)
+		metrics["upstream."+u.Upstream+".error_rate"] = u.ErrorRate
+		set("upstream."+u.Upstream+".mean_latency_seconds", u.MeanLatency.Seconds())
+	}
+	if k := analytics.Keepalive; k != nil {
+		metrics["keepalive.reuse_rate"] = k.ReuseRate
+	}
+	if s := analytics.ResponseSizes; s != nil {
+		metrics["response_size.mean"] = float64(s.Mean)
+	}
+	return metrics
+}
+
+// Store : Snapshots saved as JSON files in a directory, one per day
+type Store struct {
+	Dir string
+}
+
+// Save : Saves the snapshot, replacing the one of the same day
+func (s *Store) Save(snapshot *Snapshot) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return errors.Wrap(err, ErrWritingSnapshot)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, ErrWritingSnapshot)
+	}
+	// written aside first, so an interrupted save leaves the previous one
+	path := filepath.Join(s.Dir, snapshot.Day+".json")
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return errors.Wrap(err, ErrWritingSnapshot)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return errors.Wrap(err, ErrWritingSnapshot)
+	}
+	return nil
+}
+
+// Trailing : The snapshots of the days days before day, oldest first. Days
+// without a snapshot are skipped.
+func (s *Store) Trailing(day time.Time, days int) ([]*Snapshot, error) {
+	var snapshots []*Snapshot
+	for i := days; i > 0; i-- {
+		path := filepath.Join(s.Dir, day.AddDate(0, 0, -i).Format(dayLayout)+".json")
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, ErrReadingSnapshot)
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, errors.Wrap(errors.Wrap(err, path), ErrReadingSnapshot)
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	return snapshots, nil
+}
+
+// Prune : Removes the snapshots of the days more than days before day, so the
+// store does not grow forever. Other files of the directory are left alone.
+func (s *Store) Prune(day time.Time, days int) error {
+	files, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, ErrPruningSnapshots)
+	}
+	oldest := day.AddDate(0, 0, -days).Format(dayLayout)
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".json")
+		if name == file.Name() || file.IsDir() {
+			continue
+		}
+		// day names sort in time order
+		if _, err := time.Parse(dayLayout, name); err != nil || name >= oldest {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.Dir, file.Name())); err != nil {
+			return errors.Wrap(err, ErrPruningSnapshots)
+		}
+	}
+	return nil
+}
+
+// NewSnapshot : The snapshot of the analytics for day
+func NewSnapshot(day time.Time, analytics *analyzer.LogAnalytics) *Snapshot {
+	return &Snapshot{Day: day.Format(dayLayout), Metrics: Metrics(analytics)}
+}
+
+// Compare : The metrics changing by at least threshold relative to their mean
+// over the baseline snapshots, the largest changes first. Metrics of the
+// baseline missing from the run count as dropped to zero; metrics new to the
+// run, or of a zero baseline, are not compared.
+func Compare(metrics map[string]float64, baseline []*Snapshot, threshold float64) []*Deviation {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, snapshot := range baseline {
+		for name, v := range snapshot.Metrics {
+			sums[name] += v
+			counts[name]++
+		}
+	}
+
+	var deviations []*Deviation
+	for name, sum := range sums {
+		mean := sum / float64(counts[name])
+		if mean == 0 {
+			continue
+		}
+		value := metrics[name]
+		change := (value - mean) / mean
+		if math.Abs(change) >= threshold {
+			deviations = append(deviations, &Deviation{Metric: name, Value: value, Baseline: mean, Change: change})
+		}
+	}
+	sort.Slice(deviations, func(i, j int) bool {
+		ci, cj := math.Abs(deviations[i].Change), math.Abs(deviations[j].Change)
+		if ci != cj {
+			return ci > cj
+		}
+		return deviations[i].Metric < deviations[j].Metric
+	})
+	return deviations
+}
@@ -0,0 +1,91 @@
+package baseline
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "baseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &Store{Dir: dir}
+	day := time.Date(2018, time.July, 10, 12, 0, 0, 0, time.UTC)
+	for _, snapshot := range []*Snapshot{
+		{Day: "2018-07-01", Metrics: map[string]float64{"unique_ips": 1}},
+		{Day: "2018-07-07", Metrics: map[string]float64{"unique_ips": 7}},
+		{Day: "2018-07-09", Metrics: map[string]float64{"unique_ips": 8}},
+		{Day: "2018-07-09", Metrics: map[string]float64{"unique_ips": 9}},
+		{Day: "2018-07-10", Metrics: map[string]float64{"unique_ips": 10}},
+	} {
+		if err := store.Save(snapshot); err != nil {
+			t.Fatalf("Store.Save() error = %v", err)
+		}
+	}
+
+	got, err := store.Trailing(day, 3)
+	if err != nil {
+		t.Fatalf("Store.Trailing() error = %v", err)
+	}
+	want := []*Snapshot{
+		{Day: "2018-07-07", Metrics: map[string]float64{"unique_ips": 7}},
+		{Day: "2018-07-09", Metrics: map[string]float64{"unique_ips": 9}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Store.Trailing() = %+v, want %+v", got, want)
+	}
+
+	if err := store.Prune(day, 3); err != nil {
+		t.Fatalf("Store.Prune() error = %v", err)
+	}
+	if got, _ = store.Trailing(day, 30); !reflect.DeepEqual(got, want) {
+		t.Errorf("Store.Trailing() after Store.Prune() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := []*Snapshot{
+		{Day: "2018-07-08", Metrics: map[string]float64{"unique_ips": 90, "pageviews": 1000, "upstream.a.error_rate": 0}},
+		{Day: "2018-07-09", Metrics: map[string]float64{"unique_ips": 110, "pageviews": 1000, "sessions": 50}},
+	}
+	metrics := map[string]float64{"unique_ips": 120, "pageviews": 300, "upstream.a.error_rate": 0.5, "bounce_rate": 0.4}
+
+	got := Compare(metrics, baseline, 0.5)
+	want := []*Deviation{
+		{Metric: "sessions", Value: 0, Baseline: 50, Change: -1},
+		{Metric: "pageviews", Value: 300, Baseline: 1000, Change: -0.7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	got := Metrics(&analyzer.LogAnalytics{
+		UniqueIPCount: 3,
+		Sessions:      2,
+		BounceRate:    0.5,
+		Upstreams: []*analyzer.UpstreamStats{
+			{Upstream: "10.0.0.1:8080", Requests: 4, MeanLatency: 25 * time.Millisecond},
+		},
+	})
+	want := map[string]float64{
+		"unique_ips":                        3,
+		"sessions":                          2,
+		"bounce_rate":                       0.5,
+		"upstream.10.0.0.1:8080.requests":   4,
+		"upstream.10.0.0.1:8080.error_rate": 0,
+		"upstream.10.0.0.1:8080.mean_latency_seconds": 0.025,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Metrics() = %v, want %v", got, want)
+	}
+}
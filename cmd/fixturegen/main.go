@@ -0,0 +1,30 @@
+// Command fixturegen writes the pathological fixture lines as a log file.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/sdileep/http-log-parser/fixtures"
+)
+
+func main() {
+	out := flag.String("o", "", "output file (defaults to stdout)")
+	flag.Parse()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := fixtures.WriteLog(w, fixtures.Pathological()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+const (
+	// ErrExampleNotParsed :
+	ErrExampleNotParsed = "the example line does not parse with its format"
+)
+
+// document : Writes the Markdown documentation of the registered formats of
+// the names, in order
+func document(w io.Writer, names []string) error {
+	fmt.Fprintln(w, "# Log formats")
+	for _, name := range names {
+		format := analyzer.LookupFormat(name)
+		if format == nil {
+			return errors.Wrap(errors.New(analyzer.ErrUnknownFormat), name)
+		}
+		fmt.Fprintf(w, "\n## %s\n", name)
+		if fields, ok := format.(analyzer.FormatFields); ok {
+			fmt.Fprintf(w, "\nFields: %s\n", strings.Join(fields.LineFields(), ", "))
+		}
+		example, ok := format.(analyzer.FormatExample)
+		if !ok || example.ExampleLine() == "" {
+			continue
+		}
+		line, err := format.ParseLine(example.ExampleLine())
+		if err != nil {
+			return errors.Wrap(errors.Wrap(errors.New(ErrExampleNotParsed), err.Error()), name)
+		}
+		parsed, err := json.MarshalIndent(line, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nExample:\n\n```\n%s\n```\n\nParsed:\n\n```json\n%s\n```\n", example.ExampleLine(), parsed)
+	}
+	return nil
+}
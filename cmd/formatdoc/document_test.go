@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+func Test_document(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := document(&buffer, analyzer.RegisteredFormats()); err != nil {
+		t.Fatalf("document() error = %v", err)
+	}
+	doc := buffer.String()
+	for _, want := range []string{
+		"## combined\n\nFields: remote_host, remote_user, time, method, url, protocol, status, bytes, referer, user_agent\n",
+		"## common\n\nFields: remote_host, remote_user, time, method, url, protocol, status, bytes\n",
+		`"RemoteHost": "177.71.128.21"`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document() = %s, want %q in it", doc, want)
+		}
+	}
+
+	if err := document(&buffer, []string{"unknown"}); err == nil || err.Error() != "unknown: "+analyzer.ErrUnknownFormat {
+		t.Errorf("document() error = %v, wantErr %v", err, analyzer.ErrUnknownFormat)
+	}
+}
@@ -0,0 +1,42 @@
+// Command formatdoc documents the log formats registered with the analyzer,
+// the presets and those of any package linked in: the line fields each
+// fills and an example line, as parsed. It is generated from the formats
+// themselves, so it never drifts from them, e.g.
+//
+//	go run github.com/sdileep/http-log-parser/cmd/formatdoc -o FORMATS.md
+//
+// It fails when an example does not parse with its format.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+func main() {
+	names := flag.String("format", "", "comma-separated names of the formats to document (defaults to all registered)")
+	out := flag.String("o", "", "output file (defaults to stdout)")
+	flag.Parse()
+
+	formatNames := analyzer.RegisteredFormats()
+	if *names != "" {
+		formatNames = strings.Split(*names, ",")
+	}
+	var buffer bytes.Buffer
+	if err := document(&buffer, formatNames); err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(buffer.Bytes())
+		return
+	}
+	if err := ioutil.WriteFile(*out, buffer.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+const (
+	// ErrAdjacentFields :
+	ErrAdjacentFields = "fields must be separated by literal text"
+	// ErrInvalidName :
+	ErrInvalidName = "invalid parser name"
+)
+
+// generate : The source of a file of package pkg, parsing lines of the tokens
+// with Parse<name>. source is the format string, quoted in a comment.
+func generate(pkg, name, source string, tokens []formats.Token) ([]byte, error) {
+	if !isExported(name) {
+		return nil, errors.Wrap(errors.New(ErrInvalidName), name)
+	}
+	tokens = mergeLiterals(tokens)
+
+	var groups, fields []string
+	for i, token := range tokens {
+		if token.Pattern == "" {
+			continue
+		}
+		if i > 0 && tokens[i-1].Pattern != "" {
+			return nil, errors.Wrap(errors.New(ErrAdjacentFields), tokens[i-1].Group+" and "+token.Group)
+		}
+		groups = append(groups, token.Group)
+		if token.Group != "" {
+			fields = append(fields, token.Group)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by parsergen from the log format below. DO NOT EDIT.\n//\n//\t%s\n\n", source)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import (\n\t\"errors\"\n\t\"strings\"\n\n\t\"github.com/sdileep/http-log-parser/analyzer\"\n)\n\n")
+
+	groupsVar := strings.ToLower(name[:1]) + name[1:] + "Groups"
+	fmt.Fprintf(&b, "// %sParser : Parse%s, for analyzer.LogAnalyzerConfig.Parser\n", name, name)
+	fmt.Fprintf(&b, "var %sParser = &analyzer.Parser{Parse: Parse%s, Fields: %#v}\n\n", name, name, fields)
+	fmt.Fprintf(&b, "// %s : The group of each field, empty for the fields skipped\n", groupsVar)
+	fmt.Fprintf(&b, "var %s = %#v\n\n", groupsVar, groups)
+
+	fmt.Fprintf(&b, "// Parse%s : Parses a line of the format without a regex, filling the\n", name)
+	b.WriteString("// fields its line regex does. Each field ends at the first occurrence of the\n")
+	b.WriteString("// text after it, the last one at the end of the line.\n")
+	fmt.Fprintf(&b, "func Parse%s(line string) (*analyzer.Line, error) {\n", name)
+	fmt.Fprintf(&b, "\tvar values [%d]string\n\trest := line\n", len(groups))
+	for i, token := range tokens {
+		// fields before the last text scan for the text after them
+		if token.Pattern != "" && i < len(tokens)-2 {
+			b.WriteString("\tvar i int\n")
+			break
+		}
+	}
+	notMatched := "\t\treturn nil, errors.New(analyzer.ErrLineNotMatched)\n\t}\n"
+	field := 0
+	for i, token := range tokens {
+		if token.Pattern == "" {
+			if i > 0 {
+				// consumed with the field before it
+				continue
+			}
+			fmt.Fprintf(&b, "\tif !strings.HasPrefix(rest, %s) {\n%s", strconv.Quote(token.Literal), notMatched)
+			fmt.Fprintf(&b, "\trest = rest[%d:]\n", len(token.Literal))
+			continue
+		}
+		fmt.Fprintf(&b, "\t// %s\n", describe(token))
+		switch {
+		case i == len(tokens)-1:
+			fmt.Fprintf(&b, "\tvalues[%d] = rest\n", field)
+		case i == len(tokens)-2:
+			// the last text ends the line
+			next := tokens[i+1].Literal
+			fmt.Fprintf(&b, "\tif !strings.HasSuffix(rest, %s) {\n%s", strconv.Quote(next), notMatched)
+			fmt.Fprintf(&b, "\tvalues[%d] = rest[:len(rest)-%d]\n", field, len(next))
+		default:
+			next := tokens[i+1].Literal
+			if len(next) == 1 {
+				fmt.Fprintf(&b, "\tif i = strings.IndexByte(rest, %s); i < 0 {\n%s", strconv.QuoteRune(rune(next[0])), notMatched)
+			} else {
+				fmt.Fprintf(&b, "\tif i = strings.Index(rest, %s); i < 0 {\n%s", strconv.Quote(next), notMatched)
+			}
+			fmt.Fprintf(&b, "\tvalues[%d], rest = rest[:i], rest[i+%d:]\n", field, len(next))
+		}
+		field++
+	}
+	b.WriteString("\n\tlineItem := &analyzer.Line{}\n")
+	fmt.Fprintf(&b, "\tanalyzer.FillFields(lineItem, %s, values[:])\n", groupsVar)
+	b.WriteString("\treturn lineItem, nil\n}\n")
+
+	code, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "generated code")
+	}
+	return code, nil
+}
+
+// mergeLiterals : The tokens, adjacent literal ones merged
+func mergeLiterals(tokens []formats.Token) []formats.Token {
+	var merged []formats.Token
+	for _, token := range tokens {
+		if n := len(merged); n > 0 && token.Pattern == "" && merged[n-1].Pattern == "" {
+			merged[n-1].Literal += token.Literal
+			continue
+		}
+		merged = append(merged, token)
+	}
+	return merged
+}
+
+// describe : The comment of a field's code
+func describe(token formats.Token) string {
+	if token.Group == "" {
+		return "skipped"
+	}
+	return token.Group
+}
+
+func isExported(name string) bool {
+	if name == "" || !unicode.IsUpper(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func Test_generate(t *testing.T) {
+	tests := []struct {
+		name      string
+		logFormat string
+		parser    string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "fields scanned to the text after them",
+			logFormat: `%h %l %t "%r" %>s`,
+			parser:    "Access",
+			want: []string{
+				"package logs\n",
+				`var AccessParser = &analyzer.Parser{Parse: ParseAccess, Fields: []string{"remote_host", "time", "request", "status"}}`,
+				`if i = strings.IndexByte(rest, ' '); i < 0 {`,
+				`if i = strings.Index(rest, "] \""); i < 0 {`,
+				"values[4] = rest\n",
+			},
+		},
+		{
+			name:      "last text ending the line",
+			logFormat: `%h "%{User-agent}i"`,
+			parser:    "Agents",
+			want: []string{
+				`if !strings.HasSuffix(rest, "\"") {`,
+				"values[1] = rest[:len(rest)-1]\n",
+			},
+		},
+		{
+			name:      "error: adjacent fields",
+			logFormat: `%h%u %>s`,
+			parser:    "Access",
+			wantErr:   "remote_host and remote_user: " + ErrAdjacentFields,
+		},
+		{
+			name:      "error: unexported name",
+			logFormat: `%h %>s`,
+			parser:    "access",
+			wantErr:   "access: " + ErrInvalidName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := formats.Apache(tt.logFormat)
+			if err != nil {
+				t.Fatalf("formats.Apache() error = %v", err)
+			}
+			code, err := generate("logs", tt.parser, tt.logFormat, format.Tokens)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("generate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(code), want) {
+					t.Errorf("generate() = %s, want it to contain %q", code, want)
+				}
+			}
+		})
+	}
+}
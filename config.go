@@ -0,0 +1,738 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/analyzer"
+	"github.com/sdileep/http-log-parser/formats"
+	"github.com/sdileep/http-log-parser/server"
+	"github.com/sdileep/http-log-parser/verify"
+)
+
+// fileConfig : Analyzer settings, as read from the -config JSON file
+type fileConfig struct {
+	MostActiveIPsCount      int  `json:"mostActiveIPsCount"`
+	MostVisitedURLsCount    int  `json:"mostVisitedURLsCount"`
+	MostActiveNetworksCount int  `json:"mostActiveNetworksCount"`
+	IPv6AggregatePrefix     int  `json:"ipv6AggregatePrefix"`
+	IPv4NetworkPrefix       int  `json:"ipv4NetworkPrefix"`
+	IPv6NetworkPrefix       int  `json:"ipv6NetworkPrefix"`
+	KeepRawURLs             bool `json:"keepRawURLs"`
+	MaxURLs                 int  `json:"maxURLs"`
+	ResourceStats           bool `json:"resourceStats"`
+	StrictSchema            bool `json:"strictSchema"`
+	TimeOrdered             bool `json:"timeOrdered"`
+	QueueSize               int  `json:"queueSize"`
+	// TrustedProxies : Addresses and CIDR networks of the proxies whose
+	// X-Forwarded-For gives the client
+	TrustedProxies []string `json:"trustedProxies"`
+	// TimeLayouts : Layouts of the logged times, as in Go's time.Parse, or
+	// "unix" and "unix_ms", tried in order
+	TimeLayouts []string `json:"timeLayouts"`
+	// QueuePolicy : "block", "drop-newest" or "drop-oldest", for follow mode
+	QueuePolicy string `json:"queuePolicy"`
+	// Tokenizer : "scan" or "regex", how Common and Combined Log Format lines
+	// are split
+	Tokenizer string `json:"tokenizer"`
+	// ReadAheadSize : Bytes read ahead of parsing, e.g. 4194304
+	ReadAheadSize int `json:"readAheadSize"`
+	// Enrichers : Built-in enrichers to run, in order
+	Enrichers              []enricherConfig `json:"enrichers"`
+	EnrichmentCacheSize    int              `json:"enrichmentCacheSize"`
+	TopEnrichedValuesCount int              `json:"topEnrichedValuesCount"`
+	// EnrichmentConcurrency, PreserveEnrichmentOrder : Lines enriched at
+	// once, and whether they are still counted in the order they were read
+	EnrichmentConcurrency   int  `json:"enrichmentConcurrency"`
+	PreserveEnrichmentOrder bool `json:"preserveEnrichmentOrder"`
+	// Script : Starlark file defining filter, derive and score hooks
+	Script            string `json:"script"`
+	TopScoredIPsCount int    `json:"topScoredIPsCount"`
+	// Plugins : Plugin files, whose formats, enrichers and sinks can be used by name
+	Plugins []string `json:"plugins"`
+	// Format : Line format, "common", "combined" (the default), another
+	// preset, e.g. "haproxy", "caddy", "cloudflare", "fastly", "heroku" or
+	// "varnish", one of a plugin, or one registered with analyzer.RegisterFormat
+	Format string `json:"format"`
+	// ApacheLogFormat : Line format as an Apache LogFormat string or directive
+	// line, e.g. "%h %l %u %t \"%r\" %>s %b"
+	ApacheLogFormat string `json:"apacheLogFormat"`
+	// NginxLogFormat : Line format as an nginx log_format string or directive
+	NginxLogFormat string `json:"nginxLogFormat"`
+	// EnvoyLogFormat : Line format as an Envoy access log format string
+	EnvoyLogFormat string `json:"envoyLogFormat"`
+	// LineRegex : Line format, instead of the combined log format, with the
+	// ten positional groups and optional named ones
+	LineRegex string `json:"lineRegex"`
+	// JSON : Lines are JSON objects, their fields read from the keys of
+	// JSONFields, e.g. {"remote_host": "client.ip", "url": "path"}
+	JSON       bool              `json:"json"`
+	JSONFields map[string]string `json:"jsonFields"`
+	// Logfmt : Lines are logfmt key=value pairs, their fields read from the
+	// keys of LogfmtFields, e.g. {"remote_host": "remote_addr"}
+	Logfmt       bool              `json:"logfmt"`
+	LogfmtFields map[string]string `json:"logfmtFields"`
+	// W3C : Lines are of the W3C extended log format, e.g. of IIS
+	W3C bool `json:"w3c"`
+	// CloudFront : Lines are of the CloudFront standard log format
+	CloudFront bool `json:"cloudFront"`
+	// CEFFields : The CEF extension keys or LEEF attributes of the line
+	// fields, with "format": "cef", e.g. {"status": "cn1"}
+	CEFFields map[string]string `json:"cefFields"`
+	// Sinks : Plugin sinks every report is also written to
+	Sinks []string `json:"sinks"`
+	// SinkPolicy : Retries, batching and rate limit of the sink writes, e.g.
+	// {"maxRetries": 5, "backoff": "1s", "deadLetterPath": "failed.jsonl"}
+	SinkPolicy *sinkPolicyConfig `json:"sinkPolicy"`
+	// Server : TLS and auth of the HTTP server of -http-addr, e.g.
+	// {"tls": {"certFile": "server.pem", "keyFile": "server-key.pem", "caFile": "clients-ca.pem"}, "auth": {"bearerToken": "s3cr3t"}}
+	Server *endpointConfig `json:"server"`
+	// Client : TLS and auth of the requests to shard workers, e.g.
+	// {"tls": {"caFile": "ca.pem"}, "auth": {"username": "coordinator", "password": "s3cr3t"}}
+	Client *endpointConfig `json:"client"`
+	// Redaction : Secrets removed from URLs and referrers before anything is exported
+	Redaction *redactionConfig `json:"redaction"`
+	// SearchIndex : Recent lines kept searchable on /search in follow mode,
+	// e.g. {"maxLines": 100000, "maxAge": "1h"}
+	SearchIndex *searchIndexConfig `json:"searchIndex"`
+	// Syslog : Lines may carry a syslog header, stripped before parsing
+	Syslog bool `json:"syslog"`
+	// Multiline : How continuation lines are joined into the record they
+	// continue, e.g. {"start": "^\\d{4}/\\d\\d/\\d\\d "} or {"continuation": "^\\s", "maxLines": 200}
+	Multiline *multilineConfig `json:"multiline"`
+	// Fields : The line fields needed, the others not parsed, e.g.
+	// ["remote_host", "url"]
+	Fields []string `json:"fields"`
+	// DisabledCollectors : e.g. ["urls", "networks"]
+	DisabledCollectors []string `json:"disabledCollectors"`
+	// Pageviews : Rules pageviews are counted by, {} for the defaults
+	Pageviews *pageviewsConfig `json:"pageviews"`
+	// Sessions : Session reconstruction, e.g. {"timeout": "30m"}, {} for the defaults
+	Sessions             *sessionsConfig `json:"sessions"`
+	TopLandingPagesCount int             `json:"topLandingPagesCount"`
+	TopExitPagesCount    int             `json:"topExitPagesCount"`
+	TopReferrersCount    int             `json:"topReferrersCount"`
+	// ReferrerBlocklist : File of spam domains, one per line, filtered out of
+	// the referrers besides the built-in ones
+	ReferrerBlocklist string `json:"referrerBlocklist"`
+	TopCampaignsCount int    `json:"topCampaignsCount"`
+	// TopErrorMessagesCount : Most common messages of error logs, with -error-log
+	TopErrorMessagesCount int `json:"topErrorMessagesCount"`
+	// TimeseriesInterval : e.g. "1h", daily when not set
+	TimeseriesInterval string `json:"timeseriesInterval"`
+	// TimeseriesRetention : e.g. [{"interval": "1m", "age": "24h"}, {"interval": "1h", "age": "720h"}]
+	TimeseriesRetention []retentionTierConfig `json:"timeseriesRetention"`
+	DeviceTimeseries    bool                  `json:"deviceTimeseries"`
+	// EndpointGroups : e.g. [{"name": "api", "path": "^/api/"}]
+	EndpointGroups         []endpointGroupConfig `json:"endpointGroups"`
+	UpstreamsCount         int                   `json:"upstreamsCount"`
+	TopUsersCount          int                   `json:"topUsersCount"`
+	NonReusingClientsCount int                   `json:"nonReusingClientsCount"`
+	UncompressedURLsCount  int                   `json:"uncompressedURLsCount"`
+	LargeResponseBytes     int                   `json:"largeResponseBytes"`
+	SlowestRequestsCount   int                   `json:"slowestRequestsCount"`
+	LargestResponsesCount  int                   `json:"largestResponsesCount"`
+	MatchedSamplesCount    int                   `json:"matchedSamplesCount"`
+	UnmatchedSamplesCount  int                   `json:"unmatchedSamplesCount"`
+	// Percentiles : e.g. [50, 99, 99.9]
+	Percentiles []float64 `json:"percentiles"`
+	// LatencyBuckets : Latency histogram bucket bounds, e.g. ["100ms", "250ms", "1s"]
+	LatencyBuckets []string `json:"latencyBuckets"`
+	// SizeBuckets : Response size histogram bucket bounds in bytes, e.g. [1024, 102400]
+	SizeBuckets    []int `json:"sizeBuckets"`
+	TopMoversCount int   `json:"topMoversCount"`
+	// UniqueIPWindows : Windows unique IPs are estimated in, e.g. ["5m", "1h"]
+	UniqueIPWindows []string `json:"uniqueIPWindows"`
+	// Annotations : Known events, e.g. [{"time": "2018-07-10T22:00:00+02:00", "label": "deploy v1.2"}]
+	Annotations []annotationConfig `json:"annotations"`
+	// Verify : Tolerances of -verify, e.g. {"tolerance": 0.01, "tolerances": {"Timeseries": 0.05}, "ignore": ["FirstTimeIPs"]}
+	Verify *verifyConfig `json:"verify"`
+
+	plugins []*analyzer.Plugin
+}
+
+// redactionConfig : e.g. {"dropQueryParams": "(?i)token|password|session", "hashEmails": true}
+type redactionConfig struct {
+	DropQueryParams string `json:"dropQueryParams"`
+	HashEmails      bool   `json:"hashEmails"`
+}
+
+// pageviewsConfig : e.g. {"methods": ["GET"], "assetPaths": "^/static/", "botUserAgents": "(?i)bot|monitor"}
+type pageviewsConfig struct {
+	Methods       []string `json:"methods"`
+	AssetPaths    string   `json:"assetPaths"`
+	BotUserAgents string   `json:"botUserAgents"`
+}
+
+type sessionsConfig struct {
+	Timeout string `json:"timeout"`
+}
+
+// endpointConfig : TLS and credentials of a network endpoint. The keys of
+// server.TLS and server.Auth are matched case-insensitively, e.g. "certFile"
+// or "bearerToken".
+type endpointConfig struct {
+	TLS  *server.TLS  `json:"tls"`
+	Auth *server.Auth `json:"auth"`
+}
+
+// multilineConfig : e.g. {"continuation": "^\\s"}, or {"start": "^\\S+ \\S+ \\S+ \\[", "unwrap": true}
+type multilineConfig struct {
+	Start        string `json:"start"`
+	Continuation string `json:"continuation"`
+	Unwrap       bool   `json:"unwrap"`
+	MaxLines     int    `json:"maxLines"`
+}
+
+type searchIndexConfig struct {
+	MaxLines int    `json:"maxLines"`
+	MaxAge   string `json:"maxAge"`
+}
+
+type sinkPolicyConfig struct {
+	MaxRetries     int    `json:"maxRetries"`
+	Backoff        string `json:"backoff"`
+	MaxBackoff     string `json:"maxBackoff"`
+	BatchSize      int    `json:"batchSize"`
+	MinInterval    string `json:"minInterval"`
+	DeadLetterPath string `json:"deadLetterPath"`
+}
+
+type retentionTierConfig struct {
+	Interval string `json:"interval"`
+	Age      string `json:"age"`
+}
+
+type endpointGroupConfig struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// annotationConfig : A known event, at an RFC 3339 time
+type annotationConfig struct {
+	Time  string `json:"time"`
+	Label string `json:"label"`
+}
+
+// verifyConfig : The relative and absolute tolerances of the numbers, the
+// relative ones of some analytics by path, and the analytics not compared
+type verifyConfig struct {
+	Tolerance         float64            `json:"tolerance"`
+	AbsoluteTolerance float64            `json:"absoluteTolerance"`
+	Tolerances        map[string]float64 `json:"tolerances"`
+	Ignore            []string           `json:"ignore"`
+}
+
+// enricherConfig : A step of the enrichment chain, e.g. {"name": "rdns", "timeout": "200ms"}
+type enricherConfig struct {
+	Name    string `json:"name"`
+	Timeout string `json:"timeout"`
+	// File : The networks of the geoip enricher, see analyzer.NewGeoIPEnricher
+	File string `json:"file"`
+}
+
+var queuePolicies = map[string]analyzer.QueuePolicy{
+	"":            analyzer.QueueBlock,
+	"block":       analyzer.QueueBlock,
+	"drop-newest": analyzer.QueueDropNewest,
+	"drop-oldest": analyzer.QueueDropOldest,
+}
+
+var tokenizers = map[string]analyzer.Tokenizer{
+	"":      analyzer.TokenizerScan,
+	"scan":  analyzer.TokenizerScan,
+	"regex": analyzer.TokenizerRegex,
+}
+
+// loadConfig : Reads the config file, settings it leaves out keep their
+// defaults. An empty path returns the defaults.
+func loadConfig(path string) (*fileConfig, error) {
+	config := &fileConfig{
+		MostActiveIPsCount:    4,
+		MostVisitedURLsCount:  3,
+		TopErrorMessagesCount: 5,
+	}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading config")
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrap(err, "error parsing config")
+	}
+	if _, ok := queuePolicies[config.QueuePolicy]; !ok {
+		return nil, errors.Errorf("unknown queue policy %q", config.QueuePolicy)
+	}
+	if _, ok := tokenizers[config.Tokenizer]; !ok {
+		return nil, errors.Errorf("unknown tokenizer %q", config.Tokenizer)
+	}
+	for _, path := range config.Plugins {
+		plugin, err := analyzer.LoadPlugin(path)
+		if err != nil {
+			return nil, err
+		}
+		config.plugins = append(config.plugins, plugin)
+	}
+
+	return config, nil
+}
+
+func (c *fileConfig) analyzerConfig(lineRegex *regexp.Regexp) (*analyzer.LogAnalyzerConfig, error) {
+	if c.LineRegex != "" {
+		var err error
+		if lineRegex, err = regexp.Compile(c.LineRegex); err != nil {
+			return nil, errors.Wrap(err, "lineRegex")
+		}
+	}
+	if c.ApacheLogFormat != "" {
+		format, err := formats.Apache(c.ApacheLogFormat)
+		if err != nil {
+			return nil, errors.Wrap(err, "apacheLogFormat")
+		}
+		lineRegex = format.LineRegex
+	}
+	if c.NginxLogFormat != "" {
+		format, err := formats.Nginx(c.NginxLogFormat)
+		if err != nil {
+			return nil, errors.Wrap(err, "nginxLogFormat")
+		}
+		lineRegex = format.LineRegex
+	}
+	if c.EnvoyLogFormat != "" {
+		format, err := formats.Envoy(c.EnvoyLogFormat)
+		if err != nil {
+			return nil, errors.Wrap(err, "envoyLogFormat")
+		}
+		lineRegex = format.LineRegex
+	}
+	jsonLines, jsonFields := c.JSON, c.JSONFields
+	heroku, cef := c.Format == "heroku", c.Format == "cef"
+	var formatName string
+	if fields, ok := jsonFormats[c.Format]; ok {
+		jsonLines = true
+		if len(jsonFields) == 0 {
+			jsonFields = fields
+		}
+	} else if c.Format != "" && !heroku && !cef {
+		lineRegex = c.lineRegex(c.Format)
+		if lineRegex == nil {
+			formatName = c.Format
+		}
+	}
+
+	var enrichers []*analyzer.EnrichmentStep
+	for _, e := range c.Enrichers {
+		var err error
+		enricher := c.pluginEnricher(e.Name)
+		if enricher == nil && e.Name == "geoip" {
+			if enricher, err = geoIPEnricher(e.File); err != nil {
+				return nil, errors.Wrapf(err, "enricher %s", e.Name)
+			}
+		}
+		if enricher == nil {
+			if enricher, err = analyzer.NewBuiltinEnricher(e.Name); err != nil {
+				return nil, err
+			}
+		}
+		step := &analyzer.EnrichmentStep{Enricher: enricher}
+		if e.Timeout != "" {
+			if step.Timeout, err = time.ParseDuration(e.Timeout); err != nil {
+				return nil, errors.Wrapf(err, "enricher %s timeout", e.Name)
+			}
+		}
+		enrichers = append(enrichers, step)
+	}
+
+	var script *analyzer.Script
+	if c.Script != "" {
+		src, err := ioutil.ReadFile(c.Script)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading script")
+		}
+		if script, err = analyzer.LoadScript(c.Script, src); err != nil {
+			return nil, err
+		}
+	}
+
+	var redaction *analyzer.RedactionPolicy
+	if c.Redaction != nil {
+		redaction = &analyzer.RedactionPolicy{HashEmails: c.Redaction.HashEmails}
+		if c.Redaction.DropQueryParams != "" {
+			dropQueryParams, err := regexp.Compile(c.Redaction.DropQueryParams)
+			if err != nil {
+				return nil, errors.Wrap(err, "redaction dropQueryParams")
+			}
+			redaction.DropQueryParams = dropQueryParams
+		}
+	}
+
+	var pageviews *analyzer.PageviewRules
+	if c.Pageviews != nil {
+		var err error
+		pageviews = &analyzer.PageviewRules{Methods: c.Pageviews.Methods}
+		if c.Pageviews.AssetPaths != "" {
+			if pageviews.AssetPaths, err = regexp.Compile(c.Pageviews.AssetPaths); err != nil {
+				return nil, errors.Wrap(err, "pageviews assetPaths")
+			}
+		}
+		if c.Pageviews.BotUserAgents != "" {
+			if pageviews.BotUserAgents, err = regexp.Compile(c.Pageviews.BotUserAgents); err != nil {
+				return nil, errors.Wrap(err, "pageviews botUserAgents")
+			}
+		}
+	}
+
+	var sessions *analyzer.SessionRules
+	if c.Sessions != nil {
+		sessions = &analyzer.SessionRules{}
+		if c.Sessions.Timeout != "" {
+			var err error
+			if sessions.Timeout, err = time.ParseDuration(c.Sessions.Timeout); err != nil {
+				return nil, errors.Wrap(err, "sessions timeout")
+			}
+		}
+	}
+
+	var referrerBlocklist []string
+	if c.ReferrerBlocklist != "" {
+		file, err := os.Open(c.ReferrerBlocklist)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading referrer blocklist")
+		}
+		defer file.Close()
+		domains, err := analyzer.ReadReferrerBlocklist(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading referrer blocklist")
+		}
+		referrerBlocklist = append(domains, analyzer.DefaultReferrerSpam...)
+	}
+
+	var timeseriesInterval time.Duration
+	if c.TimeseriesInterval != "" {
+		var err error
+		if timeseriesInterval, err = time.ParseDuration(c.TimeseriesInterval); err != nil {
+			return nil, errors.Wrap(err, "timeseries interval")
+		}
+	}
+	var retention []*analyzer.RetentionTier
+	for _, r := range c.TimeseriesRetention {
+		interval, err := time.ParseDuration(r.Interval)
+		if err != nil {
+			return nil, errors.Wrap(err, "timeseries retention interval")
+		}
+		age, err := time.ParseDuration(r.Age)
+		if err != nil {
+			return nil, errors.Wrap(err, "timeseries retention age")
+		}
+		retention = append(retention, &analyzer.RetentionTier{Interval: interval, Age: age})
+	}
+
+	var endpointGroups []*analyzer.EndpointGroup
+	for _, g := range c.EndpointGroups {
+		path, err := regexp.Compile(g.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "endpoint group %s path", g.Name)
+		}
+		endpointGroups = append(endpointGroups, &analyzer.EndpointGroup{Name: g.Name, Path: path})
+	}
+
+	var latencyBuckets []time.Duration
+	for _, b := range c.LatencyBuckets {
+		bucket, err := time.ParseDuration(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "latency buckets")
+		}
+		latencyBuckets = append(latencyBuckets, bucket)
+	}
+
+	var uniqueIPWindows []time.Duration
+	for _, w := range c.UniqueIPWindows {
+		window, err := time.ParseDuration(w)
+		if err != nil {
+			return nil, errors.Wrap(err, "unique IP windows")
+		}
+		uniqueIPWindows = append(uniqueIPWindows, window)
+	}
+
+	var annotations []*analyzer.Annotation
+	for _, a := range c.Annotations {
+		at, err := time.Parse(time.RFC3339, a.Time)
+		if err != nil {
+			return nil, errors.Wrapf(err, "annotation %s time", a.Label)
+		}
+		annotations = append(annotations, &analyzer.Annotation{Time: at, Label: a.Label})
+	}
+
+	var lineIndex *analyzer.LineIndex
+	if c.SearchIndex != nil {
+		var maxAge time.Duration
+		if c.SearchIndex.MaxAge != "" {
+			var err error
+			if maxAge, err = time.ParseDuration(c.SearchIndex.MaxAge); err != nil {
+				return nil, errors.Wrap(err, "search index max age")
+			}
+		}
+		maxLines := c.SearchIndex.MaxLines
+		if maxLines <= 0 {
+			maxLines = defaultSearchIndexLines
+		}
+		lineIndex = analyzer.NewLineIndex(maxLines, maxAge)
+	}
+
+	var multiline *analyzer.MultilineRules
+	if c.Multiline != nil {
+		var err error
+		multiline = &analyzer.MultilineRules{Unwrap: c.Multiline.Unwrap, MaxLines: c.Multiline.MaxLines}
+		if c.Multiline.Start != "" {
+			if multiline.Start, err = regexp.Compile(c.Multiline.Start); err != nil {
+				return nil, errors.Wrap(err, "multiline start")
+			}
+		}
+		if c.Multiline.Continuation != "" {
+			if multiline.Continuation, err = regexp.Compile(c.Multiline.Continuation); err != nil {
+				return nil, errors.Wrap(err, "multiline continuation")
+			}
+		}
+	}
+
+	var disabledCollectors []analyzer.Collector
+	for _, name := range c.DisabledCollectors {
+		disabledCollectors = append(disabledCollectors, analyzer.Collector(name))
+	}
+
+	return &analyzer.LogAnalyzerConfig{
+		LineRegex:               lineRegex,
+		JSON:                    jsonLines,
+		JSONFields:              jsonFields,
+		Logfmt:                  c.Logfmt,
+		LogfmtFields:            c.LogfmtFields,
+		W3C:                     c.W3C,
+		CloudFront:              c.CloudFront,
+		Heroku:                  heroku,
+		CEF:                     cef,
+		CEFFields:               c.CEFFields,
+		FormatName:              formatName,
+		Fields:                  c.Fields,
+		Syslog:                  c.Syslog,
+		Multiline:               multiline,
+		MostActiveIPsCount:      c.MostActiveIPsCount,
+		MostVisitedURLsCount:    c.MostVisitedURLsCount,
+		MostActiveNetworksCount: c.MostActiveNetworksCount,
+		IPv6AggregatePrefix:     c.IPv6AggregatePrefix,
+		IPv4NetworkPrefix:       c.IPv4NetworkPrefix,
+		IPv6NetworkPrefix:       c.IPv6NetworkPrefix,
+		TrustedProxies:          c.TrustedProxies,
+		TimeLayouts:             c.TimeLayouts,
+		KeepRawURLs:             c.KeepRawURLs,
+		MaxURLs:                 c.MaxURLs,
+		ResourceStats:           c.ResourceStats,
+		StrictSchema:            c.StrictSchema,
+		TimeOrdered:             c.TimeOrdered,
+		QueueSize:               c.QueueSize,
+		QueuePolicy:             queuePolicies[c.QueuePolicy],
+		Tokenizer:               tokenizers[c.Tokenizer],
+		ReadAheadSize:           c.ReadAheadSize,
+		Enrichers:               enrichers,
+		EnrichmentCacheSize:     c.EnrichmentCacheSize,
+		EnrichmentConcurrency:   c.EnrichmentConcurrency,
+		PreserveEnrichmentOrder: c.PreserveEnrichmentOrder,
+		TopEnrichedValuesCount:  c.TopEnrichedValuesCount,
+		Script:                  script,
+		TopScoredIPsCount:       c.TopScoredIPsCount,
+		Redaction:               redaction,
+		LineIndex:               lineIndex,
+		DisabledCollectors:      disabledCollectors,
+		Pageviews:               pageviews,
+		Sessions:                sessions,
+		TopLandingPagesCount:    c.TopLandingPagesCount,
+		TopExitPagesCount:       c.TopExitPagesCount,
+		TopReferrersCount:       c.TopReferrersCount,
+		ReferrerBlocklist:       referrerBlocklist,
+		TopCampaignsCount:       c.TopCampaignsCount,
+		TopErrorMessagesCount:   c.TopErrorMessagesCount,
+		TimeseriesInterval:      timeseriesInterval,
+		TimeseriesRetention:     retention,
+		DeviceTimeseries:        c.DeviceTimeseries,
+		EndpointGroups:          endpointGroups,
+		UpstreamsCount:          c.UpstreamsCount,
+		TopUsersCount:           c.TopUsersCount,
+		NonReusingClientsCount:  c.NonReusingClientsCount,
+		UncompressedURLsCount:   c.UncompressedURLsCount,
+		LargeResponseBytes:      c.LargeResponseBytes,
+		SlowestRequestsCount:    c.SlowestRequestsCount,
+		LargestResponsesCount:   c.LargestResponsesCount,
+		MatchedSamplesCount:     c.MatchedSamplesCount,
+		UnmatchedSamplesCount:   c.UnmatchedSamplesCount,
+		Percentiles:             c.Percentiles,
+		LatencyBuckets:          latencyBuckets,
+		SizeBuckets:             c.SizeBuckets,
+		Annotations:             annotations,
+		TopMoversCount:          c.TopMoversCount,
+		UniqueIPWindows:         uniqueIPWindows,
+	}, nil
+}
+
+// serve : Serves the handler on the address, over TLS and behind the auth
+// of the server config if any
+func (c *fileConfig) serve(httpServer *http.Server) error {
+	if c.Server != nil && c.Server.Auth != nil {
+		httpServer.Handler = c.Server.Auth.Handler(httpServer.Handler)
+	}
+	if c.Server == nil || c.Server.TLS == nil {
+		// shard workers answer gRPC, over HTTP/2 without TLS too
+		httpServer.Handler = server.H2C(httpServer.Handler)
+		return httpServer.ListenAndServe()
+	}
+	tlsConfig, err := c.Server.TLS.ServerConfig()
+	if err != nil {
+		return err
+	}
+	httpServer.TLSConfig = tlsConfig
+	return httpServer.ListenAndServeTLS("", "")
+}
+
+// coordinator : The coordinator of the workers, with the TLS and auth of
+// the client config
+func (c *fileConfig) coordinator(workers []string) (*server.Coordinator, error) {
+	coordinator := &server.Coordinator{Workers: workers}
+	if c.Client == nil {
+		return coordinator, nil
+	}
+	if c.Client.TLS != nil {
+		var err error
+		if coordinator.TLS, err = c.Client.TLS.ClientConfig(); err != nil {
+			return nil, err
+		}
+	}
+	coordinator.Auth = c.Client.Auth
+	return coordinator, nil
+}
+
+// sinks : The plugin sinks selected by the config, wrapped with the sink
+// policy if any
+func (c *fileConfig) sinks() ([]analyzer.Sink, error) {
+	policy, err := c.sinkPolicy()
+	if err != nil {
+		return nil, err
+	}
+	var sinks []analyzer.Sink
+	for _, name := range c.Sinks {
+		sink := c.pluginSink(name)
+		if sink == nil {
+			return nil, errors.Errorf("unknown sink %q", name)
+		}
+		if policy != nil {
+			sink = analyzer.NewReliableSink(sink, policy)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func (c *fileConfig) sinkPolicy() (*analyzer.SinkPolicy, error) {
+	if c.SinkPolicy == nil {
+		return nil, nil
+	}
+	policy := &analyzer.SinkPolicy{
+		MaxRetries:     c.SinkPolicy.MaxRetries,
+		BatchSize:      c.SinkPolicy.BatchSize,
+		DeadLetterPath: c.SinkPolicy.DeadLetterPath,
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		to    *time.Duration
+	}{
+		{"backoff", c.SinkPolicy.Backoff, &policy.Backoff},
+		{"max backoff", c.SinkPolicy.MaxBackoff, &policy.MaxBackoff},
+		{"min interval", c.SinkPolicy.MinInterval, &policy.MinInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		var err error
+		if *d.to, err = time.ParseDuration(d.value); err != nil {
+			return nil, errors.Wrap(err, "sink policy "+d.name)
+		}
+	}
+	return policy, nil
+}
+
+// verifyTolerances : The tolerances of -verify, exact comparisons but for
+// DefaultIgnored when not set
+func (c *fileConfig) verifyTolerances() *verify.Tolerances {
+	if c.Verify == nil {
+		return &verify.Tolerances{}
+	}
+	return &verify.Tolerances{
+		Relative: c.Verify.Tolerance,
+		Absolute: c.Verify.AbsoluteTolerance,
+		Paths:    c.Verify.Tolerances,
+		Ignored:  c.Verify.Ignore,
+	}
+}
+
+// defaultSearchIndexLines : Lines the search index keeps when maxLines is not set
+const defaultSearchIndexLines = 100000
+
+// jsonFormats : The presets of JSON logs, by format name
+var jsonFormats = map[string]map[string]string{
+	"caddy":        analyzer.CaddyJSONFields,
+	"traefik-json": analyzer.TraefikJSONFields,
+	"cloudflare":   analyzer.CloudflareJSONFields,
+	"fastly-json":  analyzer.FastlyJSONFields,
+}
+
+func (c *fileConfig) lineRegex(name string) *regexp.Regexp {
+	for _, format := range formats.Presets {
+		if format.Name == name {
+			return format.LineRegex
+		}
+	}
+	for _, plugin := range c.plugins {
+		if lineRegex, ok := plugin.LineRegexes[name]; ok {
+			return lineRegex
+		}
+	}
+	return nil
+}
+
+// geoIPEnricher : The geoip enricher of the networks of the file
+func geoIPEnricher(file string) (analyzer.Enricher, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return analyzer.NewGeoIPEnricher(f)
+}
+
+func (c *fileConfig) pluginEnricher(name string) analyzer.Enricher {
+	for _, plugin := range c.plugins {
+		for _, enricher := range plugin.Enrichers {
+			if enricher.Name() == name {
+				return enricher
+			}
+		}
+	}
+	return nil
+}
+
+func (c *fileConfig) pluginSink(name string) analyzer.Sink {
+	for _, plugin := range c.plugins {
+		for _, sink := range plugin.Sinks {
+			if sink.Name() == name {
+				return sink
+			}
+		}
+	}
+	return nil
+}
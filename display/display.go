@@ -0,0 +1,155 @@
+// Package display formats report values, bytes, durations, percentages and
+// counts, the same way in every output, for a locale and units of choice, and
+// translates report labels to the locale's language (English, German or
+// French). Values are returned as plain text, outputs escape them as they
+// need.
+package display
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	// ErrUnknownUnits :
+	ErrUnknownUnits = "unknown units"
+	// ErrUnknownLocale :
+	ErrUnknownLocale = "unknown locale"
+)
+
+// DefaultPrecision : Decimals shown when not set
+const DefaultPrecision = 1
+
+// Units : Multiples sizes are shown in
+type Units int
+
+const (
+	// IEC : Binary multiples, KiB, MiB...
+	IEC Units = iota
+	// SI : Decimal multiples, kB, MB...
+	SI
+)
+
+var unitNames = map[string]Units{"iec": IEC, "si": SI}
+
+// ParseUnits : Units by name, "iec" or "si"
+func ParseUnits(name string) (Units, error) {
+	units, ok := unitNames[name]
+	if !ok {
+		return 0, errors.Wrap(errors.New(ErrUnknownUnits), name)
+	}
+	return units, nil
+}
+
+// Options : How values are formatted
+type Options struct {
+	// Locale : BCP 47 tag of the language of the labels and of the decimal
+	// and thousands separators, e.g. "de", English when not set
+	Locale string
+	Units  Units
+	// Precision : Decimals of fractional values, DefaultPrecision when not
+	// set, negative for none
+	Precision int
+}
+
+// Formatter : Formats values according to options
+type Formatter struct {
+	printer   *message.Printer
+	units     Units
+	precision int
+}
+
+// New : Returns a formatter for the options, English IEC with
+// DefaultPrecision decimals for nil options
+func New(options *Options) (*Formatter, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	tag := language.English
+	if options.Locale != "" {
+		var err error
+		if tag, err = language.Parse(options.Locale); err != nil {
+			return nil, errors.Wrap(errors.New(ErrUnknownLocale), options.Locale)
+		}
+	}
+	if _, ok := multiples[options.Units]; !ok {
+		return nil, errors.New(ErrUnknownUnits)
+	}
+	precision := options.Precision
+	if precision == 0 {
+		precision = DefaultPrecision
+	} else if precision < 0 {
+		precision = 0
+	}
+	return &Formatter{
+		printer:   message.NewPrinter(tag, message.Catalog(labels)),
+		units:     options.Units,
+		precision: precision,
+	}, nil
+}
+
+// multiples : Base and symbols of the multiples of each units
+var multiples = map[Units]struct {
+	base    float64
+	symbols []string
+}{
+	IEC: {1024, []string{"KiB", "MiB", "GiB", "TiB", "PiB"}},
+	SI:  {1000, []string{"kB", "MB", "GB", "TB", "PB"}},
+}
+
+// Sprintf : Formats a report label, given by its English format string, in
+// the language of the locale
+func (f *Formatter) Sprintf(key string, args ...interface{}) string {
+	return f.printer.Sprintf(key, args...)
+}
+
+// Count : e.g. "1,234"
+func (f *Formatter) Count(n int) string {
+	return f.printer.Sprintf("%d", n)
+}
+
+// Number : A fractional value, e.g. "2.5"
+func (f *Formatter) Number(v float64) string {
+	return f.printer.Sprintf("%.*f", f.precision, v)
+}
+
+// Bytes : A size in the largest multiple it reaches, e.g. "512 B", "1.5 KiB"
+func (f *Formatter) Bytes(n int64) string {
+	m := multiples[f.units]
+	if float64(n) < m.base && float64(n) > -m.base {
+		return f.printer.Sprintf("%d B", n)
+	}
+	v := float64(n)
+	symbol := ""
+	for _, s := range m.symbols {
+		if v < m.base && v > -m.base {
+			break
+		}
+		v /= m.base
+		symbol = s
+	}
+	return f.Number(v) + " " + symbol
+}
+
+// Duration : A duration in its most readable unit, e.g. "850 µs", "12.5 ms",
+// "1.5 s", or "2m5s" from a minute
+func (f *Formatter) Duration(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return f.Number(d.Seconds()) + " s"
+	case d >= time.Millisecond:
+		return f.Number(float64(d)/float64(time.Millisecond)) + " ms"
+	default:
+		return f.Number(float64(d)/float64(time.Microsecond)) + " µs"
+	}
+}
+
+// Percent : A ratio as a percentage, e.g. "12.5%" for 0.125
+func (f *Formatter) Percent(ratio float64) string {
+	return f.Number(ratio*100) + "%"
+}
@@ -0,0 +1,83 @@
+package display
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		format  func(f *Formatter) string
+		want    string
+	}{
+		{name: "count", format: func(f *Formatter) string { return f.Count(1234567) }, want: "1,234,567"},
+		{name: "bytes", format: func(f *Formatter) string { return f.Bytes(512) }, want: "512 B"},
+		{name: "kibibytes", format: func(f *Formatter) string { return f.Bytes(1536) }, want: "1.5 KiB"},
+		{name: "mebibytes", format: func(f *Formatter) string { return f.Bytes(10 << 20) }, want: "10.0 MiB"},
+		{name: "si", options: &Options{Units: SI}, format: func(f *Formatter) string { return f.Bytes(1500) }, want: "1.5 kB"},
+		{name: "microseconds", format: func(f *Formatter) string { return f.Duration(850 * time.Microsecond) }, want: "850.0 µs"},
+		{name: "milliseconds", format: func(f *Formatter) string { return f.Duration(12500 * time.Microsecond) }, want: "12.5 ms"},
+		{name: "seconds", format: func(f *Formatter) string { return f.Duration(1500 * time.Millisecond) }, want: "1.5 s"},
+		{name: "minutes", format: func(f *Formatter) string { return f.Duration(125400 * time.Millisecond) }, want: "2m5s"},
+		{name: "percent", format: func(f *Formatter) string { return f.Percent(0.125) }, want: "12.5%"},
+		{name: "no decimals", options: &Options{Precision: -1}, format: func(f *Formatter) string { return f.Percent(0.125) }, want: "12%"},
+		{name: "german", options: &Options{Locale: "de", Precision: 2}, format: func(f *Formatter) string { return f.Bytes(1234 << 20) }, want: "1,21 GiB"},
+		{name: "german count", options: &Options{Locale: "de"}, format: func(f *Formatter) string { return f.Count(1234567) }, want: "1.234.567"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.options)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := tt.format(f); got != tt.want {
+				t.Errorf("Formatter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *Options
+		wantErr string
+	}{
+		{name: "unknown locale", options: &Options{Locale: "not a locale!"}, wantErr: "not a locale!: " + ErrUnknownLocale},
+		{name: "unknown units", options: &Options{Units: Units(7)}, wantErr: ErrUnknownUnits},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.options)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatter_Sprintf(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "en", want: "sessions: 1,234, bounce rate: 12.5%\n"},
+		{locale: "de", want: "Sitzungen: 1.234, Absprungrate: 12,5%\n"},
+		{locale: "de-CH", want: "Sitzungen: 1’234, Absprungrate: 12.5%\n"},
+		{locale: "fr", want: "sessions : 1\u00a0234, taux de rebond : 12,5%\n"},
+		{locale: "ja", want: "sessions: 1,234, bounce rate: 12.5%\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			f, err := New(&Options{Locale: tt.locale})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := f.Sprintf("sessions: %s, bounce rate: %s\n", f.Count(1234), f.Percent(0.125)); got != tt.want {
+				t.Errorf("Formatter.Sprintf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
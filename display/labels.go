@@ -0,0 +1,89 @@
+package display
+
+import (
+	"golang.org/x/text/language"
+	"golang.org/x/text/message/catalog"
+)
+
+// translations : Report labels by language, keyed by their English format
+// string. Labels missing from a language are shown in English.
+var translations = map[language.Tag]map[string]string{
+	language.German: {
+		"unique ips count: %s\n":                            "Anzahl eindeutiger IPs: %s\n",
+		"unique ips in the last %s: %s\n":                   "eindeutige IPs in den letzten %s: %s\n",
+		"most visited urls: %v\n":                           "meistbesuchte URLs: %v\n",
+		"most active ips: %v\n":                             "aktivste IPs: %v\n",
+		"first-time ips: %v\n":                              "erstmals gesehene IPs: %v\n",
+		"pageviews: %s\n":                                   "Seitenaufrufe: %s\n",
+		"top referrers: %v (spam referrals filtered: %s)\n": "Top-Verweise: %v (gefilterte Spam-Verweise: %s)\n",
+		"top campaigns: %q\n":                               "Top-Kampagnen: %q\n",
+		"upstream %s: %s requests, %s errors":               "Upstream %s: %s Anfragen, %s Fehler",
+		"user %s: %s requests, %s, %s errors\n":             "Benutzer %s: %s Anfragen, %s, %s Fehler\n",
+		", mean latency %s":                                 ", mittlere Latenz %s",
+		"keepalive: %s requests on %s connections (%s per connection), %s on reused connections\n": "Keepalive: %s Anfragen über %s Verbindungen (%s pro Verbindung), %s über wiederverwendete Verbindungen\n",
+		"clients never reusing connections: %v\n":                                                  "Clients ohne Wiederverwendung von Verbindungen: %v\n",
+		"compression of %q: %s responses, %s compressed":                                           "Komprimierung von %q: %s Antworten, %s komprimiert",
+		", ratio %s":                         ", Verhältnis %s",
+		"large uncompressed responses: %v\n": "große unkomprimierte Antworten: %v\n",
+		"cache: %s requests, hit ratio %s, byte hit ratio %s\n": "Cache: %s Anfragen, Trefferquote %s, Byte-Trefferquote %s\n",
+		"slowest requests:\n":                    "langsamste Anfragen:\n",
+		"events:\n":                              "Ereignisse:\n",
+		"top rising %s:\n":                       "stärkste Anstiege %s:\n",
+		"top falling %s:\n":                      "stärkste Rückgänge %s:\n",
+		"deviations from the %d-day baseline:\n": "Abweichungen von der Basislinie der letzten %d Tage:\n",
+		"  %s: %s, baseline %s (%s)\n":           "  %s: %s, Basislinie %s (%s)\n",
+		"response sizes: mean %s":                "Antwortgrößen: Mittelwert %s",
+		"largest responses:\n":                   "größte Antworten:\n",
+		"sessions: %s, bounce rate: %s\n":        "Sitzungen: %s, Absprungrate: %s\n",
+		"top landing pages: %v\n":                "Top-Einstiegsseiten: %v\n",
+		"top exit pages: %v\n":                   "Top-Ausstiegsseiten: %v\n",
+		"top scored ips: %v\n":                   "IPs mit höchster Bewertung: %v\n",
+		"top %s: %v\n":                           "Top %s: %v\n",
+		"%s timeseries:\n":                       "Zeitreihe %s:\n",
+	},
+	language.French: {
+		"unique ips count: %s\n":                            "nombre d'IP uniques : %s\n",
+		"unique ips in the last %s: %s\n":                   "IP uniques des dernières %s : %s\n",
+		"most visited urls: %v\n":                           "URL les plus visitées : %v\n",
+		"most active ips: %v\n":                             "IP les plus actives : %v\n",
+		"first-time ips: %v\n":                              "IP vues pour la première fois : %v\n",
+		"pageviews: %s\n":                                   "pages vues : %s\n",
+		"top referrers: %v (spam referrals filtered: %s)\n": "principaux référents : %v (référents indésirables filtrés : %s)\n",
+		"top campaigns: %q\n":                               "principales campagnes : %q\n",
+		"upstream %s: %s requests, %s errors":               "amont %s : %s requêtes, %s d'erreurs",
+		"user %s: %s requests, %s, %s errors\n":             "utilisateur %s : %s requêtes, %s, %s d'erreurs\n",
+		", mean latency %s":                                 ", latence moyenne %s",
+		"keepalive: %s requests on %s connections (%s per connection), %s on reused connections\n": "keepalive : %s requêtes sur %s connexions (%s par connexion), %s sur des connexions réutilisées\n",
+		"clients never reusing connections: %v\n":                                                  "clients ne réutilisant jamais leurs connexions : %v\n",
+		"compression of %q: %s responses, %s compressed":                                           "compression de %q : %s réponses, %s compressées",
+		", ratio %s":                         ", taux %s",
+		"large uncompressed responses: %v\n": "grandes réponses non compressées : %v\n",
+		"cache: %s requests, hit ratio %s, byte hit ratio %s\n": "cache : %s requêtes, taux de succès %s, taux de succès en octets %s\n",
+		"slowest requests:\n":                    "requêtes les plus lentes :\n",
+		"events:\n":                              "événements :\n",
+		"top rising %s:\n":                       "plus fortes hausses %s :\n",
+		"top falling %s:\n":                      "plus fortes baisses %s :\n",
+		"deviations from the %d-day baseline:\n": "écarts par rapport à la référence des %d derniers jours :\n",
+		"  %s: %s, baseline %s (%s)\n":           "  %s : %s, référence %s (%s)\n",
+		"response sizes: mean %s":                "tailles des réponses : moyenne %s",
+		"largest responses:\n":                   "réponses les plus grandes :\n",
+		"sessions: %s, bounce rate: %s\n":        "sessions : %s, taux de rebond : %s\n",
+		"top landing pages: %v\n":                "principales pages d'entrée : %v\n",
+		"top exit pages: %v\n":                   "principales pages de sortie : %v\n",
+		"top scored ips: %v\n":                   "IP les mieux notées : %v\n",
+		"top %s: %v\n":                           "principaux %s : %v\n",
+		"%s timeseries:\n":                       "série temporelle %s :\n",
+	},
+}
+
+// labels : The catalog of the translated report labels
+var labels = func() catalog.Catalog {
+	builder := catalog.NewBuilder(catalog.Fallback(language.English))
+	for tag, messages := range translations {
+		for key, msg := range messages {
+			// the keys and messages are constant and valid
+			_ = builder.SetString(tag, key, msg)
+		}
+	}
+	return builder
+}()
@@ -0,0 +1,76 @@
+// Code generated by parsergen from the log format below. DO NOT EDIT.
+//
+//	%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+// CombinedParser : ParseCombined, for analyzer.LogAnalyzerConfig.Parser
+var CombinedParser = &analyzer.Parser{Parse: ParseCombined, Fields: []string{"remote_host", "remote_user", "time", "request", "status", "bytes", "referer", "user_agent"}}
+
+// combinedGroups : The group of each field, empty for the fields skipped
+var combinedGroups = []string{"remote_host", "", "remote_user", "time", "request", "status", "bytes", "referer", "user_agent"}
+
+// ParseCombined : Parses a line of the format without a regex, filling the
+// fields its line regex does. Each field ends at the first occurrence of the
+// text after it, the last one at the end of the line.
+func ParseCombined(line string) (*analyzer.Line, error) {
+	var values [9]string
+	rest := line
+	var i int
+	// remote_host
+	if i = strings.IndexByte(rest, ' '); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[0], rest = rest[:i], rest[i+1:]
+	// skipped
+	if i = strings.IndexByte(rest, ' '); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[1], rest = rest[:i], rest[i+1:]
+	// remote_user
+	if i = strings.Index(rest, " ["); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[2], rest = rest[:i], rest[i+2:]
+	// time
+	if i = strings.Index(rest, "] \""); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[3], rest = rest[:i], rest[i+3:]
+	// request
+	if i = strings.Index(rest, "\" "); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[4], rest = rest[:i], rest[i+2:]
+	// status
+	if i = strings.IndexByte(rest, ' '); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[5], rest = rest[:i], rest[i+1:]
+	// bytes
+	if i = strings.Index(rest, " \""); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[6], rest = rest[:i], rest[i+2:]
+	// referer
+	if i = strings.Index(rest, "\" \""); i < 0 {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[7], rest = rest[:i], rest[i+3:]
+	// user_agent
+	if !strings.HasSuffix(rest, "\"") {
+		return nil, errors.New(analyzer.ErrLineNotMatched)
+	}
+	values[8] = rest[:len(rest)-1]
+
+	lineItem := &analyzer.Line{}
+	analyzer.FillFields(lineItem, combinedGroups, values[:])
+	return lineItem, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+func TestParseCombined(t *testing.T) {
+	file, err := os.Open("../../analyzer/test-data/golden/combined/task.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		want, err := analyzer.Parse(scanner.Bytes())
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", scanner.Text(), err)
+		}
+		got, err := ParseCombined(scanner.Text())
+		if err != nil {
+			t.Fatalf("ParseCombined(%q) error = %v", scanner.Text(), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseCombined(%q) = %+v, want %+v", scanner.Text(), got, want)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseCombined("not a log line"); err == nil || err.Error() != analyzer.ErrLineNotMatched {
+		t.Errorf("ParseCombined() error = %v, wantErr %v", err, analyzer.ErrLineNotMatched)
+	}
+}
@@ -0,0 +1,37 @@
+// Command parsergen : Example analyzer of combined logs, parsed by code
+// generated by cmd/parsergen instead of a line regex.
+//
+//	go generate ./examples/parsergen
+//	go run ./examples/parsergen access.log
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+//go:generate go run ../../cmd/parsergen -apache "%h %l %u %t \"%r\" %>s %b \"%{Referer}i\" \"%{User-agent}i\"" -name Combined -o combined_parser.go
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: parsergen <log file>")
+	}
+	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{
+		Parser:               CombinedParser,
+		MostActiveIPsCount:   3,
+		MostVisitedURLsCount: 3,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	analytics, err := logAnalyzer.Analyze(os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Unique IPs:", analytics.UniqueIPCount)
+	fmt.Println("Most active IPs:", analytics.MostActiveIPs)
+	fmt.Println("Most visited URLs:", analytics.MostVisitedURLs)
+}
@@ -0,0 +1,56 @@
+// Command plugin : Example analyzer plugin, adding the "vhost_combined" line
+// format, the "urlext" enricher and the "jsonlines" sink.
+//
+//	go build -buildmode=plugin -o example.so ./examples/plugin
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+// Plugin : Looked up by analyzer.LoadPlugin
+var Plugin = analyzer.Plugin{
+	Name: "example",
+	LineRegexes: map[string]*regexp.Regexp{
+		// combined format, prefixed by the virtual host
+		"vhost_combined": regexp.MustCompile(`^\S+ (\S+) \S+ \S+ \[([^]]+)\] "(\S*) ?(\S*) ?([^"]*)"() (\S+) (\S+) "([^"]*)" "([^"]*)"$`),
+	},
+	Enrichers: []analyzer.Enricher{urlExtEnricher{}},
+	Sinks:     []analyzer.Sink{jsonLinesSink{encoder: json.NewEncoder(os.Stdout)}},
+}
+
+// urlExtEnricher : Extension of the requested file (field "ext"), e.g. "html"
+type urlExtEnricher struct{}
+
+func (urlExtEnricher) Name() string { return "urlext" }
+
+func (urlExtEnricher) Key(line *analyzer.Line) string {
+	if i := strings.IndexAny(line.URL, "?#"); i >= 0 {
+		return line.URL[:i]
+	}
+	return line.URL
+}
+
+func (urlExtEnricher) Enrich(ctx context.Context, key string) (map[string]string, error) {
+	return map[string]string{"ext": strings.TrimPrefix(path.Ext(key), ".")}, nil
+}
+
+// jsonLinesSink : Writes every report to stdout as a line of JSON
+type jsonLinesSink struct {
+	encoder *json.Encoder
+}
+
+func (jsonLinesSink) Name() string { return "jsonlines" }
+
+func (s jsonLinesSink) Write(analytics *analyzer.LogAnalytics) error {
+	return s.encoder.Encode(analytics)
+}
+
+func main() {}
@@ -0,0 +1,149 @@
+// Package fixtures generates pathological access log lines. They are meant to
+// harden parsers and to serve as regression fixtures for any supported format.
+package fixtures
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fixture : A generated log line along with what makes it interesting
+type Fixture struct {
+	Name string
+	Line string
+}
+
+// Client : A remote host the generated lines are attributed to
+type Client struct {
+	Name    string
+	Address string
+}
+
+// Clients : Client addresses in the representations a parser may come across
+var Clients = []Client{
+	{Name: "ipv4", Address: "177.71.128.21"},
+	{Name: "ipv6-compressed", Address: "2001:db8::1"},
+	{Name: "ipv6-full", Address: "2001:0DB8:0000:0000:0000:0000:0000:0001"},
+	{Name: "ipv6-mapped-ipv4", Address: "::ffff:177.71.128.21"},
+	{Name: "hostname", Address: "crawl-66-249-66-1.googlebot.com"},
+}
+
+const (
+	timestamp = "10/Jul/2018:22:21:28 +0200"
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/61.0"
+)
+
+// Pathological : Deterministically generates the edge case lines, one set per
+// client representation, in the NCSA combined log format
+func Pathological() []Fixture {
+	var fixtures []Fixture
+	for _, client := range Clients {
+		for _, c := range cases {
+			fixtures = append(fixtures, Fixture{
+				Name: client.Name + "/" + c.name,
+				Line: combined(client.Address, c),
+			})
+		}
+	}
+	return fixtures
+}
+
+// WriteLog : Writes the fixture lines to w, one per line
+func WriteLog(w io.Writer, fixtures []Fixture) error {
+	for _, f := range fixtures {
+		if _, err := fmt.Fprintln(w, f.Line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type lineCase struct {
+	name      string
+	user      string
+	request   string
+	status    string
+	bytes     string
+	referer   string
+	userAgent string
+}
+
+var cases = []lineCase{
+	{
+		name:    "plain",
+		request: "GET /intranet-analytics/ HTTP/1.1",
+	},
+	{
+		name:      "escaped-quotes-in-user-agent",
+		request:   "GET /faq/ HTTP/1.1",
+		userAgent: `Mozilla/5.0 \"quoted\" (compatible; \"Bot\")`,
+	},
+	{
+		name:    "escaped-quotes-in-referer",
+		request: "GET /faq/ HTTP/1.1",
+		referer: `http://example.net/search?q=\"log parser\"`,
+	},
+	{
+		name:    "escaped-quotes-in-url",
+		request: `GET /search?q=\"drop table\" HTTP/1.1`,
+	},
+	{
+		name:    "zero-byte-response",
+		request: "HEAD /healthz HTTP/1.1",
+		status:  "204",
+		bytes:   "0",
+	},
+	{
+		name:    "dash-byte-response",
+		request: "GET /not-modified/ HTTP/1.1",
+		status:  "304",
+		bytes:   "-",
+	},
+	{
+		name:    "long-method",
+		request: strings.Repeat("X", 30) + " /webdav/ HTTP/1.1",
+		status:  "405",
+	},
+	{
+		name:    "no-protocol",
+		request: "GET /legacy/",
+	},
+	{
+		name:    "empty-request",
+		request: "",
+		status:  "408",
+		bytes:   "0",
+	},
+	{
+		name:    "absolute-url",
+		request: "GET http://example.net/faq/ HTTP/1.1",
+	},
+	{
+		name:    "authenticated-user-with-space",
+		user:    "first last",
+		request: "GET /admin/ HTTP/1.1",
+	},
+	{
+		name:    "oversized-bytes",
+		request: "GET /downloads/archive.tar HTTP/1.1",
+		bytes:   "99999999999999999999",
+	},
+}
+
+func combined(address string, c lineCase) string {
+	user := or(c.user, "-")
+	status := or(c.status, "200")
+	bytes := or(c.bytes, "3574")
+	referer := or(c.referer, "-")
+	ua := or(c.userAgent, userAgent)
+	return fmt.Sprintf(`%s - %s [%s] "%s" %s %s "%s" "%s"`,
+		address, user, timestamp, c.request, status, bytes, referer, ua)
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,36 @@
+package fixtures
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPathological(t *testing.T) {
+	fixtures := Pathological()
+	if len(fixtures) != len(Clients)*len(cases) {
+		t.Errorf("Pathological() = %d fixtures, want %d", len(fixtures), len(Clients)*len(cases))
+	}
+	names := make(map[string]bool)
+	for _, f := range fixtures {
+		if names[f.Name] {
+			t.Errorf("Pathological() duplicate fixture name %s", f.Name)
+		}
+		names[f.Name] = true
+		if strings.ContainsAny(f.Line, "\r\n") {
+			t.Errorf("Pathological() fixture %s spans several lines", f.Name)
+		}
+	}
+}
+
+func TestWriteLog(t *testing.T) {
+	var buffer bytes.Buffer
+	fixtures := Pathological()
+	if err := WriteLog(&buffer, fixtures); err != nil {
+		t.Fatalf("WriteLog() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != len(fixtures) {
+		t.Errorf("WriteLog() wrote %d lines, want %d", len(lines), len(fixtures))
+	}
+}
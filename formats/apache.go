@@ -0,0 +1,178 @@
+package formats
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrInvalidLogFormat :
+	ErrInvalidLogFormat = "invalid log format"
+	// ErrUnknownDirective :
+	ErrUnknownDirective = "unknown log format directive"
+)
+
+// directive : How a log format directive is matched, and the named group of
+// the line field it captures, if any
+type directive struct {
+	pattern string
+	group   string
+}
+
+// apacheDirectives : Apache mod_log_config directives, by letter
+var apacheDirectives = map[string]directive{
+	"a": {`\S+`, "remote_host"},
+	"h": {`\S+`, "remote_host"},
+	"l": {`\S+`, ""},
+	"u": {`\S+`, "remote_user"},
+	"t": {`[^]]+`, "time"},
+	"r": {`.*?`, "request"},
+	"m": {`\S+`, "method"},
+	"U": {`[^\s?]*`, "url"},
+	"q": {`\S*`, "query"},
+	"H": {`\S+`, "protocol"},
+	"s": {`\S+`, "status"},
+	"b": {`\S+`, "bytes"},
+	"B": {`\S+`, "bytes"},
+	"A": {`\S+`, ""},
+	"D": {`\S+`, "duration_us"},
+	"f": {`\S+`, ""},
+	"I": {`\S+`, ""},
+	"k": {`\S+`, ""},
+	"L": {`\S+`, ""},
+	"O": {`\S+`, "bytes"},
+	"p": {`\S+`, ""},
+	"P": {`\S+`, ""},
+	"R": {`\S+`, ""},
+	"S": {`\S+`, ""},
+	"T": {`\S+`, "duration"},
+	"v": {`\S+`, "vhost"},
+	"V": {`\S+`, "vhost"},
+	"X": {`\S+`, ""},
+}
+
+// apacheHeaders : Request (i) and response (o) headers captured as line
+// fields, by lower case name
+var apacheHeaders = map[string]string{
+	"i:referer":          "referer",
+	"i:user-agent":       "user_agent",
+	"i:x-forwarded-for":  "x_forwarded_for",
+	"o:content-type":     "content_type",
+	"o:content-encoding": "content_encoding",
+}
+
+// apacheSSLVariables : mod_ssl variables of %{VARNAME}x captured as line
+// fields, the others are matched and skipped
+var apacheSSLVariables = map[string]string{
+	"SSL_PROTOCOL": "ssl_protocol",
+	"SSL_CIPHER":   "ssl_cipher",
+}
+
+// apacheDurationUnits : The groups of the units of %{UNIT}T, the time
+// taken to serve the request
+var apacheDurationUnits = map[string]string{
+	"s":  "duration",
+	"ms": "duration_ms",
+	"us": "duration_us",
+}
+
+// apacheDirective : A directive, e.g. %>s, %{User-agent}i or %400,501{Referer}i
+var apacheDirective = regexp.MustCompile(`%[<>]?!?[0-9,]*(?:\{([^}]*)\})?(\^t[io]|[a-zA-Z%])`)
+
+// logFormatLine : A whole LogFormat directive line of an Apache config
+var logFormatLine = regexp.MustCompile(`^\s*LogFormat\s+"((?:[^"\\]|\\.)*)"(?:\s+(\S+))?\s*$`)
+
+// Apache : Compiles an Apache LogFormat string, e.g. `%h %l %u %t "%r" %>s %b`,
+// or a whole LogFormat directive line pasted from a vhost config, into a
+// format mapping each directive to a line field. Directives with no line
+// field are matched and skipped, e.g. %k or other headers; %{format}t times
+// are matched but not parsed. The time taken, %D (microseconds), %T (seconds)
+// or %{UNIT}T, is the duration. mod_ssl's %{SSL_PROTOCOL}x and
+// %{SSL_CIPHER}x are the TLS protocol and cipher.
+func Apache(logFormat string) (*Format, error) {
+	name := "apache"
+	if m := logFormatLine.FindStringSubmatch(logFormat); m != nil {
+		logFormat = unescapeLogFormat(m[1])
+		if m[2] != "" {
+			name = m[2]
+		}
+	}
+
+	var tokens []Token
+	groups := 0
+	last := 0
+	for _, m := range apacheDirective.FindAllStringSubmatchIndex(logFormat, -1) {
+		tokens = append(tokens, literal(logFormat[last:m[0]])...)
+		last = m[1]
+		param, letter := "", logFormat[m[4]:m[5]]
+		if m[2] >= 0 {
+			param = logFormat[m[2]:m[3]]
+		}
+
+		var d directive
+		switch {
+		case letter == "%":
+			tokens = append(tokens, Token{Literal: "%"})
+			continue
+		case letter == "i" || letter == "o":
+			d = directive{`.*?`, apacheHeaders[letter+":"+strings.ToLower(param)]}
+		case letter == "x":
+			d = directive{`\S+`, apacheSSLVariables[strings.ToUpper(param)]}
+		case letter == "t" && param != "":
+			d = directive{`.*?`, ""}
+		case letter == "T" && param != "":
+			group, ok := apacheDurationUnits[param]
+			if !ok {
+				return nil, errors.Wrap(errors.New(ErrUnknownDirective), logFormat[m[0]:m[1]])
+			}
+			d = directive{`\S+`, group}
+		case letter == "C" || letter == "e" || letter == "n" || letter == "^ti" || letter == "^to":
+			d = directive{`.*?`, ""}
+		default:
+			var ok bool
+			if d, ok = apacheDirectives[letter]; !ok {
+				return nil, errors.Wrap(errors.New(ErrUnknownDirective), logFormat[m[0]:m[1]])
+			}
+		}
+		if d.group == "" {
+			tokens = append(tokens, Token{Pattern: d.pattern})
+			continue
+		}
+		if letter == "t" {
+			// the time is logged in brackets
+			tokens = append(tokens, Token{Literal: "["}, Token{Pattern: d.pattern, Group: d.group}, Token{Literal: "]"})
+		} else {
+			tokens = append(tokens, Token{Pattern: d.pattern, Group: d.group})
+		}
+		groups++
+	}
+	tokens = append(tokens, literal(logFormat[last:])...)
+
+	if groups == 0 {
+		return nil, errors.Wrap(errors.New(ErrInvalidLogFormat), "no directive maps to a line field")
+	}
+	return compileTokens(name, tokens)
+}
+
+// VHostCombined : Apache's vhost_combined format, the combined log format
+// prefixed with the virtual host and port, e.g. `www.example.com:443 10.0.0.1
+// - - [...] "GET / HTTP/1.1" ...`, for servers logging several sites to one
+// file. The bytes sent, %O including headers in Apache's definition, are
+// read as the response size, as %O is wherever it is logged.
+var VHostCombined = func() *Format {
+	format, err := Apache(`%v:%p %h %l %u %t "%r" %>s %O "%{Referer}i" "%{User-Agent}i"`)
+	if err != nil {
+		panic(err)
+	}
+	format.Name = "vhost-combined"
+	format.Example = `www.example.com:443 203.0.113.7 - - [11/Oct/2023:14:32:52 +0000] "GET /index.html HTTP/1.1" 200 6340 "-" "Mozilla/5.0 (X11; Linux x86_64)"`
+	return format
+}()
+
+// unescapeLogFormat : The format string of a LogFormat directive, of which
+// \" \t and \n are escapes
+func unescapeLogFormat(s string) string {
+	return strings.NewReplacer(`\"`, `"`, `\t`, "\t", `\n`, "\n", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,149 @@
+package formats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApache(t *testing.T) {
+	tests := []struct {
+		name      string
+		logFormat string
+		line      string
+		wantName  string
+		want      map[string]string
+	}{
+		{
+			name:      "common",
+			logFormat: `%h %l %u %t "%r" %>s %b`,
+			line:      `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			wantName:  "apache",
+			want: map[string]string{
+				"remote_host": "127.0.0.1",
+				"remote_user": "frank",
+				"time":        "10/Oct/2000:13:55:36 -0700",
+				"request":     "GET /apache_pb.gif HTTP/1.0",
+				"status":      "200",
+				"bytes":       "2326",
+			},
+		},
+		{
+			name:      "pasted vhost combined directive",
+			logFormat: `LogFormat "%v:%p %h %l %u %t \"%r\" %>s %O \"%{Referer}i\" \"%{User-Agent}i\"" vhost_combined`,
+			line:      `example.com:443 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 2326 "http://example.com/start" "Mozilla/5.0 (X11)"`,
+			wantName:  "vhost_combined",
+			want: map[string]string{
+				"vhost":       "example.com",
+				"remote_host": "127.0.0.1",
+				"remote_user": "-",
+				"time":        "10/Oct/2000:13:55:36 -0700",
+				"request":     "GET / HTTP/1.1",
+				"status":      "200",
+				"bytes":       "2326",
+				"referer":     "http://example.com/start",
+				"user_agent":  "Mozilla/5.0 (X11)",
+			},
+		},
+		{
+			name:      "reordered fields and request parts",
+			logFormat: `%>s %{%Y-%m-%d}t %a "%m %U%q %H" %B %D %{Content-Type}o 100%%`,
+			line:      `404 2000-10-10 10.0.0.1 "POST /search?q=go HTTP/2.0" 512 1234 text/html 100%`,
+			wantName:  "apache",
+			want: map[string]string{
+				"status":       "404",
+				"remote_host":  "10.0.0.1",
+				"method":       "POST",
+				"url":          "/search",
+				"query":        "?q=go",
+				"protocol":     "HTTP/2.0",
+				"bytes":        "512",
+				"content_type": "text/html",
+				"duration_us":  "1234",
+			},
+		},
+		{
+			name:      "mod_ssl variables",
+			logFormat: `%h %t "%r" %>s %b %{SSL_PROTOCOL}x %{SSL_CIPHER}x %{SSL_CLIENT_S_DN}x`,
+			line:      `10.0.0.1 [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 2326 TLSv1.2 ECDHE-RSA-AES128-GCM-SHA256 -`,
+			wantName:  "apache",
+			want: map[string]string{
+				"remote_host":  "10.0.0.1",
+				"time":         "10/Oct/2000:13:55:36 -0700",
+				"request":      "GET / HTTP/1.1",
+				"status":       "200",
+				"bytes":        "2326",
+				"ssl_protocol": "TLSv1.2",
+				"ssl_cipher":   "ECDHE-RSA-AES128-GCM-SHA256",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Apache(tt.logFormat)
+			if err != nil {
+				t.Fatalf("Apache() error = %v", err)
+			}
+			if format.Name != tt.wantName {
+				t.Errorf("Apache() name = %q, want %q", format.Name, tt.wantName)
+			}
+			result := format.LineRegex.FindStringSubmatch(tt.line)
+			if result == nil {
+				t.Fatalf("Apache() regex %s does not match %q", format.LineRegex, tt.line)
+			}
+			got := map[string]string{}
+			for i, name := range format.LineRegex.SubexpNames() {
+				if name != "" {
+					got[name] = result[i]
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apache() groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApache_errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		logFormat string
+		wantErr   string
+	}{
+		{name: "unknown directive", logFormat: `%h %J`, wantErr: "%J: " + ErrUnknownDirective},
+		{name: "unknown time unit", logFormat: `%h %{h}T`, wantErr: "%{h}T: " + ErrUnknownDirective},
+		{name: "no line field", logFormat: `%l %p`, wantErr: "no directive maps to a line field: " + ErrInvalidLogFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apache(tt.logFormat)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Apache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApache_tokens(t *testing.T) {
+	format, err := Apache(`%h %l [%{%d/%b/%Y}t] %t "%r" 100%%`)
+	if err != nil {
+		t.Fatalf("Apache() error = %v", err)
+	}
+	want := []Token{
+		{Pattern: `\S+`, Group: "remote_host"},
+		{Literal: " "},
+		{Pattern: `\S+`},
+		{Literal: " ["},
+		{Pattern: `.*?`},
+		{Literal: "] "},
+		{Literal: "["},
+		{Pattern: `[^]]+`, Group: "time"},
+		{Literal: "]"},
+		{Literal: ` "`},
+		{Pattern: `.*?`, Group: "request"},
+		{Literal: `" 100`},
+		{Literal: "%"},
+	}
+	if !reflect.DeepEqual(format.Tokens, want) {
+		t.Errorf("Apache() tokens = %+v, want %+v", format.Tokens, want)
+	}
+}
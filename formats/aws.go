@@ -0,0 +1,56 @@
+package formats
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// awsRequest : The groups of the quoted request of AWS load balancer logs, up
+// to the end of the line. The absolute URL of the request is split into its
+// scheme and host, kept as extras, and the path read as the URL. Requests a
+// TCP listener could not parse are logged "- - - ".
+const awsRequest = `"(?P<method>\S*) (?:(?P<scheme>[a-z]+)://(?P<host>[^/\s]*))?(?P<url>[^\s?]*)(?P<query>\?\S*)? (?P<protocol>[^"]*)"`
+
+// awsBalancer : The groups from the client to the sent bytes, shared by ALB
+// and classic ELB logs. Backend fields are "-" and durations -1 when the
+// request reached no backend; the load balancer's status is the one the
+// client got, the backend's is kept as an extra.
+const awsBalancer = `(?P<elb>\S+) (?P<remote_host>\S+):\d+ (?:(?P<upstream>\S+:\d+)|-) ` +
+	`(?P<request_processing_time>\S+) (?P<duration>\S+) (?P<response_processing_time>\S+) ` +
+	`(?P<status>\S+) (?P<%s>\S+) (?P<received_bytes>\d+) (?P<bytes>\d+) `
+
+// ALB : AWS Application Load Balancer access logs. The target processing time
+// is the duration, and the target the upstream.
+var ALB = &Format{
+	Name: "alb",
+	LineRegex: regexp.MustCompile(`^(?P<type>\S+) (?P<time>\S+) ` + fmt.Sprintf(awsBalancer, "target_status_code") + awsRequest +
+		` "(?P<user_agent>(?:[^"\\]|\\.)*)" (?P<ssl_cipher>\S+) (?P<ssl_protocol>\S+)` +
+		`(?: (?P<target_group_arn>\S+) "(?P<trace_id>[^"]*)")?.*$`),
+	Example: `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`,
+}
+
+// ClassicELB : AWS Classic Load Balancer access logs. The backend processing
+// time is the duration, and the backend instance the upstream. Logs written
+// before the user agent and SSL fields were added are read as well.
+var ClassicELB = &Format{
+	Name: "elb",
+	LineRegex: regexp.MustCompile(`^(?P<time>\S+) ` + fmt.Sprintf(awsBalancer, "backend_status_code") + awsRequest +
+		`(?: "(?P<user_agent>(?:[^"\\]|\\.)*)" (?P<ssl_cipher>\S+) (?P<ssl_protocol>\S+))?$`),
+	Example: `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`,
+}
+
+// S3 : AWS S3 server access logs. The total time, in milliseconds, is the
+// duration, and the cipher suite and TLS version the TLS cipher and protocol;
+// the bucket owner, bucket, requester, request ID, operation (e.g.
+// REST.GET.OBJECT), key, error code, object size and turnaround time, along
+// with the version ID and the other fields S3 added later (host ID, signature
+// version, authentication type, host header), are kept as extras.
+var S3 = &Format{
+	Name: "s3",
+	LineRegex: regexp.MustCompile(`^(?P<bucket_owner>\S+) (?P<bucket>\S+) \[(?P<time>[^\]]+)\] (?P<remote_host>\S+) ` +
+		`(?P<requester>\S+) (?P<request_id>\S+) (?P<operation>\S+) (?P<key>\S+) (?:"(?P<request>[^"]*)"|-) ` +
+		`(?P<status>\S+) (?P<error_code>\S+) (?P<bytes>\S+) (?P<object_size>\S+) (?P<duration_ms>\S+) (?P<turnaround_time>\S+) ` +
+		`"(?P<referer>[^"]*)" "(?P<user_agent>(?:[^"\\]|\\.)*)" (?P<version_id>\S+)` +
+		`(?: (?P<host_id>\S+) (?P<signature_version>\S+) (?P<ssl_cipher>\S+) (?P<authentication_type>\S+) (?P<host_header>\S+) (?P<ssl_protocol>\S+))?.*$`),
+	Example: `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be 3E57427F3EXAMPLE REST.GET.VERSIONING - "GET /awsexamplebucket1?versioning HTTP/1.1" 200 - 113 - 7 - "-" "S3Console/0.4" - s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader awsexamplebucket1.s3.us-west-1.amazonaws.com TLSV1.2 - -`,
+}
@@ -0,0 +1,121 @@
+package formats
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnvoyDefaultLogFormat : The format Envoy logs HTTP requests with when its
+// access log sets none
+const EnvoyDefaultLogFormat = `[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" ` +
+	`%RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% %RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)% ` +
+	`"%REQ(X-FORWARDED-FOR)%" "%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%"`
+
+// envoyOperators : Envoy command operators captured as line fields or under a
+// name of their own, by operator and upper case argument. Other operators are
+// captured into the line's extras under their lower case name and argument,
+// e.g. %REQ(X-TENANT)% as "req_x_tenant".
+var envoyOperators = map[string]directive{
+	"START_TIME":                             {`\S+`, "time"},
+	"REQ(:METHOD)":                           {`\S+`, "method"},
+	"REQ(:PATH)":                             {`\S*`, "url"},
+	"REQ(X-ENVOY-ORIGINAL-PATH?:PATH)":       {`\S*`, "url"},
+	"PROTOCOL":                               {`\S+`, "protocol"},
+	"RESPONSE_CODE":                          {`\S+`, "status"},
+	"RESPONSE_FLAGS":                         {`\S+`, "response_flags"},
+	"BYTES_RECEIVED":                         {`\S+`, "received_bytes"},
+	"BYTES_SENT":                             {`\S+`, "bytes"},
+	"DURATION":                               {`\S+`, "duration_ms"},
+	"RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)":    {`\S+`, "upstream_service_time"},
+	"REQ(X-FORWARDED-FOR)":                   {`.*?`, "x_forwarded_for"},
+	"REQ(USER-AGENT)":                        {`.*?`, "user_agent"},
+	"REQ(REFERER)":                           {`.*?`, "referer"},
+	"REQ(X-REQUEST-ID)":                      {`\S*`, "request_id"},
+	"REQ(:AUTHORITY)":                        {`\S*`, "authority"},
+	"UPSTREAM_HOST":                          {`.*?`, "upstream"},
+	"UPSTREAM_CLUSTER":                       {`\S+`, "upstream_cluster"},
+	"DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT": {`\S+`, "remote_host"},
+	"RESP(CONTENT-TYPE)":                     {`.*?`, "content_type"},
+	"RESP(CONTENT-ENCODING)":                 {`.*?`, "content_encoding"},
+	"DOWNSTREAM_TLS_VERSION":                 {`\S+`, "ssl_protocol"},
+	"DOWNSTREAM_TLS_CIPHER":                  {`\S+`, "ssl_cipher"},
+}
+
+// envoyOperator : A command operator of a format string, e.g. %PROTOCOL%,
+// %REQ(USER-AGENT)% or %REQ(USER-AGENT):64%, truncated to 64 characters
+var envoyOperator = regexp.MustCompile(`%([A-Z_]+)(?:\(([^)]*)\))?(?::\d+)?%`)
+
+// nonWord : Runs of characters not allowed in group names
+var nonWord = regexp.MustCompile(`\W+`)
+
+// Envoy : Compiles an Envoy access log format string, e.g.
+// EnvoyDefaultLogFormat, into a format mapping each command operator to a line
+// field. The client is the downstream remote address, or the first address of
+// X-Forwarded-For when the format logs no downstream address, as the default
+// does. The response flags (e.g. UH or UF), upstream host and cluster are
+// kept, and operators with no line field are captured into the line's extras.
+// %START_TIME% is parsed in its default format only.
+func Envoy(logFormat string) (*Format, error) {
+	logFormat = strings.TrimSuffix(logFormat, `\n`)
+	logsDownstream := strings.Contains(logFormat, "%DOWNSTREAM_REMOTE_ADDRESS")
+
+	var buffer strings.Builder
+	buffer.WriteString("^")
+	mapped := 0
+	last := 0
+	for _, m := range envoyOperator.FindAllStringSubmatchIndex(logFormat, -1) {
+		buffer.WriteString(regexp.QuoteMeta(logFormat[last:m[0]]))
+		last = m[1]
+		operator, argument := logFormat[m[2]:m[3]], ""
+		key := operator
+		if m[4] >= 0 {
+			argument = logFormat[m[4]:m[5]]
+			key += "(" + strings.ToUpper(argument) + ")"
+		}
+
+		d, ok := envoyOperators[key]
+		switch {
+		case key == "DOWNSTREAM_REMOTE_ADDRESS":
+			// the port is left out, e.g. of 10.0.0.1:52346
+			buffer.WriteString(`(?P<remote_host>\S+?)(?::\d+)?`)
+			mapped++
+			continue
+		case key == "REQ(X-FORWARDED-FOR)" && !logsDownstream:
+			// the client first, then the proxies
+			buffer.WriteString(`(?P<x_forwarded_for>(?P<remote_host>[^\s,"]*)[^"]*)`)
+			mapped++
+			continue
+		case key == "START_TIME" && argument != "":
+			d = directive{`.*?`, "start_time"}
+		case !ok:
+			group := strings.Trim(nonWord.ReplaceAllString(strings.ToLower(operator+"_"+argument), "_"), "_")
+			d = directive{`.*?`, group}
+		default:
+			mapped++
+		}
+		buffer.WriteString("(?P<" + d.group + ">" + d.pattern + ")")
+	}
+	buffer.WriteString(regexp.QuoteMeta(logFormat[last:]))
+	buffer.WriteString("$")
+
+	if mapped == 0 {
+		return nil, errors.Wrap(errors.New(ErrInvalidLogFormat), "no command operator maps to a line field")
+	}
+	lineRegex, err := regexp.Compile(buffer.String())
+	if err != nil {
+		return nil, errors.Wrap(errors.New(ErrInvalidLogFormat), err.Error())
+	}
+	return &Format{Name: "envoy", LineRegex: lineRegex}, nil
+}
+
+// EnvoyDefault : Envoy's default access log format
+var EnvoyDefault = func() *Format {
+	format, err := Envoy(EnvoyDefaultLogFormat)
+	if err != nil {
+		panic(err)
+	}
+	format.Example = `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`
+	return format
+}()
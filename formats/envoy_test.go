@@ -0,0 +1,64 @@
+package formats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvoy(t *testing.T) {
+	tests := []struct {
+		name      string
+		logFormat string
+		line      string
+		want      map[string]string
+	}{
+		{
+			name: "downstream address and custom header",
+			logFormat: `%START_TIME% %DOWNSTREAM_REMOTE_ADDRESS% "%REQ(:METHOD)% %REQ(:PATH)% %PROTOCOL%" %RESPONSE_CODE% ` +
+				`%RESPONSE_FLAGS% %BYTES_SENT% %DURATION% "%REQ(X-FORWARDED-FOR)%" "%REQ(X-Tenant):16%" %UPSTREAM_CLUSTER%\n`,
+			line: `2021-03-01T10:00:00.000Z 10.0.0.5:52346 "GET /cart?id=7 HTTP/1.1" 200 - 512 12 "203.0.113.9" "shop" outbound|80||cart`,
+			want: map[string]string{
+				"time":             "2021-03-01T10:00:00.000Z",
+				"remote_host":      "10.0.0.5",
+				"method":           "GET",
+				"url":              "/cart?id=7",
+				"protocol":         "HTTP/1.1",
+				"status":           "200",
+				"response_flags":   "-",
+				"bytes":            "512",
+				"duration_ms":      "12",
+				"x_forwarded_for":  "203.0.113.9",
+				"req_x_tenant":     "shop",
+				"upstream_cluster": "outbound|80||cart",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Envoy(tt.logFormat)
+			if err != nil {
+				t.Fatalf("Envoy() error = %v", err)
+			}
+			result := format.LineRegex.FindStringSubmatch(tt.line)
+			if result == nil {
+				t.Fatalf("Envoy() regex %s does not match %q", format.LineRegex, tt.line)
+			}
+			got := map[string]string{}
+			for i, name := range format.LineRegex.SubexpNames() {
+				if name != "" {
+					got[name] = result[i]
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Envoy() groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvoy_errors(t *testing.T) {
+	_, err := Envoy(`%REQ(X-TENANT)% %ROUTE_NAME%`)
+	if want := "no command operator maps to a line field: " + ErrInvalidLogFormat; err == nil || err.Error() != want {
+		t.Errorf("Envoy() error = %v, wantErr %v", err, want)
+	}
+}
@@ -0,0 +1,21 @@
+package formats
+
+import "regexp"
+
+// Fastly : Fastly real-time log streaming in the combined log format,
+// followed by the cache state, the datacenter and the elapsed milliseconds:
+// %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"
+// %{fastly_info.state}V %{server.datacenter}V %{time.elapsed.msec}V. The cache
+// state, e.g. HIT, MISS, PASS or HIT-STALE, is the cache_status extra, for
+// cache reports; the datacenter, e.g. LHR, is kept as an extra too.
+var Fastly = func() *Format {
+	buffer := requestPrefix()
+	buffer.WriteString(`(\S+)\s`)                  // 8) bytes
+	buffer.WriteString(`"((?:[^"]*(?:\\")?)*)"\s`) // 9) referrer
+	buffer.WriteString(`"(.*)"\s`)                 // 10) user agent
+	buffer.WriteString(`(?P<cache_status>\S+)\s`)  // fastly_info.state
+	buffer.WriteString(`(?P<datacenter>\S+)\s`)    // server.datacenter
+	buffer.WriteString(`(?P<duration_ms>\d+)$`)    // time.elapsed.msec
+	return &Format{Name: "fastly", LineRegex: regexp.MustCompile(buffer.String()),
+		Example: `203.0.113.7 - - [11/Oct/2023:14:32:52 +0000] "GET /static/app.js HTTP/1.1" 200 48213 "https://www.example.com/" "Mozilla/5.0 (X11; Linux x86_64)" HIT LHR 1`}
+}()
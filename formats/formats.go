@@ -0,0 +1,108 @@
+// Package formats provides ready-made log formats, so their line regex need
+// not be hand-built.
+package formats
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format : A log format, as a line regex capturing the ten positional groups
+// the analyzer reads: 1) remote host, 2) time, 3) method, 4) URL, 5)
+// protocol, 6) URL of a request with no protocol, 7) status, 8) bytes, 9)
+// referrer and 10) user agent, as unnamed groups, along with optional named
+// groups, e.g. the remote user. Groups a format does not log are captured
+// empty.
+type Format struct {
+	Name      string
+	LineRegex *regexp.Regexp
+	// Example : A line of the format, for documentation, set for the presets
+	Example string
+	// Tokens : The literal text and fields the line regex was compiled from,
+	// for formats compiled by Apache or Nginx, e.g. to generate a parser of
+	// the format with cmd/parsergen. nil for the other formats.
+	Tokens []Token
+}
+
+// Presets : The ready-made formats, which config files select by name
+var Presets = []*Format{CommonLog, CombinedLog, ALB, ClassicELB, S3, HAProxy, EnvoyDefault, Traefik, Squid, Varnish, Fastly, VHostCombined}
+
+// Token : A part of a compiled log format, either literal text or a field
+type Token struct {
+	// Literal : Text lines repeat as is, for literal tokens
+	Literal string
+	// Pattern : What the field matches, as a regex, for field tokens
+	Pattern string
+	// Group : The named group the field is captured into, empty for fields
+	// matched and skipped
+	Group string
+}
+
+// compileTokens : The format of the tokens, its line regex matching them in
+// order
+func compileTokens(name string, tokens []Token) (*Format, error) {
+	var buffer strings.Builder
+	buffer.WriteString("^")
+	for _, token := range tokens {
+		switch {
+		case token.Pattern == "":
+			buffer.WriteString(regexp.QuoteMeta(token.Literal))
+		case token.Group == "":
+			buffer.WriteString("(?:" + token.Pattern + ")")
+		default:
+			buffer.WriteString("(?P<" + token.Group + ">" + token.Pattern + ")")
+		}
+	}
+	buffer.WriteString("$")
+	lineRegex, err := regexp.Compile(buffer.String())
+	if err != nil {
+		return nil, errors.Wrap(errors.New(ErrInvalidLogFormat), err.Error())
+	}
+	return &Format{Name: name, LineRegex: lineRegex, Tokens: tokens}, nil
+}
+
+// literal : The token of the text, nil when empty
+func literal(text string) []Token {
+	if text == "" {
+		return nil
+	}
+	return []Token{{Literal: text}}
+}
+
+// requestPrefix : The groups shared by the NCSA formats, up to the bytes
+func requestPrefix() *bytes.Buffer {
+	var buffer bytes.Buffer
+	buffer.WriteString(`^(\S+)\s`)                           // 1) IP
+	buffer.WriteString(`\S+\s+`)                             // remote logname
+	buffer.WriteString(`(?P<remote_user>\S+(?:\s+\S+)*)\s+`) // remote user
+	buffer.WriteString(`\[([^]]+)\]\s`)                      // 2) date
+	buffer.WriteString(`"(\S*)\s?`)                          // 3) method
+	buffer.WriteString(`(?:((?:[^"]*(?:\\")?)*)\s`)          // 4) URL
+	buffer.WriteString(`([^"]*)"\s|`)                        // 5) protocol
+	buffer.WriteString(`((?:[^"]*(?:\\")?)*)"\s)`)           // 6) or, possibly URL with no protocol
+	buffer.WriteString(`(\S+)\s`)                            // 7) status code
+	return &buffer
+}
+
+// CommonLog : NCSA Common Log Format, e.g. Apache's "%h %l %u %t \"%r\" %>s %b"
+var CommonLog = func() *Format {
+	buffer := requestPrefix()
+	buffer.WriteString(`(\S+)`) // 8) bytes
+	buffer.WriteString(`()()$`) // 9) referrer and 10) user agent, not logged
+	return &Format{Name: "common", LineRegex: regexp.MustCompile(buffer.String()),
+		Example: `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574`}
+}()
+
+// CombinedLog : NCSA Combined Log Format, the Common Log Format followed by
+// the referrer and user agent, as nginx logs by default
+var CombinedLog = func() *Format {
+	buffer := requestPrefix()
+	buffer.WriteString(`(\S+)\s`)                  // 8) bytes
+	buffer.WriteString(`"((?:[^"]*(?:\\")?)*)"\s`) // 9) referrer
+	buffer.WriteString(`"(.*)"$`)                  // 10) user agent
+	return &Format{Name: "combined", LineRegex: regexp.MustCompile(buffer.String()),
+		Example: `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574 "-" "Mozilla/5.0 (X11; Linux x86_64)"`}
+}()
@@ -0,0 +1,65 @@
+package formats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		format *Format
+		line   string
+		want   []string
+	}{
+		{
+			name:   "common",
+			format: CommonLog,
+			line:   `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			want:   []string{"127.0.0.1", "frank", "10/Oct/2000:13:55:36 -0700", "GET", "/apache_pb.gif", "HTTP/1.0", "", "200", "2326", "", ""},
+		},
+		{
+			name:   "common, no bytes",
+			format: CommonLog,
+			line:   `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "HEAD / HTTP/1.0" 304 -`,
+			want:   []string{"127.0.0.1", "-", "10/Oct/2000:13:55:36 -0700", "HEAD", "/", "HTTP/1.0", "", "304", "-", "", ""},
+		},
+		{
+			name:   "combined",
+			format: CombinedLog,
+			line:   `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+			want:   []string{"127.0.0.1", "frank", "10/Oct/2000:13:55:36 -0700", "GET", "/apache_pb.gif", "HTTP/1.0", "", "200", "2326", "http://www.example.com/start.html", "Mozilla/4.08"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.format.LineRegex.FindStringSubmatch(tt.line)
+			if result == nil {
+				t.Fatalf("%s does not match %q", tt.format.Name, tt.line)
+			}
+			if got := result[1:]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s groups = %q, want %q", tt.format.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonLog_rejectsCombined(t *testing.T) {
+	line := `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 2326 "-" "curl/7.64.0"`
+	if CommonLog.LineRegex.MatchString(line) {
+		t.Errorf("common matches the combined line %q", line)
+	}
+}
+
+func TestPresets_examples(t *testing.T) {
+	names := make(map[string]bool)
+	for _, format := range Presets {
+		if names[format.Name] {
+			t.Errorf("preset %s listed twice", format.Name)
+		}
+		names[format.Name] = true
+		if format.Example == "" || !format.LineRegex.MatchString(format.Example) {
+			t.Errorf("%s example %q does not match its line regex", format.Name, format.Example)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package formats
+
+import "regexp"
+
+// HAProxy : HAProxy's HTTP log format (option httplog), with or without the
+// syslog prefix. Of the Tq/Tw/Tc/Tr/Ta timers, in milliseconds and -1 when a
+// step was not reached, the total active time (Tt before HAProxy 1.7) is the
+// duration; the others are kept as extras, along with the frontend and
+// backend names, the termination state, the connection counts and queues,
+// and the captured headers. The server is the upstream, <NOSRV> when the
+// request reached none. Accept dates carry no time zone and are read as UTC.
+var HAProxy = &Format{
+	Name: "haproxy",
+	LineRegex: regexp.MustCompile(`^(?:.*?haproxy\[\d+\]: )?(?P<remote_host>\S+):\d+ \[(?P<time>[^\]]+)\] ` +
+		`(?P<frontend>\S+) (?P<backend>[^/\s]+)/(?P<upstream>\S+) ` +
+		`(?P<tq>-?\d+)/(?P<tw>-?\d+)/(?P<tc>-?\d+)/(?P<tr>-?\d+)/(?P<duration_ms>\+?-?\d+) ` +
+		`(?P<status>-?\d+) (?P<bytes>\+?\d+) (?P<request_cookie>\S+) (?P<response_cookie>\S+) (?P<termination_state>\S+) ` +
+		`(?P<actconn>\d+)/(?P<feconn>\d+)/(?P<beconn>\d+)/(?P<srv_conn>\d+)/(?P<retries>\+?\d+) (?P<srv_queue>\d+)/(?P<backend_queue>\d+) ` +
+		`(?:\{(?P<request_headers>[^}]*)\} )?(?:\{(?P<response_headers>[^}]*)\} )?"(?P<request>[^"]*)"?$`),
+	Example: `Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+}
@@ -0,0 +1,91 @@
+package formats
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// nginxVariables : nginx variables captured as line fields, by name. Other
+// variables are captured under their own name, into the line's extras.
+var nginxVariables = map[string]directive{
+	"remote_addr":                {`\S+`, "remote_host"},
+	"remote_user":                {`\S+`, "remote_user"},
+	"time_local":                 {`\S+ [+-]\d{4}`, "time"},
+	"request":                    {`.*?`, "request"},
+	"request_method":             {`\S+`, "method"},
+	"request_uri":                {`\S*`, "url"},
+	"uri":                        {`\S*`, "url"},
+	"server_protocol":            {`\S+`, "protocol"},
+	"server_name":                {`\S+`, "vhost"},
+	"status":                     {`\S+`, "status"},
+	"body_bytes_sent":            {`\S+`, "bytes"},
+	"http_referer":               {`.*?`, "referer"},
+	"http_user_agent":            {`.*?`, "user_agent"},
+	"http_x_forwarded_for":       {`.*?`, "x_forwarded_for"},
+	"upstream_addr":              {`.*?`, "upstream"},
+	"request_time":               {`\S+`, "duration"},
+	"connection":                 {`\S+`, "connection"},
+	"connection_requests":        {`\S+`, "connection_requests"},
+	"sent_http_content_type":     {`.*?`, "content_type"},
+	"sent_http_content_encoding": {`.*?`, "content_encoding"},
+	"gzip_ratio":                 {`\S+`, "gzip_ratio"},
+	"ssl_protocol":               {`\S+`, "ssl_protocol"},
+	"ssl_cipher":                 {`\S+`, "ssl_cipher"},
+}
+
+// nginxVariable : A variable of a log format, e.g. $status or ${status}
+var nginxVariable = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// logFormatDirective : A whole log_format directive of an nginx config, its
+// name, optional escape parameter and format strings
+var logFormatDirective = regexp.MustCompile(`^\s*log_format\s+(\S+)\s+(?:escape=\S+\s+)?((?:(?:'[^']*'|"[^"]*")\s*)+);?\s*$`)
+
+// formatStrings : The quoted strings of a log_format directive
+var formatStrings = regexp.MustCompile(`'([^']*)'|"([^"]*)"`)
+
+// Nginx : Compiles an nginx log_format string, e.g.
+// `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`,
+// or a whole log_format directive pasted from an nginx config, into a format
+// mapping each variable to a line field. Variables with no line field, e.g.
+// custom ones, are captured into the line's extras under their name.
+func Nginx(logFormat string) (*Format, error) {
+	name := "nginx"
+	if m := logFormatDirective.FindStringSubmatch(logFormat); m != nil {
+		name = m[1]
+		var parts []string
+		for _, s := range formatStrings.FindAllStringSubmatch(m[2], -1) {
+			parts = append(parts, s[1]+s[2])
+		}
+		logFormat = strings.Join(parts, "")
+	}
+
+	var tokens []Token
+	mapped := 0
+	last := 0
+	for _, m := range nginxVariable.FindAllStringSubmatchIndex(logFormat, -1) {
+		tokens = append(tokens, literal(logFormat[last:m[0]])...)
+		last = m[1]
+		variable := ""
+		if m[2] >= 0 {
+			variable = logFormat[m[2]:m[3]]
+		} else {
+			variable = logFormat[m[4]:m[5]]
+		}
+
+		d, ok := nginxVariables[variable]
+		if !ok {
+			d = directive{`.*?`, variable}
+		} else {
+			mapped++
+		}
+		tokens = append(tokens, Token{Pattern: d.pattern, Group: d.group})
+	}
+	tokens = append(tokens, literal(logFormat[last:])...)
+
+	if mapped == 0 {
+		return nil, errors.Wrap(errors.New(ErrInvalidLogFormat), "no variable maps to a line field")
+	}
+	return compileTokens(name, tokens)
+}
@@ -0,0 +1,82 @@
+package formats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNginx(t *testing.T) {
+	tests := []struct {
+		name      string
+		logFormat string
+		line      string
+		wantName  string
+		want      map[string]string
+	}{
+		{
+			name:      "combined",
+			logFormat: `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+			line:      `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574 "-" "Mozilla/5.0 (X11)"`,
+			wantName:  "nginx",
+			want: map[string]string{
+				"remote_host": "177.71.128.21",
+				"remote_user": "-",
+				"time":        "10/Jul/2018:22:21:28 +0200",
+				"request":     "GET /intranet-analytics/ HTTP/1.1",
+				"status":      "200",
+				"bytes":       "3574",
+				"referer":     "-",
+				"user_agent":  "Mozilla/5.0 (X11)",
+			},
+		},
+		{
+			name: "pasted directive with custom variables",
+			logFormat: `log_format upstreams '$remote_addr [$time_local] "$request" $status $body_bytes_sent '
+                     'rt=$request_time ua="${upstream_addr}" cache=$upstream_cache_status rid=$request_id';`,
+			line:     `10.0.0.1 [10/Jul/2018:22:21:28 +0200] "GET / HTTP/1.1" 200 120 rt=0.010 ua="10.0.0.1:80, 10.0.0.2:80" cache=HIT rid=5f2c`,
+			wantName: "upstreams",
+			want: map[string]string{
+				"remote_host":           "10.0.0.1",
+				"time":                  "10/Jul/2018:22:21:28 +0200",
+				"request":               "GET / HTTP/1.1",
+				"status":                "200",
+				"bytes":                 "120",
+				"duration":              "0.010",
+				"upstream":              "10.0.0.1:80, 10.0.0.2:80",
+				"upstream_cache_status": "HIT",
+				"request_id":            "5f2c",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Nginx(tt.logFormat)
+			if err != nil {
+				t.Fatalf("Nginx() error = %v", err)
+			}
+			if format.Name != tt.wantName {
+				t.Errorf("Nginx() name = %q, want %q", format.Name, tt.wantName)
+			}
+			result := format.LineRegex.FindStringSubmatch(tt.line)
+			if result == nil {
+				t.Fatalf("Nginx() regex %s does not match %q", format.LineRegex, tt.line)
+			}
+			got := map[string]string{}
+			for i, name := range format.LineRegex.SubexpNames() {
+				if name != "" {
+					got[name] = result[i]
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Nginx() groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNginx_errors(t *testing.T) {
+	_, err := Nginx(`$host $request_id`)
+	if want := "no variable maps to a line field: " + ErrInvalidLogFormat; err == nil || err.Error() != want {
+		t.Errorf("Nginx() error = %v, wantErr %v", err, want)
+	}
+}
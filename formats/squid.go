@@ -0,0 +1,19 @@
+package formats
+
+import "regexp"
+
+// Squid : Squid's native access.log format, "%ts.%03tu %6tr %>a %Ss/%03>Hs
+// %<st %rm %ru %[un %Sh/%<a %mt": the Unix time, the elapsed milliseconds
+// (right-aligned), the client, the cache result code and status, the bytes,
+// the method, the URL, the user name, the hierarchy code and peer, and the
+// content type. The result code, e.g. TCP_MEM_HIT or TCP_MISS, is the
+// cache_status extra, for cache reports; the peer is the upstream, "-" when
+// none was contacted, as for hits. The hierarchy code and user name are kept
+// as extras.
+var Squid = &Format{
+	Name: "squid",
+	LineRegex: regexp.MustCompile(`^\s*(?P<time>\d+\.\d+)\s+(?P<duration_ms>\d+) (?P<remote_host>\S+) ` +
+		`(?P<cache_status>[A-Z_]+)/(?P<status>\d{3}) (?P<bytes>\d+) (?P<method>\S+) (?P<url>\S+) ` +
+		`(?P<remote_user>\S+) (?P<hierarchy>[A-Z_]+)/(?P<upstream>\S+) (?P<content_type>\S+)$`),
+	Example: `1697034772.123    218 192.168.0.68 TCP_MISS/200 18734 GET http://example.com/index.html - HIER_DIRECT/93.184.216.34 text/html`,
+}
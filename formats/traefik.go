@@ -0,0 +1,17 @@
+package formats
+
+import "regexp"
+
+// Traefik : Traefik's access log text format, the combined log format
+// followed by the number of requests since Traefik started, the router name,
+// the server URL and the duration in milliseconds. The router is the
+// upstream, so that backend reports are per route; the server URL and request
+// count are kept as extras, as are the client's user name. Requests matching
+// no router log "-" for both.
+var Traefik = &Format{
+	Name: "traefik",
+	LineRegex: regexp.MustCompile(`^(?P<remote_host>\S+) \S+ (?P<remote_user>\S+) \[(?P<time>[^\]]+)\] ` +
+		`"(?P<request>[^"]*)" (?P<status>\S+) (?P<bytes>\S+) "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)" ` +
+		`(?P<request_count>\d+) "(?P<upstream>[^"]*)" "(?P<service_url>[^"]*)" (?P<duration_ms>\d+)ms$`),
+	Example: `192.168.1.10 - - [10/Oct/2023:13:55:36 +0000] "GET /whoami HTTP/1.1" 200 412 "-" "curl/8.1.2" 1 "whoami@docker" "http://172.17.0.3:80" 3ms`,
+}
@@ -0,0 +1,19 @@
+package formats
+
+import "regexp"
+
+// Varnish : varnishncsa's default output, the combined log format, followed
+// by %{Varnish:hitmiss}x, e.g. varnishncsa -F '%h %l %u %t "%r" %s %b
+// "%{Referer}i" "%{User-agent}i" %{Varnish:hitmiss}x'. The hit or miss is the
+// cache_status extra, for cache reports; %{Varnish:handling}x (hit, miss,
+// pass, pipe or synth) can be logged in its place. Requests Varnish did not
+// look up, e.g. piped ones, log "-".
+var Varnish = func() *Format {
+	buffer := requestPrefix()
+	buffer.WriteString(`(\S+)\s`)                  // 8) bytes
+	buffer.WriteString(`"((?:[^"]*(?:\\")?)*)"\s`) // 9) referrer
+	buffer.WriteString(`"(.*)"\s`)                 // 10) user agent
+	buffer.WriteString(`(?P<cache_status>\S+)$`)   // hit or miss
+	return &Format{Name: "varnish", LineRegex: regexp.MustCompile(buffer.String()),
+		Example: `192.168.1.20 - - [11/Oct/2023:14:32:52 +0000] "GET http://www.example.com/ HTTP/1.1" 200 6340 "-" "Mozilla/5.0 (X11; Linux x86_64)" miss`}
+}()
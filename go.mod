@@ -1,5 +1,11 @@
 module github.com/sdileep/http-log-parser
 
-go 1.13
+go 1.16
 
-require github.com/pkg/errors v0.8.1
+require (
+	github.com/pkg/errors v0.9.1
+	go.starlark.net v0.0.0-20210223155950-e043a3d3c984
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
+	google.golang.org/grpc v1.56.3
+)
@@ -1,46 +1,693 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sdileep/http-log-parser/analyzer"
+	"github.com/sdileep/http-log-parser/baseline"
+	"github.com/sdileep/http-log-parser/display"
+	"github.com/sdileep/http-log-parser/formats"
+	"github.com/sdileep/http-log-parser/server"
+	"github.com/sdileep/http-log-parser/verify"
 )
 
 func main() {
-	var buffer bytes.Buffer
-	buffer.WriteString(`^(\S+)\s`)                  // 1) IP
-	buffer.WriteString(`\S+\s+`)                    // remote logname
-	buffer.WriteString(`(?:\S+\s+)+`)               // remote user
-	buffer.WriteString(`\[([^]]+)\]\s`)             // 2) date
-	buffer.WriteString(`"(\S*)\s?`)                 // 3) method
-	buffer.WriteString(`(?:((?:[^"]*(?:\\")?)*)\s`) // 4) URL
-	buffer.WriteString(`([^"]*)"\s|`)               // 5) protocol
-	buffer.WriteString(`((?:[^"]*(?:\\")?)*)"\s)`)  // 6) or, possibly URL with no protocol
-	buffer.WriteString(`(\S+)\s`)                   // 7) status code
-	buffer.WriteString(`(\S+)\s`)                   // 8) bytes
-	buffer.WriteString(`"((?:[^"]*(?:\\")?)*)"\s`)  // 9) referrer
-	buffer.WriteString(`"(.*)"$`)                   // 10) user agent
-
-	lineRegex, err := regexp.Compile(buffer.String())
-	if err != nil {
-		log.Fatalf("regexp: %s", err)
-	}
-
-	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{
-		LineRegex:            lineRegex,
-		MostActiveIPsCount:   4,
-		MostVisitedURLsCount: 3,
+	configPath := flag.String("config", "", "JSON config file; in follow mode, its top-N settings are reloaded on SIGHUP")
+	manifestPath := flag.String("manifest", "", "file listing the log files to analyze as a resumable batch, one per line")
+	statePath := flag.String("state", "", "batch completion state file, an interrupted batch resumes from it")
+	parallelism := flag.Int("parallelism", 0, "maximum number of batch files analyzed at once, 1 by default, or 4 × the CPUs with -autoscale")
+	autoscale := flag.Bool("autoscale", false, "tune the number of batch files analyzed at once on the measured throughput, up to -parallelism")
+	follow := flag.Bool("follow", false, "keep analyzing lines appended to the log file, printing snapshots (also on SIGUSR1)")
+	followState := flag.String("follow-state", "", "in follow mode, file the analyzer state is loaded from at start and saved to when following stops, to migrate it between hosts")
+	dryRun := flag.Bool("dry-run", false, "only estimate the lines, memory and runtime a full analysis of the log file would take")
+	errorLog := flag.Bool("error-log", false, "the log files are Apache or nginx error logs; report their levels, most common messages and errors over time")
+	sampleLines := flag.Int("sample-lines", analyzer.DefaultEstimateSampleLines, "lines sampled by a dry run")
+	httpAddr := flag.String("http-addr", "", "in follow mode, address to serve /healthz, /metrics and the latest /analytics on, e.g. :8080")
+	locale := flag.String("locale", "en", "locale of the labels (en, de or fr) and of the decimal and thousands separators in the printed report")
+	units := flag.String("units", "iec", "multiples of the sizes in the printed report, iec (KiB) or si (kB)")
+	precision := flag.Int("precision", display.DefaultPrecision, "decimals of the values in the printed report, negative for none")
+	baselineDir := flag.String("baseline-dir", "", "directory of daily snapshots; a run is compared against the trailing days, then saved as today's")
+	baselineDays := flag.Int("baseline-days", baseline.DefaultDays, "trailing days the run is compared against")
+	baselineRetention := flag.Int("baseline-retention", 0, "days of snapshots kept in the baseline directory, all when 0")
+	baselineThreshold := flag.Float64("baseline-threshold", baseline.DefaultThreshold, "relative change from the baseline flagged as a deviation, e.g. 0.5 for 50%")
+	shardDir := flag.String("shard-dir", "", "run as a shard worker, serving the gRPC shard service on -http-addr for a coordinator, reading log files from this directory")
+	shardWorkers := flag.String("shard-workers", "", "comma separated base URLs of shard workers, e.g. http://10.0.0.2:8080; the log files are split among them and their counts merged")
+	shardTimeout := flag.Duration("shard-timeout", server.DefaultShardTimeout, "how long a shard worker may take to answer before the run fails")
+	previous := flag.String("previous", "", "comma separated log files of a previous period; the URLs and IPs whose traffic moved the most since are reported")
+	snapshotInterval := flag.Duration("snapshot-interval", analyzer.DefaultSnapshotInterval, "how often snapshots are printed in follow mode")
+	seenIPsPath := flag.String("seen-ips", "", "Bloom filter file of the client IPs seen by previous runs; the most active IPs not in it are reported as first-time visitors, and the IPs of the run are added to it")
+	seenIPsCapacity := flag.Int("seen-ips-capacity", analyzer.DefaultSeenIPsCapacity, "IPs a new -seen-ips filter is sized for, at 1.2 bytes each")
+	decayHalfLife := flag.Duration("decay-half-life", 0, "in follow mode, rank the most active IPs and most visited URLs by hits halving in weight every half-life, e.g. 5m, rather than all-time totals")
+	pprofAddr := flag.String("pprof-addr", "", "address to serve the runtime profiles on while analyzing, under /debug/pprof/, e.g. localhost:6060")
+	readAhead := flag.Int("read-ahead", 0, "bytes of the log files read ahead of parsing, e.g. 4194304 on network filesystems or spinning disks, none when 0")
+	warnings := flag.Bool("warnings", false, "log the lines skipped as malformed or too long, those whose time did not parse, and URLs counted as (other) past maxURLs")
+	verifyPath := flag.String("verify", "", "expected analytics JSON file the analysis is compared against, within the tolerances of the config's verify; differences exit with status 1")
+	verifyUpdate := flag.Bool("verify-update", false, "write the analysis to the -verify file as the expected analytics, instead of comparing against it")
+	profileDir := flag.String("profile", "", "directory the CPU profile of the run (cpu.out) and its heap profile at the end (mem.out) are written to")
+	flag.Parse()
+
+	lineRegex := formats.CombinedLog.LineRegex
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sizeUnits, err := display.ParseUnits(*units)
+	if err != nil {
+		log.Fatal(err)
+	}
+	formatter, err := display.New(&display.Options{Locale: *locale, Units: sizeUnits, Precision: *precision})
+	if err != nil {
+		log.Fatal(err)
+	}
+	analyzerConfig, err := config.analyzerConfig(lineRegex)
+	if err != nil {
+		log.Fatal(err)
+	}
+	analyzerConfig.SnapshotInterval = *snapshotInterval
+	analyzerConfig.DecayHalfLife = *decayHalfLife
+	if *readAhead > 0 {
+		analyzerConfig.ReadAheadSize = *readAhead
+	}
+	if *seenIPsPath != "" {
+		if analyzerConfig.SeenIPs, err = loadSeenIPs(*seenIPsPath, *seenIPsCapacity); err != nil {
+			log.Fatal(err)
+		}
+		// runs ending in an error leave the filter as it was
+		defer func() {
+			if err := saveSeenIPs(analyzerConfig.SeenIPs, *seenIPsPath); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	analyzerConfig.SnapshotSignals = snapshotSignals
+	if *warnings {
+		analyzerConfig.OnWarning = func(w analyzer.Warning) {
+			log.Printf("warning: %s", w)
+		}
+	}
+	logAnalyzer, err := analyzer.NewLogAnalyzer(analyzerConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sinks, err := config.sinks()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *pprofAddr != "" {
+		go func() {
+			if err := config.serve(&http.Server{Addr: *pprofAddr, Handler: pprofHandler()}); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	if *profileDir != "" {
+		stopProfiles, err := startProfiles(*profileDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer stopProfiles()
+	}
+
+	if *manifestPath != "" {
+		analytics, err := logAnalyzer.RunBatch(&analyzer.BatchConfig{
+			ManifestPath: *manifestPath,
+			StatePath:    *statePath,
+			Parallelism:  *parallelism,
+			Autoscale:    *autoscale,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		printAnalytics(formatter, analytics)
+		writeSinks(sinks, analytics)
+		flushSinks(sinks)
+		if *baselineDir != "" {
+			compareBaseline(formatter, &baseline.Store{Dir: *baselineDir}, *baselineDays, *baselineThreshold, *baselineRetention, analytics)
+		}
+		return
+	}
+
+	if *shardDir != "" {
+		if *httpAddr == "" {
+			log.Fatal("a shard worker needs -http-addr")
+		}
+		s := server.New(logAnalyzer)
+		s.ServeShards(*shardDir)
+		log.Fatal(config.serve(&http.Server{Addr: *httpAddr, Handler: s}))
+	}
+
+	filePaths := flag.Args()
+	if *shardWorkers != "" {
+		coordinator, err := config.coordinator(strings.Split(*shardWorkers, ","))
+		if err != nil {
+			log.Fatal(err)
+		}
+		coordinator.Timeout = *shardTimeout
+		analytics, err := coordinator.Run(logAnalyzer, filePaths)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printAnalytics(formatter, analytics)
+		writeSinks(sinks, analytics)
+		flushSinks(sinks)
+		return
+	}
+	if len(filePaths) == 0 {
+		filePaths = []string{"./analyzer/test-data/programming-task.log"}
+	}
+
+	if *dryRun {
+		for _, filePath := range filePaths {
+			estimate, err := logAnalyzer.EstimateCost(filePath, *sampleLines)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s\n", filePath)
+			fmt.Printf("sampled lines: %d (complete: %t)\n", estimate.SampledLines, estimate.Complete)
+			fmt.Printf("estimated lines: %d\n", estimate.EstimatedLines)
+			fmt.Printf("parse rate: %.0f lines/s, parse errors: %.1f%%\n", estimate.LinesPerSecond, estimate.ParseErrorRate*100)
+			fmt.Printf("estimated memory: %d bytes for %d distinct keys\n", estimate.EstimatedMemoryBytes, estimate.EstimatedKeys)
+			fmt.Printf("estimated runtime: %s\n", estimate.EstimatedRuntime)
+		}
+		return
+	}
+
+	if *errorLog {
+		analytics, err := logAnalyzer.AnalyzeErrorLog(filePaths...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printErrorLogAnalytics(formatter, analytics)
+		return
+	}
+
+	if *follow {
+		if len(filePaths) != 1 {
+			log.Fatal("follow mode takes a single log file")
+		}
+		// on SIGINT/SIGTERM, stop following and print the final report
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopCh := make(chan os.Signal, 1)
+		signal.Notify(stopCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-stopCh
+			cancel()
+		}()
+		if *configPath != "" {
+			go reloadConfig(*configPath, lineRegex, logAnalyzer)
+		}
+		var analyticsServer *server.Server
+		if *httpAddr != "" {
+			analyticsServer = server.New(logAnalyzer)
+			if analyzerConfig.LineIndex != nil {
+				analyticsServer.ServeSearch(analyzerConfig.LineIndex)
+			}
+			httpServer := &http.Server{Addr: *httpAddr, Handler: analyticsServer}
+			defer httpServer.Close()
+			go func() {
+				if err := config.serve(httpServer); err != nil && err != http.ErrServerClosed {
+					log.Fatal(err)
+				}
+			}()
+		}
+
+		if *followState != "" {
+			if err := loadState(logAnalyzer, *followState); err != nil {
+				log.Fatal(err)
+			}
+		}
+		snapshotCh, errCh := logAnalyzer.Follow(ctx, filePaths[0])
+		for analytics := range snapshotCh {
+			header := time.Now().Format(time.RFC3339)
+			if ctx.Err() != nil {
+				header = "final report"
+			}
+			fmt.Printf("\n%s\n", header)
+			printAnalytics(formatter, analytics)
+			writeSinks(sinks, analytics)
+			if analyticsServer != nil {
+				analyticsServer.Publish(filePaths[0], analytics)
+			}
+		}
+		flushSinks(sinks)
+		if *followState != "" {
+			if err := saveState(logAnalyzer, *followState); err != nil {
+				log.Print(err)
+			}
+		}
+		if err := <-errCh; err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *previous != "" {
+		comparison, err := logAnalyzer.ComparePeriods(strings.Split(*previous, ","), filePaths)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printAnalytics(formatter, comparison.Current)
+		printMovers(formatter, "URLs", comparison.URLs)
+		printMovers(formatter, "IPs", comparison.IPs)
+		return
+	}
+
+	analytics, err := logAnalyzer.AnalyzeFiles(filePaths...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if n := logAnalyzer.SelfMetrics().TimeParseErrors; n > 0 {
+		log.Printf("warning: the time of %d lines did not parse, set timeLayouts to their layout", n)
+	}
+
+	printAnalytics(formatter, analytics)
+	writeSinks(sinks, analytics)
+	flushSinks(sinks)
+	if *baselineDir != "" {
+		compareBaseline(formatter, &baseline.Store{Dir: *baselineDir}, *baselineDays, *baselineThreshold, *baselineRetention, analytics)
+	}
+	if len(filePaths) > 1 {
+		for _, filePath := range filePaths {
+			fmt.Printf("\n%s\n", filePath)
+			printAnalytics(formatter, analytics.Sources[filePath])
+		}
+	}
+	if *verifyPath != "" {
+		if !verifyAnalytics(*verifyPath, *verifyUpdate, config.verifyTolerances(), analytics) {
+			os.Exit(1)
+		}
+	}
+}
+
+func printAnalytics(f *display.Formatter, analytics *analyzer.LogAnalytics) {
+	fmt.Print(f.Sprintf("unique ips count: %s\n", f.Count(analytics.UniqueIPCount)))
+	for _, w := range analytics.UniqueIPWindows {
+		fmt.Print(f.Sprintf("unique ips in the last %s: %s\n", f.Duration(w.Window), f.Count(w.UniqueIPs)))
+	}
+	fmt.Print(f.Sprintf("most visited urls: %v\n", analytics.MostVisitedURLs))
+	fmt.Print(f.Sprintf("most active ips: %v\n", analytics.MostActiveIPs))
+	if analytics.Pageviews > 0 {
+		fmt.Print(f.Sprintf("pageviews: %s\n", f.Count(analytics.Pageviews)))
+	}
+	if len(analytics.FirstTimeIPs) > 0 {
+		fmt.Print(f.Sprintf("first-time ips: %v\n", analytics.FirstTimeIPs))
+	}
+	if len(analytics.TopReferrers) > 0 || analytics.SpamReferrals > 0 {
+		fmt.Print(f.Sprintf("top referrers: %v (spam referrals filtered: %s)\n", analytics.TopReferrers, f.Count(analytics.SpamReferrals)))
+	}
+	if len(analytics.TopCampaigns) > 0 {
+		fmt.Print(f.Sprintf("top campaigns: %q\n", analytics.TopCampaigns))
+	}
+	for _, upstream := range analytics.Upstreams {
+		fmt.Print(f.Sprintf("upstream %s: %s requests, %s errors", upstream.Upstream, f.Count(upstream.Requests), f.Percent(upstream.ErrorRate)))
+		if upstream.LatencyPercentiles != nil {
+			fmt.Print(f.Sprintf(", mean latency %s", f.Duration(upstream.MeanLatency)))
+			names := make([]string, 0, len(upstream.LatencyPercentiles))
+			for name := range upstream.LatencyPercentiles {
+				names = append(names, name)
+			}
+			for _, name := range sortPercentiles(names) {
+				fmt.Printf(", %s %s", name, f.Duration(upstream.LatencyPercentiles[name]))
+			}
+		}
+		fmt.Println()
+	}
+	for _, user := range analytics.TopUsers {
+		fmt.Print(f.Sprintf("user %s: %s requests, %s, %s errors\n", user.User, f.Count(user.Requests), f.Bytes(user.Bytes), f.Percent(user.ErrorRate)))
+	}
+	if k := analytics.Keepalive; k != nil {
+		fmt.Print(f.Sprintf("keepalive: %s requests on %s connections (%s per connection), %s on reused connections\n", f.Count(k.Requests), f.Count(k.Connections), f.Number(k.RequestsPerConnection), f.Percent(k.ReuseRate)))
+		if len(k.NonReusingClients) > 0 {
+			fmt.Print(f.Sprintf("clients never reusing connections: %v\n", k.NonReusingClients))
+		}
+	}
+	for _, c := range analytics.Compression {
+		fmt.Print(f.Sprintf("compression of %q: %s responses, %s compressed", c.ContentType, f.Count(c.Responses), f.Percent(c.CompressedShare)))
+		if c.Ratio > 0 {
+			fmt.Print(f.Sprintf(", ratio %s", f.Number(c.Ratio)))
+		}
+		fmt.Println()
+	}
+	if len(analytics.UncompressedURLs) > 0 {
+		fmt.Print(f.Sprintf("large uncompressed responses: %v\n", analytics.UncompressedURLs))
+	}
+	if c := analytics.Cache; c != nil {
+		fmt.Print(f.Sprintf("cache: %s requests, hit ratio %s, byte hit ratio %s\n", f.Count(c.Requests), f.Percent(c.HitRatio), f.Percent(c.ByteHitRatio)))
+	}
+	if len(analytics.RequestsByVHost) > 0 {
+		fmt.Print(f.Sprintf("requests by virtual host: %v\n", analytics.RequestsByVHost))
+	}
+	if len(analytics.TLSProtocols) > 0 {
+		fmt.Print(f.Sprintf("requests by TLS protocol: %v\n", analytics.TLSProtocols))
+	}
+	if len(analytics.TLSCiphers) > 0 {
+		fmt.Print(f.Sprintf("requests by TLS cipher: %v\n", analytics.TLSCiphers))
+	}
+	if len(analytics.Annotations) > 0 {
+		fmt.Print(f.Sprintf("events:\n"))
+		for _, a := range analytics.Annotations {
+			fmt.Printf("  %s %s\n", a.Time.Format(time.RFC3339), a.Label)
+		}
+	}
+	if len(analytics.SlowestRequests) > 0 {
+		fmt.Print(f.Sprintf("slowest requests:\n"))
+		for _, r := range analytics.SlowestRequests {
+			fmt.Printf("  %s %s %s %s %d\n", f.Duration(r.Duration), r.Time.Format(time.RFC3339), r.RemoteHost, r.URL, r.Status)
+		}
+	}
+	if s := analytics.ResponseSizes; s != nil {
+		fmt.Print(f.Sprintf("response sizes: mean %s", f.Bytes(int64(s.Mean))))
+		names := make([]string, 0, len(s.Percentiles))
+		for name := range s.Percentiles {
+			names = append(names, name)
+		}
+		for _, name := range sortPercentiles(names) {
+			fmt.Printf(", %s %s", name, f.Bytes(int64(s.Percentiles[name])))
+		}
+		fmt.Println()
+	}
+	if len(analytics.LargestResponses) > 0 {
+		fmt.Print(f.Sprintf("largest responses:\n"))
+		for _, r := range analytics.LargestResponses {
+			fmt.Printf("  %s %s %s %s %d\n", f.Bytes(int64(r.Bytes)), r.Time.Format(time.RFC3339), r.RemoteHost, r.URL, r.Status)
+		}
+	}
+	if len(analytics.MatchedSamples) > 0 {
+		fmt.Print(f.Sprintf("sample of matched lines:\n"))
+		for _, line := range analytics.MatchedSamples {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if len(analytics.UnmatchedSamples) > 0 {
+		fmt.Print(f.Sprintf("sample of unmatched lines:\n"))
+		for _, line := range analytics.UnmatchedSamples {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if analytics.Sessions > 0 {
+		fmt.Print(f.Sprintf("sessions: %s, bounce rate: %s\n", f.Count(analytics.Sessions), f.Percent(analytics.BounceRate)))
+		fmt.Print(f.Sprintf("top landing pages: %v\n", analytics.TopLandingPages))
+		fmt.Print(f.Sprintf("top exit pages: %v\n", analytics.TopExitPages))
+	}
+	if len(analytics.TopScoredIPs) > 0 {
+		fmt.Print(f.Sprintf("top scored ips: %v\n", analytics.TopScoredIPs))
+	}
+	for _, field := range sortedKeys(analytics.TopEnrichedValues) {
+		fmt.Print(f.Sprintf("top %s: %v\n", field, analytics.TopEnrichedValues[field]))
+	}
+	for _, series := range sortedSeries(analytics.Timeseries) {
+		fmt.Print(f.Sprintf("%s timeseries:\n", series))
+		for _, bucket := range analytics.Timeseries[series] {
+			fmt.Printf("  %s: %s", bucket.Start.Format("2006-01-02 15:04"), shares(f, bucket.Counts))
+			if len(bucket.Annotations) > 0 {
+				fmt.Printf(" [%s]", strings.Join(bucket.Annotations, "; "))
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// printErrorLogAnalytics : Prints the levels, modules, top messages and
+// clients, and levels timeseries of error logs
+func printErrorLogAnalytics(f *display.Formatter, analytics *analyzer.ErrorLogAnalytics) {
+	fmt.Print(f.Sprintf("lines: %s, parse errors: %s\n", f.Count(analytics.Lines), f.Count(analytics.ParseErrors)))
+	fmt.Print(f.Sprintf("levels: %s\n", shares(f, analytics.Levels)))
+	if len(analytics.Modules) > 0 {
+		fmt.Print(f.Sprintf("modules: %s\n", shares(f, analytics.Modules)))
+	}
+	fmt.Print(f.Sprintf("top messages:\n"))
+	for _, message := range analytics.TopMessages {
+		fmt.Printf("  %s\n", message)
+	}
+	if len(analytics.TopClients) > 0 {
+		fmt.Print(f.Sprintf("top clients: %v\n", analytics.TopClients))
+	}
+	if len(analytics.Timeseries) > 0 {
+		fmt.Print(f.Sprintf("levels timeseries:\n"))
+		for _, bucket := range analytics.Timeseries {
+			fmt.Printf("  %s: %s\n", bucket.Start.Format("2006-01-02 15:04"), shares(f, bucket.Counts))
+		}
+	}
+}
+
+// printMovers : Prints the risers and fallers of URLs or IPs
+func printMovers(f *display.Formatter, keys string, movers *analyzer.Movers) {
+	for _, list := range []struct {
+		label  string
+		movers []*analyzer.Mover
+	}{{"top rising %s:\n", movers.Risers}, {"top falling %s:\n", movers.Fallers}} {
+		if len(list.movers) == 0 {
+			continue
+		}
+		fmt.Print(f.Sprintf(list.label, keys))
+		for _, m := range list.movers {
+			fmt.Printf("  %s: %s → %s (%s)\n", m.Key, f.Count(m.Previous), f.Count(m.Current), f.Percent(m.Change))
+		}
+	}
+}
+
+// shares : The counts with their share of the total, e.g. "bot 1 (25.0%), desktop 3 (75.0%)"
+func shares(f *display.Formatter, counts map[string]int) string {
+	labels := make([]string, 0, len(counts))
+	total := 0
+	for label, count := range counts {
+		labels = append(labels, label)
+		total += count
+	}
+	sort.Strings(labels)
+	parts := make([]string, 0, len(labels))
+	for _, label := range labels {
+		parts = append(parts, fmt.Sprintf("%s %s (%s)", label, f.Count(counts[label]), f.Percent(float64(counts[label])/float64(total))))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortPercentiles : Sorts percentile names in increasing order, e.g. p50, p99, p99.9
+func sortPercentiles(names []string) []string {
+	sort.Slice(names, func(i, j int) bool {
+		pi, _ := strconv.ParseFloat(strings.TrimPrefix(names[i], "p"), 64)
+		pj, _ := strconv.ParseFloat(strings.TrimPrefix(names[j], "p"), 64)
+		return pi < pj
 	})
+	return names
+}
+
+func sortedSeries(m map[string][]*analyzer.TimeseriesBucket) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compareBaseline : Prints the deviations of the run from the trailing days,
+// then saves it as today's snapshot, pruning the ones older than retention
+// days when set. A failing store is reported and does not stop the run.
+func compareBaseline(f *display.Formatter, store *baseline.Store, days int, threshold float64, retention int, analytics *analyzer.LogAnalytics) {
+	today := time.Now()
+	snapshot := baseline.NewSnapshot(today, analytics)
+	snapshots, err := store.Trailing(today, days)
+	if err != nil {
+		log.Printf("baseline: %v", err)
+		return
+	}
+	if len(snapshots) > 0 {
+		deviations := baseline.Compare(snapshot.Metrics, snapshots, threshold)
+		if len(deviations) > 0 {
+			fmt.Print(f.Sprintf("deviations from the %d-day baseline:\n", days))
+			for _, d := range deviations {
+				fmt.Print(f.Sprintf("  %s: %s, baseline %s (%s)\n", d.Metric, f.Number(d.Value), f.Number(d.Baseline), f.Percent(d.Change)))
+			}
+		}
+	}
+	if err := store.Save(snapshot); err != nil {
+		log.Printf("baseline: %v", err)
+		return
+	}
+	if retention > 0 {
+		if err := store.Prune(today, retention); err != nil {
+			log.Printf("baseline: %v", err)
+		}
+	}
+}
 
-	analytics, err := logAnalyzer.Analyze("./analyzer/test-data/programming-task.log")
+// verifyAnalytics : Compares the analytics against the expected ones stored
+// at path, printing the differences, or stores them there when update is
+// set. Whether they matched.
+func verifyAnalytics(path string, update bool, tolerances *verify.Tolerances, analytics *analyzer.LogAnalytics) bool {
+	if update {
+		file, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := verify.Write(file, analytics); err != nil {
+			log.Fatal(err)
+		}
+		if err := file.Close(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\nexpected analytics written to %s\n", path)
+		return true
+	}
+	file, err := os.Open(path)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer file.Close()
+	expected, err := verify.Read(file)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	differences, err := verify.Compare(expected.Analytics, analytics, tolerances)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(differences) == 0 {
+		fmt.Printf("\nverified against %s\n", path)
+		return true
+	}
+	fmt.Printf("\ndifferences from %s:\n", path)
+	for _, d := range differences {
+		fmt.Printf("  %s\n", d)
+	}
+	return false
+}
+
+// loadState : Loads the analyzer state saved at statePath, if any
+func loadState(logAnalyzer analyzer.LogAnalyzer, statePath string) error {
+	file, err := os.Open(statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return logAnalyzer.LoadState(file)
+}
+
+// saveState : Saves the analyzer state to statePath, written aside first so an
+// interrupted save leaves the previous state
+func saveState(logAnalyzer analyzer.LogAnalyzer, statePath string) error {
+	file, err := os.Create(statePath + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := logAnalyzer.SaveState(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(statePath+".tmp", statePath)
+}
+
+// loadSeenIPs : Reads the seen IPs filter at path, a new one sized for
+// capacity IPs when there is none yet
+func loadSeenIPs(path string, capacity int) (*analyzer.SeenIPs, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return analyzer.NewSeenIPs(capacity), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return analyzer.ReadSeenIPs(bufio.NewReader(file))
+}
 
-	fmt.Printf("unique ips count: %d\n", analytics.UniqueIPCount)
-	fmt.Printf("most visited urls: %v\n", analytics.MostVisitedURLs)
-	fmt.Printf("most active ips: %v\n", analytics.MostActiveIPs)
+// saveSeenIPs : Writes the seen IPs filter to path, aside first so an
+// interrupted save leaves the previous filter
+func saveSeenIPs(seenIPs *analyzer.SeenIPs, path string) error {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	if err := seenIPs.Write(writer); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// writeSinks : Delivers the report to the plugin sinks. A failing sink is
+// reported and does not stop the run.
+func writeSinks(sinks []analyzer.Sink, analytics *analyzer.LogAnalytics) {
+	for _, sink := range sinks {
+		if err := sink.Write(analytics); err != nil {
+			log.Printf("sink %s: %v", sink.Name(), err)
+		}
+	}
+}
+
+// flushSinks : Writes the reports the sinks still hold in a batch, before
+// exiting
+func flushSinks(sinks []analyzer.Sink) {
+	for _, sink := range sinks {
+		if reliableSink, ok := sink.(*analyzer.ReliableSink); ok {
+			if err := reliableSink.Flush(); err != nil {
+				log.Printf("sink %s: %v", sink.Name(), err)
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reloadConfig : Applies the config file to the running analyzer on every
+// reload signal. A config that fails to load is reported and ignored.
+func reloadConfig(configPath string, lineRegex *regexp.Regexp, logAnalyzer analyzer.LogAnalyzer) {
+	if len(reloadSignals) == 0 {
+		return
+	}
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, reloadSignals...)
+	for range reloadCh {
+		config, err := loadConfig(configPath)
+		var analyzerConfig *analyzer.LogAnalyzerConfig
+		if err == nil {
+			analyzerConfig, err = config.analyzerConfig(lineRegex)
+		}
+		if err == nil {
+			err = logAnalyzer.Reconfigure(analyzerConfig)
+		}
+		if err != nil {
+			log.Printf("config not reloaded: %v", err)
+			continue
+		}
+		log.Printf("config reloaded from %s", configPath)
+	}
 }
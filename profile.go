@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+// startProfiles : Starts profiling the CPU into dir/cpu.out. The returned
+// function stops it and writes the heap profile into dir/mem.out, once the
+// analysis is done.
+func startProfiles(dir string) (func(), error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	cpuFile, err := os.Create(filepath.Join(dir, "cpu.out"))
+	if err != nil {
+		return nil, err
+	}
+	if err := runtimepprof.StartCPUProfile(cpuFile); err != nil {
+		cpuFile.Close()
+		return nil, err
+	}
+	return func() {
+		runtimepprof.StopCPUProfile()
+		if err := cpuFile.Close(); err != nil {
+			log.Print(err)
+		}
+		memFile, err := os.Create(filepath.Join(dir, "mem.out"))
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		defer memFile.Close()
+		// the heap profile is as of the last garbage collection
+		runtime.GC()
+		if err := runtimepprof.WriteHeapProfile(memFile); err != nil {
+			log.Print(err)
+		}
+	}, nil
+}
+
+// pprofHandler : The net/http/pprof endpoints under /debug/pprof/, on a mux
+// of their own rather than http.DefaultServeMux
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
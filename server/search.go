@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+// maxSearchLimit : Most lines a search answers
+const maxSearchLimit = 1000
+
+// ServeSearch : Also serves GET /search, the recent lines of the index
+// matching its parameters, newest first:
+//
+//	ip      client address, e.g. 1.2.3.4
+//	status  response status, e.g. 404
+//	url     URL path prefix, e.g. /api/
+//	since   lines logged in the last duration, e.g. 1h, or since an RFC 3339 time
+//	limit   lines answered, analyzer.DefaultSearchLimit when not set
+func (s *Server) ServeSearch(index *analyzer.LineIndex) {
+	s.mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		query, err := searchQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, index.Search(query))
+	})
+}
+
+func searchQuery(r *http.Request) (*analyzer.SearchQuery, error) {
+	params := r.URL.Query()
+	query := &analyzer.SearchQuery{
+		RemoteHost: params.Get("ip"),
+		URLPrefix:  params.Get("url"),
+	}
+	var err error
+	if status := params.Get("status"); status != "" {
+		if query.Status, err = strconv.Atoi(status); err != nil {
+			return nil, err
+		}
+	}
+	if since := params.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			query.Since = time.Now().Add(-d)
+		} else if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, err
+		}
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, err
+		}
+	}
+	if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+	return query, nil
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrReadingCA :
+	ErrReadingCA = "error reading CA file"
+	// ErrNoCACertificates :
+	ErrNoCACertificates = "no certificates in CA file"
+	// ErrLoadingCertificate :
+	ErrLoadingCertificate = "error loading certificate"
+)
+
+// TLS : TLS of a server, or of a client of one, from PEM files
+type TLS struct {
+	// CertFile, KeyFile : The server's certificate, or the client's for
+	// mutual TLS
+	CertFile string
+	KeyFile  string
+	// CAFile : CAs verifying the peer instead of the system ones. A server
+	// then requires clients to present a certificate they signed (mutual
+	// TLS).
+	CAFile string
+	// ServerName : Name the client verifies the server's certificate for,
+	// the host of the URL when not set
+	ServerName string
+}
+
+// ServerConfig : The TLS config of a server
+func (t *TLS) ServerConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrLoadingCertificate)
+	}
+	config.Certificates = []tls.Certificate{certificate}
+	if t.CAFile != "" {
+		if config.ClientCAs, err = loadCA(t.CAFile); err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// ClientConfig : The TLS config of a client
+func (t *TLS) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: t.ServerName}
+	if t.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, ErrLoadingCertificate)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if t.CAFile != "" {
+		var err error
+		if config.RootCAs, err = loadCA(t.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func loadCA(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, ErrReadingCA)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Wrap(errors.New(ErrNoCACertificates), caFile)
+	}
+	return pool, nil
+}
+
+// Auth : Credentials of requests, checked by a server or sent by a client.
+// Either basic auth or a bearer token.
+type Auth struct {
+	Username string
+	Password string
+	// BearerToken : Sent as "Authorization: Bearer <token>"
+	BearerToken string
+	// Tenants : Tokens a server also accepts, seeing the analytics of some
+	// sources only, on /analytics only
+	Tenants []*Tenant
+}
+
+// Handler : Answers 401 to requests without the basic auth, bearer token or
+// tenant credentials, but for /healthz, left open to load balancer health
+// checks, and the page of /, which asks for a token itself. Answers 403 to
+// tenants asking for more than /analytics.
+func (a *Auth) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/" || a.authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if tenant := a.tenant(r); tenant != nil {
+			if r.URL.Path != "/analytics" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, withTenant(r, tenant))
+			return
+		}
+		if a.BearerToken != "" || len(a.Tenants) > 0 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		} else {
+			w.Header().Set("WWW-Authenticate", `Basic realm="http-log-parser"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func (a *Auth) authorized(r *http.Request) bool {
+	if a.BearerToken != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(a.BearerToken)) == 1
+	}
+	if a.Username != "" {
+		username, password, ok := r.BasicAuth()
+		return ok && subtle.ConstantTimeCompare([]byte(username), []byte(a.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(a.Password)) == 1
+	}
+	return len(a.Tenants) == 0
+}
+
+// tenant : The tenant of the request's bearer token, if any
+func (a *Auth) tenant(r *http.Request) *Tenant {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return nil
+	}
+	token := []byte(strings.TrimPrefix(authorization, "Bearer "))
+	for _, tenant := range a.Tenants {
+		if tenant.Token != "" && subtle.ConstantTimeCompare(token, []byte(tenant.Token)) == 1 {
+			return tenant
+		}
+	}
+	return nil
+}
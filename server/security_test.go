@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuth_Handler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name     string
+		auth     *Auth
+		path     string
+		sendAuth *Auth
+		want     int
+	}{
+		{"bearer", &Auth{BearerToken: "t0ken"}, "/metrics", &Auth{BearerToken: "t0ken"}, http.StatusOK},
+		{"wrong bearer", &Auth{BearerToken: "t0ken"}, "/metrics", &Auth{BearerToken: "other"}, http.StatusUnauthorized},
+		{"no credentials", &Auth{BearerToken: "t0ken"}, "/metrics", &Auth{}, http.StatusUnauthorized},
+		{"health check", &Auth{BearerToken: "t0ken"}, "/healthz", &Auth{}, http.StatusOK},
+		{"basic", &Auth{Username: "u", Password: "p"}, shardMethod, &Auth{Username: "u", Password: "p"}, http.StatusOK},
+		{"wrong password", &Auth{Username: "u", Password: "p"}, shardMethod, &Auth{Username: "u", Password: "x"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			// as a Coordinator sends them
+			headers, err := rpcCredentials{tt.sendAuth}.GetRequestMetadata(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range headers {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			tt.auth.Handler(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLS_ClientConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(New(nil))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := http.Get(ts.URL + "/healthz"); err == nil {
+		t.Error("http.Get() trusted the test server's certificate")
+	}
+	tlsConfig, err := (&TLS{CAFile: caFile}).ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("TLS.ClientConfig() client error = %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,73 @@
+// Package server exposes a running analyzer over HTTP, so the analyzer itself
+// can be monitored while it follows a log, or can analyze shards of logs for
+// a coordinator.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Server : HTTP handler serving
+//
+//	/           a page browsing the analytics
+//	/healthz    200 "ok" while the analyzer runs
+//	/metrics    the analyzer's self-metrics as JSON
+//	/analytics  the latest analytics of the sources, as published
+//	/search     recent lines, once ServeSearch is called
+//	/httplogparser.Shard/Analyze  gRPC method answering the partial counts
+//	            of a shard of logs, once ServeShards is called
+type Server struct {
+	logAnalyzer analyzer.LogAnalyzer
+	mux         *http.ServeMux
+	// analytics : The latest analytics published, by source
+	analyticsMu sync.RWMutex
+	analytics   map[string]*analyzer.LogAnalytics
+}
+
+// New : Returns a server for the analyzer
+func New(logAnalyzer analyzer.LogAnalyzer) *Server {
+	s := &Server{
+		logAnalyzer: logAnalyzer,
+		mux:         http.NewServeMux(),
+		analytics:   make(map[string]*analyzer.LogAnalytics),
+	}
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/metrics", s.selfMetrics)
+	s.mux.HandleFunc("/analytics", s.serveAnalytics)
+	s.mux.Handle("/", uiHandler())
+	return s
+}
+
+// ServeHTTP : Implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// H2C : The handler, also serving HTTP/2 over plain HTTP, as the gRPC calls
+// of a Coordinator to its http workers are. It wraps the outermost handler,
+// e.g. the one checking auth, which then checks every call.
+func H2C(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+func (s *Server) selfMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.logAnalyzer.SelfMetrics())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+	"github.com/sdileep/http-log-parser/formats"
+)
+
+func newTestServer(t *testing.T) *Server {
+	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{
+		LineRegex: regexp.MustCompile(`^(\S+) (\S+) (\S+) (\S+) (\S+) (\S+) (\S+) (\S+) (\S+) (\S+)$`),
+	})
+	if err != nil {
+		t.Fatalf("error creating analyzer: %v", err)
+	}
+	return New(logAnalyzer)
+}
+
+func TestServer_healthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Errorf("GET /healthz = %d %q, want 200 \"ok\\n\"", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServer_ui(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		target   string
+		wantCode int
+	}{
+		{"/", http.StatusOK},
+		{"/unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("GET %s = %d, want %d", tt.target, rec.Code, tt.wantCode)
+		}
+		if tt.wantCode == http.StatusOK && !strings.Contains(rec.Body.String(), "fetch(\"analytics\"") {
+			t.Errorf("GET %s is not the analytics page", tt.target)
+		}
+	}
+}
+
+func TestServer_metrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want 200", rec.Code)
+	}
+	var got analyzer.SelfMetrics
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("GET /metrics body is not self-metrics JSON: %v", err)
+	}
+	if got.StartedAt.IsZero() {
+		t.Errorf("GET /metrics StartedAt is not set")
+	}
+}
+
+func TestCoordinator_Run(t *testing.T) {
+	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{
+		LineRegex:            formats.CombinedLog.LineRegex,
+		MostActiveIPsCount:   1,
+		MostVisitedURLsCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("error creating analyzer: %v", err)
+	}
+	worker := New(logAnalyzer)
+	worker.ServeShards("../analyzer/test-data")
+	auth := &Auth{BearerToken: "t0ken"}
+	plain := httptest.NewServer(H2C(auth.Handler(worker)))
+	defer plain.Close()
+	secure := httptest.NewUnstartedServer(auth.Handler(worker))
+	secure.EnableHTTP2 = true
+	secure.StartTLS()
+	defer secure.Close()
+
+	coordinator := &Coordinator{Workers: []string{plain.URL, secure.URL}, TLS: secure.Client().Transport.(*http.Transport).TLSClientConfig}
+	if _, err := coordinator.Run(logAnalyzer, []string{"programming-task.log", "programming-task.log"}); err == nil {
+		t.Fatalf("Coordinator.Run() ran without the workers' credentials")
+	}
+	coordinator.Auth = auth
+	if _, err := coordinator.Run(logAnalyzer, []string{"programming-task.log", "../../go.mod"}); err == nil {
+		t.Fatalf("Coordinator.Run() read a file out of the worker's directory")
+	}
+	got, err := coordinator.Run(logAnalyzer, []string{"programming-task.log", "programming-task.log"})
+	if err != nil {
+		t.Fatalf("Coordinator.Run() error = %v", err)
+	}
+	want, err := logAnalyzer.AnalyzeFiles("../analyzer/test-data/programming-task.log", "../analyzer/test-data/programming-task.log")
+	if err != nil {
+		t.Fatalf("logAnalyzer.AnalyzeFiles() error = %v", err)
+	}
+	want.Sources = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Coordinator.Run() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCoordinator_Run_timeout(t *testing.T) {
+	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{LineRegex: formats.CombinedLog.LineRegex})
+	if err != nil {
+		t.Fatalf("error creating analyzer: %v", err)
+	}
+	// accepts connections, never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	coordinator := &Coordinator{Workers: []string{"http://" + listener.Addr().String()}, Timeout: 100 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		_, err := coordinator.Run(logAnalyzer, []string{"programming-task.log"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Coordinator.Run() error = nil, want the worker to time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Coordinator.Run() waited for a worker that never replies")
+	}
+}
+
+func TestServer_analytics(t *testing.T) {
+	s := newTestServer(t)
+	s.Publish("/var/log/shop.log", &analyzer.LogAnalytics{UniqueIPCount: 1})
+	s.Publish("/var/log/blog.log", &analyzer.LogAnalytics{UniqueIPCount: 2})
+	auth := &Auth{
+		BearerToken: "admin",
+		Tenants:     []*Tenant{{Token: "shop", Sources: []string{"/var/log/shop*"}}},
+	}
+	handler := auth.Handler(s)
+
+	tests := []struct {
+		name        string
+		token       string
+		target      string
+		wantCode    int
+		wantSources []string
+	}{
+		{"admin sees every source", "admin", "/analytics", http.StatusOK, []string{"/var/log/blog.log", "/var/log/shop.log"}},
+		{"tenant sees its sources", "shop", "/analytics", http.StatusOK, []string{"/var/log/shop.log"}},
+		{"tenant source", "shop", "/analytics?source=/var/log/shop.log", http.StatusOK, nil},
+		{"other tenant's source", "shop", "/analytics?source=/var/log/blog.log", http.StatusForbidden, nil},
+		{"tenant metrics", "shop", "/metrics", http.StatusForbidden, nil},
+		{"unknown source", "admin", "/analytics?source=/var/log/none.log", http.StatusNotFound, nil},
+		{"unknown token", "other", "/analytics", http.StatusUnauthorized, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("GET %s = %d, want %d", tt.target, rec.Code, tt.wantCode)
+			}
+			if tt.wantSources == nil {
+				return
+			}
+			var got map[string]*analyzer.LogAnalytics
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			var sources []string
+			for source := range got {
+				sources = append(sources, source)
+			}
+			sort.Strings(sources)
+			if !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("GET %s sources = %v, want %v", tt.target, sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestServer_search(t *testing.T) {
+	index := analyzer.NewLineIndex(10, 0)
+	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{
+		LineRegex: formats.CombinedLog.LineRegex,
+		LineIndex: index,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(logAnalyzer)
+	s.ServeSearch(index)
+
+	tests := []struct {
+		target   string
+		wantCode int
+	}{
+		{"/search?ip=1.2.3.4&status=404&url=/api/&since=1h&limit=10", http.StatusOK},
+		{"/search?since=2018-07-10T22:00:00Z", http.StatusOK},
+		{"/search?status=notfound", http.StatusBadRequest},
+		{"/search?since=yesterday", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("GET %s = %d, want %d", tt.target, rec.Code, tt.wantCode)
+		}
+	}
+}
+
+func TestServer_search_lines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "access.log")
+	line := `177.71.128.21 - - [10/Jul/2018:22:21:28 +0200] "GET /intranet-analytics/ HTTP/1.1" 200 3574 "-" "curl/7.58.0"` + "\n"
+	if err := ioutil.WriteFile(filePath, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	index := analyzer.NewLineIndex(10, 0)
+	logAnalyzer, err := analyzer.NewLogAnalyzer(&analyzer.LogAnalyzerConfig{
+		LineRegex:          formats.CombinedLog.LineRegex,
+		LineIndex:          index,
+		FollowPollInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logAnalyzer.Follow(ctx, filePath)
+	s := New(logAnalyzer)
+	s.ServeSearch(index)
+
+	// the UI shows the request built from these fields
+	want := map[string]interface{}{"Method": "GET", "URL": "/intranet-analytics/", "Protocol": "HTTP/1.1"}
+	timeout := time.After(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+		var lines []struct{ Line map[string]interface{} }
+		if err := json.Unmarshal(rec.Body.Bytes(), &lines); err != nil {
+			t.Fatalf("GET /search = %q, not JSON: %v", rec.Body.String(), err)
+		}
+		if len(lines) == 1 {
+			for key, value := range want {
+				if lines[0].Line[key] != value {
+					t.Errorf("GET /search line %s = %v, want %v", key, lines[0].Line[key], value)
+				}
+			}
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("GET /search = %d lines, want 1", len(lines))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/analyzer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ErrNoWorkers :
+	ErrNoWorkers = "no shard workers"
+	// ErrShardFailed :
+	ErrShardFailed = "shard failed"
+	// ErrInvalidWorker :
+	ErrInvalidWorker = "invalid shard worker URL"
+
+	// DefaultShardTimeout : How long a worker may take to answer its shard
+	DefaultShardTimeout = 10 * time.Minute
+)
+
+// shardMethod : The gRPC method workers analyze shards on
+const shardMethod = "/httplogparser.Shard/Analyze"
+
+// ShardRequest : The log files of a shard, relative to the worker's directory
+type ShardRequest struct {
+	Files []string `json:"files"`
+}
+
+// jsonCodec : Encodes the shard messages as JSON, which partials already
+// serialize to, rather than as protocol buffers
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// shardAnalyzer : What the shard service is implemented by
+type shardAnalyzer interface {
+	analyzeShard(shard *ShardRequest) (*analyzer.Partial, error)
+}
+
+// shardService : The gRPC service of shard workers, with the single unary
+// method Analyze answering the analyzer.Partial of a ShardRequest
+var shardService = grpc.ServiceDesc{
+	ServiceName: "httplogparser.Shard",
+	HandlerType: (*shardAnalyzer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Analyze",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+			var shard ShardRequest
+			if err := dec(&shard); err != nil {
+				return nil, err
+			}
+			return srv.(shardAnalyzer).analyzeShard(&shard)
+		},
+	}},
+}
+
+// shardWorker : Analyzes the shards of the log files of dir
+type shardWorker struct {
+	logAnalyzer analyzer.LogAnalyzer
+	dir         string
+}
+
+func (w *shardWorker) analyzeShard(shard *ShardRequest) (*analyzer.Partial, error) {
+	filePaths := make([]string, 0, len(shard.Files))
+	for _, file := range shard.Files {
+		// rooted first, so ".." cannot leave dir
+		filePaths = append(filePaths, filepath.Join(w.dir, filepath.Clean("/"+file)))
+	}
+	partial, err := w.logAnalyzer.AnalyzePartial(filePaths...)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return partial, nil
+}
+
+// ServeShards : Also serves the gRPC method httplogparser.Shard/Analyze,
+// analyzing the files of a ShardRequest and answering their
+// analyzer.Partial, JSON encoded, so the server acts as the worker of a
+// Coordinator. Files are read from dir only, whatever their path. gRPC
+// needs HTTP/2: over TLS, or over plain HTTP through H2C.
+func (s *Server) ServeShards(dir string) {
+	grpcServer := grpc.NewServer()
+	grpcServer.RegisterService(&shardService, &shardWorker{logAnalyzer: s.logAnalyzer, dir: dir})
+	s.mux.Handle("/httplogparser.Shard/", grpcServer)
+}
+
+// Coordinator : Leader of shard workers, e.g. one per host holding logs. It
+// splits the log files among the workers, then merges their partials.
+type Coordinator struct {
+	// Workers : Base URLs of the workers, e.g. http://10.0.0.2:8080, or
+	// https:// for workers serving TLS
+	Workers []string
+	// TLS : The TLS config of https workers, the system's when not set
+	TLS *tls.Config
+	// Auth : The credentials sent to the workers, basic auth or a bearer
+	// token, nil for none
+	Auth *Auth
+	// Timeout : How long a worker may take to answer its shard,
+	// DefaultShardTimeout when not set. A worker past it fails the run.
+	Timeout time.Duration
+}
+
+// Run : Analyzes the files across the workers, each getting every nth file,
+// and reports them merged by logAnalyzer, which must share the workers'
+// config. Any failing shard fails the run.
+func (c *Coordinator) Run(logAnalyzer analyzer.LogAnalyzer, files []string) (*analyzer.LogAnalytics, error) {
+	if len(c.Workers) == 0 {
+		return nil, errors.New(ErrNoWorkers)
+	}
+	shards := make([][]string, len(c.Workers))
+	for i, file := range files {
+		shards[i%len(shards)] = append(shards[i%len(shards)], file)
+	}
+
+	partials := make([]*analyzer.Partial, len(shards))
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i := range shards {
+		if len(shards[i]) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			partials[i], errs[i] = c.runShard(c.Workers[i], shards[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, errors.Wrap(errors.Wrap(err, c.Workers[i]), ErrShardFailed)
+		}
+	}
+	return logAnalyzer.MergePartials(partials...), nil
+}
+
+func (c *Coordinator) runShard(worker string, files []string) (*analyzer.Partial, error) {
+	workerURL, err := url.Parse(worker)
+	if err != nil || workerURL.Host == "" {
+		return nil, errors.New(ErrInvalidWorker)
+	}
+	transport := insecure.NewCredentials()
+	switch workerURL.Scheme {
+	case "https":
+		tlsConfig := c.TLS
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		transport = credentials.NewTLS(tlsConfig)
+	case "http":
+	default:
+		return nil, errors.New(ErrInvalidWorker)
+	}
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transport)}
+	if c.Auth != nil {
+		options = append(options, grpc.WithPerRPCCredentials(rpcCredentials{c.Auth}))
+	}
+	conn, err := grpc.Dial(workerURL.Host, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultShardTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var partial analyzer.Partial
+	err = conn.Invoke(ctx, shardMethod, &ShardRequest{Files: files}, &partial, grpc.CallContentSubtype(jsonCodec{}.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return &partial, nil
+}
+
+// rpcCredentials : Sends the basic auth or bearer token of auth as the
+// Authorization header of every call, as Auth.Handler checks it
+type rpcCredentials struct {
+	auth *Auth
+}
+
+func (c rpcCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	switch {
+	case c.auth.BearerToken != "":
+		return map[string]string{"authorization": "Bearer " + c.auth.BearerToken}, nil
+	case c.auth.Username != "":
+		credentials := base64.StdEncoding.EncodeToString([]byte(c.auth.Username + ":" + c.auth.Password))
+		return map[string]string{"authorization": "Basic " + credentials}, nil
+	}
+	return nil, nil
+}
+
+// RequireTransportSecurity : Credentials may be sent over plain HTTP, as the
+// HTTP client sends them
+func (c rpcCredentials) RequireTransportSecurity() bool {
+	return false
+}
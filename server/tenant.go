@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+// Tenant : A team querying the analytics of its own sites only, e.g. one log
+// per vhost, with an API token of its own
+type Tenant struct {
+	// Token : Sent as "Authorization: Bearer <token>"
+	Token string
+	// Sources : Patterns of the sources the tenant sees, as of path.Match,
+	// e.g. "/var/log/nginx/shop.*.log"
+	Sources []string
+}
+
+// allows : Whether the source matches one of the tenant's patterns
+func (t *Tenant) allows(source string) bool {
+	for _, pattern := range t.Sources {
+		if matched, _ := path.Match(pattern, source); matched {
+			return true
+		}
+	}
+	return false
+}
+
+type tenantKey struct{}
+
+// tenantOf : The tenant of the request, nil for unscoped credentials or none
+func tenantOf(r *http.Request) *Tenant {
+	tenant, _ := r.Context().Value(tenantKey{}).(*Tenant)
+	return tenant
+}
+
+func withTenant(r *http.Request, tenant *Tenant) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+}
+
+// Publish : Makes the analytics the latest of the source, e.g. the log file
+// being followed, served on /analytics
+func (s *Server) Publish(source string, analytics *analyzer.LogAnalytics) {
+	s.analyticsMu.Lock()
+	defer s.analyticsMu.Unlock()
+	s.analytics[source] = analytics
+}
+
+// serveAnalytics : The latest analytics of ?source=, or of every source by
+// name; tenants only see, and may only ask for, the sources they are scoped
+// to
+func (s *Server) serveAnalytics(w http.ResponseWriter, r *http.Request) {
+	tenant := tenantOf(r)
+	s.analyticsMu.RLock()
+	defer s.analyticsMu.RUnlock()
+
+	if source := r.URL.Query().Get("source"); source != "" {
+		if tenant != nil && !tenant.allows(source) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		analytics, ok := s.analytics[source]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, analytics)
+		return
+	}
+
+	sources := make([]string, 0, len(s.analytics))
+	for source := range s.analytics {
+		if tenant == nil || tenant.allows(source) {
+			sources = append(sources, source)
+		}
+	}
+	sort.Strings(sources)
+	visible := make(map[string]*analyzer.LogAnalytics, len(sources))
+	for _, source := range sources {
+		visible[source] = s.analytics[source]
+	}
+	writeJSON(w, visible)
+}
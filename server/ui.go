@@ -0,0 +1,22 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// ui : A single page browsing /analytics, with sortable tables and time
+// series charts
+//
+//go:embed ui
+var ui embed.FS
+
+// uiHandler : Serves the page on /, and 404 on other unknown paths
+func uiHandler() http.Handler {
+	files, err := fs.Sub(ui, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// snapshotSignals : Signals that make a follow run print a snapshot right away
+var snapshotSignals = []os.Signal{syscall.SIGUSR1}
+
+// reloadSignals : Signals that make a follow run reload its config file
+var reloadSignals = []os.Signal{syscall.SIGHUP}
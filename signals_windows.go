@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+// snapshotSignals : Windows has no SIGUSR1, follow runs only print periodic snapshots
+var snapshotSignals []os.Signal
+
+// reloadSignals : Windows has no SIGHUP, the config file is only read at start
+var reloadSignals []os.Signal
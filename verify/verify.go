@@ -0,0 +1,215 @@
+// Package verify compares a fresh analysis against expected analytics stored
+// as JSON, within tolerances, to regression-test changes to a logging
+// pipeline: the log format, the config or the analyzer itself.
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+const (
+	// ErrReadingExpected :
+	ErrReadingExpected = "error reading expected analytics"
+	// ErrUnsupportedVersion :
+	ErrUnsupportedVersion = "unsupported version of expected analytics"
+)
+
+// Version : The version of the expected analytics files written by Write.
+// It changes when the analytics change in ways old files cannot be compared
+// with, which Read then rejects.
+const Version = 1
+
+// DefaultIgnored : The analytics left out of the comparison by default, as
+// they differ from one run to the next: lines sampled at random
+var DefaultIgnored = []string{"MatchedSamples", "UnmatchedSamples"}
+
+// Expected : The analytics a run is expected to report, as stored
+type Expected struct {
+	Version   int                    `json:"version"`
+	Analytics *analyzer.LogAnalytics `json:"analytics"`
+}
+
+// Tolerances : How far the numbers of a run may be from the expected ones.
+// A number is within tolerance when it is within Absolute of the expected
+// one, or within Relative of it relative to the expected one, e.g. 0.01 for
+// 1%. Paths name analytics as Difference does, e.g. "Timeseries" or
+// "RequestsByVHost.example.com", and apply to what they contain.
+type Tolerances struct {
+	Relative float64
+	Absolute float64
+	// Paths : Relative tolerances of some analytics, by path, overriding
+	// Relative for them
+	Paths map[string]float64
+	// Ignored : The paths of analytics not compared, DefaultIgnored when nil
+	Ignored []string
+}
+
+// Difference : An analytic of a run departing from the expected one
+type Difference struct {
+	// Path : Where the analytic is, as JSON field names and keys separated by
+	// dots, with list indexes in brackets, e.g. "Upstreams[0].Requests"
+	Path string
+	// Expected, Actual : The values, as decoded from JSON, nil when missing
+	Expected interface{}
+	Actual   interface{}
+}
+
+// String : The difference, as printed
+func (d *Difference) String() string {
+	switch {
+	case d.Actual == nil:
+		return fmt.Sprintf("%s: missing, expected %s", d.Path, value(d.Expected))
+	case d.Expected == nil:
+		return fmt.Sprintf("%s: unexpected %s", d.Path, value(d.Actual))
+	}
+	return fmt.Sprintf("%s: %s, expected %s", d.Path, value(d.Actual), value(d.Expected))
+}
+
+// value : A value of a difference, as JSON
+func value(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Write : Writes the analytics as the expected ones of later runs
+func Write(w io.Writer, analytics *analyzer.LogAnalytics) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&Expected{Version: Version, Analytics: analytics})
+}
+
+// Read : Reads expected analytics written by Write
+func Read(r io.Reader) (*Expected, error) {
+	var expected Expected
+	if err := json.NewDecoder(r).Decode(&expected); err != nil {
+		return nil, errors.Wrap(err, ErrReadingExpected)
+	}
+	if expected.Version != Version {
+		return nil, errors.Wrap(errors.New(ErrUnsupportedVersion), strconv.Itoa(expected.Version))
+	}
+	if expected.Analytics == nil {
+		expected.Analytics = &analyzer.LogAnalytics{}
+	}
+	return &expected, nil
+}
+
+// Compare : The differences of the actual analytics from the expected ones,
+// in path order. Numbers are compared within the tolerances, any other value
+// exactly, and lists element by element. Nil tolerances compare numbers
+// exactly.
+func Compare(expected, actual *analyzer.LogAnalytics, tolerances *Tolerances) ([]*Difference, error) {
+	if tolerances == nil {
+		tolerances = &Tolerances{}
+	}
+	e, err := decoded(expected)
+	if err != nil {
+		return nil, err
+	}
+	a, err := decoded(actual)
+	if err != nil {
+		return nil, err
+	}
+	c := &comparison{tolerances: tolerances, ignored: make(map[string]bool)}
+	ignored := tolerances.Ignored
+	if ignored == nil {
+		ignored = DefaultIgnored
+	}
+	for _, path := range ignored {
+		c.ignored[path] = true
+	}
+	c.compare("", e, a, tolerances.Relative)
+	sort.Slice(c.differences, func(i, j int) bool { return c.differences[i].Path < c.differences[j].Path })
+	return c.differences, nil
+}
+
+// decoded : The analytics as generic JSON values, as they are stored
+func decoded(analytics *analyzer.LogAnalytics) (interface{}, error) {
+	data, err := json.Marshal(analytics)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	return v, json.Unmarshal(data, &v)
+}
+
+// comparison : The differences found so far
+type comparison struct {
+	tolerances  *Tolerances
+	ignored     map[string]bool
+	differences []*Difference
+}
+
+// compare : Compares the values at path, numbers within relative
+func (c *comparison) compare(path string, expected, actual interface{}, relative float64) {
+	if c.ignored[path] {
+		return
+	}
+	if r, ok := c.tolerances.Paths[path]; ok {
+		relative = r
+	}
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		if a, ok := actual.(map[string]interface{}); ok {
+			for key, v := range e {
+				c.compare(join(path, key), v, a[key], relative)
+			}
+			for key, v := range a {
+				if _, ok := e[key]; !ok {
+					c.compare(join(path, key), nil, v, relative)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := actual.([]interface{}); ok {
+			for i := 0; i < len(e) || i < len(a); i++ {
+				var ev, av interface{}
+				if i < len(e) {
+					ev = e[i]
+				}
+				if i < len(a) {
+					av = a[i]
+				}
+				c.compare(fmt.Sprintf("%s[%d]", path, i), ev, av, relative)
+			}
+			return
+		}
+	case float64:
+		if a, ok := actual.(float64); ok {
+			if diff := math.Abs(a - e); diff > c.tolerances.Absolute && diff > relative*math.Abs(e) {
+				c.differences = append(c.differences, &Difference{Path: path, Expected: e, Actual: a})
+			}
+			return
+		}
+	}
+	if !equal(expected, actual) {
+		c.differences = append(c.differences, &Difference{Path: path, Expected: expected, Actual: actual})
+	}
+}
+
+// equal : Whether the JSON values are the same
+func equal(expected, actual interface{}) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+	return value(expected) == value(actual)
+}
+
+// join : The path of key in path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
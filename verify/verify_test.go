@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sdileep/http-log-parser/analyzer"
+)
+
+func TestCompare(t *testing.T) {
+	expected := &analyzer.LogAnalytics{
+		UniqueIPCount:    100,
+		MostActiveIPs:    []string{"10.0.0.1", "10.0.0.2"},
+		RequestsByVHost:  map[string]int{"a.example.com": 1000, "b.example.com": 10},
+		BounceRate:       0.5,
+		MatchedSamples:   []string{"a line"},
+		UnmatchedSamples: []string{"another line"},
+	}
+	tests := []struct {
+		name       string
+		actual     *analyzer.LogAnalytics
+		tolerances *Tolerances
+		want       []string
+	}{
+		{name: "same", actual: expected},
+		{
+			name: "exact",
+			actual: &analyzer.LogAnalytics{
+				UniqueIPCount:   101,
+				MostActiveIPs:   []string{"10.0.0.2", "10.0.0.1", "10.0.0.3"},
+				RequestsByVHost: map[string]int{"a.example.com": 1000, "c.example.com": 1},
+				BounceRate:      0.5,
+			},
+			want: []string{
+				`MostActiveIPs[0]: "10.0.0.2", expected "10.0.0.1"`,
+				`MostActiveIPs[1]: "10.0.0.1", expected "10.0.0.2"`,
+				`MostActiveIPs[2]: unexpected "10.0.0.3"`,
+				`RequestsByVHost.b.example.com: missing, expected 10`,
+				`RequestsByVHost.c.example.com: unexpected 1`,
+				`UniqueIPCount: 101, expected 100`,
+			},
+		},
+		{
+			name:       "within relative tolerance",
+			actual:     &analyzer.LogAnalytics{UniqueIPCount: 101, MostActiveIPs: expected.MostActiveIPs, RequestsByVHost: map[string]int{"a.example.com": 990, "b.example.com": 10}, BounceRate: 0.504},
+			tolerances: &Tolerances{Relative: 0.01},
+		},
+		{
+			name:       "tolerance of a path",
+			actual:     &analyzer.LogAnalytics{UniqueIPCount: 101, MostActiveIPs: expected.MostActiveIPs, RequestsByVHost: map[string]int{"a.example.com": 900, "b.example.com": 12}, BounceRate: 0.5},
+			tolerances: &Tolerances{Relative: 0.01, Paths: map[string]float64{"RequestsByVHost": 0.1}},
+			want:       []string{`RequestsByVHost.b.example.com: 12, expected 10`},
+		},
+		{
+			name:       "absolute tolerance",
+			actual:     &analyzer.LogAnalytics{UniqueIPCount: 102, MostActiveIPs: expected.MostActiveIPs, RequestsByVHost: map[string]int{"a.example.com": 1000, "b.example.com": 12}, BounceRate: 0.5},
+			tolerances: &Tolerances{Absolute: 2},
+		},
+		{
+			name:       "ignored",
+			actual:     &analyzer.LogAnalytics{UniqueIPCount: 100, MostActiveIPs: []string{"10.0.0.9"}, RequestsByVHost: expected.RequestsByVHost, BounceRate: 0.5},
+			tolerances: &Tolerances{Ignored: []string{"MostActiveIPs"}},
+			want:       []string{`MatchedSamples: missing, expected ["a line"]`, `UnmatchedSamples: missing, expected ["another line"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differences, err := Compare(expected, tt.actual, tt.tolerances)
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			var got []string
+			for _, d := range differences {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	analytics := &analyzer.LogAnalytics{UniqueIPCount: 3, MostVisitedURLs: []string{"/a"}, RequestsByVHost: map[string]int{"example.com": 3}}
+	var buffer bytes.Buffer
+	if err := Write(&buffer, analytics); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	expected, err := Read(&buffer)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if expected.Version != Version || !reflect.DeepEqual(expected.Analytics, analytics) {
+		t.Errorf("Read() = %+v, want the analytics written", expected.Analytics)
+	}
+
+	for _, tt := range []struct {
+		data    string
+		wantErr string
+	}{
+		{data: `{"version": 2, "analytics": {}}`, wantErr: "2: " + ErrUnsupportedVersion},
+		{data: `{"UniqueIPCount": 3}`, wantErr: "0: " + ErrUnsupportedVersion},
+		{data: `not json`, wantErr: ErrReadingExpected},
+	} {
+		if _, err := Read(strings.NewReader(tt.data)); err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) && !strings.HasPrefix(err.Error(), tt.wantErr) {
+			t.Errorf("Read(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+		}
+	}
+}